@@ -0,0 +1,139 @@
+// Package auth authenticates requests with an API key or a JWT bearer token
+// and puts the resulting model.Principal in their context.
+package auth
+
+import (
+	"crypto/rsa"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"github.com/golang-jwt/jwt/v4"
+	"io/ioutil"
+	"net/http"
+	"precisely/model"
+	"precisely/service"
+	"precisely/utils"
+	"strings"
+)
+
+const APIKeyHeader = "X-API-Key"
+
+type Config struct {
+	// HS256Secret verifies HS256 tokens when set.
+	HS256Secret string
+	// RS256PublicKeyFile names the PEM encoded RSA public key verifying RS256
+	// tokens when set.
+	RS256PublicKeyFile string
+	// Issuer and Audience, when set, must match the iss and aud claims.
+	Issuer   string
+	Audience string
+	// AdminAPIKey, when set, authenticates as an admin without being stored,
+	// to create the first API keys.
+	AdminAPIKey string
+}
+
+type Authenticator struct {
+	hsSecret     []byte
+	rsKey        *rsa.PublicKey
+	methods      []string
+	issuer       string
+	audience     string
+	adminKeyHash string
+}
+
+// claims are the claims of a bearer token; sub names the principal, roles
+// holds its roles and groups the groups it is granted documents through.
+type claims struct {
+	Roles  []string `json:"roles"`
+	Groups []string `json:"groups"`
+	jwt.RegisteredClaims
+}
+
+func NewAuthenticator(cfg Config) (*Authenticator, error) {
+	a := &Authenticator{issuer: cfg.Issuer, audience: cfg.Audience}
+	if cfg.HS256Secret != "" {
+		a.hsSecret = []byte(cfg.HS256Secret)
+		a.methods = append(a.methods, jwt.SigningMethodHS256.Alg())
+	}
+	if cfg.RS256PublicKeyFile != "" {
+		pem, err := ioutil.ReadFile(cfg.RS256PublicKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		if a.rsKey, err = jwt.ParseRSAPublicKeyFromPEM(pem); err != nil {
+			return nil, fmt.Errorf("parsing %s: %w", cfg.RS256PublicKeyFile, err)
+		}
+		a.methods = append(a.methods, jwt.SigningMethodRS256.Alg())
+	}
+	if cfg.AdminAPIKey != "" {
+		a.adminKeyHash = model.HashAPIKey(cfg.AdminAPIKey)
+	}
+	return a, nil
+}
+
+// Middleware rejects the requests that are not authenticated with 401 and
+// hands the principal of the others to the next handler.
+func (a *Authenticator) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		principal, err := a.authenticate(r)
+		if err != nil {
+			if errors.Is(err, model.UnauthenticatedValue) {
+				w.Header().Set("WWW-Authenticate", `Bearer, ApiKey header="`+APIKeyHeader+`"`)
+				utils.JsonRespond(w, false, http.StatusUnauthorized, err, nil)
+				return
+			}
+			utils.RespondError(w, err)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(model.WithPrincipal(r.Context(), principal)))
+	})
+}
+
+func (a *Authenticator) authenticate(r *http.Request) (*model.Principal, error) {
+	if key := r.Header.Get(APIKeyHeader); key != "" {
+		if a.adminKeyHash != "" && subtle.ConstantTimeCompare([]byte(model.HashAPIKey(key)), []byte(a.adminKeyHash)) == 1 {
+			return &model.Principal{Subject: model.AdminSubject, Roles: []string{model.RoleAdmin}, Method: model.AuthAPIKey}, nil
+		}
+		return service.APIKeyService.Authenticate(r.Context(), key)
+	}
+	authorization := r.Header.Get("Authorization")
+	if len(authorization) > 7 && strings.EqualFold(authorization[:7], "Bearer ") {
+		return a.parseToken(authorization[7:])
+	}
+	return nil, model.UnauthenticatedValue
+}
+
+// parseToken verifies a bearer token with the configured keys. Every failure
+// is reported as UnauthenticatedValue, with the reason wrapped.
+func (a *Authenticator) parseToken(raw string) (*model.Principal, error) {
+	if len(a.methods) == 0 {
+		return nil, fmt.Errorf("%w: bearer tokens are not accepted", model.UnauthenticatedValue)
+	}
+	var c claims
+	parser := &jwt.Parser{ValidMethods: a.methods}
+	_, err := parser.ParseWithClaims(raw, &c, func(token *jwt.Token) (interface{}, error) {
+		if token.Method.Alg() == jwt.SigningMethodRS256.Alg() {
+			return a.rsKey, nil
+		}
+		return a.hsSecret, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", model.UnauthenticatedValue, err)
+	}
+	if c.Subject == "" || c.ExpiresAt == nil {
+		return nil, fmt.Errorf("%w: token must have sub and exp claims", model.UnauthenticatedValue)
+	}
+	if strings.HasPrefix(c.Subject, model.APIKeySubjectPrefix) {
+		return nil, fmt.Errorf("%w: token sub must not start with %s", model.UnauthenticatedValue, model.APIKeySubjectPrefix)
+	}
+	if a.issuer != "" && !c.VerifyIssuer(a.issuer, true) {
+		return nil, fmt.Errorf("%w: token has another issuer", model.UnauthenticatedValue)
+	}
+	if a.audience != "" && !c.VerifyAudience(a.audience, true) {
+		return nil, fmt.Errorf("%w: token has another audience", model.UnauthenticatedValue)
+	}
+	if c.Roles == nil {
+		c.Roles = []string{}
+	}
+	return &model.Principal{Subject: c.Subject, Roles: c.Roles, Groups: c.Groups, Method: model.AuthJWT}, nil
+}
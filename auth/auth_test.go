@@ -0,0 +1,164 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"precisely/model"
+	"precisely/service"
+	"precisely/utils"
+	"testing"
+	"time"
+)
+
+type apiKeyServiceMock struct{}
+
+func (m *apiKeyServiceMock) Create(context.Context, model.APIKey) (*model.APIKey, error) {
+	return nil, nil
+}
+
+func (m *apiKeyServiceMock) GetAll(context.Context) ([]*model.APIKey, error) {
+	return nil, nil
+}
+
+func (m *apiKeyServiceMock) Revoke(context.Context, int64) error {
+	return nil
+}
+
+func (m *apiKeyServiceMock) Authenticate(_ context.Context, key string) (*model.Principal, error) {
+	if key == "prk_valid" {
+		return &model.Principal{Subject: "apikey:1", Roles: []string{"writer"}, Method: model.AuthAPIKey}, nil
+	}
+	return nil, model.UnauthenticatedValue
+}
+
+func newAuthenticator(t *testing.T) (*Authenticator, *rsa.PrivateKey) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "jwt.pem")
+	if err := ioutil.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	a, err := NewAuthenticator(Config{
+		HS256Secret:        "secret",
+		RS256PublicKeyFile: path,
+		Issuer:             "https://issuer.example",
+		AdminAPIKey:        "prk_admin",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return a, key
+}
+
+func sign(t *testing.T, method jwt.SigningMethod, key interface{}, c claims) string {
+	token, err := jwt.NewWithClaims(method, c).SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+func serve(a *Authenticator, header, value string) (*httptest.ResponseRecorder, *model.Principal) {
+	var principal *model.Principal
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		principal = model.PrincipalFrom(r.Context())
+	})
+	req, _ := http.NewRequest(http.MethodGet, "/documents", nil)
+	if header != "" {
+		req.Header.Set(header, value)
+	}
+	rr := httptest.NewRecorder()
+	a.Middleware(next).ServeHTTP(rr, req)
+	return rr, principal
+}
+
+func TestMiddleware(t *testing.T) {
+	service.APIKeyService = &apiKeyServiceMock{}
+	a, rsaKey := newAuthenticator(t)
+	valid := claims{
+		Roles:  []string{"reader"},
+		Groups: []string{"legal"},
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   "alice",
+			Issuer:    "https://issuer.example",
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+		},
+	}
+	expired := valid
+	expired.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-time.Minute))
+	foreign := valid
+	foreign.Issuer = "https://elsewhere.example"
+	unbounded := valid
+	unbounded.ExpiresAt = nil
+	impostor := valid
+	impostor.Subject = model.APIKeySubject(1)
+
+	tests := []struct {
+		name   string
+		header string
+		value  string
+		want   *model.Principal
+	}{
+		{name: "Anonymous"},
+		{name: "APIKey", header: APIKeyHeader, value: "prk_valid",
+			want: &model.Principal{Subject: "apikey:1", Roles: []string{"writer"}, Method: model.AuthAPIKey}},
+		{name: "AdminAPIKey", header: APIKeyHeader, value: "prk_admin",
+			want: &model.Principal{Subject: model.AdminSubject, Roles: []string{model.RoleAdmin}, Method: model.AuthAPIKey}},
+		{name: "UnknownAPIKey", header: APIKeyHeader, value: "prk_unknown"},
+		{name: "HS256", header: "Authorization", value: "Bearer " + sign(t, jwt.SigningMethodHS256, []byte("secret"), valid),
+			want: &model.Principal{Subject: "alice", Roles: []string{"reader"}, Groups: []string{"legal"}, Method: model.AuthJWT}},
+		{name: "RS256", header: "Authorization", value: "Bearer " + sign(t, jwt.SigningMethodRS256, rsaKey, valid),
+			want: &model.Principal{Subject: "alice", Roles: []string{"reader"}, Groups: []string{"legal"}, Method: model.AuthJWT}},
+		{name: "WrongSecret", header: "Authorization", value: "Bearer " + sign(t, jwt.SigningMethodHS256, []byte("guess"), valid)},
+		{name: "UnacceptedAlgorithm", header: "Authorization", value: "Bearer " + sign(t, jwt.SigningMethodHS512, []byte("secret"), valid)},
+		{name: "NoneAlgorithm", header: "Authorization", value: "Bearer " + sign(t, jwt.SigningMethodNone, jwt.UnsafeAllowNoneSignatureType, valid)},
+		{name: "Expired", header: "Authorization", value: "Bearer " + sign(t, jwt.SigningMethodHS256, []byte("secret"), expired)},
+		{name: "WithoutExpiry", header: "Authorization", value: "Bearer " + sign(t, jwt.SigningMethodHS256, []byte("secret"), unbounded)},
+		{name: "ForeignIssuer", header: "Authorization", value: "Bearer " + sign(t, jwt.SigningMethodHS256, []byte("secret"), foreign)},
+		{name: "APIKeySubject", header: "Authorization", value: "Bearer " + sign(t, jwt.SigningMethodHS256, []byte("secret"), impostor)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr, principal := serve(a, tt.header, tt.value)
+			if tt.want == nil {
+				var res utils.HttpResponse
+				err := json.Unmarshal(rr.Body.Bytes(), &res)
+				if err != nil {
+					t.Error(err)
+				}
+				assert.EqualValues(t, http.StatusUnauthorized, rr.Code)
+				assert.NotEmpty(t, rr.Header().Get("WWW-Authenticate"))
+				assert.Nil(t, principal)
+				return
+			}
+			assert.EqualValues(t, http.StatusOK, rr.Code)
+			assert.Equal(t, tt.want, principal)
+		})
+	}
+}
+
+func TestMiddleware_BearerTokensDisabled(t *testing.T) {
+	a, err := NewAuthenticator(Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	token := sign(t, jwt.SigningMethodHS256, []byte(""), claims{RegisteredClaims: jwt.RegisteredClaims{Subject: "alice"}})
+	rr, _ := serve(a, "Authorization", "Bearer "+token)
+	assert.EqualValues(t, http.StatusUnauthorized, rr.Code)
+}
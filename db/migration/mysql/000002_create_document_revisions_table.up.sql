@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS document_revisions(
+    id INT NOT NULL AUTO_INCREMENT PRIMARY KEY,
+    document_id INT NOT NULL,
+    revision INT NOT NULL,
+    operation VARCHAR (10) NOT NULL,
+    title VARCHAR (100) NOT NULL,
+    content json NOT NULL,
+    signee VARCHAR (100) NOT NULL,
+    created_at DATETIME (6) NOT NULL,
+    UNIQUE (document_id, revision),
+    INDEX (document_id, created_at)
+    );
+INSERT INTO document_revisions(document_id, revision, operation, title, content, signee, created_at)
+    SELECT id, 1, 'create', title, content, signee, UTC_TIMESTAMP(6) FROM documents;
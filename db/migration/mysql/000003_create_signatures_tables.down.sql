@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS document_signatures;
+DROP TABLE IF EXISTS signee_keys;
\ No newline at end of file
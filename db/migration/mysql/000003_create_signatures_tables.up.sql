@@ -0,0 +1,18 @@
+CREATE TABLE IF NOT EXISTS signee_keys(
+    id INT NOT NULL AUTO_INCREMENT PRIMARY KEY,
+    signee VARCHAR (100) NOT NULL,
+    public_key VARBINARY (32) UNIQUE NOT NULL,
+    created_at DATETIME (6) NOT NULL,
+    INDEX (signee)
+    );
+CREATE TABLE IF NOT EXISTS document_signatures(
+    id INT NOT NULL AUTO_INCREMENT PRIMARY KEY,
+    document_id INT NOT NULL,
+    key_id INT NOT NULL,
+    signee VARCHAR (100) NOT NULL,
+    signature VARBINARY (64) NOT NULL,
+    digest CHAR (64) NOT NULL,
+    created_at DATETIME (6) NOT NULL,
+    INDEX (document_id),
+    FOREIGN KEY (key_id) REFERENCES signee_keys(id)
+    );
@@ -0,0 +1,13 @@
+ALTER TABLE documents ADD COLUMN signing_mode VARCHAR (10) NOT NULL DEFAULT 'parallel';
+CREATE TABLE IF NOT EXISTS document_signees(
+    id INT NOT NULL AUTO_INCREMENT PRIMARY KEY,
+    document_id INT NOT NULL,
+    position INT NOT NULL,
+    signee VARCHAR (100) NOT NULL,
+    status VARCHAR (10) NOT NULL DEFAULT 'pending',
+    reason VARCHAR (255) NOT NULL DEFAULT '',
+    signed_at DATETIME (6) NULL,
+    declined_at DATETIME (6) NULL,
+    UNIQUE (document_id, signee),
+    INDEX (document_id, position)
+    );
@@ -0,0 +1 @@
+ALTER TABLE documents DROP INDEX deleted_at, DROP COLUMN deleted_at;
\ No newline at end of file
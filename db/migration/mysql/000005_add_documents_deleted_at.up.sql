@@ -0,0 +1 @@
+ALTER TABLE documents ADD COLUMN deleted_at DATETIME (6) NULL, ADD INDEX (deleted_at);
@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS api_keys(
+    id INT NOT NULL AUTO_INCREMENT PRIMARY KEY,
+    name VARCHAR (100) NOT NULL,
+    roles VARCHAR (255) NOT NULL,
+    key_hash CHAR (64) UNIQUE NOT NULL,
+    created_at DATETIME (6) NOT NULL,
+    revoked_at DATETIME (6) NULL
+    );
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS document_grants;
+DROP INDEX documents_owner_idx ON documents;
+ALTER TABLE documents DROP COLUMN owner;
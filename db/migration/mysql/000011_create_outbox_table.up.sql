@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS outbox(
+    id INT NOT NULL AUTO_INCREMENT PRIMARY KEY,
+    event_id VARCHAR (64) NOT NULL,
+    event VARCHAR (50) NOT NULL,
+    document_id INT NOT NULL,
+    payload TEXT NOT NULL,
+    attempts INT NOT NULL DEFAULT 0,
+    next_attempt_at DATETIME (6) NULL,
+    delivered_to VARCHAR (255) NOT NULL DEFAULT '',
+    last_error VARCHAR (1024) NOT NULL DEFAULT '',
+    created_at DATETIME (6) NOT NULL,
+    delivered_at DATETIME (6) NULL,
+    INDEX (next_attempt_at),
+    INDEX (delivered_at)
+    );
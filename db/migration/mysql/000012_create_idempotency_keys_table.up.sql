@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS idempotency_keys(
+    id INT NOT NULL AUTO_INCREMENT PRIMARY KEY,
+    scope VARCHAR (255) NOT NULL,
+    idempotency_key VARCHAR (255) NOT NULL,
+    fingerprint CHAR (64) NOT NULL,
+    status_code INT NOT NULL,
+    header TEXT NOT NULL,
+    body MEDIUMTEXT NOT NULL,
+    created_at DATETIME (6) NOT NULL,
+    expires_at DATETIME (6) NOT NULL,
+    UNIQUE (scope, idempotency_key),
+    INDEX (expires_at)
+    );
@@ -0,0 +1,3 @@
+DROP INDEX document_revisions_sequence_idx ON document_revisions;
+ALTER TABLE document_revisions DROP COLUMN sequence;
+DROP TABLE IF EXISTS sequences;
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS documents;
\ No newline at end of file
@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS documents(
+    id SERIAL PRIMARY KEY,
+    title VARCHAR (100) UNIQUE NOT NULL,
+    content jsonb NOT NULL,
+    signee VARCHAR (100) NOT NULL
+    );
@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS document_revisions(
+    id SERIAL PRIMARY KEY,
+    document_id INT NOT NULL,
+    revision INT NOT NULL,
+    operation VARCHAR (10) NOT NULL,
+    title VARCHAR (100) NOT NULL,
+    content jsonb NOT NULL,
+    signee VARCHAR (100) NOT NULL,
+    created_at TIMESTAMP (6) NOT NULL,
+    UNIQUE (document_id, revision)
+    );
+CREATE INDEX IF NOT EXISTS document_revisions_document_id_created_at_idx ON document_revisions (document_id, created_at);
+INSERT INTO document_revisions(document_id, revision, operation, title, content, signee, created_at)
+    SELECT id, 1, 'create', title, content, signee, NOW() AT TIME ZONE 'UTC' FROM documents;
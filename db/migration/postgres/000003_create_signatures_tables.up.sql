@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS signee_keys(
+    id SERIAL PRIMARY KEY,
+    signee VARCHAR (100) NOT NULL,
+    public_key BYTEA UNIQUE NOT NULL,
+    created_at TIMESTAMP (6) NOT NULL
+    );
+CREATE INDEX IF NOT EXISTS signee_keys_signee_idx ON signee_keys (signee);
+CREATE TABLE IF NOT EXISTS document_signatures(
+    id SERIAL PRIMARY KEY,
+    document_id INT NOT NULL,
+    key_id INT NOT NULL REFERENCES signee_keys(id),
+    signee VARCHAR (100) NOT NULL,
+    signature BYTEA NOT NULL,
+    digest CHAR (64) NOT NULL,
+    created_at TIMESTAMP (6) NOT NULL
+    );
+CREATE INDEX IF NOT EXISTS document_signatures_document_id_idx ON document_signatures (document_id);
@@ -0,0 +1,13 @@
+ALTER TABLE documents ADD COLUMN signing_mode VARCHAR (10) NOT NULL DEFAULT 'parallel';
+CREATE TABLE IF NOT EXISTS document_signees(
+    id SERIAL PRIMARY KEY,
+    document_id INT NOT NULL,
+    position INT NOT NULL,
+    signee VARCHAR (100) NOT NULL,
+    status VARCHAR (10) NOT NULL DEFAULT 'pending',
+    reason VARCHAR (255) NOT NULL DEFAULT '',
+    signed_at TIMESTAMP (6) NULL,
+    declined_at TIMESTAMP (6) NULL,
+    UNIQUE (document_id, signee)
+    );
+CREATE INDEX IF NOT EXISTS document_signees_document_id_position_idx ON document_signees (document_id, position);
@@ -0,0 +1,2 @@
+ALTER TABLE documents ADD COLUMN deleted_at TIMESTAMP (6) NULL;
+CREATE INDEX IF NOT EXISTS documents_deleted_at_idx ON documents (deleted_at);
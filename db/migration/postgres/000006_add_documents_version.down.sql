@@ -0,0 +1 @@
+ALTER TABLE documents DROP COLUMN version;
\ No newline at end of file
@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS api_keys(
+    id SERIAL PRIMARY KEY,
+    name VARCHAR (100) NOT NULL,
+    roles VARCHAR (255) NOT NULL,
+    key_hash CHAR (64) UNIQUE NOT NULL,
+    created_at TIMESTAMP (6) NOT NULL,
+    revoked_at TIMESTAMP (6) NULL
+    );
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS document_grants;
+DROP INDEX IF EXISTS documents_owner_idx;
+ALTER TABLE documents DROP COLUMN owner;
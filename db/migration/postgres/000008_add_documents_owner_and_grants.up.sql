@@ -0,0 +1,12 @@
+ALTER TABLE documents ADD COLUMN owner VARCHAR (100) NOT NULL DEFAULT '';
+CREATE INDEX IF NOT EXISTS documents_owner_idx ON documents (owner);
+CREATE TABLE IF NOT EXISTS document_grants(
+    id SERIAL PRIMARY KEY,
+    document_id INT NOT NULL,
+    grantee_type VARCHAR (10) NOT NULL,
+    grantee VARCHAR (100) NOT NULL,
+    role VARCHAR (10) NOT NULL,
+    created_at TIMESTAMP (6) NOT NULL,
+    UNIQUE (document_id, grantee_type, grantee)
+    );
+CREATE INDEX IF NOT EXISTS document_grants_grantee_type_grantee_idx ON document_grants (grantee_type, grantee);
@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS audit_log(
+    id SERIAL PRIMARY KEY,
+    actor VARCHAR (100) NOT NULL,
+    action VARCHAR (20) NOT NULL,
+    document_id INT NOT NULL,
+    request_id VARCHAR (128) NOT NULL,
+    before_hash VARCHAR (64) NOT NULL,
+    after_hash VARCHAR (64) NOT NULL,
+    prev_hash VARCHAR (64) UNIQUE NOT NULL,
+    hash CHAR (64) NOT NULL,
+    created_at TIMESTAMP (6) NOT NULL
+    );
+CREATE INDEX IF NOT EXISTS audit_log_document_id_idx ON audit_log (document_id);
+CREATE INDEX IF NOT EXISTS audit_log_actor_idx ON audit_log (actor);
@@ -0,0 +1,24 @@
+CREATE TABLE IF NOT EXISTS webhooks(
+    id SERIAL PRIMARY KEY,
+    url VARCHAR (2048) NOT NULL,
+    secret VARCHAR (100) NOT NULL,
+    events VARCHAR (255) NOT NULL,
+    disabled BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP (6) NOT NULL
+    );
+CREATE TABLE IF NOT EXISTS webhook_deliveries(
+    id SERIAL PRIMARY KEY,
+    webhook_id INT NOT NULL,
+    event_id VARCHAR (64) NOT NULL,
+    event VARCHAR (50) NOT NULL,
+    payload TEXT NOT NULL,
+    status VARCHAR (10) NOT NULL,
+    attempts INT NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMP (6) NULL,
+    response_code INT NOT NULL DEFAULT 0,
+    last_error VARCHAR (1024) NOT NULL DEFAULT '',
+    created_at TIMESTAMP (6) NOT NULL,
+    delivered_at TIMESTAMP (6) NULL
+    );
+CREATE INDEX IF NOT EXISTS webhook_deliveries_webhook_id_idx ON webhook_deliveries (webhook_id);
+CREATE INDEX IF NOT EXISTS webhook_deliveries_status_next_attempt_at_idx ON webhook_deliveries (status, next_attempt_at);
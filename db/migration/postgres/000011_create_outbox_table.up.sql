@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS outbox(
+    id SERIAL PRIMARY KEY,
+    event_id VARCHAR (64) NOT NULL,
+    event VARCHAR (50) NOT NULL,
+    document_id INT NOT NULL,
+    payload TEXT NOT NULL,
+    attempts INT NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMP (6) NULL,
+    delivered_to VARCHAR (255) NOT NULL DEFAULT '',
+    last_error VARCHAR (1024) NOT NULL DEFAULT '',
+    created_at TIMESTAMP (6) NOT NULL,
+    delivered_at TIMESTAMP (6) NULL
+    );
+CREATE INDEX IF NOT EXISTS outbox_next_attempt_at_idx ON outbox (next_attempt_at);
+CREATE INDEX IF NOT EXISTS outbox_delivered_at_idx ON outbox (delivered_at);
@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS idempotency_keys(
+    id SERIAL PRIMARY KEY,
+    scope VARCHAR (255) NOT NULL,
+    idempotency_key VARCHAR (255) NOT NULL,
+    fingerprint CHAR (64) NOT NULL,
+    status_code INT NOT NULL,
+    header TEXT NOT NULL,
+    body TEXT NOT NULL,
+    created_at TIMESTAMP (6) NOT NULL,
+    expires_at TIMESTAMP (6) NOT NULL,
+    UNIQUE (scope, idempotency_key)
+    );
+CREATE INDEX IF NOT EXISTS idempotency_keys_expires_at_idx ON idempotency_keys (expires_at);
@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS sequences(
+    name VARCHAR (64) PRIMARY KEY,
+    last_issued BIGINT NOT NULL
+    );
+ALTER TABLE document_revisions ADD COLUMN sequence BIGINT NOT NULL DEFAULT 0;
+UPDATE document_revisions SET sequence = id;
+CREATE UNIQUE INDEX IF NOT EXISTS document_revisions_sequence_idx ON document_revisions (sequence);
+INSERT INTO sequences(name, last_issued)
+    SELECT 'document_revisions', COALESCE(MAX(sequence), 0) FROM document_revisions;
@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS audit_head(
+    id INT PRIMARY KEY,
+    entries BIGINT NOT NULL,
+    hash VARCHAR (64) NOT NULL
+    );
+INSERT INTO audit_head(id, entries, hash)
+    VALUES(1, (SELECT COUNT(*) FROM audit_log), COALESCE((SELECT hash FROM audit_log ORDER BY id DESC LIMIT 1), ''));
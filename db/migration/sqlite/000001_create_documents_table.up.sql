@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS documents(
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    title VARCHAR (100) UNIQUE NOT NULL,
+    content TEXT NOT NULL,
+    signee VARCHAR (100) NOT NULL
+    );
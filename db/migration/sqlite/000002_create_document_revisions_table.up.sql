@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS document_revisions(
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    document_id INTEGER NOT NULL,
+    revision INTEGER NOT NULL,
+    operation VARCHAR (10) NOT NULL,
+    title VARCHAR (100) NOT NULL,
+    content TEXT NOT NULL,
+    signee VARCHAR (100) NOT NULL,
+    created_at DATETIME NOT NULL,
+    UNIQUE (document_id, revision)
+    );
+CREATE INDEX IF NOT EXISTS document_revisions_document_id_created_at_idx ON document_revisions (document_id, created_at);
+INSERT INTO document_revisions(document_id, revision, operation, title, content, signee, created_at)
+    SELECT id, 1, 'create', title, content, signee, STRFTIME('%Y-%m-%d %H:%M:%f', 'now') FROM documents;
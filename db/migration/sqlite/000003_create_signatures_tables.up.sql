@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS signee_keys(
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    signee VARCHAR (100) NOT NULL,
+    public_key BLOB UNIQUE NOT NULL,
+    created_at DATETIME NOT NULL
+    );
+CREATE INDEX IF NOT EXISTS signee_keys_signee_idx ON signee_keys (signee);
+CREATE TABLE IF NOT EXISTS document_signatures(
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    document_id INTEGER NOT NULL,
+    key_id INTEGER NOT NULL REFERENCES signee_keys(id),
+    signee VARCHAR (100) NOT NULL,
+    signature BLOB NOT NULL,
+    digest CHAR (64) NOT NULL,
+    created_at DATETIME NOT NULL
+    );
+CREATE INDEX IF NOT EXISTS document_signatures_document_id_idx ON document_signatures (document_id);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS document_signees;
+ALTER TABLE documents DROP COLUMN signing_mode;
\ No newline at end of file
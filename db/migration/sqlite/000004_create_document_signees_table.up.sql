@@ -0,0 +1,13 @@
+ALTER TABLE documents ADD COLUMN signing_mode VARCHAR (10) NOT NULL DEFAULT 'parallel';
+CREATE TABLE IF NOT EXISTS document_signees(
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    document_id INTEGER NOT NULL,
+    position INTEGER NOT NULL,
+    signee VARCHAR (100) NOT NULL,
+    status VARCHAR (10) NOT NULL DEFAULT 'pending',
+    reason VARCHAR (255) NOT NULL DEFAULT '',
+    signed_at DATETIME NULL,
+    declined_at DATETIME NULL,
+    UNIQUE (document_id, signee)
+    );
+CREATE INDEX IF NOT EXISTS document_signees_document_id_position_idx ON document_signees (document_id, position);
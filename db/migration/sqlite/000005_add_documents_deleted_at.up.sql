@@ -0,0 +1,2 @@
+ALTER TABLE documents ADD COLUMN deleted_at DATETIME NULL;
+CREATE INDEX IF NOT EXISTS documents_deleted_at_idx ON documents (deleted_at);
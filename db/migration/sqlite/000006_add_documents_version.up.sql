@@ -0,0 +1 @@
+ALTER TABLE documents ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS api_keys(
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name VARCHAR (100) NOT NULL,
+    roles VARCHAR (255) NOT NULL,
+    key_hash CHAR (64) UNIQUE NOT NULL,
+    created_at DATETIME NOT NULL,
+    revoked_at DATETIME NULL
+    );
@@ -0,0 +1,24 @@
+CREATE TABLE IF NOT EXISTS webhooks(
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    url VARCHAR (2048) NOT NULL,
+    secret VARCHAR (100) NOT NULL,
+    events VARCHAR (255) NOT NULL,
+    disabled BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at DATETIME NOT NULL
+    );
+CREATE TABLE IF NOT EXISTS webhook_deliveries(
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    webhook_id INTEGER NOT NULL,
+    event_id VARCHAR (64) NOT NULL,
+    event VARCHAR (50) NOT NULL,
+    payload TEXT NOT NULL,
+    status VARCHAR (10) NOT NULL,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at DATETIME NULL,
+    response_code INTEGER NOT NULL DEFAULT 0,
+    last_error VARCHAR (1024) NOT NULL DEFAULT '',
+    created_at DATETIME NOT NULL,
+    delivered_at DATETIME NULL
+    );
+CREATE INDEX IF NOT EXISTS webhook_deliveries_webhook_id_idx ON webhook_deliveries (webhook_id);
+CREATE INDEX IF NOT EXISTS webhook_deliveries_status_next_attempt_at_idx ON webhook_deliveries (status, next_attempt_at);
@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS outbox(
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    event_id VARCHAR (64) NOT NULL,
+    event VARCHAR (50) NOT NULL,
+    document_id INTEGER NOT NULL,
+    payload TEXT NOT NULL,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at DATETIME NULL,
+    delivered_to VARCHAR (255) NOT NULL DEFAULT '',
+    last_error VARCHAR (1024) NOT NULL DEFAULT '',
+    created_at DATETIME NOT NULL,
+    delivered_at DATETIME NULL
+    );
+CREATE INDEX IF NOT EXISTS outbox_next_attempt_at_idx ON outbox (next_attempt_at);
+CREATE INDEX IF NOT EXISTS outbox_delivered_at_idx ON outbox (delivered_at);
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS document_revisions_sequence_idx;
+ALTER TABLE document_revisions DROP COLUMN sequence;
+DROP TABLE IF EXISTS sequences;
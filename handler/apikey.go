@@ -0,0 +1,63 @@
+package handler
+
+import (
+	"encoding/json"
+	"github.com/gorilla/mux"
+	"net/http"
+	"precisely/model"
+	"precisely/service"
+	"precisely/tracing"
+	"precisely/utils"
+	"strconv"
+)
+
+func CreateAPIKeyHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracing.Start(r.Context(), "CreateAPIKeyHandler")
+	defer span.End()
+
+	var newKey model.APIKey
+	err := json.NewDecoder(r.Body).Decode(&newKey)
+	if err != nil {
+		utils.RespondError(w, model.BodyInvalidValue)
+		return
+	}
+	key, err := service.APIKeyService.Create(ctx, newKey)
+	if err != nil {
+		utils.RespondError(w, err)
+		return
+	}
+	utils.JsonRespond(w, true, http.StatusCreated, err, key)
+	return
+}
+
+func GetAPIKeysHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracing.Start(r.Context(), "GetAPIKeysHandler")
+	defer span.End()
+
+	keys, err := service.APIKeyService.GetAll(ctx)
+	if err != nil {
+		utils.RespondError(w, err)
+		return
+	}
+	utils.JsonRespond(w, true, http.StatusOK, err, keys)
+	return
+}
+
+func RevokeAPIKeyHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracing.Start(r.Context(), "RevokeAPIKeyHandler")
+	defer span.End()
+
+	idStr, _ := mux.Vars(r)["id"]
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		utils.RespondError(w, model.IDInvalidValue)
+		return
+	}
+	err = service.APIKeyService.Revoke(ctx, id)
+	if err != nil {
+		utils.RespondError(w, err)
+		return
+	}
+	utils.JsonRespond(w, true, http.StatusOK, err, nil)
+	return
+}
@@ -0,0 +1,92 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"precisely/model"
+	"precisely/service"
+	"precisely/utils"
+	"testing"
+)
+
+var (
+	createAPIKeyService func(key model.APIKey) (*model.APIKey, error)
+	getAPIKeysService   func() ([]*model.APIKey, error)
+	revokeAPIKeyService func(id int64) error
+)
+
+type apiKeyServiceMock struct{}
+
+func (m *apiKeyServiceMock) Create(_ context.Context, key model.APIKey) (*model.APIKey, error) {
+	return createAPIKeyService(key)
+}
+
+func (m *apiKeyServiceMock) GetAll(_ context.Context) ([]*model.APIKey, error) {
+	return getAPIKeysService()
+}
+
+func (m *apiKeyServiceMock) Revoke(_ context.Context, id int64) error {
+	return revokeAPIKeyService(id)
+}
+
+func (m *apiKeyServiceMock) Authenticate(_ context.Context, key string) (*model.Principal, error) {
+	return nil, model.UnauthenticatedValue
+}
+
+func TestCreateAPIKeyHandler(t *testing.T) {
+	service.APIKeyService = &apiKeyServiceMock{}
+	tests := []struct {
+		name string
+		err  error
+		code int
+	}{
+		{name: "Created", code: http.StatusCreated},
+		{name: "Forbidden", err: model.ForbiddenValue, code: http.StatusForbidden},
+		{name: "Unprocessable", err: model.APIKeyNameInvalidValue, code: http.StatusUnprocessableEntity},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			createAPIKeyService = func(key model.APIKey) (*model.APIKey, error) {
+				if tt.err != nil {
+					return nil, tt.err
+				}
+				key.ID, key.Key = 1, "prk_secret"
+				return &key, nil
+			}
+			req, _ := http.NewRequest(http.MethodPost, "/api-keys", bytes.NewBufferString(`{"name": "ci", "roles": ["writer"]}`))
+			rr := httptest.NewRecorder()
+			http.HandlerFunc(CreateAPIKeyHandler).ServeHTTP(rr, req)
+
+			var res utils.HttpResponse
+			err := json.Unmarshal(rr.Body.Bytes(), &res)
+			if err != nil {
+				t.Error(err)
+			}
+			assert.EqualValues(t, tt.code, res.Code)
+			if tt.err == nil {
+				assert.EqualValues(t, "prk_secret", res.Data.(map[string]interface{})["key"])
+			}
+		})
+	}
+}
+
+func TestRevokeAPIKeyHandler_NotFound(t *testing.T) {
+	service.APIKeyService = &apiKeyServiceMock{}
+	revokeAPIKeyService = func(id int64) error {
+		return sql.ErrNoRows
+	}
+	req, _ := http.NewRequest(http.MethodDelete, "/api-keys/3", nil)
+	req = mux.SetURLVars(req, map[string]string{
+		"id": "3",
+	})
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(RevokeAPIKeyHandler).ServeHTTP(rr, req)
+
+	assert.EqualValues(t, http.StatusNotFound, rr.Code)
+}
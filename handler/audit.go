@@ -0,0 +1,86 @@
+package handler
+
+import (
+	"net/http"
+	"net/url"
+	"precisely/model"
+	"precisely/service"
+	"precisely/tracing"
+	"precisely/utils"
+	"strconv"
+	"time"
+)
+
+func GetAuditHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracing.Start(r.Context(), "GetAuditHandler")
+	defer span.End()
+
+	query, err := parseAuditQuery(r.URL.Query())
+	if err != nil {
+		utils.RespondError(w, err)
+		return
+	}
+	page, err := service.AuditService.GetAll(ctx, query)
+	if err != nil {
+		utils.RespondError(w, err)
+		return
+	}
+	meta := utils.PageMeta{
+		Total:      page.Total,
+		Limit:      query.Limit,
+		NextCursor: page.NextCursor,
+	}
+	utils.JsonRespondWithMeta(w, true, http.StatusOK, err, page.Entries, meta)
+	return
+}
+
+// parseAuditQuery reads limit, cursor and the actor, action, document_id,
+// since and until filters from the query string.
+func parseAuditQuery(values url.Values) (model.AuditQuery, error) {
+	query := model.AuditQuery{
+		Actor:  values.Get("actor"),
+		Action: values.Get("action"),
+	}
+	var err error
+	if v := values.Get("limit"); v != "" {
+		if query.Limit, err = strconv.Atoi(v); err != nil || query.Limit <= 0 {
+			return query, model.LimitInvalidValue
+		}
+	}
+	if v := values.Get("cursor"); v != "" {
+		if query.Cursor, err = model.DecodeCursor(v); err != nil {
+			return query, err
+		}
+	}
+	if v := values.Get("document_id"); v != "" {
+		if query.DocumentID, err = strconv.ParseInt(v, 10, 64); err != nil || query.DocumentID <= 0 {
+			return query, model.AuditDocumentIDInvalidValue
+		}
+	}
+	if v := values.Get("since"); v != "" {
+		if query.Since, err = time.Parse(time.RFC3339Nano, v); err != nil {
+			return query, model.AuditTimeInvalidValue
+		}
+	}
+	if v := values.Get("until"); v != "" {
+		if query.Until, err = time.Parse(time.RFC3339Nano, v); err != nil {
+			return query, model.AuditTimeInvalidValue
+		}
+	}
+	return query, query.Validate()
+}
+
+// VerifyAuditHandler reports whether the audit log is intact. A broken chain
+// is still a successful verification, reported with valid false.
+func VerifyAuditHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracing.Start(r.Context(), "VerifyAuditHandler")
+	defer span.End()
+
+	verification, err := service.AuditService.Verify(ctx)
+	if err != nil {
+		utils.RespondError(w, err)
+		return
+	}
+	utils.JsonRespond(w, true, http.StatusOK, err, verification)
+	return
+}
@@ -0,0 +1,93 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"precisely/model"
+	"precisely/service"
+	"precisely/utils"
+	"testing"
+	"time"
+)
+
+var (
+	getAuditService    func(query model.AuditQuery) (*model.AuditPage, error)
+	verifyAuditService func() (*model.AuditVerification, error)
+)
+
+type auditServiceMock struct{}
+
+func (m *auditServiceMock) GetAll(_ context.Context, query model.AuditQuery) (*model.AuditPage, error) {
+	return getAuditService(query)
+}
+
+func (m *auditServiceMock) Verify(_ context.Context) (*model.AuditVerification, error) {
+	return verifyAuditService()
+}
+
+func TestGetAuditHandler(t *testing.T) {
+	service.AuditService = &auditServiceMock{}
+	tests := []struct {
+		name   string
+		target string
+		err    error
+		code   int
+	}{
+		{name: "Filtered", target: "/audit?actor=alice&action=update&document_id=7&since=2022-01-10T09:00:00Z&limit=10", code: http.StatusOK},
+		{name: "Invalid document", target: "/audit?document_id=x", code: http.StatusBadRequest},
+		{name: "Invalid since", target: "/audit?since=yesterday", code: http.StatusBadRequest},
+		{name: "Invalid action", target: "/audit?action=approve", code: http.StatusBadRequest},
+		{name: "Invalid limit", target: "/audit?limit=1000", code: http.StatusBadRequest},
+		{name: "Forbidden", target: "/audit", err: model.ForbiddenValue, code: http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			getAuditService = func(query model.AuditQuery) (*model.AuditPage, error) {
+				if tt.err != nil {
+					return nil, tt.err
+				}
+				assert.Equal(t, model.AuditQuery{
+					Actor:      "alice",
+					Action:     model.AuditUpdate,
+					DocumentID: 7,
+					Since:      time.Date(2022, 1, 10, 9, 0, 0, 0, time.UTC),
+					Limit:      10,
+				}, query)
+				return &model.AuditPage{Entries: []*model.AuditEntry{{ID: 1, Actor: "alice", Action: model.AuditUpdate, DocumentID: 7}}, Total: 1}, nil
+			}
+			req, _ := http.NewRequest(http.MethodGet, tt.target, nil)
+			rr := httptest.NewRecorder()
+			http.HandlerFunc(GetAuditHandler).ServeHTTP(rr, req)
+
+			var res utils.HttpResponse
+			if err := json.Unmarshal(rr.Body.Bytes(), &res); err != nil {
+				t.Error(err)
+			}
+			assert.EqualValues(t, tt.code, res.Code)
+			if tt.code == http.StatusOK {
+				assert.Len(t, res.Data, 1)
+			}
+		})
+	}
+}
+
+func TestVerifyAuditHandler(t *testing.T) {
+	service.AuditService = &auditServiceMock{}
+	verifyAuditService = func() (*model.AuditVerification, error) {
+		return &model.AuditVerification{Entries: 1, BrokenAt: 2, Reason: "hash does not match the content of the entry"}, nil
+	}
+	req, _ := http.NewRequest(http.MethodGet, "/audit/verify", nil)
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(VerifyAuditHandler).ServeHTTP(rr, req)
+
+	var res utils.HttpResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &res); err != nil {
+		t.Error(err)
+	}
+	assert.EqualValues(t, http.StatusOK, res.Code)
+	assert.Equal(t, false, res.Data.(map[string]interface{})["valid"])
+	assert.EqualValues(t, 2, res.Data.(map[string]interface{})["broken_at"])
+}
@@ -0,0 +1,53 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"precisely/model"
+	"precisely/service"
+	"precisely/tracing"
+	"precisely/utils"
+)
+
+// batchItem is the result of one operation of a batch, with the code it would
+// have been answered with as a request of its own.
+type batchItem struct {
+	Code      int             `json:"code"`
+	Status    bool            `json:"status"`
+	Data      *model.Document `json:"data"`
+	Error     string          `json:"error,omitempty"`
+	ErrorCode string          `json:"error_code,omitempty"`
+}
+
+func BatchHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracing.Start(r.Context(), "BatchHandler")
+	defer span.End()
+
+	var batch model.Batch
+	err := json.NewDecoder(r.Body).Decode(&batch)
+	if err != nil {
+		utils.RespondError(w, model.BodyInvalidValue)
+		return
+	}
+
+	results, err := service.DocumentService.Batch(ctx, batch)
+	if err != nil {
+		utils.RespondError(w, err)
+		return
+	}
+
+	items := make([]batchItem, len(results))
+	for i, result := range results {
+		if result.Err != nil {
+			err := utils.PublicError(result.Err)
+			items[i] = batchItem{Code: utils.ErrorStatus(err), Error: err.Error(), ErrorCode: model.ErrorOf(err).Code}
+			continue
+		}
+		items[i] = batchItem{Code: http.StatusOK, Status: true, Data: result.Document}
+		if batch.Operations[i].Op == model.BatchCreate {
+			items[i].Code = http.StatusCreated
+		}
+	}
+	utils.JsonRespond(w, true, http.StatusOK, nil, items)
+	return
+}
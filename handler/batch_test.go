@@ -0,0 +1,75 @@
+package handler
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"precisely/model"
+	"precisely/service"
+	"precisely/utils"
+	"testing"
+)
+
+func TestBatchHandler(t *testing.T) {
+	service.DocumentService = &serviceMock{}
+	batchMessageService = func(batch model.Batch) ([]model.BatchResult, error) {
+		return []model.BatchResult{
+			{Document: &model.Document{ID: 1, Title: "title", Signee: "signee", Version: 1}},
+			{Err: sql.ErrNoRows},
+			{},
+		}, nil
+	}
+
+	body := `{"mode": "best_effort", "operations": [
+		{"op": "create", "title": "title", "signee": "signee"},
+		{"op": "update", "id": 2, "version": 1, "title": "title", "signee": "signee"},
+		{"op": "delete", "id": 3, "version": 1}
+	]}`
+	req, _ := http.NewRequest(http.MethodPost, "/documents:batch", bytes.NewBufferString(body))
+	rr := httptest.NewRecorder()
+	handler := http.HandlerFunc(BatchHandler)
+	handler.ServeHTTP(rr, req)
+
+	assert.EqualValues(t, http.StatusOK, rr.Code)
+	var response struct {
+		Data []batchItem `json:"data"`
+	}
+	assert.Nil(t, json.NewDecoder(rr.Body).Decode(&response))
+	assert.Len(t, response.Data, 3)
+	assert.EqualValues(t, http.StatusCreated, response.Data[0].Code)
+	assert.EqualValues(t, http.StatusNotFound, response.Data[1].Code)
+	assert.False(t, response.Data[1].Status)
+	assert.EqualValues(t, http.StatusOK, response.Data[2].Code)
+}
+
+func TestBatchHandler_AtomicFailure(t *testing.T) {
+	service.DocumentService = &serviceMock{}
+	batchMessageService = func(batch model.Batch) ([]model.BatchResult, error) {
+		return nil, &model.BatchOperationError{Index: 1, Err: model.VersionMismatchValue}
+	}
+
+	req, _ := http.NewRequest(http.MethodPost, "/documents:batch", bytes.NewBufferString(`{"operations": []}`))
+	rr := httptest.NewRecorder()
+	handler := http.HandlerFunc(BatchHandler)
+	handler.ServeHTTP(rr, req)
+
+	assert.EqualValues(t, http.StatusPreconditionFailed, rr.Code)
+	assert.Contains(t, rr.Body.String(), "operation 1: ")
+}
+
+func TestBatchHandler_InvalidBody(t *testing.T) {
+	service.DocumentService = &serviceMock{}
+
+	req, _ := http.NewRequest(http.MethodPost, "/documents:batch", bytes.NewBufferString(`{"operations": `))
+	rr := httptest.NewRecorder()
+	handler := http.HandlerFunc(BatchHandler)
+	handler.ServeHTTP(rr, req)
+
+	var res utils.HttpResponse
+	assert.Nil(t, json.NewDecoder(rr.Body).Decode(&res))
+	assert.EqualValues(t, http.StatusBadRequest, rr.Code)
+	assert.Equal(t, model.BodyInvalidValue.Code, res.ErrorCode)
+}
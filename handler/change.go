@@ -0,0 +1,61 @@
+package handler
+
+import (
+	"net/http"
+	"net/url"
+	"precisely/model"
+	"precisely/service"
+	"precisely/tracing"
+	"precisely/utils"
+	"strconv"
+	"time"
+)
+
+// GetChangesHandler serves the change feed after the since cursor. With wait,
+// it holds the request until a change comes or wait elapses, answering an
+// empty page in the latter case.
+func GetChangesHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracing.Start(r.Context(), "GetChangesHandler")
+	defer span.End()
+
+	query, err := parseChangeQuery(r.URL.Query())
+	if err != nil {
+		utils.RespondError(w, err)
+		return
+	}
+	page, err := service.DocumentService.GetChanges(ctx, query)
+	if err != nil {
+		utils.RespondError(w, err)
+		return
+	}
+	meta := utils.PageMeta{
+		Total:      int64(len(page.Changes)),
+		Limit:      query.Limit,
+		NextCursor: page.NextCursor,
+	}
+	utils.JsonRespondWithMeta(w, true, http.StatusOK, err, page.Changes, meta)
+	return
+}
+
+// parseChangeQuery reads since, limit and wait, a duration such as "5s", from
+// the query string.
+func parseChangeQuery(values url.Values) (model.ChangeQuery, error) {
+	var query model.ChangeQuery
+	var err error
+	if v := values.Get("since"); v != "" {
+		if query.Since, err = model.DecodeCursor(v); err != nil {
+			return query, err
+		}
+	}
+	if v := values.Get("limit"); v != "" {
+		if query.Limit, err = strconv.Atoi(v); err != nil || query.Limit <= 0 {
+			return query, model.LimitInvalidValue
+		}
+	}
+	if v := values.Get("wait"); v != "" {
+		if query.Wait, err = time.ParseDuration(v); err != nil {
+			return query, model.WaitInvalidValue
+		}
+	}
+	return query, query.Validate()
+}
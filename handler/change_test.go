@@ -0,0 +1,70 @@
+package handler
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"precisely/model"
+	"precisely/service"
+	"precisely/utils"
+	"testing"
+	"time"
+)
+
+func TestGetChangesHandler_Success(t *testing.T) {
+	service.DocumentService = &serviceMock{}
+	var got model.ChangeQuery
+	getChangesMessageService = func(query model.ChangeQuery) (*model.ChangePage, error) {
+		got = query
+		return &model.ChangePage{
+			Changes: []*model.Change{
+				{Sequence: 4, Operation: model.ChangeUpdate, DocumentID: 1, Document: &model.Document{ID: 1, Title: "title"}},
+				{Sequence: 5, Operation: model.ChangeDelete, DocumentID: 2},
+			},
+			NextCursor: model.EncodeCursor(5),
+		}, nil
+	}
+	req, _ := http.NewRequest(http.MethodGet, "/documents/changes?since="+model.EncodeCursor(3)+"&limit=2&wait=5s", nil)
+	rr := httptest.NewRecorder()
+	handler := http.HandlerFunc(GetChangesHandler)
+	handler.ServeHTTP(rr, req)
+
+	var res utils.HttpResponse
+	err := json.Unmarshal(rr.Body.Bytes(), &res)
+	if err != nil {
+		t.Error(err)
+	}
+	assert.EqualValues(t, http.StatusOK, res.Code)
+	assert.EqualValues(t, model.ChangeQuery{Since: 3, Limit: 2, Wait: 5 * time.Second}, got)
+	changes := res.Data.([]interface{})
+	assert.EqualValues(t, 2, len(changes))
+	assert.EqualValues(t, "title", changes[0].(map[string]interface{})["document"].(map[string]interface{})["title"])
+	assert.Nil(t, changes[1].(map[string]interface{})["document"])
+	assert.EqualValues(t, model.EncodeCursor(5), res.Meta.(map[string]interface{})["next_cursor"])
+}
+
+func TestGetChangesHandler_InvalidQuery(t *testing.T) {
+	service.DocumentService = &serviceMock{}
+	for _, query := range []string{"since=nope", "limit=0", "wait=soon", "wait=1h"} {
+		req, _ := http.NewRequest(http.MethodGet, "/documents/changes?"+query, nil)
+		rr := httptest.NewRecorder()
+		handler := http.HandlerFunc(GetChangesHandler)
+		handler.ServeHTTP(rr, req)
+
+		assert.EqualValues(t, http.StatusBadRequest, rr.Code, query)
+	}
+}
+
+func TestGetChangesHandler_Forbidden(t *testing.T) {
+	service.DocumentService = &serviceMock{}
+	getChangesMessageService = func(query model.ChangeQuery) (*model.ChangePage, error) {
+		return nil, model.ForbiddenValue
+	}
+	req, _ := http.NewRequest(http.MethodGet, "/documents/changes", nil)
+	rr := httptest.NewRecorder()
+	handler := http.HandlerFunc(GetChangesHandler)
+	handler.ServeHTTP(rr, req)
+
+	assert.EqualValues(t, http.StatusForbidden, rr.Code)
+}
@@ -1,84 +1,138 @@
 package handler
 
 import (
-	"database/sql"
 	"encoding/json"
-	"errors"
 	"github.com/gorilla/mux"
+	"io/ioutil"
+	"mime"
 	"net/http"
+	"net/url"
 	"precisely/model"
 	"precisely/service"
+	"precisely/tracing"
 	"precisely/utils"
 	"strconv"
+	"strings"
+	"time"
 )
 
 func CreateHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracing.Start(r.Context(), "CreateHandler")
+	defer span.End()
+
 	var newDocument model.Document
 	err := json.NewDecoder(r.Body).Decode(&newDocument)
 	if err != nil {
-		utils.JsonRespond(w, false, http.StatusBadRequest, err, nil)
+		utils.RespondError(w, model.BodyInvalidValue)
 		return
 	}
-	document, err := service.DocumentService.Create(newDocument)
+	document, err := service.DocumentService.Create(ctx, newDocument)
 	if err != nil {
-		if errors.Is(err, model.TitleInvalidValue) || errors.Is(err, model.SigneeInvalidValue) {
-			utils.JsonRespond(w, false, http.StatusUnprocessableEntity, err, nil)
-			return
-		}
-		utils.JsonRespond(w, false, http.StatusInternalServerError, err, nil)
+		utils.RespondError(w, err)
 		return
 	}
+	w.Header().Set("ETag", etag(document.Version))
 	utils.JsonRespond(w, true, http.StatusCreated, err, document)
 	return
 }
 
 func UpdateHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracing.Start(r.Context(), "UpdateHandler")
+	defer span.End()
+
 	var updatedDocument model.Document
 	err := json.NewDecoder(r.Body).Decode(&updatedDocument)
 	if err != nil {
-		utils.JsonRespond(w, false, http.StatusBadRequest, err, nil)
+		utils.RespondError(w, model.BodyInvalidValue)
 		return
 	}
 	idStr, _ := mux.Vars(r)["id"]
 	id, err := strconv.ParseInt(idStr, 10, 64)
 	if err != nil {
-		utils.JsonRespond(w, false, http.StatusBadRequest, err, nil)
+		utils.RespondError(w, model.IDInvalidValue)
+		return
+	}
+
+	version, err := parseIfMatch(r)
+	if err != nil {
+		utils.RespondError(w, err)
 		return
 	}
 
 	updatedDocument.ID = id
-	document, err := service.DocumentService.Update(updatedDocument)
+	updatedDocument.Version = version
+	document, err := service.DocumentService.Update(ctx, updatedDocument)
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			utils.JsonRespond(w, false, http.StatusNotFound, err, nil)
-			return
-		} else if errors.Is(err, model.TitleInvalidValue) || errors.Is(err, model.SigneeInvalidValue) {
-			utils.JsonRespond(w, false, http.StatusUnprocessableEntity, err, nil)
-			return
-		}
-		utils.JsonRespond(w, false, http.StatusInternalServerError, err, nil)
+		utils.RespondError(w, err)
 		return
 	}
 
+	w.Header().Set("ETag", etag(document.Version))
+	utils.JsonRespond(w, true, http.StatusOK, err, document)
+	return
+}
+
+func PatchHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracing.Start(r.Context(), "PatchHandler")
+	defer span.End()
+
+	patchType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil || (patchType != service.MergePatchType && patchType != service.JSONPatchType) {
+		utils.RespondError(w, model.PatchTypeInvalidValue)
+		return
+	}
+	patch, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		utils.RespondError(w, model.BodyInvalidValue)
+		return
+	}
+	if !json.Valid(patch) {
+		utils.RespondError(w, model.BodyInvalidValue)
+		return
+	}
+	idStr, _ := mux.Vars(r)["id"]
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		utils.RespondError(w, model.IDInvalidValue)
+		return
+	}
+	version, err := parseIfMatch(r)
+	if err != nil {
+		utils.RespondError(w, err)
+		return
+	}
+
+	document, err := service.DocumentService.Patch(ctx, id, version, patchType, patch)
+	if err != nil {
+		utils.RespondError(w, err)
+		return
+	}
+
+	w.Header().Set("ETag", etag(document.Version))
 	utils.JsonRespond(w, true, http.StatusOK, err, document)
 	return
 }
 
 func DeleteHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracing.Start(r.Context(), "DeleteHandler")
+	defer span.End()
+
 	idStr, _ := mux.Vars(r)["id"]
 	id, err := strconv.ParseInt(idStr, 10, 64)
 	if err != nil {
-		utils.JsonRespond(w, false, http.StatusBadRequest, err, nil)
+		utils.RespondError(w, model.IDInvalidValue)
 		return
 	}
 
-	err = service.DocumentService.Delete(id)
+	version, err := parseIfMatch(r)
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			utils.JsonRespond(w, false, http.StatusNotFound, err, nil)
-			return
-		}
-		utils.JsonRespond(w, false, http.StatusInternalServerError, err, nil)
+		utils.RespondError(w, err)
+		return
+	}
+
+	err = service.DocumentService.Delete(ctx, id, version)
+	if err != nil {
+		utils.RespondError(w, err)
 		return
 	}
 
@@ -87,33 +141,190 @@ func DeleteHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 func GetByIdHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracing.Start(r.Context(), "GetByIdHandler")
+	defer span.End()
+
 	idStr, _ := mux.Vars(r)["id"]
 	id, err := strconv.ParseInt(idStr, 10, 64)
 	if err != nil {
-		utils.JsonRespond(w, false, http.StatusBadRequest, err, nil)
+		utils.RespondError(w, model.IDInvalidValue)
 		return
 	}
 
-	document, err := service.DocumentService.Get(id)
-	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			utils.JsonRespond(w, false, http.StatusNotFound, err, nil)
+	var document *model.Document
+	if asOfStr := r.URL.Query().Get("asOf"); asOfStr != "" {
+		asOf, parseErr := time.Parse(time.RFC3339Nano, asOfStr)
+		if parseErr != nil {
+			utils.RespondError(w, model.AsOfInvalidValue)
 			return
 		}
-		utils.JsonRespond(w, false, http.StatusInternalServerError, err, nil)
+		document, err = service.DocumentService.GetAsOf(ctx, id, asOf)
+	} else {
+		document, err = service.DocumentService.Get(ctx, id)
+	}
+	if err != nil {
+		utils.RespondError(w, err)
 		return
 	}
 
+	if document.Version != 0 {
+		w.Header().Set("ETag", etag(document.Version))
+	}
 	utils.JsonRespond(w, true, http.StatusOK, err, document)
 	return
 }
 
 func GetAllHandler(w http.ResponseWriter, r *http.Request) {
-	documents, err := service.DocumentService.GetAll()
+	ctx, span := tracing.Start(r.Context(), "GetAllHandler")
+	defer span.End()
+
+	opts, err := parseListOptions(r.URL.Query())
+	if err != nil {
+		utils.RespondError(w, err)
+		return
+	}
+	page, err := service.DocumentService.GetAll(ctx, opts)
 	if err != nil {
-		utils.JsonRespond(w, false, http.StatusInternalServerError, err, nil)
+		utils.RespondError(w, err)
 		return
 	}
-	utils.JsonRespond(w, true, http.StatusOK, err, documents)
+	meta := utils.PageMeta{
+		Total:      page.Total,
+		Limit:      opts.Limit,
+		Offset:     opts.Offset,
+		NextCursor: page.NextCursor,
+	}
+	utils.JsonRespondWithMeta(w, true, http.StatusOK, err, page.Documents, meta)
 	return
 }
+
+// parseListOptions reads limit, offset, cursor, sort and the title/signee
+// filters from the query string. A filter is given as "title=x" for an exact
+// match, "title_prefix=x" or "title_contains=x".
+func parseListOptions(query url.Values) (model.ListOptions, error) {
+	var opts model.ListOptions
+	var err error
+	if v := query.Get("limit"); v != "" {
+		if opts.Limit, err = strconv.Atoi(v); err != nil || opts.Limit <= 0 {
+			return opts, model.LimitInvalidValue
+		}
+	}
+	if v := query.Get("offset"); v != "" {
+		if opts.Offset, err = strconv.Atoi(v); err != nil {
+			return opts, model.OffsetInvalidValue
+		}
+	}
+	if v := query.Get("cursor"); v != "" {
+		if opts.Cursor, err = model.DecodeCursor(v); err != nil {
+			return opts, err
+		}
+	}
+	if v := query.Get("sort"); v != "" {
+		if opts.Sort, err = model.ParseSort(v); err != nil {
+			return opts, err
+		}
+	}
+	for _, field := range []string{"title", "signee"} {
+		for _, operator := range []model.FilterOperator{model.FilterExact, model.FilterPrefix, model.FilterContains} {
+			param := field
+			if operator != model.FilterExact {
+				param += "_" + string(operator)
+			}
+			if v, ok := query[param]; ok {
+				opts.Filters = append(opts.Filters, model.Filter{Field: field, Operator: operator, Value: v[0]})
+			}
+		}
+	}
+	return opts, opts.Validate()
+}
+
+func GetTrashHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracing.Start(r.Context(), "GetTrashHandler")
+	defer span.End()
+
+	opts, err := parseListOptions(r.URL.Query())
+	if err != nil {
+		utils.RespondError(w, err)
+		return
+	}
+	opts.Trashed = true
+	page, err := service.DocumentService.GetAll(ctx, opts)
+	if err != nil {
+		utils.RespondError(w, err)
+		return
+	}
+	meta := utils.PageMeta{
+		Total:      page.Total,
+		Limit:      opts.Limit,
+		Offset:     opts.Offset,
+		NextCursor: page.NextCursor,
+	}
+	utils.JsonRespondWithMeta(w, true, http.StatusOK, err, page.Documents, meta)
+	return
+}
+
+func RestoreDeletedHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracing.Start(r.Context(), "RestoreDeletedHandler")
+	defer span.End()
+
+	idStr, _ := mux.Vars(r)["id"]
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		utils.RespondError(w, model.IDInvalidValue)
+		return
+	}
+
+	document, err := service.DocumentService.RestoreDeleted(ctx, id)
+	if err != nil {
+		utils.RespondError(w, err)
+		return
+	}
+
+	w.Header().Set("ETag", etag(document.Version))
+	utils.JsonRespond(w, true, http.StatusOK, err, document)
+	return
+}
+
+func PurgeHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracing.Start(r.Context(), "PurgeHandler")
+	defer span.End()
+
+	idStr, _ := mux.Vars(r)["id"]
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		utils.RespondError(w, model.IDInvalidValue)
+		return
+	}
+
+	err = service.DocumentService.Purge(ctx, id)
+	if err != nil {
+		utils.RespondError(w, err)
+		return
+	}
+
+	utils.JsonRespond(w, true, http.StatusOK, err, nil)
+	return
+}
+
+// etag is the entity tag of a document at the given version.
+func etag(version int64) string {
+	return `"` + strconv.FormatInt(version, 10) + `"`
+}
+
+// parseIfMatch reads the version a request expects a document to be at from
+// its If-Match header. "*" matches any version and yields zero.
+func parseIfMatch(r *http.Request) (int64, error) {
+	ifMatch := strings.TrimSpace(r.Header.Get("If-Match"))
+	if ifMatch == "" {
+		return 0, model.PreconditionRequiredValue
+	}
+	if ifMatch == "*" {
+		return 0, nil
+	}
+	ifMatch = strings.TrimPrefix(ifMatch, "W/")
+	version, err := strconv.ParseInt(strings.Trim(ifMatch, `"`), 10, 64)
+	if err != nil || version <= 0 {
+		return 0, model.VersionMismatchValue
+	}
+	return version, nil
+}
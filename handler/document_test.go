@@ -2,8 +2,11 @@ package handler
 
 import (
 	"bytes"
+	"context"
 	"database/sql"
 	"encoding/json"
+	"fmt"
+	"github.com/go-sql-driver/mysql"
 	"github.com/gorilla/mux"
 	"github.com/stretchr/testify/assert"
 	"net/http"
@@ -12,38 +15,97 @@ import (
 	"precisely/service"
 	"precisely/utils"
 	"testing"
+	"time"
 )
 
 var (
 	getMessageService    func(id int64) (*model.Document, error)
 	createMessageService func(doc model.Document) (*model.Document, error)
 	updateMessageService func(doc model.Document) (*model.Document, error)
-	deleteMessageService func(id int64) error
-	getAllMessageService func() ([]*model.Document, error)
+	patchMessageService  func(id, version int64, patchType string, patch []byte) (*model.Document, error)
+	deleteMessageService func(id, version int64) error
+	getAllMessageService func(opts model.ListOptions) (*model.DocumentPage, error)
+
+	getRevisionsMessageService func(id int64) ([]*model.Revision, error)
+	getRevisionMessageService  func(id, rev int64) (*model.Revision, error)
+	getAsOfMessageService      func(id int64, asOf time.Time) (*model.Document, error)
+	getChangesMessageService   func(query model.ChangeQuery) (*model.ChangePage, error)
+	streamChangesService       func(since int64, filter model.ChangeFilter, send func(*model.Change) error) error
+	restoreMessageService      func(id, rev, version int64) (*model.Document, error)
+
+	restoreDeletedMessageService func(id int64) (*model.Document, error)
+	purgeMessageService          func(id int64) error
+	purgeExpiredMessageService   func(retention time.Duration) (int64, error)
+
+	batchMessageService func(batch model.Batch) ([]model.BatchResult, error)
 )
 
 type serviceMock struct{}
 
-func (m *serviceMock) Get(id int64) (*model.Document, error) {
+func (m *serviceMock) Get(_ context.Context, id int64) (*model.Document, error) {
 	return getMessageService(id)
 }
 
-func (m *serviceMock) GetAll() ([]*model.Document, error) {
-	return getAllMessageService()
+func (m *serviceMock) GetAll(_ context.Context, opts model.ListOptions) (*model.DocumentPage, error) {
+	return getAllMessageService(opts)
 }
 
-func (m *serviceMock) Delete(id int64) error {
-	return deleteMessageService(id)
+func (m *serviceMock) Delete(_ context.Context, id, version int64) error {
+	return deleteMessageService(id, version)
 }
 
-func (m *serviceMock) Create(doc model.Document) (*model.Document, error) {
+func (m *serviceMock) Create(_ context.Context, doc model.Document) (*model.Document, error) {
 	return createMessageService(doc)
 }
 
-func (m *serviceMock) Update(doc model.Document) (*model.Document, error) {
+func (m *serviceMock) Update(_ context.Context, doc model.Document) (*model.Document, error) {
 	return updateMessageService(doc)
 }
 
+func (m *serviceMock) Patch(_ context.Context, id, version int64, patchType string, patch []byte) (*model.Document, error) {
+	return patchMessageService(id, version, patchType, patch)
+}
+
+func (m *serviceMock) GetRevisions(_ context.Context, id int64) ([]*model.Revision, error) {
+	return getRevisionsMessageService(id)
+}
+
+func (m *serviceMock) GetRevision(_ context.Context, id, rev int64) (*model.Revision, error) {
+	return getRevisionMessageService(id, rev)
+}
+
+func (m *serviceMock) GetAsOf(_ context.Context, id int64, asOf time.Time) (*model.Document, error) {
+	return getAsOfMessageService(id, asOf)
+}
+
+func (m *serviceMock) GetChanges(_ context.Context, query model.ChangeQuery) (*model.ChangePage, error) {
+	return getChangesMessageService(query)
+}
+
+func (m *serviceMock) StreamChanges(_ context.Context, since int64, filter model.ChangeFilter, send func(*model.Change) error) error {
+	return streamChangesService(since, filter, send)
+}
+
+func (m *serviceMock) Restore(_ context.Context, id, rev, version int64) (*model.Document, error) {
+	return restoreMessageService(id, rev, version)
+}
+
+func (m *serviceMock) RestoreDeleted(_ context.Context, id int64) (*model.Document, error) {
+	return restoreDeletedMessageService(id)
+}
+
+func (m *serviceMock) Purge(_ context.Context, id int64) error {
+	return purgeMessageService(id)
+}
+
+func (m *serviceMock) Batch(_ context.Context, batch model.Batch) ([]model.BatchResult, error) {
+	return batchMessageService(batch)
+}
+
+func (m *serviceMock) PurgeExpired(_ context.Context, retention time.Duration) (int64, error) {
+	return purgeExpiredMessageService(retention)
+}
+
 func TestGetAllHandler(t *testing.T) {
 	service.DocumentService = &serviceMock{}
 	testData := []*model.Document{
@@ -53,8 +115,8 @@ func TestGetAllHandler(t *testing.T) {
 			Signee: "signee",
 		},
 	}
-	getAllMessageService = func() ([]*model.Document, error) {
-		return testData, nil
+	getAllMessageService = func(opts model.ListOptions) (*model.DocumentPage, error) {
+		return &model.DocumentPage{Documents: testData, Total: 1}, nil
 	}
 	rr := httptest.NewRecorder()
 	req, _ := http.NewRequest(http.MethodGet, "/documents", nil)
@@ -69,6 +131,57 @@ func TestGetAllHandler(t *testing.T) {
 	assert.Nil(t, err)
 	assert.EqualValues(t, 1, len(res.Data.([]interface{})))
 	assert.EqualValues(t, http.StatusOK, res.Code)
+	assert.EqualValues(t, 1, res.Meta.(map[string]interface{})["total"])
+	assert.EqualValues(t, model.DefaultListLimit, res.Meta.(map[string]interface{})["limit"])
+}
+
+func TestGetAllHandler_ListOptions(t *testing.T) {
+	service.DocumentService = &serviceMock{}
+	var got model.ListOptions
+	getAllMessageService = func(opts model.ListOptions) (*model.DocumentPage, error) {
+		got = opts
+		return &model.DocumentPage{Documents: []*model.Document{}, Total: 10, NextCursor: "Mw"}, nil
+	}
+	rr := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/documents?limit=2&cursor=Mw&sort=-id&title_prefix=Con&signee=bob", nil)
+	handler := http.HandlerFunc(GetAllHandler)
+	handler.ServeHTTP(rr, req)
+
+	var res utils.HttpResponse
+	err := json.Unmarshal(rr.Body.Bytes(), &res)
+	if err != nil {
+		t.Error(err)
+	}
+	assert.EqualValues(t, http.StatusOK, res.Code)
+	assert.EqualValues(t, 2, got.Limit)
+	assert.EqualValues(t, 3, got.Cursor)
+	assert.EqualValues(t, []model.SortField{{Field: "id", Desc: true}}, got.Sort)
+	assert.EqualValues(t, []model.Filter{
+		{Field: "title", Operator: model.FilterPrefix, Value: "Con"},
+		{Field: "signee", Operator: model.FilterExact, Value: "bob"},
+	}, got.Filters)
+	assert.EqualValues(t, "Mw", res.Meta.(map[string]interface{})["next_cursor"])
+}
+
+func TestGetAllHandler_BadRequest(t *testing.T) {
+	service.DocumentService = &serviceMock{}
+	getAllMessageService = func(opts model.ListOptions) (*model.DocumentPage, error) {
+		t.Error("service should not be called")
+		return nil, nil
+	}
+	for _, query := range []string{"limit=0", "limit=1000", "offset=-1", "sort=content", "cursor=!!", "cursor=Mw&sort=title"} {
+		rr := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, "/documents?"+query, nil)
+		handler := http.HandlerFunc(GetAllHandler)
+		handler.ServeHTTP(rr, req)
+
+		var res utils.HttpResponse
+		err := json.Unmarshal(rr.Body.Bytes(), &res)
+		if err != nil {
+			t.Error(err)
+		}
+		assert.EqualValues(t, http.StatusBadRequest, res.Code, query)
+	}
 }
 
 func TestCreateHandler_Success(t *testing.T) {
@@ -125,9 +238,30 @@ func TestCreateHandler_UnprocessableEntity(t *testing.T) {
 	assert.EqualValues(t, http.StatusUnprocessableEntity, res.Code)
 }
 
+func TestCreateHandler_DuplicateTitle(t *testing.T) {
+	service.DocumentService = &serviceMock{}
+	createMessageService = func(doc model.Document) (*model.Document, error) {
+		return nil, &mysql.MySQLError{Number: 1062, Message: "Duplicate entry 'title' for key 'title'"}
+	}
+	req, err := http.NewRequest(http.MethodPost, "/documents", bytes.NewBufferString(`{"title": "title", "signee": "signee"}`))
+	if err != nil {
+		t.Error(err)
+	}
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(CreateHandler).ServeHTTP(rr, req)
+
+	var res utils.HttpResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &res); err != nil {
+		t.Error(err)
+	}
+	assert.EqualValues(t, http.StatusConflict, res.Code)
+	assert.EqualValues(t, "duplicate_title", res.ErrorCode)
+	assert.EqualValues(t, model.DuplicateTitleValue.Error(), res.Error)
+}
+
 func TestDeleteHandler_Success(t *testing.T) {
 	service.DocumentService = &serviceMock{}
-	deleteMessageService = func(id int64) error {
+	deleteMessageService = func(id, version int64) error {
 		return nil
 	}
 
@@ -135,6 +269,7 @@ func TestDeleteHandler_Success(t *testing.T) {
 	if err != nil {
 		t.Error(err)
 	}
+	req.Header.Set("If-Match", `"1"`)
 	req = mux.SetURLVars(req, map[string]string{
 		"id": "1",
 	})
@@ -152,13 +287,14 @@ func TestDeleteHandler_Success(t *testing.T) {
 
 func TestDeleteHandler_NotFound(t *testing.T) {
 	service.DocumentService = &serviceMock{}
-	deleteMessageService = func(id int64) error {
+	deleteMessageService = func(id, version int64) error {
 		return sql.ErrNoRows
 	}
 	req, err := http.NewRequest(http.MethodDelete, "/documents", nil)
 	if err != nil {
 		t.Error(err)
 	}
+	req.Header.Set("If-Match", `"1"`)
 	req = mux.SetURLVars(req, map[string]string{
 		"id": "1",
 	})
@@ -172,7 +308,8 @@ func TestDeleteHandler_NotFound(t *testing.T) {
 		t.Error(err)
 	}
 	assert.EqualValues(t, http.StatusNotFound, res.Code)
-	assert.EqualValues(t, sql.ErrNoRows.Error(), res.Error)
+	assert.EqualValues(t, model.NotFoundValue.Error(), res.Error)
+	assert.EqualValues(t, "not_found", res.ErrorCode)
 }
 
 func TestUpdateHandler_Success(t *testing.T) {
@@ -186,6 +323,7 @@ func TestUpdateHandler_Success(t *testing.T) {
 				Header: "updated header",
 				Data:   "updated data",
 			},
+			Version: doc.Version + 1,
 		}, nil
 	}
 
@@ -204,6 +342,7 @@ func TestUpdateHandler_Success(t *testing.T) {
 	if err != nil {
 		t.Error(err)
 	}
+	req.Header.Set("If-Match", `"1"`)
 	req = mux.SetURLVars(req, map[string]string{
 		"id": "1",
 	})
@@ -226,6 +365,7 @@ func TestUpdateHandler_Success(t *testing.T) {
 	assert.EqualValues(t, "updated signee", doc["signee"])
 	assert.EqualValues(t, "updated header", content["header"])
 	assert.EqualValues(t, "updated data", content["data"])
+	assert.EqualValues(t, `"2"`, rr.Header().Get("ETag"))
 }
 
 func TestUpdateHandler_NotFound(t *testing.T) {
@@ -249,6 +389,7 @@ func TestUpdateHandler_NotFound(t *testing.T) {
 	if err != nil {
 		t.Error(err)
 	}
+	req.Header.Set("If-Match", `"1"`)
 	req = mux.SetURLVars(req, map[string]string{
 		"id": "1",
 	})
@@ -281,6 +422,7 @@ func TestUpdateHandler_InvalidJsonBody(t *testing.T) {
 	if err != nil {
 		t.Error(err)
 	}
+	req.Header.Set("If-Match", `"1"`)
 	req = mux.SetURLVars(req, map[string]string{
 		"id": "1",
 	})
@@ -296,6 +438,7 @@ func TestUpdateHandler_InvalidJsonBody(t *testing.T) {
 
 	assert.Nil(t, err)
 	assert.EqualValues(t, http.StatusBadRequest, res.Code)
+	assert.EqualValues(t, model.BodyInvalidValue.Code, res.ErrorCode)
 }
 
 func TestGetByIdHandler_Success(t *testing.T) {
@@ -308,7 +451,8 @@ func TestGetByIdHandler_Success(t *testing.T) {
 				Header: "header",
 				Data:   "data",
 			},
-			Signee: "signee",
+			Signee:  "signee",
+			Version: 3,
 		}, nil
 	}
 	req, _ := http.NewRequest(http.MethodGet, "/messages", nil)
@@ -334,6 +478,39 @@ func TestGetByIdHandler_Success(t *testing.T) {
 	assert.EqualValues(t, "signee", doc["signee"])
 	assert.EqualValues(t, "header", content["header"])
 	assert.EqualValues(t, "data", content["data"])
+	assert.EqualValues(t, `"3"`, rr.Header().Get("ETag"))
+}
+
+func TestGetByIdHandler_ContextErrors(t *testing.T) {
+	service.DocumentService = &serviceMock{}
+	tests := []struct {
+		err  error
+		code int
+	}{
+		{err: context.DeadlineExceeded, code: http.StatusGatewayTimeout},
+		{err: context.Canceled, code: utils.StatusClientClosedRequest},
+	}
+	for _, tt := range tests {
+		getMessageService = func(id int64) (*model.Document, error) {
+			return nil, fmt.Errorf("querying document: %w", tt.err)
+		}
+		req, _ := http.NewRequest(http.MethodGet, "/messages", nil)
+		req = mux.SetURLVars(req, map[string]string{
+			"id": "1",
+		})
+		rr := httptest.NewRecorder()
+		handler := http.HandlerFunc(GetByIdHandler)
+		handler.ServeHTTP(rr, req)
+
+		var res utils.HttpResponse
+		err := json.Unmarshal(rr.Body.Bytes(), &res)
+		if err != nil {
+			t.Error(err)
+		}
+
+		assert.EqualValues(t, tt.code, rr.Code)
+		assert.EqualValues(t, tt.code, res.Code)
+	}
 }
 
 func TestGetByIdHandler_NotFound(t *testing.T) {
@@ -358,3 +535,190 @@ func TestGetByIdHandler_NotFound(t *testing.T) {
 	assert.Nil(t, err)
 	assert.EqualValues(t, http.StatusNotFound, res.Code)
 }
+
+func TestGetTrashHandler(t *testing.T) {
+	service.DocumentService = &serviceMock{}
+	var got model.ListOptions
+	getAllMessageService = func(opts model.ListOptions) (*model.DocumentPage, error) {
+		got = opts
+		return &model.DocumentPage{Documents: []*model.Document{{ID: 1, Title: "title"}}, Total: 1}, nil
+	}
+	rr := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/documents/trash?sort=-deleted_at", nil)
+	handler := http.HandlerFunc(GetTrashHandler)
+	handler.ServeHTTP(rr, req)
+
+	var res utils.HttpResponse
+	err := json.Unmarshal(rr.Body.Bytes(), &res)
+	if err != nil {
+		t.Error(err)
+	}
+	assert.EqualValues(t, http.StatusOK, res.Code)
+	assert.True(t, got.Trashed)
+	assert.EqualValues(t, "deleted_at", got.Sort[0].Field)
+}
+
+func TestRestoreDeletedHandler_NotFound(t *testing.T) {
+	service.DocumentService = &serviceMock{}
+	restoreDeletedMessageService = func(id int64) (*model.Document, error) {
+		return nil, sql.ErrNoRows
+	}
+	req, _ := http.NewRequest(http.MethodPost, "/documents/1/restore", nil)
+	req = mux.SetURLVars(req, map[string]string{
+		"id": "1",
+	})
+	rr := httptest.NewRecorder()
+	handler := http.HandlerFunc(RestoreDeletedHandler)
+	handler.ServeHTTP(rr, req)
+
+	var res utils.HttpResponse
+	err := json.Unmarshal(rr.Body.Bytes(), &res)
+	if err != nil {
+		t.Error(err)
+	}
+	assert.EqualValues(t, http.StatusNotFound, res.Code)
+}
+
+func TestPurgeHandler_Success(t *testing.T) {
+	service.DocumentService = &serviceMock{}
+	var purged int64
+	purgeMessageService = func(id int64) error {
+		purged = id
+		return nil
+	}
+	req, _ := http.NewRequest(http.MethodDelete, "/documents/trash/1", nil)
+	req = mux.SetURLVars(req, map[string]string{
+		"id": "1",
+	})
+	rr := httptest.NewRecorder()
+	handler := http.HandlerFunc(PurgeHandler)
+	handler.ServeHTTP(rr, req)
+
+	var res utils.HttpResponse
+	err := json.Unmarshal(rr.Body.Bytes(), &res)
+	if err != nil {
+		t.Error(err)
+	}
+	assert.EqualValues(t, http.StatusOK, res.Code)
+	assert.EqualValues(t, 1, purged)
+}
+
+func TestUpdateHandler_Preconditions(t *testing.T) {
+	service.DocumentService = &serviceMock{}
+	var gotVersion int64
+	updateMessageService = func(doc model.Document) (*model.Document, error) {
+		gotVersion = doc.Version
+		if doc.Version != 0 && doc.Version != 4 {
+			return nil, model.VersionMismatchValue
+		}
+		doc.Version = 5
+		return &doc, nil
+	}
+	jsonBody := `{"title": "title", "signee": "signee"}`
+
+	tests := []struct {
+		ifMatch     string
+		wantCode    int
+		wantVersion int64
+	}{
+		{ifMatch: "", wantCode: http.StatusPreconditionRequired},
+		{ifMatch: `"3"`, wantCode: http.StatusPreconditionFailed, wantVersion: 3},
+		{ifMatch: `W/"4"`, wantCode: http.StatusOK, wantVersion: 4},
+		{ifMatch: "*", wantCode: http.StatusOK, wantVersion: 0},
+		{ifMatch: `"abc"`, wantCode: http.StatusPreconditionFailed},
+	}
+	for _, tt := range tests {
+		gotVersion = -1
+		req, _ := http.NewRequest(http.MethodPut, "/documents/1", bytes.NewBufferString(jsonBody))
+		req = mux.SetURLVars(req, map[string]string{
+			"id": "1",
+		})
+		if tt.ifMatch != "" {
+			req.Header.Set("If-Match", tt.ifMatch)
+		}
+		rr := httptest.NewRecorder()
+		handler := http.HandlerFunc(UpdateHandler)
+		handler.ServeHTTP(rr, req)
+
+		assert.EqualValues(t, tt.wantCode, rr.Code, tt.ifMatch)
+		if tt.wantCode == http.StatusOK {
+			assert.EqualValues(t, tt.wantVersion, gotVersion, tt.ifMatch)
+			assert.EqualValues(t, `"5"`, rr.Header().Get("ETag"), tt.ifMatch)
+		}
+	}
+}
+
+func TestDeleteHandler_PreconditionRequired(t *testing.T) {
+	service.DocumentService = &serviceMock{}
+	deleteMessageService = func(id, version int64) error {
+		t.Error("service should not be called")
+		return nil
+	}
+	req, _ := http.NewRequest(http.MethodDelete, "/documents/1", nil)
+	req = mux.SetURLVars(req, map[string]string{
+		"id": "1",
+	})
+	rr := httptest.NewRecorder()
+	handler := http.HandlerFunc(DeleteHandler)
+	handler.ServeHTTP(rr, req)
+
+	var res utils.HttpResponse
+	err := json.Unmarshal(rr.Body.Bytes(), &res)
+	if err != nil {
+		t.Error(err)
+	}
+	assert.EqualValues(t, http.StatusPreconditionRequired, res.Code)
+}
+
+func TestPatchHandler(t *testing.T) {
+	service.DocumentService = &serviceMock{}
+	var gotType string
+	patchMessageService = func(id, version int64, patchType string, patch []byte) (*model.Document, error) {
+		gotType = patchType
+		return &model.Document{ID: id, Title: "title", Signee: "signee", Version: version + 1}, nil
+	}
+
+	tests := []struct {
+		contentType string
+		body        string
+		wantCode    int
+	}{
+		{contentType: "application/merge-patch+json", body: `{"content": {"data": "data"}}`, wantCode: http.StatusOK},
+		{contentType: "application/json-patch+json; charset=utf-8", body: `[]`, wantCode: http.StatusOK},
+		{contentType: "application/json", body: `{}`, wantCode: http.StatusUnsupportedMediaType},
+		{contentType: "application/merge-patch+json", body: `{`, wantCode: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		gotType = ""
+		req, _ := http.NewRequest(http.MethodPatch, "/documents/1", bytes.NewBufferString(tt.body))
+		req = mux.SetURLVars(req, map[string]string{
+			"id": "1",
+		})
+		req.Header.Set("Content-Type", tt.contentType)
+		req.Header.Set("If-Match", `"2"`)
+		rr := httptest.NewRecorder()
+		handler := http.HandlerFunc(PatchHandler)
+		handler.ServeHTTP(rr, req)
+
+		assert.EqualValues(t, tt.wantCode, rr.Code, tt.contentType)
+		if tt.wantCode == http.StatusOK {
+			assert.EqualValues(t, `"3"`, rr.Header().Get("ETag"))
+			assert.Contains(t, tt.contentType, gotType)
+		}
+	}
+}
+
+func TestDeleteHandler_IDOutOfRange(t *testing.T) {
+	req, _ := http.NewRequest(http.MethodDelete, "/documents/99999999999999999999", nil)
+	req = mux.SetURLVars(req, map[string]string{
+		"id": "99999999999999999999",
+	})
+	rr := httptest.NewRecorder()
+	handler := http.HandlerFunc(DeleteHandler)
+	handler.ServeHTTP(rr, req)
+
+	var res utils.HttpResponse
+	assert.Nil(t, json.Unmarshal(rr.Body.Bytes(), &res))
+	assert.EqualValues(t, http.StatusBadRequest, rr.Code)
+	assert.EqualValues(t, model.IDInvalidValue.Code, res.ErrorCode)
+}
@@ -0,0 +1,132 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"precisely/model"
+	"precisely/service"
+	"precisely/tracing"
+	"precisely/utils"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// EventStreamDuration is how long an event stream lasts before the server
+// ends it, shorter than its write timeout. Clients reconnect with the
+// Last-Event-ID they saw and miss nothing.
+var EventStreamDuration = 10 * time.Second
+
+// eventStreamGrace is what a stream must leave of the write timeout of the
+// server for its last events to be written once it ends.
+const eventStreamGrace = time.Second
+
+// ConfigureEventStream sets EventStreamDuration to duration, or keeps the
+// default when duration is zero, and fails unless the streams end at least
+// eventStreamGrace before writeTimeout, past which the server would cut them
+// without their last events.
+func ConfigureEventStream(duration, writeTimeout time.Duration) error {
+	if duration < 0 {
+		return fmt.Errorf("invalid event stream duration %s, expect a positive duration", duration)
+	}
+	if duration == 0 {
+		duration = EventStreamDuration
+	}
+	if duration > writeTimeout-eventStreamGrace {
+		return fmt.Errorf("event stream duration %s leaves less than %s of the write timeout %s", duration, eventStreamGrace, writeTimeout)
+	}
+	EventStreamDuration = duration
+	return nil
+}
+
+// eventStreamRetry is the reconnection delay clients are told to wait, in
+// milliseconds.
+const eventStreamRetry = 1000
+
+// EventsHandler streams the changes of documents as Server-Sent Events, each
+// with the change feed cursor of the change as its id. A client resumes after
+// the id in Last-Event-ID, or the cursor in since; without either the stream
+// starts from now on. The changes may be restricted to those of document_id,
+// signee or both.
+func EventsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracing.Start(r.Context(), "EventsHandler")
+	defer span.End()
+
+	query := r.URL.Query()
+	filter := model.ChangeFilter{Signee: strings.TrimSpace(query.Get("signee"))}
+	if v := query.Get("document_id"); v != "" {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || id <= 0 {
+			utils.RespondError(w, model.EventFilterInvalidValue)
+			return
+		}
+		filter.DocumentID = id
+	}
+	cursor := r.Header.Get("Last-Event-ID")
+	if cursor == "" {
+		cursor = query.Get("since")
+	}
+	var since int64
+	if cursor != "" {
+		var err error
+		if since, err = model.DecodeCursor(cursor); err != nil {
+			utils.RespondError(w, err)
+			return
+		}
+	}
+	flusher := findFlusher(w)
+	if flusher == nil {
+		utils.RespondError(w, model.StreamingUnsupportedValue)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, EventStreamDuration)
+	defer cancel()
+	started := false
+	start := func() {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("X-Accel-Buffering", "no")
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprintf(w, "retry: %d\n\n", eventStreamRetry)
+		flusher.Flush()
+		started = true
+	}
+	err := service.DocumentService.StreamChanges(ctx, since, filter, func(change *model.Change) error {
+		if !started {
+			start()
+		}
+		if change == nil {
+			return nil
+		}
+		data, err := json.Marshal(change)
+		if err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", model.EncodeCursor(change.Sequence), change.Operation, data); err != nil {
+			return err
+		}
+		flusher.Flush()
+		return nil
+	})
+	if err != nil && !started {
+		utils.RespondError(w, err)
+	}
+}
+
+// findFlusher returns the first of the wrapped response writers that flushes,
+// nil when none does.
+func findFlusher(w http.ResponseWriter) http.Flusher {
+	for {
+		switch rw := w.(type) {
+		case http.Flusher:
+			return rw
+		case interface{ Unwrap() http.ResponseWriter }:
+			w = rw.Unwrap()
+		default:
+			return nil
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package handler
+
+import (
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"precisely/model"
+	"precisely/service"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEventsHandler_Streams(t *testing.T) {
+	service.DocumentService = &serviceMock{}
+	var gotSince int64
+	var gotFilter model.ChangeFilter
+	streamChangesService = func(since int64, filter model.ChangeFilter, send func(*model.Change) error) error {
+		gotSince, gotFilter = since, filter
+		if err := send(nil); err != nil {
+			return err
+		}
+		if err := send(&model.Change{Sequence: 4, Operation: model.ChangeUpdate, DocumentID: 1, Signee: "bob"}); err != nil {
+			return err
+		}
+		return send(&model.Change{Sequence: 6, Operation: model.ChangeDelete, DocumentID: 1, Signee: "bob"})
+	}
+	req, _ := http.NewRequest(http.MethodGet, "/documents/events?document_id=1&signee=bob", nil)
+	req.Header.Set("Last-Event-ID", model.EncodeCursor(3))
+	rr := httptest.NewRecorder()
+	handler := http.HandlerFunc(EventsHandler)
+	handler.ServeHTTP(rr, req)
+
+	assert.EqualValues(t, http.StatusOK, rr.Code)
+	assert.EqualValues(t, "text/event-stream", rr.Header().Get("Content-Type"))
+	assert.EqualValues(t, 3, gotSince)
+	assert.EqualValues(t, model.ChangeFilter{DocumentID: 1, Signee: "bob"}, gotFilter)
+	events := strings.Split(strings.TrimSpace(rr.Body.String()), "\n\n")
+	assert.EqualValues(t, 3, len(events))
+	assert.EqualValues(t, "retry: 1000", events[0])
+	assert.True(t, strings.HasPrefix(events[1], "id: "+model.EncodeCursor(4)+"\nevent: update\ndata: {\"sequence\":4,"))
+	assert.True(t, strings.HasPrefix(events[2], "id: "+model.EncodeCursor(6)+"\nevent: delete\ndata: "))
+}
+
+func TestEventsHandler_ResumesFromSince(t *testing.T) {
+	service.DocumentService = &serviceMock{}
+	var gotSince int64
+	streamChangesService = func(since int64, filter model.ChangeFilter, send func(*model.Change) error) error {
+		gotSince = since
+		return send(nil)
+	}
+	req, _ := http.NewRequest(http.MethodGet, "/documents/events?since="+model.EncodeCursor(8), nil)
+	rr := httptest.NewRecorder()
+	handler := http.HandlerFunc(EventsHandler)
+	handler.ServeHTTP(rr, req)
+
+	assert.EqualValues(t, http.StatusOK, rr.Code)
+	assert.EqualValues(t, 8, gotSince)
+}
+
+func TestEventsHandler_InvalidQuery(t *testing.T) {
+	service.DocumentService = &serviceMock{}
+	for _, query := range []string{"document_id=x", "document_id=0", "since=nope"} {
+		req, _ := http.NewRequest(http.MethodGet, "/documents/events?"+query, nil)
+		rr := httptest.NewRecorder()
+		handler := http.HandlerFunc(EventsHandler)
+		handler.ServeHTTP(rr, req)
+
+		assert.EqualValues(t, http.StatusBadRequest, rr.Code, query)
+	}
+}
+
+func TestEventsHandler_Forbidden(t *testing.T) {
+	service.DocumentService = &serviceMock{}
+	streamChangesService = func(since int64, filter model.ChangeFilter, send func(*model.Change) error) error {
+		return model.ForbiddenValue
+	}
+	req, _ := http.NewRequest(http.MethodGet, "/documents/events", nil)
+	rr := httptest.NewRecorder()
+	handler := http.HandlerFunc(EventsHandler)
+	handler.ServeHTTP(rr, req)
+
+	assert.EqualValues(t, http.StatusForbidden, rr.Code)
+	assert.NotEqual(t, "text/event-stream", rr.Header().Get("Content-Type"))
+}
+
+func TestConfigureEventStream(t *testing.T) {
+	defer func(duration time.Duration) { EventStreamDuration = duration }(EventStreamDuration)
+
+	assert.Nil(t, ConfigureEventStream(0, 15*time.Second))
+	assert.EqualValues(t, 10*time.Second, EventStreamDuration)
+	assert.Nil(t, ConfigureEventStream(14*time.Second, 15*time.Second))
+	assert.EqualValues(t, 14*time.Second, EventStreamDuration)
+
+	for _, duration := range []time.Duration{-time.Second, 15 * time.Second, 14*time.Second + 1, time.Minute} {
+		EventStreamDuration = 10 * time.Second
+		assert.NotNil(t, ConfigureEventStream(duration, 15*time.Second), duration)
+		assert.EqualValues(t, 10*time.Second, EventStreamDuration, duration)
+	}
+	assert.NotNil(t, ConfigureEventStream(0, 5*time.Second))
+}
@@ -0,0 +1,84 @@
+package handler
+
+import (
+	"encoding/json"
+	"github.com/gorilla/mux"
+	"net/http"
+	"precisely/model"
+	"precisely/service"
+	"precisely/tracing"
+	"precisely/utils"
+	"strconv"
+)
+
+func GetGrantsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracing.Start(r.Context(), "GetGrantsHandler")
+	defer span.End()
+
+	idStr, _ := mux.Vars(r)["id"]
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		utils.RespondError(w, model.IDInvalidValue)
+		return
+	}
+
+	grants, err := service.GrantService.GetAll(ctx, id)
+	if err != nil {
+		utils.RespondError(w, err)
+		return
+	}
+	utils.JsonRespond(w, true, http.StatusOK, err, grants)
+	return
+}
+
+func CreateGrantHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracing.Start(r.Context(), "CreateGrantHandler")
+	defer span.End()
+
+	var grant model.Grant
+	err := json.NewDecoder(r.Body).Decode(&grant)
+	if err != nil {
+		utils.RespondError(w, model.BodyInvalidValue)
+		return
+	}
+	idStr, _ := mux.Vars(r)["id"]
+	grant.DocumentID, err = strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		utils.RespondError(w, model.IDInvalidValue)
+		return
+	}
+
+	created, err := service.GrantService.Grant(ctx, grant)
+	if err != nil {
+		utils.RespondError(w, err)
+		return
+	}
+	utils.JsonRespond(w, true, http.StatusCreated, err, created)
+	return
+}
+
+func RevokeGrantHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracing.Start(r.Context(), "RevokeGrantHandler")
+	defer span.End()
+
+	idStr, _ := mux.Vars(r)["id"]
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		utils.RespondError(w, model.IDInvalidValue)
+		return
+	}
+	grantStr, _ := mux.Vars(r)["grant"]
+	grantID, err := strconv.ParseInt(grantStr, 10, 64)
+	if err != nil {
+		utils.RespondError(w, model.IDInvalidValue)
+		return
+	}
+
+	err = service.GrantService.Revoke(ctx, id, grantID)
+	if err != nil {
+		utils.RespondError(w, err)
+		return
+	}
+	utils.JsonRespond(w, true, http.StatusOK, err, nil)
+	return
+}
@@ -0,0 +1,115 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"precisely/model"
+	"precisely/service"
+	"precisely/utils"
+	"testing"
+)
+
+var (
+	getGrantsService   func(documentID int64) ([]*model.Grant, error)
+	grantService       func(grant model.Grant) (*model.Grant, error)
+	revokeGrantService func(documentID, id int64) error
+)
+
+type grantServiceMock struct{}
+
+func (m *grantServiceMock) GetAll(_ context.Context, documentID int64) ([]*model.Grant, error) {
+	return getGrantsService(documentID)
+}
+
+func (m *grantServiceMock) Grant(_ context.Context, grant model.Grant) (*model.Grant, error) {
+	return grantService(grant)
+}
+
+func (m *grantServiceMock) Revoke(_ context.Context, documentID, id int64) error {
+	return revokeGrantService(documentID, id)
+}
+
+func TestCreateGrantHandler(t *testing.T) {
+	service.GrantService = &grantServiceMock{}
+	tests := []struct {
+		name string
+		err  error
+		code int
+	}{
+		{name: "Created", code: http.StatusCreated},
+		{name: "Not found", err: sql.ErrNoRows, code: http.StatusNotFound},
+		{name: "Forbidden", err: model.ForbiddenValue, code: http.StatusForbidden},
+		{name: "Unprocessable", err: model.RoleInvalidValue, code: http.StatusUnprocessableEntity},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			grantService = func(grant model.Grant) (*model.Grant, error) {
+				if tt.err != nil {
+					return nil, tt.err
+				}
+				assert.EqualValues(t, 7, grant.DocumentID)
+				grant.ID = 1
+				return &grant, nil
+			}
+			req, _ := http.NewRequest(http.MethodPost, "/documents/7/grants", bytes.NewBufferString(`{"grantee_type": "group", "grantee": "legal", "role": "signer"}`))
+			req = mux.SetURLVars(req, map[string]string{
+				"id": "7",
+			})
+			rr := httptest.NewRecorder()
+			http.HandlerFunc(CreateGrantHandler).ServeHTTP(rr, req)
+
+			var res utils.HttpResponse
+			err := json.Unmarshal(rr.Body.Bytes(), &res)
+			if err != nil {
+				t.Error(err)
+			}
+			assert.EqualValues(t, tt.code, res.Code)
+			if tt.err == nil {
+				assert.EqualValues(t, "legal", res.Data.(map[string]interface{})["grantee"])
+			}
+		})
+	}
+}
+
+func TestGetGrantsHandler(t *testing.T) {
+	service.GrantService = &grantServiceMock{}
+	getGrantsService = func(documentID int64) ([]*model.Grant, error) {
+		return []*model.Grant{{ID: 1, DocumentID: documentID, GranteeType: model.GranteeUser, Grantee: "bob", Role: model.RoleEditor}}, nil
+	}
+	req, _ := http.NewRequest(http.MethodGet, "/documents/7/grants", nil)
+	req = mux.SetURLVars(req, map[string]string{
+		"id": "7",
+	})
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(GetGrantsHandler).ServeHTTP(rr, req)
+
+	var res utils.HttpResponse
+	err := json.Unmarshal(rr.Body.Bytes(), &res)
+	if err != nil {
+		t.Error(err)
+	}
+	assert.EqualValues(t, http.StatusOK, res.Code)
+	assert.Len(t, res.Data, 1)
+}
+
+func TestRevokeGrantHandler_NotFound(t *testing.T) {
+	service.GrantService = &grantServiceMock{}
+	revokeGrantService = func(documentID, id int64) error {
+		return sql.ErrNoRows
+	}
+	req, _ := http.NewRequest(http.MethodDelete, "/documents/7/grants/3", nil)
+	req = mux.SetURLVars(req, map[string]string{
+		"id":    "7",
+		"grant": "3",
+	})
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(RevokeGrantHandler).ServeHTTP(rr, req)
+
+	assert.EqualValues(t, http.StatusNotFound, rr.Code)
+}
@@ -0,0 +1,25 @@
+package handler
+
+import (
+	"net/http"
+	"precisely/service"
+	"precisely/tracing"
+	"precisely/utils"
+)
+
+// HealthzHandler answers as long as the process serves requests.
+func HealthzHandler(w http.ResponseWriter, r *http.Request) {
+	utils.JsonRespond(w, true, http.StatusOK, nil, nil)
+}
+
+// ReadyzHandler answers 503 while the server should not receive traffic.
+func ReadyzHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracing.Start(r.Context(), "ReadyzHandler")
+	defer span.End()
+
+	if err := service.HealthService.Ready(ctx); err != nil {
+		utils.RespondError(w, err)
+		return
+	}
+	utils.JsonRespond(w, true, http.StatusOK, nil, nil)
+}
@@ -0,0 +1,68 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"precisely/model"
+	"precisely/service"
+	"precisely/utils"
+	"testing"
+)
+
+var readyMessageService func() error
+
+type healthServiceMock struct{}
+
+func (m *healthServiceMock) Ready(_ context.Context) error {
+	return readyMessageService()
+}
+
+func (m *healthServiceMock) Drain() {}
+
+func TestHealthzHandler(t *testing.T) {
+	req, _ := http.NewRequest(http.MethodGet, "/healthz", nil)
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(HealthzHandler).ServeHTTP(rr, req)
+
+	assert.EqualValues(t, http.StatusOK, rr.Code)
+}
+
+func TestReadyzHandler(t *testing.T) {
+	service.HealthService = &healthServiceMock{}
+	tests := []struct {
+		name string
+		err  error
+		code int
+	}{
+		{name: "Ready", code: http.StatusOK},
+		{name: "Draining", err: model.DrainingValue, code: http.StatusServiceUnavailable},
+		{name: "Schema", err: model.SchemaVersionMismatchValue, code: http.StatusServiceUnavailable},
+		{name: "Unreachable", err: model.NotReady(errors.New("dial tcp 10.0.0.1:3306: connect: connection refused")), code: http.StatusServiceUnavailable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			readyMessageService = func() error {
+				return tt.err
+			}
+			req, _ := http.NewRequest(http.MethodGet, "/readyz", nil)
+			rr := httptest.NewRecorder()
+			http.HandlerFunc(ReadyzHandler).ServeHTTP(rr, req)
+
+			var res utils.HttpResponse
+			err := json.Unmarshal(rr.Body.Bytes(), &res)
+			if err != nil {
+				t.Error(err)
+			}
+			assert.EqualValues(t, tt.code, rr.Code)
+			assert.EqualValues(t, tt.code, res.Code)
+			assert.NotContains(t, rr.Body.String(), "10.0.0.1")
+			if tt.err != nil {
+				assert.Equal(t, model.ErrorOf(tt.err).Code, res.ErrorCode)
+			}
+		})
+	}
+}
@@ -0,0 +1,143 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"precisely/logging"
+	"precisely/model"
+	"precisely/service"
+	"precisely/utils"
+	"time"
+)
+
+const (
+	IdempotencyKeyHeader = "Idempotency-Key"
+	// IdempotentReplayedHeader marks a response replayed from the first
+	// attempt of a request.
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+)
+
+// idempotencyRecordTimeout bounds recording the outcome of a request, which
+// outlives the request so that a client going away does not leave its key
+// taken.
+const idempotencyRecordTimeout = 5 * time.Second
+
+// Idempotent has next run once per Idempotency-Key of the principal: a retry
+// under the key gets the recorded response of the first attempt, or 422 when
+// its method, path or body differ, or 409 while the first attempt is still in
+// progress. Responses with a 5xx status are not recorded, so that retrying
+// them runs next again, and neither are failures of a request whose client
+// went away, which tell of the cancellation rather than of the request.
+// Requests without the header run next as usual.
+func Idempotent(next http.HandlerFunc) http.HandlerFunc {
+	return IdempotentRedacting(next)
+}
+
+// IdempotentRedacting is Idempotent for a handler whose response carries a
+// secret in the given fields of its data, such as a new API key. The fields
+// are left out of the recorded response, so that a retry learns what the
+// first attempt made without a second secret being made or the first one
+// being stored.
+func IdempotentRedacting(next http.HandlerFunc, fields ...string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(IdempotencyKeyHeader)
+		if key == "" {
+			next(w, r)
+			return
+		}
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			utils.RespondError(w, model.BodyInvalidValue)
+			return
+		}
+		r.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+		record, err := service.IdempotencyService.Begin(r.Context(), key, model.RequestFingerprint(r.Method, r.URL.Path, body))
+		if err != nil {
+			utils.RespondError(w, err)
+			return
+		}
+		if record.Completed() {
+			for name, values := range record.Header {
+				w.Header()[name] = values
+			}
+			w.Header().Set(IdempotentReplayedHeader, "true")
+			w.WriteHeader(record.StatusCode)
+			w.Write(record.Body)
+			return
+		}
+
+		recorder := &bodyRecorder{ResponseWriter: w, code: http.StatusOK}
+		next(recorder, r)
+
+		ctx, cancel := context.WithTimeout(context.Background(), idempotencyRecordTimeout)
+		defer cancel()
+		log := logging.FromContext(r.Context()).WithField("idempotency_key", key)
+		if !replayable(r, recorder.code) {
+			if err := service.IdempotencyService.Release(ctx, *record); err != nil {
+				log.WithError(err).Error("failed releasing an idempotency key")
+			}
+			return
+		}
+		if err := service.IdempotencyService.Complete(ctx, *record, recorder.code, w.Header(), redact(recorder.body.Bytes(), fields)); err != nil {
+			log.WithError(err).Error("failed recording an idempotent response")
+		}
+	}
+}
+
+// replayable reports whether the response of r with the given status is the
+// outcome of the request, to be replayed to its retries. A success is, even
+// when the client went away, since what it reports was done.
+func replayable(r *http.Request, code int) bool {
+	switch {
+	case code >= http.StatusInternalServerError, code == utils.StatusClientClosedRequest:
+		return false
+	case r.Context().Err() != nil:
+		return code < http.StatusBadRequest
+	}
+	return true
+}
+
+// redact leaves fields out of the data of a JSON response body. Other bodies,
+// such as those of errors, are kept as they are.
+func redact(body []byte, fields []string) []byte {
+	if len(fields) == 0 {
+		return body
+	}
+	var response map[string]json.RawMessage
+	var data map[string]json.RawMessage
+	if json.Unmarshal(body, &response) != nil || json.Unmarshal(response["data"], &data) != nil {
+		return body
+	}
+	for _, field := range fields {
+		delete(data, field)
+	}
+	response["data"], _ = json.Marshal(data)
+	redacted, _ := json.Marshal(response)
+	return redacted
+}
+
+// bodyRecorder keeps a copy of the status and body of the response it
+// writes.
+type bodyRecorder struct {
+	http.ResponseWriter
+	code int
+	body bytes.Buffer
+}
+
+func (r *bodyRecorder) WriteHeader(code int) {
+	r.code = code
+	r.ResponseWriter.WriteHeader(code)
+}
+
+func (r *bodyRecorder) Write(b []byte) (int, error) {
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
+
+func (r *bodyRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
@@ -0,0 +1,169 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"precisely/model"
+	"precisely/utils"
+	"strings"
+	"testing"
+	"time"
+)
+
+// countingHandler responds with code and the body it was sent, counting its
+// calls.
+func countingHandler(calls *int, code int) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		*calls++
+		body, _ := ioutil.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Location", "/documents/1")
+		w.WriteHeader(code)
+		w.Write(body)
+	}
+}
+
+func idempotentRequest(key, body string) *http.Request {
+	req, _ := http.NewRequest(http.MethodPost, "/documents", bytes.NewBufferString(body))
+	if key != "" {
+		req.Header.Set(IdempotencyKeyHeader, key)
+	}
+	return req.WithContext(model.WithPrincipal(context.Background(), &model.Principal{Subject: "ci", Roles: []string{"writer"}}))
+}
+
+func TestIdempotent_Replay(t *testing.T) {
+	model.IdempotencyRepository = model.NewMemoryIdempotencyRepository()
+	calls := 0
+	h := Idempotent(countingHandler(&calls, http.StatusCreated))
+
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, idempotentRequest("k1", `{"title":"a"}`))
+	assert.Equal(t, http.StatusCreated, rr.Code)
+	assert.Equal(t, `{"title":"a"}`, rr.Body.String())
+	assert.Empty(t, rr.Header().Get(IdempotentReplayedHeader))
+
+	rr = httptest.NewRecorder()
+	h.ServeHTTP(rr, idempotentRequest("k1", `{"title":"a"}`))
+	assert.Equal(t, 1, calls)
+	assert.Equal(t, http.StatusCreated, rr.Code)
+	assert.Equal(t, `{"title":"a"}`, rr.Body.String())
+	assert.Equal(t, "/documents/1", rr.Header().Get("Location"))
+	assert.Equal(t, "true", rr.Header().Get(IdempotentReplayedHeader))
+
+	rr = httptest.NewRecorder()
+	h.ServeHTTP(rr, idempotentRequest("k1", `{"title":"b"}`))
+	assert.Equal(t, 1, calls)
+	assert.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+
+	rr = httptest.NewRecorder()
+	h.ServeHTTP(rr, idempotentRequest("", `{"title":"a"}`))
+	assert.Equal(t, 2, calls, "requests without a key always run")
+}
+
+func TestIdempotent_ServerError(t *testing.T) {
+	model.IdempotencyRepository = model.NewMemoryIdempotencyRepository()
+	calls := 0
+	h := Idempotent(countingHandler(&calls, http.StatusInternalServerError))
+	for i := 0; i < 2; i++ {
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, idempotentRequest("k1", `{"title":"a"}`))
+		assert.Equal(t, http.StatusInternalServerError, rr.Code)
+	}
+	assert.Equal(t, 2, calls, "failed requests are not replayed")
+}
+
+func TestIdempotent_ClientGone(t *testing.T) {
+	tests := []struct {
+		name   string
+		code   int
+		replay bool
+	}{
+		{name: "Client closed request", code: utils.StatusClientClosedRequest},
+		{name: "Failed after the client went away", code: http.StatusNotFound},
+		{name: "Succeeded before the client went away", code: http.StatusCreated, replay: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			model.IdempotencyRepository = model.NewMemoryIdempotencyRepository()
+			calls := 0
+			req := idempotentRequest("k1", `{"title":"a"}`)
+			ctx, cancel := context.WithCancel(req.Context())
+			count := countingHandler(&calls, tt.code)
+			h := Idempotent(func(w http.ResponseWriter, r *http.Request) {
+				cancel()
+				count(w, r)
+			})
+			h.ServeHTTP(httptest.NewRecorder(), req.WithContext(ctx))
+
+			rr := httptest.NewRecorder()
+			Idempotent(countingHandler(&calls, http.StatusCreated)).ServeHTTP(rr, idempotentRequest("k1", `{"title":"a"}`))
+			if tt.replay {
+				assert.Equal(t, 1, calls)
+				assert.Equal(t, "true", rr.Header().Get(IdempotentReplayedHeader))
+			} else {
+				assert.Equal(t, 2, calls, "the retry runs again")
+				assert.Equal(t, http.StatusCreated, rr.Code)
+			}
+		})
+	}
+}
+
+func TestIdempotentRedacting(t *testing.T) {
+	model.IdempotencyRepository = model.NewMemoryIdempotencyRepository()
+	calls := 0
+	h := IdempotentRedacting(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		utils.JsonRespond(w, true, http.StatusCreated, nil, model.APIKey{ID: 7, Name: "ci", Key: "prk_secret"})
+	}, "key")
+
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, idempotentRequest("k1", `{"name":"ci"}`))
+	assert.Contains(t, rr.Body.String(), "prk_secret")
+
+	rr = httptest.NewRecorder()
+	h.ServeHTTP(rr, idempotentRequest("k1", `{"name":"ci"}`))
+	assert.Equal(t, 1, calls)
+	assert.Equal(t, http.StatusCreated, rr.Code)
+	assert.NotContains(t, rr.Body.String(), "prk_secret")
+	var res struct {
+		Data model.APIKey `json:"data"`
+	}
+	assert.Nil(t, json.Unmarshal(rr.Body.Bytes(), &res))
+	assert.EqualValues(t, 7, res.Data.ID)
+}
+
+func TestIdempotent_Errors(t *testing.T) {
+	model.IdempotencyRepository = model.NewMemoryIdempotencyRepository()
+	ctx := model.WithPrincipal(context.Background(), &model.Principal{Subject: "ci", Roles: []string{"writer"}})
+	if _, _, err := model.IdempotencyRepository.Reserve(ctx, model.IdempotencyRecord{
+		Scope:       "ci",
+		Key:         "busy",
+		Fingerprint: model.RequestFingerprint(http.MethodPost, "/documents", []byte(`{}`)),
+		ExpiresAt:   time.Now().Add(time.Minute),
+	}); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name string
+		req  *http.Request
+		code int
+	}{
+		{name: "In progress", req: idempotentRequest("busy", `{}`), code: http.StatusConflict},
+		{name: "Key too long", req: idempotentRequest(strings.Repeat("k", model.MaxIdempotencyKeyLength+1), `{}`), code: http.StatusBadRequest},
+		{name: "Unauthenticated", req: idempotentRequest("k1", `{}`).WithContext(context.Background()), code: http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			rr := httptest.NewRecorder()
+			Idempotent(countingHandler(&calls, http.StatusCreated)).ServeHTTP(rr, tt.req)
+			assert.Equal(t, tt.code, rr.Code)
+			assert.Equal(t, 0, calls)
+		})
+	}
+}
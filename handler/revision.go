@@ -0,0 +1,93 @@
+package handler
+
+import (
+	"github.com/gorilla/mux"
+	"net/http"
+	"precisely/model"
+	"precisely/service"
+	"precisely/tracing"
+	"precisely/utils"
+	"strconv"
+)
+
+func GetRevisionsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracing.Start(r.Context(), "GetRevisionsHandler")
+	defer span.End()
+
+	idStr, _ := mux.Vars(r)["id"]
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		utils.RespondError(w, model.IDInvalidValue)
+		return
+	}
+
+	revisions, err := service.DocumentService.GetRevisions(ctx, id)
+	if err != nil {
+		utils.RespondError(w, err)
+		return
+	}
+
+	utils.JsonRespond(w, true, http.StatusOK, err, revisions)
+	return
+}
+
+func GetRevisionHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracing.Start(r.Context(), "GetRevisionHandler")
+	defer span.End()
+
+	idStr, _ := mux.Vars(r)["id"]
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		utils.RespondError(w, model.IDInvalidValue)
+		return
+	}
+	revStr, _ := mux.Vars(r)["rev"]
+	rev, err := strconv.ParseInt(revStr, 10, 64)
+	if err != nil {
+		utils.RespondError(w, model.IDInvalidValue)
+		return
+	}
+
+	revision, err := service.DocumentService.GetRevision(ctx, id, rev)
+	if err != nil {
+		utils.RespondError(w, err)
+		return
+	}
+
+	utils.JsonRespond(w, true, http.StatusOK, err, revision)
+	return
+}
+
+func RestoreRevisionHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracing.Start(r.Context(), "RestoreRevisionHandler")
+	defer span.End()
+
+	idStr, _ := mux.Vars(r)["id"]
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		utils.RespondError(w, model.IDInvalidValue)
+		return
+	}
+	revStr, _ := mux.Vars(r)["rev"]
+	rev, err := strconv.ParseInt(revStr, 10, 64)
+	if err != nil {
+		utils.RespondError(w, model.IDInvalidValue)
+		return
+	}
+
+	version, err := parseIfMatch(r)
+	if err != nil {
+		utils.RespondError(w, err)
+		return
+	}
+
+	document, err := service.DocumentService.Restore(ctx, id, rev, version)
+	if err != nil {
+		utils.RespondError(w, err)
+		return
+	}
+
+	w.Header().Set("ETag", etag(document.Version))
+	utils.JsonRespond(w, true, http.StatusOK, err, document)
+	return
+}
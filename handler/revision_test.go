@@ -0,0 +1,173 @@
+package handler
+
+import (
+	"database/sql"
+	"encoding/json"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"precisely/model"
+	"precisely/service"
+	"precisely/utils"
+	"testing"
+	"time"
+)
+
+func TestGetRevisionsHandler_Success(t *testing.T) {
+	service.DocumentService = &serviceMock{}
+	getRevisionsMessageService = func(id int64) ([]*model.Revision, error) {
+		return []*model.Revision{
+			{Revision: 1, Operation: model.RevisionCreate, Document: model.Document{ID: id, Title: "title"}},
+			{Revision: 2, Operation: model.RevisionUpdate, Document: model.Document{ID: id, Title: "new title"}},
+		}, nil
+	}
+	req, _ := http.NewRequest(http.MethodGet, "/documents/1/revisions", nil)
+	req = mux.SetURLVars(req, map[string]string{
+		"id": "1",
+	})
+	rr := httptest.NewRecorder()
+	handler := http.HandlerFunc(GetRevisionsHandler)
+	handler.ServeHTTP(rr, req)
+
+	var res utils.HttpResponse
+	err := json.Unmarshal(rr.Body.Bytes(), &res)
+	if err != nil {
+		t.Error(err)
+	}
+	revisions := res.Data.([]interface{})
+	assert.EqualValues(t, http.StatusOK, res.Code)
+	assert.EqualValues(t, 2, len(revisions))
+	assert.EqualValues(t, "new title", revisions[1].(map[string]interface{})["title"])
+	assert.EqualValues(t, model.RevisionUpdate, revisions[1].(map[string]interface{})["operation"])
+}
+
+func TestGetRevisionHandler_NotFound(t *testing.T) {
+	service.DocumentService = &serviceMock{}
+	getRevisionMessageService = func(id, rev int64) (*model.Revision, error) {
+		return nil, sql.ErrNoRows
+	}
+	req, _ := http.NewRequest(http.MethodGet, "/documents/1/revisions/4", nil)
+	req = mux.SetURLVars(req, map[string]string{
+		"id":  "1",
+		"rev": "4",
+	})
+	rr := httptest.NewRecorder()
+	handler := http.HandlerFunc(GetRevisionHandler)
+	handler.ServeHTTP(rr, req)
+
+	var res utils.HttpResponse
+	err := json.Unmarshal(rr.Body.Bytes(), &res)
+	if err != nil {
+		t.Error(err)
+	}
+	assert.EqualValues(t, http.StatusNotFound, res.Code)
+}
+
+func TestRestoreRevisionHandler_UnprocessableEntity(t *testing.T) {
+	service.DocumentService = &serviceMock{}
+	restoreMessageService = func(id, rev, version int64) (*model.Document, error) {
+		return nil, model.RevisionInvalidValue
+	}
+	req, _ := http.NewRequest(http.MethodPost, "/documents/1/revisions/3/restore", nil)
+	req.Header.Set("If-Match", `"4"`)
+	req = mux.SetURLVars(req, map[string]string{
+		"id":  "1",
+		"rev": "3",
+	})
+	rr := httptest.NewRecorder()
+	handler := http.HandlerFunc(RestoreRevisionHandler)
+	handler.ServeHTTP(rr, req)
+
+	var res utils.HttpResponse
+	err := json.Unmarshal(rr.Body.Bytes(), &res)
+	if err != nil {
+		t.Error(err)
+	}
+	assert.EqualValues(t, http.StatusUnprocessableEntity, res.Code)
+}
+
+func TestRestoreRevisionHandler_IfMatch(t *testing.T) {
+	service.DocumentService = &serviceMock{}
+	var got int64
+	restoreMessageService = func(id, rev, version int64) (*model.Document, error) {
+		got = version
+		if version != 4 {
+			return nil, model.VersionMismatchValue
+		}
+		return &model.Document{ID: id, Title: "old title", Signee: "signee", Version: 5}, nil
+	}
+	tests := []struct {
+		name    string
+		ifMatch string
+		code    int
+	}{
+		{name: "Current version", ifMatch: `"4"`, code: http.StatusOK},
+		{name: "Stale version", ifMatch: `"3"`, code: http.StatusPreconditionFailed},
+		{name: "Missing", code: http.StatusPreconditionRequired},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got = 0
+			req, _ := http.NewRequest(http.MethodPost, "/documents/1/revisions/1/restore", nil)
+			if tt.ifMatch != "" {
+				req.Header.Set("If-Match", tt.ifMatch)
+			}
+			req = mux.SetURLVars(req, map[string]string{
+				"id":  "1",
+				"rev": "1",
+			})
+			rr := httptest.NewRecorder()
+			http.HandlerFunc(RestoreRevisionHandler).ServeHTTP(rr, req)
+
+			assert.EqualValues(t, tt.code, rr.Code)
+			if tt.code == http.StatusOK {
+				assert.EqualValues(t, 4, got)
+				assert.Equal(t, `"5"`, rr.Header().Get("ETag"))
+			}
+		})
+	}
+}
+
+func TestGetByIdHandler_AsOf(t *testing.T) {
+	service.DocumentService = &serviceMock{}
+	var got time.Time
+	getAsOfMessageService = func(id int64, asOf time.Time) (*model.Document, error) {
+		got = asOf
+		return &model.Document{ID: id, Title: "signed title", Signee: "signee"}, nil
+	}
+	req, _ := http.NewRequest(http.MethodGet, "/documents/1?asOf=2021-12-01T10:00:00Z", nil)
+	req = mux.SetURLVars(req, map[string]string{
+		"id": "1",
+	})
+	rr := httptest.NewRecorder()
+	handler := http.HandlerFunc(GetByIdHandler)
+	handler.ServeHTTP(rr, req)
+
+	var res utils.HttpResponse
+	err := json.Unmarshal(rr.Body.Bytes(), &res)
+	if err != nil {
+		t.Error(err)
+	}
+	assert.EqualValues(t, http.StatusOK, res.Code)
+	assert.EqualValues(t, "signed title", res.Data.(map[string]interface{})["title"])
+	assert.True(t, got.Equal(time.Date(2021, 12, 1, 10, 0, 0, 0, time.UTC)))
+}
+
+func TestGetByIdHandler_InvalidAsOf(t *testing.T) {
+	req, _ := http.NewRequest(http.MethodGet, "/documents/1?asOf=yesterday", nil)
+	req = mux.SetURLVars(req, map[string]string{
+		"id": "1",
+	})
+	rr := httptest.NewRecorder()
+	handler := http.HandlerFunc(GetByIdHandler)
+	handler.ServeHTTP(rr, req)
+
+	var res utils.HttpResponse
+	err := json.Unmarshal(rr.Body.Bytes(), &res)
+	if err != nil {
+		t.Error(err)
+	}
+	assert.EqualValues(t, http.StatusBadRequest, res.Code)
+	assert.EqualValues(t, model.AsOfInvalidValue.Error(), res.Error)
+}
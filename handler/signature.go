@@ -0,0 +1,113 @@
+package handler
+
+import (
+	"encoding/json"
+	"github.com/gorilla/mux"
+	"net/http"
+	"precisely/model"
+	"precisely/service"
+	"precisely/tracing"
+	"precisely/utils"
+	"strconv"
+)
+
+func RegisterKeyHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracing.Start(r.Context(), "RegisterKeyHandler")
+	defer span.End()
+
+	var newKey model.SigneeKey
+	err := json.NewDecoder(r.Body).Decode(&newKey)
+	if err != nil {
+		utils.RespondError(w, model.BodyInvalidValue)
+		return
+	}
+	key, err := service.SignatureService.RegisterKey(ctx, newKey)
+	if err != nil {
+		utils.RespondError(w, err)
+		return
+	}
+	utils.JsonRespond(w, true, http.StatusCreated, err, key)
+	return
+}
+
+func GetKeysHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracing.Start(r.Context(), "GetKeysHandler")
+	defer span.End()
+
+	keys, err := service.SignatureService.GetKeys(ctx, r.URL.Query().Get("signee"))
+	if err != nil {
+		utils.RespondError(w, err)
+		return
+	}
+	utils.JsonRespond(w, true, http.StatusOK, err, keys)
+	return
+}
+
+func SignHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracing.Start(r.Context(), "SignHandler")
+	defer span.End()
+
+	var input struct {
+		KeyID     int64  `json:"key_id"`
+		Signature []byte `json:"signature"`
+	}
+	err := json.NewDecoder(r.Body).Decode(&input)
+	if err != nil {
+		utils.RespondError(w, model.BodyInvalidValue)
+		return
+	}
+	idStr, _ := mux.Vars(r)["id"]
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		utils.RespondError(w, model.IDInvalidValue)
+		return
+	}
+
+	signature, err := service.SignatureService.Sign(ctx, id, input.KeyID, input.Signature)
+	if err != nil {
+		utils.RespondError(w, err)
+		return
+	}
+	utils.JsonRespond(w, true, http.StatusCreated, err, signature)
+	return
+}
+
+func GetSignaturesHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracing.Start(r.Context(), "GetSignaturesHandler")
+	defer span.End()
+
+	idStr, _ := mux.Vars(r)["id"]
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		utils.RespondError(w, model.IDInvalidValue)
+		return
+	}
+
+	signatures, err := service.SignatureService.GetSignatures(ctx, id)
+	if err != nil {
+		utils.RespondError(w, err)
+		return
+	}
+	utils.JsonRespond(w, true, http.StatusOK, err, signatures)
+	return
+}
+
+func VerifyHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracing.Start(r.Context(), "VerifyHandler")
+	defer span.End()
+
+	idStr, _ := mux.Vars(r)["id"]
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		utils.RespondError(w, model.IDInvalidValue)
+		return
+	}
+
+	verification, err := service.SignatureService.Verify(ctx, id)
+	if err != nil {
+		utils.RespondError(w, err)
+		return
+	}
+	utils.JsonRespond(w, true, http.StatusOK, err, verification)
+	return
+}
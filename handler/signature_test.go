@@ -0,0 +1,148 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"precisely/model"
+	"precisely/service"
+	"precisely/utils"
+	"testing"
+)
+
+var (
+	registerKeyMessageService   func(key model.SigneeKey) (*model.SigneeKey, error)
+	getKeysMessageService       func(signee string) ([]*model.SigneeKey, error)
+	signMessageService          func(documentID, keyID int64, signature []byte) (*model.Signature, error)
+	getSignaturesMessageService func(documentID int64) ([]*model.Signature, error)
+	verifyMessageService        func(documentID int64) (*model.Verification, error)
+)
+
+type signatureServiceMock struct{}
+
+func (m *signatureServiceMock) RegisterKey(_ context.Context, key model.SigneeKey) (*model.SigneeKey, error) {
+	return registerKeyMessageService(key)
+}
+
+func (m *signatureServiceMock) GetKeys(_ context.Context, signee string) ([]*model.SigneeKey, error) {
+	return getKeysMessageService(signee)
+}
+
+func (m *signatureServiceMock) Sign(_ context.Context, documentID, keyID int64, signature []byte) (*model.Signature, error) {
+	return signMessageService(documentID, keyID, signature)
+}
+
+func (m *signatureServiceMock) GetSignatures(_ context.Context, documentID int64) ([]*model.Signature, error) {
+	return getSignaturesMessageService(documentID)
+}
+
+func (m *signatureServiceMock) Verify(_ context.Context, documentID int64) (*model.Verification, error) {
+	return verifyMessageService(documentID)
+}
+
+func TestRegisterKeyHandler_UnprocessableEntity(t *testing.T) {
+	service.SignatureService = &signatureServiceMock{}
+	var got model.SigneeKey
+	registerKeyMessageService = func(key model.SigneeKey) (*model.SigneeKey, error) {
+		got = key
+		return nil, model.PublicKeyInvalidValue
+	}
+	jsonBody := `{"signee": "signee", "public_key": "c2hvcnQ="}`
+
+	req, _ := http.NewRequest(http.MethodPost, "/keys", bytes.NewBufferString(jsonBody))
+	rr := httptest.NewRecorder()
+	handler := http.HandlerFunc(RegisterKeyHandler)
+	handler.ServeHTTP(rr, req)
+
+	var res utils.HttpResponse
+	err := json.Unmarshal(rr.Body.Bytes(), &res)
+	if err != nil {
+		t.Error(err)
+	}
+	assert.EqualValues(t, http.StatusUnprocessableEntity, res.Code)
+	assert.EqualValues(t, "short", string(got.PublicKey))
+}
+
+func TestSignHandler_Success(t *testing.T) {
+	service.SignatureService = &signatureServiceMock{}
+	signMessageService = func(documentID, keyID int64, signature []byte) (*model.Signature, error) {
+		return &model.Signature{ID: 1, DocumentID: documentID, KeyID: keyID, Signee: "signee", Signature: signature}, nil
+	}
+	jsonBody := `{"key_id": 3, "signature": "c2lnbmF0dXJl"}`
+
+	req, _ := http.NewRequest(http.MethodPost, "/documents/1/signatures", bytes.NewBufferString(jsonBody))
+	req = mux.SetURLVars(req, map[string]string{
+		"id": "1",
+	})
+	rr := httptest.NewRecorder()
+	handler := http.HandlerFunc(SignHandler)
+	handler.ServeHTTP(rr, req)
+
+	var res utils.HttpResponse
+	err := json.Unmarshal(rr.Body.Bytes(), &res)
+	if err != nil {
+		t.Error(err)
+	}
+	sig := res.Data.(map[string]interface{})
+	assert.EqualValues(t, http.StatusCreated, res.Code)
+	assert.EqualValues(t, 3, sig["key_id"])
+	assert.EqualValues(t, "c2lnbmF0dXJl", sig["signature"])
+}
+
+func TestSignHandler_InvalidSignature(t *testing.T) {
+	service.SignatureService = &signatureServiceMock{}
+	signMessageService = func(documentID, keyID int64, signature []byte) (*model.Signature, error) {
+		return nil, model.SignatureInvalidValue
+	}
+	jsonBody := `{"key_id": 3, "signature": "c2lnbmF0dXJl"}`
+
+	req, _ := http.NewRequest(http.MethodPost, "/documents/1/signatures", bytes.NewBufferString(jsonBody))
+	req = mux.SetURLVars(req, map[string]string{
+		"id": "1",
+	})
+	rr := httptest.NewRecorder()
+	handler := http.HandlerFunc(SignHandler)
+	handler.ServeHTTP(rr, req)
+
+	var res utils.HttpResponse
+	err := json.Unmarshal(rr.Body.Bytes(), &res)
+	if err != nil {
+		t.Error(err)
+	}
+	assert.EqualValues(t, http.StatusUnprocessableEntity, res.Code)
+	assert.EqualValues(t, model.SignatureInvalidValue.Error(), res.Error)
+}
+
+func TestVerifyHandler_Success(t *testing.T) {
+	service.SignatureService = &signatureServiceMock{}
+	verifyMessageService = func(documentID int64) (*model.Verification, error) {
+		return &model.Verification{
+			DocumentID: documentID,
+			Valid:      false,
+			Signatures: []*model.SignatureVerification{
+				{Signature: model.Signature{ID: 1, DocumentID: documentID}, Valid: false},
+			},
+		}, nil
+	}
+	req, _ := http.NewRequest(http.MethodGet, "/documents/1/verify", nil)
+	req = mux.SetURLVars(req, map[string]string{
+		"id": "1",
+	})
+	rr := httptest.NewRecorder()
+	handler := http.HandlerFunc(VerifyHandler)
+	handler.ServeHTTP(rr, req)
+
+	var res utils.HttpResponse
+	err := json.Unmarshal(rr.Body.Bytes(), &res)
+	if err != nil {
+		t.Error(err)
+	}
+	verification := res.Data.(map[string]interface{})
+	assert.EqualValues(t, http.StatusOK, res.Code)
+	assert.EqualValues(t, false, verification["valid"])
+	assert.EqualValues(t, 1, len(verification["signatures"].([]interface{})))
+}
@@ -0,0 +1,126 @@
+package handler
+
+import (
+	"encoding/json"
+	"github.com/gorilla/mux"
+	"io"
+	"net/http"
+	"precisely/model"
+	"precisely/service"
+	"precisely/tracing"
+	"precisely/utils"
+	"strconv"
+)
+
+func GetSigneesHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracing.Start(r.Context(), "GetSigneesHandler")
+	defer span.End()
+
+	idStr, _ := mux.Vars(r)["id"]
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		utils.RespondError(w, model.IDInvalidValue)
+		return
+	}
+
+	workflow, err := service.SigneeService.GetWorkflow(ctx, id)
+	if err != nil {
+		utils.RespondError(w, err)
+		return
+	}
+	utils.JsonRespond(w, true, http.StatusOK, err, workflow)
+	return
+}
+
+func SetSigneesHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracing.Start(r.Context(), "SetSigneesHandler")
+	defer span.End()
+
+	var input struct {
+		Mode    string   `json:"mode"`
+		Signees []string `json:"signees"`
+	}
+	err := json.NewDecoder(r.Body).Decode(&input)
+	if err != nil {
+		utils.RespondError(w, model.BodyInvalidValue)
+		return
+	}
+	idStr, _ := mux.Vars(r)["id"]
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		utils.RespondError(w, model.IDInvalidValue)
+		return
+	}
+
+	workflow := model.SigningWorkflow{DocumentID: id, Mode: input.Mode}
+	for _, name := range input.Signees {
+		workflow.Signees = append(workflow.Signees, &model.Signee{Name: name})
+	}
+	updated, err := service.SigneeService.SetSignees(ctx, workflow)
+	if err != nil {
+		utils.RespondError(w, err)
+		return
+	}
+	utils.JsonRespond(w, true, http.StatusOK, err, updated)
+	return
+}
+
+func SignSigneeHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracing.Start(r.Context(), "SignSigneeHandler")
+	defer span.End()
+
+	idStr, _ := mux.Vars(r)["id"]
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		utils.RespondError(w, model.IDInvalidValue)
+		return
+	}
+	signeeStr, _ := mux.Vars(r)["signee"]
+	signeeID, err := strconv.ParseInt(signeeStr, 10, 64)
+	if err != nil {
+		utils.RespondError(w, model.IDInvalidValue)
+		return
+	}
+
+	workflow, err := service.SigneeService.Sign(ctx, id, signeeID)
+	if err != nil {
+		utils.RespondError(w, err)
+		return
+	}
+	utils.JsonRespond(w, true, http.StatusOK, err, workflow)
+	return
+}
+
+func DeclineSigneeHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracing.Start(r.Context(), "DeclineSigneeHandler")
+	defer span.End()
+
+	var input struct {
+		Reason string `json:"reason"`
+	}
+	err := json.NewDecoder(r.Body).Decode(&input)
+	if err != nil && err != io.EOF {
+		utils.RespondError(w, model.BodyInvalidValue)
+		return
+	}
+	idStr, _ := mux.Vars(r)["id"]
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		utils.RespondError(w, model.IDInvalidValue)
+		return
+	}
+	signeeStr, _ := mux.Vars(r)["signee"]
+	signeeID, err := strconv.ParseInt(signeeStr, 10, 64)
+	if err != nil {
+		utils.RespondError(w, model.IDInvalidValue)
+		return
+	}
+
+	workflow, err := service.SigneeService.Decline(ctx, id, signeeID, input.Reason)
+	if err != nil {
+		utils.RespondError(w, err)
+		return
+	}
+	utils.JsonRespond(w, true, http.StatusOK, err, workflow)
+	return
+}
@@ -0,0 +1,119 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"precisely/model"
+	"precisely/service"
+	"precisely/utils"
+	"testing"
+)
+
+var (
+	getWorkflowMessageService   func(documentID int64) (*model.SigningWorkflow, error)
+	setSigneesMessageService    func(workflow model.SigningWorkflow) (*model.SigningWorkflow, error)
+	signSigneeMessageService    func(documentID, signeeID int64) (*model.SigningWorkflow, error)
+	declineSigneeMessageService func(documentID, signeeID int64, reason string) (*model.SigningWorkflow, error)
+)
+
+type signeeServiceMock struct{}
+
+func (m *signeeServiceMock) GetWorkflow(_ context.Context, documentID int64) (*model.SigningWorkflow, error) {
+	return getWorkflowMessageService(documentID)
+}
+
+func (m *signeeServiceMock) SetSignees(_ context.Context, workflow model.SigningWorkflow) (*model.SigningWorkflow, error) {
+	return setSigneesMessageService(workflow)
+}
+
+func (m *signeeServiceMock) Sign(_ context.Context, documentID, signeeID int64) (*model.SigningWorkflow, error) {
+	return signSigneeMessageService(documentID, signeeID)
+}
+
+func (m *signeeServiceMock) Decline(_ context.Context, documentID, signeeID int64, reason string) (*model.SigningWorkflow, error) {
+	return declineSigneeMessageService(documentID, signeeID, reason)
+}
+
+func TestSetSigneesHandler_Success(t *testing.T) {
+	service.SigneeService = &signeeServiceMock{}
+	setSigneesMessageService = func(workflow model.SigningWorkflow) (*model.SigningWorkflow, error) {
+		workflow.State = model.SigningPending
+		return &workflow, nil
+	}
+	jsonBody := `{"mode": "ordered", "signees": ["alice", "bob"]}`
+
+	req, _ := http.NewRequest(http.MethodPut, "/documents/1/signees", bytes.NewBufferString(jsonBody))
+	req = mux.SetURLVars(req, map[string]string{
+		"id": "1",
+	})
+	rr := httptest.NewRecorder()
+	handler := http.HandlerFunc(SetSigneesHandler)
+	handler.ServeHTTP(rr, req)
+
+	var res utils.HttpResponse
+	err := json.Unmarshal(rr.Body.Bytes(), &res)
+	if err != nil {
+		t.Error(err)
+	}
+	workflow := res.Data.(map[string]interface{})
+	signees := workflow["signees"].([]interface{})
+	assert.EqualValues(t, http.StatusOK, res.Code)
+	assert.EqualValues(t, model.SigningOrdered, workflow["mode"])
+	assert.EqualValues(t, 2, len(signees))
+	assert.EqualValues(t, "bob", signees[1].(map[string]interface{})["signee"])
+}
+
+func TestSignSigneeHandler_Conflict(t *testing.T) {
+	service.SigneeService = &signeeServiceMock{}
+	signSigneeMessageService = func(documentID, signeeID int64) (*model.SigningWorkflow, error) {
+		return nil, model.SigneeOutOfTurnValue
+	}
+	req, _ := http.NewRequest(http.MethodPost, "/documents/1/signees/2/sign", nil)
+	req = mux.SetURLVars(req, map[string]string{
+		"id":     "1",
+		"signee": "2",
+	})
+	rr := httptest.NewRecorder()
+	handler := http.HandlerFunc(SignSigneeHandler)
+	handler.ServeHTTP(rr, req)
+
+	var res utils.HttpResponse
+	err := json.Unmarshal(rr.Body.Bytes(), &res)
+	if err != nil {
+		t.Error(err)
+	}
+	assert.EqualValues(t, http.StatusConflict, res.Code)
+}
+
+func TestDeclineSigneeHandler_Success(t *testing.T) {
+	service.SigneeService = &signeeServiceMock{}
+	var gotReason string
+	declineSigneeMessageService = func(documentID, signeeID int64, reason string) (*model.SigningWorkflow, error) {
+		gotReason = reason
+		return &model.SigningWorkflow{DocumentID: documentID, State: model.SigningDeclined}, nil
+	}
+	jsonBody := `{"reason": "wrong amount"}`
+
+	req, _ := http.NewRequest(http.MethodPost, "/documents/1/signees/2/decline", bytes.NewBufferString(jsonBody))
+	req = mux.SetURLVars(req, map[string]string{
+		"id":     "1",
+		"signee": "2",
+	})
+	rr := httptest.NewRecorder()
+	handler := http.HandlerFunc(DeclineSigneeHandler)
+	handler.ServeHTTP(rr, req)
+
+	var res utils.HttpResponse
+	err := json.Unmarshal(rr.Body.Bytes(), &res)
+	if err != nil {
+		t.Error(err)
+	}
+	assert.EqualValues(t, http.StatusOK, res.Code)
+	assert.EqualValues(t, model.SigningDeclined, res.Data.(map[string]interface{})["state"])
+	assert.EqualValues(t, "wrong amount", gotReason)
+}
@@ -0,0 +1,200 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/gorilla/mux"
+	"net/http"
+	"net/url"
+	"precisely/model"
+	"precisely/service"
+	"precisely/tracing"
+	"precisely/utils"
+	"strconv"
+)
+
+func CreateWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracing.Start(r.Context(), "CreateWebhookHandler")
+	defer span.End()
+
+	var webhook model.Webhook
+	err := json.NewDecoder(r.Body).Decode(&webhook)
+	if err != nil {
+		utils.RespondError(w, model.BodyInvalidValue)
+		return
+	}
+	created, err := service.WebhookService.Create(ctx, webhook)
+	if err != nil {
+		utils.RespondError(w, err)
+		return
+	}
+	utils.JsonRespond(w, true, http.StatusCreated, err, created)
+	return
+}
+
+func GetWebhooksHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracing.Start(r.Context(), "GetWebhooksHandler")
+	defer span.End()
+
+	webhooks, err := service.WebhookService.GetAll(ctx)
+	if err != nil {
+		utils.RespondError(w, err)
+		return
+	}
+	utils.JsonRespond(w, true, http.StatusOK, err, webhooks)
+	return
+}
+
+func GetWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracing.Start(r.Context(), "GetWebhookHandler")
+	defer span.End()
+
+	idStr, _ := mux.Vars(r)["id"]
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		utils.RespondError(w, model.IDInvalidValue)
+		return
+	}
+	webhook, err := service.WebhookService.Get(ctx, id)
+	if err != nil {
+		utils.RespondError(w, err)
+		return
+	}
+	utils.JsonRespond(w, true, http.StatusOK, err, webhook)
+	return
+}
+
+func UpdateWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracing.Start(r.Context(), "UpdateWebhookHandler")
+	defer span.End()
+
+	var webhook model.Webhook
+	err := json.NewDecoder(r.Body).Decode(&webhook)
+	if err != nil {
+		utils.RespondError(w, model.BodyInvalidValue)
+		return
+	}
+	idStr, _ := mux.Vars(r)["id"]
+	webhook.ID, err = strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		utils.RespondError(w, model.IDInvalidValue)
+		return
+	}
+	updated, err := service.WebhookService.Update(ctx, webhook)
+	if err != nil {
+		utils.RespondError(w, err)
+		return
+	}
+	utils.JsonRespond(w, true, http.StatusOK, err, updated)
+	return
+}
+
+func DeleteWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracing.Start(r.Context(), "DeleteWebhookHandler")
+	defer span.End()
+
+	idStr, _ := mux.Vars(r)["id"]
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		utils.RespondError(w, model.IDInvalidValue)
+		return
+	}
+	err = service.WebhookService.Delete(ctx, id)
+	if err != nil {
+		utils.RespondError(w, err)
+		return
+	}
+	utils.JsonRespond(w, true, http.StatusOK, err, nil)
+	return
+}
+
+// GetWebhookDeliveriesHandler lists the delivery history of a webhook, newest
+// first, optionally of a single status.
+func GetWebhookDeliveriesHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracing.Start(r.Context(), "GetWebhookDeliveriesHandler")
+	defer span.End()
+
+	query, err := parseDeliveryQuery(r.URL.Query())
+	if err != nil {
+		utils.RespondError(w, err)
+		return
+	}
+	idStr, _ := mux.Vars(r)["id"]
+	query.WebhookID, err = strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		utils.RespondError(w, model.IDInvalidValue)
+		return
+	}
+	respondDeliveries(ctx, w, query)
+}
+
+// GetDeadDeliveriesHandler lists the dead-lettered deliveries of every
+// webhook, newest first.
+func GetDeadDeliveriesHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracing.Start(r.Context(), "GetDeadDeliveriesHandler")
+	defer span.End()
+
+	query, err := parseDeliveryQuery(r.URL.Query())
+	if err != nil {
+		utils.RespondError(w, err)
+		return
+	}
+	query.Status = model.DeliveryDead
+	respondDeliveries(ctx, w, query)
+}
+
+func respondDeliveries(ctx context.Context, w http.ResponseWriter, query model.DeliveryQuery) {
+	page, err := service.WebhookService.GetDeliveries(ctx, query)
+	if err != nil {
+		utils.RespondError(w, err)
+		return
+	}
+	meta := utils.PageMeta{
+		Total:      page.Total,
+		Limit:      query.Limit,
+		NextCursor: page.NextCursor,
+	}
+	utils.JsonRespondWithMeta(w, true, http.StatusOK, err, page.Deliveries, meta)
+}
+
+// parseDeliveryQuery reads limit, cursor and status from the query string.
+func parseDeliveryQuery(values url.Values) (model.DeliveryQuery, error) {
+	query := model.DeliveryQuery{Status: values.Get("status")}
+	var err error
+	if v := values.Get("limit"); v != "" {
+		if query.Limit, err = strconv.Atoi(v); err != nil || query.Limit <= 0 {
+			return query, model.LimitInvalidValue
+		}
+	}
+	if v := values.Get("cursor"); v != "" {
+		if query.Cursor, err = model.DecodeCursor(v); err != nil {
+			return query, err
+		}
+	}
+	return query, query.Validate()
+}
+
+func RedeliverHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracing.Start(r.Context(), "RedeliverHandler")
+	defer span.End()
+
+	idStr, _ := mux.Vars(r)["id"]
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		utils.RespondError(w, model.IDInvalidValue)
+		return
+	}
+	deliveryStr, _ := mux.Vars(r)["delivery"]
+	deliveryID, err := strconv.ParseInt(deliveryStr, 10, 64)
+	if err != nil {
+		utils.RespondError(w, model.IDInvalidValue)
+		return
+	}
+	delivery, err := service.WebhookService.Redeliver(ctx, id, deliveryID)
+	if err != nil {
+		utils.RespondError(w, err)
+		return
+	}
+	utils.JsonRespond(w, true, http.StatusAccepted, err, delivery)
+	return
+}
@@ -0,0 +1,197 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"precisely/model"
+	"precisely/service"
+	"precisely/utils"
+	"testing"
+)
+
+var (
+	createWebhookService func(webhook model.Webhook) (*model.Webhook, error)
+	getDeliveriesService func(query model.DeliveryQuery) (*model.DeliveryPage, error)
+	redeliverService     func(webhookID, deliveryID int64) (*model.Delivery, error)
+	deleteWebhookService func(id int64) error
+)
+
+type webhookServiceMock struct{}
+
+func (m *webhookServiceMock) Create(_ context.Context, webhook model.Webhook) (*model.Webhook, error) {
+	return createWebhookService(webhook)
+}
+
+func (m *webhookServiceMock) Get(_ context.Context, id int64) (*model.Webhook, error) {
+	return nil, sql.ErrNoRows
+}
+
+func (m *webhookServiceMock) GetAll(_ context.Context) ([]*model.Webhook, error) {
+	return []*model.Webhook{}, nil
+}
+
+func (m *webhookServiceMock) Update(_ context.Context, webhook model.Webhook) (*model.Webhook, error) {
+	return &webhook, nil
+}
+
+func (m *webhookServiceMock) Delete(_ context.Context, id int64) error {
+	return deleteWebhookService(id)
+}
+
+func (m *webhookServiceMock) GetDeliveries(_ context.Context, query model.DeliveryQuery) (*model.DeliveryPage, error) {
+	return getDeliveriesService(query)
+}
+
+func (m *webhookServiceMock) Redeliver(_ context.Context, webhookID, deliveryID int64) (*model.Delivery, error) {
+	return redeliverService(webhookID, deliveryID)
+}
+
+func TestCreateWebhookHandler(t *testing.T) {
+	service.WebhookService = &webhookServiceMock{}
+	tests := []struct {
+		name string
+		err  error
+		code int
+	}{
+		{name: "Created", code: http.StatusCreated},
+		{name: "Forbidden", err: model.ForbiddenValue, code: http.StatusForbidden},
+		{name: "Invalid URL", err: model.WebhookURLInvalidValue, code: http.StatusUnprocessableEntity},
+		{name: "Invalid events", err: model.WebhookEventsInvalidValue, code: http.StatusUnprocessableEntity},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			createWebhookService = func(webhook model.Webhook) (*model.Webhook, error) {
+				if tt.err != nil {
+					return nil, tt.err
+				}
+				assert.Equal(t, []string{model.EventDocumentCreated}, webhook.Events)
+				webhook.ID, webhook.Secret = 1, "whsec_secret"
+				return &webhook, nil
+			}
+			req, _ := http.NewRequest(http.MethodPost, "/webhooks", bytes.NewBufferString(`{"url": "https://example.com/hook", "events": ["document.created"]}`))
+			rr := httptest.NewRecorder()
+			http.HandlerFunc(CreateWebhookHandler).ServeHTTP(rr, req)
+
+			var res utils.HttpResponse
+			if err := json.Unmarshal(rr.Body.Bytes(), &res); err != nil {
+				t.Error(err)
+			}
+			assert.EqualValues(t, tt.code, res.Code)
+			if tt.err == nil {
+				assert.EqualValues(t, "whsec_secret", res.Data.(map[string]interface{})["secret"])
+			}
+		})
+	}
+}
+
+func TestDeleteWebhookHandler_NotFound(t *testing.T) {
+	service.WebhookService = &webhookServiceMock{}
+	deleteWebhookService = func(id int64) error {
+		return sql.ErrNoRows
+	}
+	req, _ := http.NewRequest(http.MethodDelete, "/webhooks/3", nil)
+	req = mux.SetURLVars(req, map[string]string{
+		"id": "3",
+	})
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(DeleteWebhookHandler).ServeHTTP(rr, req)
+
+	assert.EqualValues(t, http.StatusNotFound, rr.Code)
+}
+
+func TestGetWebhookDeliveriesHandler(t *testing.T) {
+	service.WebhookService = &webhookServiceMock{}
+	tests := []struct {
+		name   string
+		target string
+		err    error
+		code   int
+	}{
+		{name: "Filtered", target: "/webhooks/3/deliveries?status=pending&limit=1", code: http.StatusOK},
+		{name: "Invalid status", target: "/webhooks/3/deliveries?status=lost", code: http.StatusBadRequest},
+		{name: "Invalid limit", target: "/webhooks/3/deliveries?limit=-1", code: http.StatusBadRequest},
+		{name: "Invalid cursor", target: "/webhooks/3/deliveries?cursor=!!", code: http.StatusBadRequest},
+		{name: "Unknown webhook", target: "/webhooks/3/deliveries", err: sql.ErrNoRows, code: http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			getDeliveriesService = func(query model.DeliveryQuery) (*model.DeliveryPage, error) {
+				if tt.err != nil {
+					return nil, tt.err
+				}
+				assert.Equal(t, model.DeliveryQuery{WebhookID: 3, Status: model.DeliveryPending, Limit: 1}, query)
+				return &model.DeliveryPage{Deliveries: []*model.Delivery{{ID: 9, WebhookID: 3}}, Total: 4, NextCursor: model.EncodeCursor(9)}, nil
+			}
+			req, _ := http.NewRequest(http.MethodGet, tt.target, nil)
+			req = mux.SetURLVars(req, map[string]string{
+				"id": "3",
+			})
+			rr := httptest.NewRecorder()
+			http.HandlerFunc(GetWebhookDeliveriesHandler).ServeHTTP(rr, req)
+
+			var res utils.HttpResponse
+			if err := json.Unmarshal(rr.Body.Bytes(), &res); err != nil {
+				t.Error(err)
+			}
+			assert.EqualValues(t, tt.code, res.Code)
+			if tt.code == http.StatusOK {
+				assert.Len(t, res.Data, 1)
+				assert.EqualValues(t, 4, res.Meta.(map[string]interface{})["total"])
+				assert.EqualValues(t, model.EncodeCursor(9), res.Meta.(map[string]interface{})["next_cursor"])
+			}
+		})
+	}
+}
+
+func TestGetDeadDeliveriesHandler(t *testing.T) {
+	service.WebhookService = &webhookServiceMock{}
+	getDeliveriesService = func(query model.DeliveryQuery) (*model.DeliveryPage, error) {
+		assert.Equal(t, model.DeliveryQuery{Status: model.DeliveryDead, Limit: model.DefaultListLimit}, query)
+		return &model.DeliveryPage{Deliveries: []*model.Delivery{}}, nil
+	}
+	req, _ := http.NewRequest(http.MethodGet, "/webhooks/dead-letters", nil)
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(GetDeadDeliveriesHandler).ServeHTTP(rr, req)
+
+	assert.EqualValues(t, http.StatusOK, rr.Code)
+}
+
+func TestRedeliverHandler(t *testing.T) {
+	service.WebhookService = &webhookServiceMock{}
+	tests := []struct {
+		name string
+		err  error
+		code int
+	}{
+		{name: "Accepted", code: http.StatusAccepted},
+		{name: "Other webhook", err: sql.ErrNoRows, code: http.StatusNotFound},
+		{name: "Forbidden", err: model.ForbiddenValue, code: http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			redeliverService = func(webhookID, deliveryID int64) (*model.Delivery, error) {
+				if tt.err != nil {
+					return nil, tt.err
+				}
+				assert.EqualValues(t, 3, webhookID)
+				assert.EqualValues(t, 9, deliveryID)
+				return &model.Delivery{ID: 10, WebhookID: 3, Status: model.DeliveryPending}, nil
+			}
+			req, _ := http.NewRequest(http.MethodPost, "/webhooks/3/deliveries/9/redeliver", nil)
+			req = mux.SetURLVars(req, map[string]string{
+				"id":       "3",
+				"delivery": "9",
+			})
+			rr := httptest.NewRecorder()
+			http.HandlerFunc(RedeliverHandler).ServeHTTP(rr, req)
+
+			assert.EqualValues(t, tt.code, rr.Code)
+		})
+	}
+}
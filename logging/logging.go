@@ -0,0 +1,118 @@
+// Package logging configures the structured JSON logs of the service and
+// ties every request to a request ID, logged with each of its lines.
+package logging
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/trace"
+	"net/http"
+	"precisely/response"
+	"precisely/utils"
+	"regexp"
+	"strings"
+	"time"
+)
+
+type contextKey struct{}
+
+// validRequestID bounds the request IDs accepted from clients, which end up in
+// the logs and the responses.
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`)
+
+// Configure makes the standard logrus logger write JSON lines at the given
+// level, one of trace, debug, info, warn, error, fatal or panic; info when
+// empty.
+func Configure(level string) error {
+	logrus.SetFormatter(&logrus.JSONFormatter{TimestampFormat: time.RFC3339Nano})
+	if level == "" {
+		level = "info"
+	}
+	lvl, err := logrus.ParseLevel(level)
+	if err != nil {
+		return err
+	}
+	logrus.SetLevel(lvl)
+	return nil
+}
+
+// FromContext returns a logger carrying the request ID and the trace ID of
+// ctx, if any.
+func FromContext(ctx context.Context) *logrus.Entry {
+	entry := logrus.NewEntry(logrus.StandardLogger())
+	if id := RequestID(ctx); id != "" {
+		entry = entry.WithField("request_id", id)
+	}
+	if span := trace.SpanContextFromContext(ctx); span.IsValid() {
+		entry = entry.WithField("trace_id", span.TraceID().String())
+	}
+	return entry
+}
+
+// RequestID returns the request ID of ctx, or an empty string.
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(contextKey{}).(string)
+	return id
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(b)
+}
+
+// Middleware takes the request ID from the X-Request-ID header, or generates
+// one when it is missing or malformed, echoes it in the response and writes
+// an access log line once the request is served. Server errors are logged at
+// error level and client errors at warn level, with the error answered.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(utils.RequestIDHeader)
+		if !validRequestID.MatchString(id) {
+			id = newRequestID()
+		}
+		w.Header().Set(utils.RequestIDHeader, id)
+		r = r.WithContext(context.WithValue(r.Context(), contextKey{}, id))
+
+		route := r.URL.Path
+		if current := mux.CurrentRoute(r); current != nil {
+			if template, err := current.GetPathTemplate(); err == nil {
+				route = template
+			}
+		}
+
+		recorder := response.NewRecorder(w)
+		start := time.Now()
+		next.ServeHTTP(recorder, r)
+
+		entry := FromContext(r.Context()).WithFields(logrus.Fields{
+			"method":      r.Method,
+			"route":       route,
+			"status":      recorder.Code,
+			"bytes":       recorder.Bytes,
+			"duration_ms": float64(time.Since(start).Microseconds()) / 1000,
+		})
+		if recorder.Err != nil {
+			entry = entry.WithError(recorder.Err)
+			// An error answered in place of another, such as one of the
+			// database, does not tell what it stands for.
+			if cause := errors.Unwrap(recorder.Err); cause != nil && !strings.Contains(recorder.Err.Error(), cause.Error()) {
+				entry = entry.WithField("cause", cause.Error())
+			}
+		}
+		switch {
+		case recorder.Code >= http.StatusInternalServerError:
+			entry.Error("request failed")
+		case recorder.Code >= http.StatusBadRequest:
+			entry.Warn("request rejected")
+		default:
+			entry.Info("request served")
+		}
+	})
+}
@@ -0,0 +1,85 @@
+package logging
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"precisely/metrics"
+	"precisely/utils"
+	"testing"
+)
+
+func serve(t *testing.T, requestID string) (*httptest.ResponseRecorder, *logrus.Entry) {
+	hook := test.NewGlobal()
+	r := mux.NewRouter()
+	r.HandleFunc("/documents/{id:[0-9]+}", func(w http.ResponseWriter, r *http.Request) {
+		FromContext(r.Context()).Info("handling")
+		utils.JsonRespond(w, false, http.StatusInternalServerError, errors.New("database is down"), nil)
+	})
+	r.Use(Middleware, metrics.Middleware)
+
+	req, _ := http.NewRequest(http.MethodGet, "/documents/1", nil)
+	if requestID != "" {
+		req.Header.Set(utils.RequestIDHeader, requestID)
+	}
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+
+	entries := hook.AllEntries()
+	if !assert.Len(t, entries, 2) {
+		t.FailNow()
+	}
+	assert.Equal(t, "handling", entries[0].Message)
+	assert.Equal(t, entries[1].Data["request_id"], entries[0].Data["request_id"])
+	return rr, entries[1]
+}
+
+func TestMiddleware_AccessLog(t *testing.T) {
+	rr, entry := serve(t, "abc-123")
+
+	var res utils.HttpResponse
+	err := json.Unmarshal(rr.Body.Bytes(), &res)
+	if err != nil {
+		t.Error(err)
+	}
+	assert.Equal(t, "abc-123", rr.Header().Get(utils.RequestIDHeader))
+	assert.Equal(t, "abc-123", res.RequestID)
+
+	assert.Equal(t, logrus.ErrorLevel, entry.Level)
+	assert.Equal(t, "abc-123", entry.Data["request_id"])
+	assert.Equal(t, http.MethodGet, entry.Data["method"])
+	assert.Equal(t, "/documents/{id:[0-9]+}", entry.Data["route"])
+	assert.Equal(t, http.StatusInternalServerError, entry.Data["status"])
+	assert.Equal(t, rr.Body.Len(), entry.Data["bytes"])
+	assert.Contains(t, entry.Data, "duration_ms")
+	assert.EqualError(t, entry.Data[logrus.ErrorKey].(error), "database is down")
+}
+
+func TestMiddleware_GeneratesRequestID(t *testing.T) {
+	for _, requestID := range []string{"", "not a valid id\n"} {
+		rr, entry := serve(t, requestID)
+
+		generated := rr.Header().Get(utils.RequestIDHeader)
+		assert.Len(t, generated, 32)
+		assert.Equal(t, generated, entry.Data["request_id"])
+	}
+}
+
+func TestMiddleware_LogsHiddenCause(t *testing.T) {
+	hook := test.NewGlobal()
+	h := Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		utils.RespondError(w, errors.New("dial tcp 10.0.0.1:3306: connection refused"))
+	}))
+	req, _ := http.NewRequest(http.MethodGet, "/documents/1", nil)
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, req)
+
+	assert.NotContains(t, rr.Body.String(), "10.0.0.1")
+	entry := hook.LastEntry()
+	assert.Equal(t, "dial tcp 10.0.0.1:3306: connection refused", entry.Data["cause"])
+}
@@ -1,49 +1,232 @@
 package main
 
 import (
+	"context"
 	_ "github.com/go-sql-driver/mysql"
 	_ "github.com/golang-migrate/migrate/v4/source/file"
 	"github.com/gorilla/mux"
+	_ "github.com/lib/pq"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/sirupsen/logrus"
 	"github.com/spf13/viper"
-	"log"
 	"net/http"
+	"os"
+	"os/signal"
+	"precisely/auth"
 	"precisely/handler"
+	"precisely/logging"
+	"precisely/metrics"
 	"precisely/model"
+	"precisely/service"
+	"precisely/tracing"
+	"precisely/utils"
+	"syscall"
 	"time"
 )
 
+// serverWriteTimeout bounds the time a response takes to be written, event
+// streams included.
+const serverWriteTimeout = 15 * time.Second
+
 func main() {
 	viper.SetConfigFile(".env")
 	viper.ReadInConfig()
+	if err := logging.Configure(viper.GetString("LOG_LEVEL")); err != nil {
+		logrus.WithError(err).Fatal("invalid LOG_LEVEL")
+	}
+	utils.ProblemDetails = viper.GetBool("PROBLEM_DETAILS")
+	shutdownTracing, err := tracing.Configure(viper.GetString("OTEL_EXPORTER"), viper.GetString("OTEL_EXPORTER_FILE"))
+	if err != nil {
+		logrus.WithError(err).Fatal("invalid OTEL_EXPORTER")
+	}
+	defer shutdownTracing(context.Background())
 
-	db := model.DocumentRepository.Init(
-		viper.GetString("DRIVER"),
-		viper.GetString("MS_USERNAME"),
-		viper.GetString("MS_PASSWORD"),
-		viper.GetString("MS_PORT"),
-		viper.GetString("MS_HOST"),
-		viper.GetString("MS_DB"),
-	)
-	defer db.Close()
+	if viper.GetString("DRIVER") == "memory" {
+		model.DocumentRepository, model.SignatureRepository, model.SigneeRepository, model.GrantRepository, model.OutboxRepository, model.AuditRepository = model.NewMemoryRepositories()
+		model.HealthRepository = model.NewMemoryHealthRepository()
+		model.APIKeyRepository = model.NewMemoryAPIKeyRepository()
+		model.WebhookRepository = model.NewMemoryWebhookRepository()
+		model.IdempotencyRepository = model.NewMemoryIdempotencyRepository()
+	} else {
+		dialect, err := model.DialectFor(viper.GetString("DRIVER"))
+		if err != nil {
+			logrus.WithError(err).Fatal("invalid DRIVER")
+		}
+		db := model.DocumentRepository.Init(
+			dialect.Driver,
+			viper.GetString("MS_USERNAME"),
+			viper.GetString("MS_PASSWORD"),
+			viper.GetString("MS_PORT"),
+			viper.GetString("MS_HOST"),
+			viper.GetString("MS_DB"),
+		)
+		defer db.Close()
+		model.OperationTimeout = viper.GetDuration("DB_TIMEOUT")
+		model.SignatureRepository = model.NewSignatureRepository(db, dialect)
+		model.SigneeRepository = model.NewSigneeRepository(db, dialect)
+		model.GrantRepository = model.NewGrantRepository(db, dialect)
+		model.HealthRepository = model.NewHealthRepository(db, dialect)
+		model.APIKeyRepository = model.NewAPIKeyRepository(db, dialect)
+		model.AuditRepository = model.NewAuditRepository(db, dialect)
+		model.WebhookRepository = model.NewWebhookRepository(db, dialect)
+		model.OutboxRepository = model.NewOutboxRepository(db, dialect)
+		model.IdempotencyRepository = model.NewIdempotencyRepository(db, dialect)
+		metrics.RegisterDB(db, dialect.Driver)
+	}
+	metrics.RegisterDocuments(model.DocumentRepository, 5*time.Second)
+	model.DocumentRepository = metrics.InstrumentDocumentRepository(model.DocumentRepository)
+
+	if retention := viper.GetDuration("TRASH_RETENTION"); retention > 0 {
+		interval := viper.GetDuration("TRASH_PURGE_INTERVAL")
+		if interval <= 0 {
+			interval = time.Hour
+		}
+		stopPurger := service.StartTrashPurger(retention, interval)
+		defer stopPurger()
+	}
+
+	if ttl := viper.GetDuration("IDEMPOTENCY_TTL"); ttl > 0 {
+		service.IdempotencyTTL = ttl
+	}
+	idempotencyInterval := viper.GetDuration("IDEMPOTENCY_CLEANUP_INTERVAL")
+	if idempotencyInterval <= 0 {
+		idempotencyInterval = time.Hour
+	}
+	stopIdempotencyPurger := service.StartIdempotencyPurger(idempotencyInterval)
+	defer stopIdempotencyPurger()
+
+	sinks, err := service.NewOutboxSinks(viper.GetString("OUTBOX_SINKS"), viper.GetString("OUTBOX_FILE"), viper.GetString("OUTBOX_HTTP_URL"))
+	if err != nil {
+		logrus.WithError(err).Fatal("invalid OUTBOX_SINKS")
+	}
+	stopRelay := service.StartOutboxRelay(service.OutboxRelayConfig{
+		Sinks:           sinks,
+		Interval:        viper.GetDuration("OUTBOX_INTERVAL"),
+		Timeout:         viper.GetDuration("OUTBOX_TIMEOUT"),
+		RetryBase:       viper.GetDuration("OUTBOX_RETRY_BASE"),
+		MaxBackoff:      viper.GetDuration("OUTBOX_MAX_BACKOFF"),
+		Retention:       viper.GetDuration("OUTBOX_RETENTION"),
+		CleanupInterval: viper.GetDuration("OUTBOX_CLEANUP_INTERVAL"),
+	})
+	defer stopRelay()
+	stopHub := service.StartChangeHub(service.ChangeHubConfig{
+		Interval: viper.GetDuration("EVENTS_INTERVAL"),
+		Buffer:   viper.GetInt("EVENTS_BUFFER"),
+	})
+	defer stopHub()
+	if err := handler.ConfigureEventStream(viper.GetDuration("EVENTS_STREAM_DURATION"), serverWriteTimeout); err != nil {
+		logrus.WithError(err).Fatal("invalid EVENTS_STREAM_DURATION")
+	}
+	stopDispatcher := service.StartWebhookDispatcher(service.WebhookDispatcherConfig{
+		Interval:    viper.GetDuration("WEBHOOK_INTERVAL"),
+		Timeout:     viper.GetDuration("WEBHOOK_TIMEOUT"),
+		RetryBase:   viper.GetDuration("WEBHOOK_RETRY_BASE"),
+		MaxBackoff:  viper.GetDuration("WEBHOOK_MAX_BACKOFF"),
+		MaxAttempts: viper.GetInt("WEBHOOK_MAX_ATTEMPTS"),
+	})
+	defer stopDispatcher()
+
+	authenticator, err := auth.NewAuthenticator(auth.Config{
+		HS256Secret:        viper.GetString("JWT_HS256_SECRET"),
+		RS256PublicKeyFile: viper.GetString("JWT_RS256_PUBLIC_KEY_FILE"),
+		Issuer:             viper.GetString("JWT_ISSUER"),
+		Audience:           viper.GetString("JWT_AUDIENCE"),
+		AdminAPIKey:        viper.GetString("ADMIN_API_KEY"),
+	})
+	if err != nil {
+		logrus.WithError(err).Fatal("invalid JWT settings")
+	}
 
 	r := mux.NewRouter()
-	r.HandleFunc("/documents", handler.CreateHandler).Methods("POST")
-	r.HandleFunc("/documents/{id:[0-9]+}", handler.UpdateHandler).Methods("PUT")
-	r.HandleFunc("/documents/{id:[0-9]+}", handler.DeleteHandler).Methods("DELETE")
-	r.HandleFunc("/documents/{id:[0-9]+}", handler.GetByIdHandler)
-	r.HandleFunc("/documents", handler.GetAllHandler)
+	r.HandleFunc("/healthz", handler.HealthzHandler).Methods("GET")
+	r.HandleFunc("/readyz", handler.ReadyzHandler).Methods("GET")
+	r.Handle("/metrics", metrics.Handler()).Methods("GET")
+
+	// Every other route needs an API key or a bearer token.
+	api := r.NewRoute().Subrouter()
+	api.Use(authenticator.Middleware)
+	// Retries of the mutations under Idempotency-Key replay their first
+	// response, without the secret of a new API key or webhook, which is not
+	// stored.
+	api.HandleFunc("/documents", handler.Idempotent(handler.CreateHandler)).Methods("POST")
+	api.HandleFunc("/documents:batch", handler.Idempotent(handler.BatchHandler)).Methods("POST")
+	api.HandleFunc("/documents/{id:[0-9]+}", handler.Idempotent(handler.UpdateHandler)).Methods("PUT")
+	api.HandleFunc("/documents/{id:[0-9]+}", handler.Idempotent(handler.PatchHandler)).Methods("PATCH")
+	api.HandleFunc("/documents/{id:[0-9]+}", handler.Idempotent(handler.DeleteHandler)).Methods("DELETE")
+	api.HandleFunc("/documents/{id:[0-9]+}", handler.GetByIdHandler)
+	api.HandleFunc("/documents/trash", handler.GetTrashHandler).Methods("GET")
+	api.HandleFunc("/documents/changes", handler.GetChangesHandler).Methods("GET")
+	api.HandleFunc("/documents/events", handler.EventsHandler).Methods("GET")
+	api.HandleFunc("/documents/trash/{id:[0-9]+}", handler.Idempotent(handler.PurgeHandler)).Methods("DELETE")
+	api.HandleFunc("/documents/{id:[0-9]+}/restore", handler.Idempotent(handler.RestoreDeletedHandler)).Methods("POST")
+	api.HandleFunc("/documents/{id:[0-9]+}/revisions", handler.GetRevisionsHandler).Methods("GET")
+	api.HandleFunc("/documents/{id:[0-9]+}/revisions/{rev:[0-9]+}", handler.GetRevisionHandler).Methods("GET")
+	api.HandleFunc("/documents/{id:[0-9]+}/revisions/{rev:[0-9]+}/restore", handler.Idempotent(handler.RestoreRevisionHandler)).Methods("POST")
+	api.HandleFunc("/documents", handler.GetAllHandler)
+	api.HandleFunc("/documents/{id:[0-9]+}/signatures", handler.Idempotent(handler.SignHandler)).Methods("POST")
+	api.HandleFunc("/documents/{id:[0-9]+}/signatures", handler.GetSignaturesHandler).Methods("GET")
+	api.HandleFunc("/documents/{id:[0-9]+}/verify", handler.VerifyHandler).Methods("GET")
+	api.HandleFunc("/documents/{id:[0-9]+}/signees", handler.GetSigneesHandler).Methods("GET")
+	api.HandleFunc("/documents/{id:[0-9]+}/signees", handler.Idempotent(handler.SetSigneesHandler)).Methods("PUT")
+	api.HandleFunc("/documents/{id:[0-9]+}/signees/{signee:[0-9]+}/sign", handler.Idempotent(handler.SignSigneeHandler)).Methods("POST")
+	api.HandleFunc("/documents/{id:[0-9]+}/signees/{signee:[0-9]+}/decline", handler.Idempotent(handler.DeclineSigneeHandler)).Methods("POST")
+	api.HandleFunc("/documents/{id:[0-9]+}/grants", handler.GetGrantsHandler).Methods("GET")
+	api.HandleFunc("/documents/{id:[0-9]+}/grants", handler.Idempotent(handler.CreateGrantHandler)).Methods("POST")
+	api.HandleFunc("/documents/{id:[0-9]+}/grants/{grant:[0-9]+}", handler.Idempotent(handler.RevokeGrantHandler)).Methods("DELETE")
+	api.HandleFunc("/keys", handler.Idempotent(handler.RegisterKeyHandler)).Methods("POST")
+	api.HandleFunc("/keys", handler.GetKeysHandler).Methods("GET")
+	api.HandleFunc("/api-keys", handler.IdempotentRedacting(handler.CreateAPIKeyHandler, "key")).Methods("POST")
+	api.HandleFunc("/api-keys", handler.GetAPIKeysHandler).Methods("GET")
+	api.HandleFunc("/api-keys/{id:[0-9]+}", handler.Idempotent(handler.RevokeAPIKeyHandler)).Methods("DELETE")
+	api.HandleFunc("/audit", handler.GetAuditHandler).Methods("GET")
+	api.HandleFunc("/audit/verify", handler.VerifyAuditHandler).Methods("GET")
+	api.HandleFunc("/webhooks", handler.IdempotentRedacting(handler.CreateWebhookHandler, "secret")).Methods("POST")
+	api.HandleFunc("/webhooks", handler.GetWebhooksHandler).Methods("GET")
+	api.HandleFunc("/webhooks/dead-letters", handler.GetDeadDeliveriesHandler).Methods("GET")
+	api.HandleFunc("/webhooks/{id:[0-9]+}", handler.GetWebhookHandler).Methods("GET")
+	api.HandleFunc("/webhooks/{id:[0-9]+}", handler.Idempotent(handler.UpdateWebhookHandler)).Methods("PUT")
+	api.HandleFunc("/webhooks/{id:[0-9]+}", handler.Idempotent(handler.DeleteWebhookHandler)).Methods("DELETE")
+	api.HandleFunc("/webhooks/{id:[0-9]+}/deliveries", handler.GetWebhookDeliveriesHandler).Methods("GET")
+	api.HandleFunc("/webhooks/{id:[0-9]+}/deliveries/{delivery:[0-9]+}/redeliver", handler.Idempotent(handler.RedeliverHandler)).Methods("POST")
 
-	r.Use(commonMiddleware)
+	r.Use(tracing.Middleware, logging.Middleware, metrics.Middleware, commonMiddleware)
 
 	http.Handle("/", r)
 	srv := &http.Server{
 		Handler:      r,
 		Addr:         "localhost:" + viper.GetString("PORT"),
-		WriteTimeout: 15 * time.Second,
+		WriteTimeout: serverWriteTimeout,
 		ReadTimeout:  15 * time.Second,
 	}
-	log.Println("Server started on port " + viper.GetString("PORT"))
-	log.Fatal(srv.ListenAndServe())
+	go func() {
+		logrus.WithField("port", viper.GetString("PORT")).Info("server started")
+		if err := srv.ListenAndServe(); err != http.ErrServerClosed {
+			logrus.WithError(err).Fatal("server failed")
+		}
+	}()
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	<-signals
+	shutdown(srv, viper.GetDuration("DRAIN_PERIOD"), viper.GetDuration("SHUTDOWN_TIMEOUT"))
+}
+
+// shutdown fails readiness for drainPeriod so that no new traffic is routed
+// here, then waits up to timeout for the requests in flight to complete.
+func shutdown(srv *http.Server, drainPeriod, timeout time.Duration) {
+	logrus.WithField("drain_period", drainPeriod.String()).Info("draining before shutting down")
+	service.HealthService.Drain()
+	time.Sleep(drainPeriod)
+
+	if timeout <= 0 {
+		timeout = 15 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	if err := srv.Shutdown(ctx); err != nil {
+		logrus.WithError(err).Error("failed waiting for requests in flight")
+	}
+	logrus.Info("server stopped")
 }
 
 func commonMiddleware(next http.Handler) http.Handler {
@@ -0,0 +1,30 @@
+package metrics
+
+import (
+	"github.com/gorilla/mux"
+	"net/http"
+	"precisely/response"
+	"strconv"
+	"time"
+)
+
+// Middleware counts and times the requests by the template of the mux route
+// they matched, so that /documents/1 and /documents/2 share their series.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route := "unmatched"
+		if current := mux.CurrentRoute(r); current != nil {
+			if template, err := current.GetPathTemplate(); err == nil {
+				route = template
+			}
+		}
+
+		recorder := response.NewRecorder(w)
+		start := time.Now()
+		next.ServeHTTP(recorder, r)
+
+		code := strconv.Itoa(recorder.Code)
+		httpRequests.WithLabelValues(route, r.Method, code).Inc()
+		httpDuration.WithLabelValues(route, r.Method, code).Observe(time.Since(start).Seconds())
+	})
+}
@@ -0,0 +1,30 @@
+package metrics
+
+import (
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestMiddleware(t *testing.T) {
+	r := mux.NewRouter()
+	r.HandleFunc("/documents/{id:[0-9]+}", func(w http.ResponseWriter, r *http.Request) {
+		if mux.Vars(r)["id"] == "2" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}).Methods("GET")
+	r.Use(Middleware)
+
+	route := "/documents/{id:[0-9]+}"
+	before := testutil.ToFloat64(httpRequests.WithLabelValues(route, "GET", "200"))
+	for _, path := range []string{"/documents/1", "/documents/3", "/documents/2"} {
+		req, _ := http.NewRequest(http.MethodGet, path, nil)
+		r.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	assert.EqualValues(t, before+2, testutil.ToFloat64(httpRequests.WithLabelValues(route, "GET", "200")))
+	assert.EqualValues(t, 1, testutil.ToFloat64(httpRequests.WithLabelValues(route, "GET", "404")))
+}
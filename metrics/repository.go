@@ -0,0 +1,84 @@
+package metrics
+
+import (
+	"context"
+	"github.com/prometheus/client_golang/prometheus"
+	"precisely/model"
+	"time"
+)
+
+// documentRepository times the main operations of the repository it wraps and
+// passes the others through.
+type documentRepository struct {
+	model.DocumentRepositoryInterface
+}
+
+// InstrumentDocumentRepository decorates repo with operation latencies.
+func InstrumentDocumentRepository(repo model.DocumentRepositoryInterface) model.DocumentRepositoryInterface {
+	return &documentRepository{repo}
+}
+
+func observe(method string, start time.Time, err error) {
+	result := "ok"
+	if err != nil {
+		result = "error"
+	}
+	repositoryDuration.WithLabelValues(method, result).Observe(time.Since(start).Seconds())
+}
+
+func (r *documentRepository) Get(ctx context.Context, id int64) (doc *model.Document, err error) {
+	defer func(start time.Time) { observe("Get", start, err) }(time.Now())
+	return r.DocumentRepositoryInterface.Get(ctx, id)
+}
+
+func (r *documentRepository) Create(ctx context.Context, newDoc model.Document) (doc *model.Document, err error) {
+	defer func(start time.Time) { observe("Create", start, err) }(time.Now())
+	return r.DocumentRepositoryInterface.Create(ctx, newDoc)
+}
+
+func (r *documentRepository) Update(ctx context.Context, upDoc model.Document) (doc *model.Document, err error) {
+	defer func(start time.Time) { observe("Update", start, err) }(time.Now())
+	return r.DocumentRepositoryInterface.Update(ctx, upDoc)
+}
+
+func (r *documentRepository) Delete(ctx context.Context, id, version int64) (err error) {
+	defer func(start time.Time) { observe("Delete", start, err) }(time.Now())
+	return r.DocumentRepositoryInterface.Delete(ctx, id, version)
+}
+
+func (r *documentRepository) GetAll(ctx context.Context, opts model.ListOptions) (page *model.DocumentPage, err error) {
+	defer func(start time.Time) { observe("GetAll", start, err) }(time.Now())
+	return r.DocumentRepositoryInterface.GetAll(ctx, opts)
+}
+
+var documentsDesc = prometheus.NewDesc(namespace+"_documents",
+	"Documents stored, by state: active or trashed.", []string{"state"}, nil)
+
+// documentCollector counts the documents on every scrape.
+type documentCollector struct {
+	repo    model.DocumentRepositoryInterface
+	timeout time.Duration
+}
+
+// RegisterDocuments exposes the number of documents of repo, counted within
+// timeout on every scrape.
+func RegisterDocuments(repo model.DocumentRepositoryInterface, timeout time.Duration) {
+	prometheus.MustRegister(&documentCollector{repo: repo, timeout: timeout})
+}
+
+func (c *documentCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- documentsDesc
+}
+
+func (c *documentCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+	for _, state := range []string{"active", "trashed"} {
+		page, err := c.repo.GetAll(ctx, model.ListOptions{Trashed: state == "trashed", Limit: 1})
+		if err != nil {
+			ch <- prometheus.NewInvalidMetric(documentsDesc, err)
+			continue
+		}
+		ch <- prometheus.MustNewConstMetric(documentsDesc, prometheus.GaugeValue, float64(page.Total), state)
+	}
+}
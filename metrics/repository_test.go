@@ -0,0 +1,50 @@
+package metrics
+
+import (
+	"context"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"precisely/model"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestInstrumentDocumentRepository(t *testing.T) {
+	documents, _, _, _, _, _ := model.NewMemoryRepositories()
+	repo := InstrumentDocumentRepository(documents)
+
+	doc, err := repo.Create(context.Background(), model.Document{Title: "title", Signee: "signee"})
+	assert.Nil(t, err)
+	_, err = repo.Get(context.Background(), doc.ID)
+	assert.Nil(t, err)
+	_, err = repo.Get(context.Background(), doc.ID+1)
+	assert.NotNil(t, err)
+
+	revisions, err := repo.GetRevisions(context.Background(), doc.ID)
+	assert.Nil(t, err)
+	assert.Len(t, revisions, 1)
+
+	// Create ok, Get ok and Get error; GetRevisions passes through untimed.
+	assert.Equal(t, 3, testutil.CollectAndCount(repositoryDuration, namespace+"_repository_operation_duration_seconds"))
+}
+
+func TestDocumentCollector(t *testing.T) {
+	documents, _, _, _, _, _ := model.NewMemoryRepositories()
+	for _, title := range []string{"a", "b", "c"} {
+		_, err := documents.Create(context.Background(), model.Document{Title: title, Signee: "signee"})
+		assert.Nil(t, err)
+	}
+	assert.Nil(t, documents.Delete(context.Background(), 1, 1))
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(&documentCollector{repo: documents, timeout: time.Second})
+	expected := `
+# HELP precisely_documents Documents stored, by state: active or trashed.
+# TYPE precisely_documents gauge
+precisely_documents{state="active"} 2
+precisely_documents{state="trashed"} 1
+`
+	assert.Nil(t, testutil.GatherAndCompare(registry, strings.NewReader(expected), namespace+"_documents"))
+}
@@ -0,0 +1,106 @@
+package model
+
+import (
+	"strings"
+	"time"
+)
+
+// The roles a principal may hold on a document, through a grant or, for
+// admin, by owning it.
+const (
+	RoleViewer = "viewer"
+	RoleSigner = "signer"
+	RoleEditor = "editor"
+
+	GranteeUser  = "user"
+	GranteeGroup = "group"
+)
+
+var (
+	GranteeTypeInvalidValue = newError(KindValidation, "grantee_type_invalid", "grantee_type had invalid value, expect user or group")
+	GranteeInvalidValue     = newError(KindValidation, "grantee_invalid", "grantee had empty value, expect a valid one")
+	RoleInvalidValue        = newError(KindValidation, "role_invalid", "role had invalid value, expect viewer, signer, editor or admin")
+)
+
+// Action is something done to a document that a role may allow.
+type Action string
+
+const (
+	ActionRead   Action = "read"
+	ActionSign   Action = "sign"
+	ActionWrite  Action = "write"
+	ActionDelete Action = "delete"
+	ActionShare  Action = "share"
+)
+
+// roleActions lists what each role allows. Signers and editors both read, but
+// only the former sign and only the latter edit.
+var roleActions = map[string][]Action{
+	RoleViewer: {ActionRead},
+	RoleSigner: {ActionRead, ActionSign},
+	RoleEditor: {ActionRead, ActionWrite},
+	RoleAdmin:  {ActionRead, ActionSign, ActionWrite, ActionDelete, ActionShare},
+}
+
+// Grant gives a user, matched by the subject of its principal, or a group,
+// matched by the groups of its principal, a role on a document.
+type Grant struct {
+	ID          int64     `json:"id"`
+	DocumentID  int64     `json:"document_id"`
+	GranteeType string    `json:"grantee_type"`
+	Grantee     string    `json:"grantee"`
+	Role        string    `json:"role"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+func (g *Grant) Validate() error {
+	g.Grantee = strings.TrimSpace(g.Grantee)
+	if g.GranteeType != GranteeUser && g.GranteeType != GranteeGroup {
+		return GranteeTypeInvalidValue
+	}
+	if g.Grantee == "" {
+		return GranteeInvalidValue
+	}
+	if _, ok := roleActions[g.Role]; !ok {
+		return RoleInvalidValue
+	}
+	return nil
+}
+
+// RoleAllows reports whether role allows action.
+func RoleAllows(role string, action Action) bool {
+	for _, a := range roleActions[role] {
+		if a == action {
+			return true
+		}
+	}
+	return false
+}
+
+// Can reports whether p may perform action on doc given the grants of doc.
+// Admins may do anything to any document and owners anything to theirs.
+func (p *Principal) Can(doc *Document, grants []*Grant, action Action) bool {
+	if p.HasRole(RoleAdmin) || doc.Owner != "" && doc.Owner == p.Subject {
+		return true
+	}
+	for _, g := range grants {
+		if p.matches(g) && RoleAllows(g.Role, action) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *Principal) matches(g *Grant) bool {
+	switch g.GranteeType {
+	case GranteeUser:
+		return g.Grantee == p.Subject
+	case GranteeGroup:
+		for _, group := range p.Groups {
+			if g.Grantee == group {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,118 @@
+package model
+
+import (
+	"context"
+	"database/sql"
+)
+
+var (
+	APIKeyRepository apiKeyRepositoryInterface = &apiKeyRepository{}
+)
+
+type apiKeyRepositoryInterface interface {
+	Create(context.Context, APIKey) (*APIKey, error)
+	GetByHash(context.Context, string) (*APIKey, error)
+	GetAll(context.Context) ([]*APIKey, error)
+	Revoke(context.Context, int64) error
+}
+
+type apiKeyRepository struct {
+	db *dialectDB
+}
+
+func NewAPIKeyRepository(db *sql.DB, dialect *Dialect) apiKeyRepositoryInterface {
+	return &apiKeyRepository{db: newDialectDB(db, dialect)}
+}
+
+const apiKeyColumns = "id, name, roles, key_hash, created_at, revoked_at"
+
+func scanAPIKey(scanner interface{ Scan(...interface{}) error }) (*APIKey, error) {
+	var key APIKey
+	var roles string
+	if err := scanner.Scan(&key.ID, &key.Name, &roles, &key.Hash, &key.CreatedAt, &key.RevokedAt); err != nil {
+		return nil, err
+	}
+	key.Roles = splitRoles(roles)
+	return &key, nil
+}
+
+func (r *apiKeyRepository) Create(ctx context.Context, key APIKey) (_ *APIKey, err error) {
+	ctx, done := startOperation(ctx, "apiKeyRepository.Create")
+	defer done(&err)
+
+	key.CreatedAt = now().UTC()
+	key.ID, err = r.db.dialect.insert(ctx, r.db, "INSERT INTO api_keys(name, roles, key_hash, created_at) VALUES(?, ?, ?, ?)",
+		key.Name, joinRoles(key.Roles), key.Hash, key.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+// GetByHash returns the API key of a hash unless it was revoked.
+func (r *apiKeyRepository) GetByHash(ctx context.Context, hash string) (_ *APIKey, err error) {
+	ctx, done := startOperation(ctx, "apiKeyRepository.GetByHash")
+	defer done(&err)
+
+	stmt, err := r.db.PrepareContext(ctx, "SELECT "+apiKeyColumns+" FROM api_keys WHERE key_hash = ? AND revoked_at IS NULL")
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	return scanAPIKey(stmt.QueryRowContext(ctx, hash))
+}
+
+// GetAll lists every API key, revoked ones included.
+func (r *apiKeyRepository) GetAll(ctx context.Context) (_ []*APIKey, err error) {
+	ctx, done := startOperation(ctx, "apiKeyRepository.GetAll")
+	defer done(&err)
+
+	stmt, err := r.db.PrepareContext(ctx, "SELECT "+apiKeyColumns+" FROM api_keys ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := make([]*APIKey, 0)
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, key)
+	}
+	return results, rows.Err()
+}
+
+// Revoke disables an API key for good, or fails with sql.ErrNoRows when there
+// is no such key left to revoke.
+func (r *apiKeyRepository) Revoke(ctx context.Context, id int64) (err error) {
+	ctx, done := startOperation(ctx, "apiKeyRepository.Revoke")
+	defer done(&err)
+
+	stmt, err := r.db.PrepareContext(ctx, "UPDATE api_keys SET revoked_at = ? WHERE id = ? AND revoked_at IS NULL")
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	result, err := stmt.ExecContext(ctx, now().UTC(), id)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
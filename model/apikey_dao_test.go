@@ -0,0 +1,95 @@
+package model
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/DATA-DOG/go-sqlmock"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestAPIKeyRepository_Create(t *testing.T) {
+	db, mock := NewMock()
+	defer db.Close()
+
+	r := NewAPIKeyRepository(db, MySQL)
+	mock.ExpectPrepare("INSERT INTO api_keys").ExpectExec().
+		WithArgs("ci", "reader,writer", "hash", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(2, 1))
+
+	got, err := r.Create(context.Background(), APIKey{Name: "ci", Roles: []string{"reader", "writer"}, Hash: "hash"})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if got.ID != 2 || got.CreatedAt.IsZero() {
+		t.Errorf("Create() = %v", got)
+	}
+}
+
+func TestAPIKeyRepository_GetByHash(t *testing.T) {
+	db, mock := NewMock()
+	defer db.Close()
+
+	r := NewAPIKeyRepository(db, MySQL)
+	created := time.Date(2022, 1, 10, 9, 0, 0, 0, time.UTC)
+	mock.ExpectPrepare("SELECT (.+) FROM api_keys WHERE key_hash = \\? AND revoked_at IS NULL").ExpectQuery().WithArgs("hash").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "roles", "key_hash", "created_at", "revoked_at"}).
+			AddRow(2, "ci", "reader,writer", "hash", created, nil))
+
+	got, err := r.GetByHash(context.Background(), "hash")
+	if err != nil {
+		t.Fatalf("GetByHash() error = %v", err)
+	}
+	want := &APIKey{ID: 2, Name: "ci", Roles: []string{"reader", "writer"}, Hash: "hash", CreatedAt: created}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("GetByHash() = %v, want %v", got, want)
+	}
+}
+
+func TestAPIKeyRepository_Revoke(t *testing.T) {
+	db, mock := NewMock()
+	defer db.Close()
+
+	r := NewAPIKeyRepository(db, MySQL)
+	mock.ExpectPrepare("UPDATE api_keys SET revoked_at").ExpectExec().WithArgs(sqlmock.AnyArg(), 2).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	if err := r.Revoke(context.Background(), 2); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("Revoke() error = %v, want %v", err, sql.ErrNoRows)
+	}
+}
+
+func TestAPIKeyRepositories(t *testing.T) {
+	repositories := map[string]apiKeyRepositoryInterface{
+		"Memory": NewMemoryAPIKeyRepository(),
+		"SQLite": NewAPIKeyRepository(openSQLite(t), SQLite),
+	}
+	for name, r := range repositories {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			created, err := r.Create(ctx, APIKey{Name: "ci", Roles: []string{"admin"}, Hash: HashAPIKey("secret")})
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := r.GetByHash(ctx, HashAPIKey("secret"))
+			if err != nil || got.ID != created.ID || !reflect.DeepEqual(got.Roles, []string{"admin"}) {
+				t.Fatalf("GetByHash() = %v, %v", got, err)
+			}
+			if err := r.Revoke(ctx, created.ID); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := r.GetByHash(ctx, HashAPIKey("secret")); !errors.Is(err, sql.ErrNoRows) {
+				t.Errorf("GetByHash() of a revoked key error = %v", err)
+			}
+			if err := r.Revoke(ctx, created.ID); !errors.Is(err, sql.ErrNoRows) {
+				t.Errorf("Revoke() twice error = %v", err)
+			}
+			keys, err := r.GetAll(ctx)
+			if err != nil || len(keys) != 1 || keys[0].RevokedAt == nil {
+				t.Errorf("GetAll() = %v, %v", keys, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,164 @@
+package model
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+)
+
+// The operations on documents the audit log records.
+const (
+	AuditCreate  = "create"
+	AuditRead    = "read"
+	AuditList    = "list"
+	AuditUpdate  = "update"
+	AuditDelete  = "delete"
+	AuditRestore = "restore"
+	AuditPurge   = "purge"
+	AuditSign    = "sign"
+	AuditDecline = "decline"
+)
+
+var auditActions = map[string]bool{
+	AuditCreate:  true,
+	AuditRead:    true,
+	AuditList:    true,
+	AuditUpdate:  true,
+	AuditDelete:  true,
+	AuditRestore: true,
+	AuditPurge:   true,
+	AuditSign:    true,
+	AuditDecline: true,
+}
+
+var (
+	AuditActionInvalidValue     = newError(KindInvalid, "audit_action_invalid", "action had invalid value, expect create, read, list, update, delete, restore, purge, sign or decline")
+	AuditDocumentIDInvalidValue = newError(KindInvalid, "audit_document_id_invalid", "document_id had invalid value, expect a positive integer")
+	AuditTimeInvalidValue       = newError(KindInvalid, "audit_time_invalid", "since or until had invalid value, expect an RFC 3339 timestamp")
+)
+
+// AuditEntry records one operation on a document: who performed it, within
+// which request, and the digests of the document before and after it, empty
+// when the document did not exist. PrevHash is the Hash of the entry before it,
+// empty for the first entry, which chains the entries so that changing,
+// inserting or removing one breaks every hash after it.
+type AuditEntry struct {
+	ID         int64     `json:"id"`
+	Actor      string    `json:"actor"`
+	Action     string    `json:"action"`
+	DocumentID int64     `json:"document_id,omitempty"`
+	RequestID  string    `json:"request_id,omitempty"`
+	BeforeHash string    `json:"before_hash,omitempty"`
+	AfterHash  string    `json:"after_hash,omitempty"`
+	PrevHash   string    `json:"prev_hash"`
+	Hash       string    `json:"hash"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+type auditorKey struct{}
+
+// WithAuditor has the changes to documents made with the returned context
+// recorded in the audit log as done by actor within the request of an id. The
+// repositories append the entry of a change in the transaction of the change,
+// so that one is never committed without the other.
+func WithAuditor(ctx context.Context, actor, requestID string) context.Context {
+	return context.WithValue(ctx, auditorKey{}, AuditEntry{Actor: actor, RequestID: requestID})
+}
+
+// NewAuditEntry returns the entry recording action on the document of an id,
+// zero for none, by the auditor of ctx, given the document before and after
+// it, nil when it did not exist. ok is false when ctx has no auditor.
+func NewAuditEntry(ctx context.Context, action string, id int64, before, after *Document) (entry AuditEntry, ok bool) {
+	entry, ok = ctx.Value(auditorKey{}).(AuditEntry)
+	entry.Action, entry.DocumentID = action, id
+	if before != nil {
+		entry.BeforeHash = before.Digest()
+	}
+	if after != nil {
+		entry.AfterHash = after.Digest()
+	}
+	return entry, ok
+}
+
+// ComputeHash is the hex SHA-256 of the JSON array of the fields of the entry
+// other than ID and Hash, PrevHash first and CreatedAt in RFC 3339 UTC.
+func (e *AuditEntry) ComputeHash() string {
+	fields, _ := json.Marshal([]interface{}{
+		e.PrevHash,
+		e.CreatedAt.UTC().Format(time.RFC3339Nano),
+		e.Actor,
+		e.Action,
+		e.DocumentID,
+		e.RequestID,
+		e.BeforeHash,
+		e.AfterHash,
+	})
+	sum := sha256.Sum256(fields)
+	return hex.EncodeToString(sum[:])
+}
+
+// AuditQuery describes a page of the audit log in id order. The filters are
+// ignored when empty, Since and Until bound CreatedAt inclusively and Cursor,
+// when set, is the id of the last entry of the previous page. The Total of a
+// page counts every entry matching the filters, whatever the cursor.
+type AuditQuery struct {
+	Actor      string
+	Action     string
+	DocumentID int64
+	Since      time.Time
+	Until      time.Time
+	Cursor     int64
+	Limit      int
+}
+
+type AuditPage struct {
+	Entries    []*AuditEntry
+	Total      int64
+	NextCursor string
+}
+
+// Validate checks the action filter and fills in the default limit.
+func (q *AuditQuery) Validate() error {
+	if q.Limit == 0 {
+		q.Limit = DefaultListLimit
+	}
+	if q.Limit < 0 || q.Limit > MaxListLimit {
+		return LimitInvalidValue
+	}
+	if q.Action != "" && !auditActions[q.Action] {
+		return AuditActionInvalidValue
+	}
+	return nil
+}
+
+// AuditVerification is the result of walking the audit log from its first
+// entry. Head is the hash of the last entry that checked out; comparing it to
+// one kept elsewhere also detects entries removed from the end of the log.
+type AuditVerification struct {
+	Valid    bool   `json:"valid"`
+	Entries  int64  `json:"entries"`
+	Head     string `json:"head,omitempty"`
+	BrokenAt int64  `json:"broken_at,omitempty"`
+	Reason   string `json:"reason,omitempty"`
+}
+
+// Check verifies the next entry of the walk, which must link to Head and hash
+// to its own Hash. On failure it records the entry as BrokenAt and returns
+// false.
+func (v *AuditVerification) Check(e *AuditEntry) bool {
+	switch {
+	case e.PrevHash != v.Head:
+		v.Reason = "prev_hash does not match the hash of the previous entry"
+	case e.Hash != e.ComputeHash():
+		v.Reason = "hash does not match the content of the entry"
+	default:
+		v.Entries++
+		v.Head = e.Hash
+		return true
+	}
+	v.Valid = false
+	v.BrokenAt = e.ID
+	return false
+}
@@ -0,0 +1,206 @@
+package model
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+var (
+	AuditRepository auditRepositoryInterface = &auditRepository{}
+)
+
+// auditRepositoryInterface is append-only: entries cannot be changed or
+// removed through it.
+type auditRepositoryInterface interface {
+	Append(context.Context, AuditEntry) (*AuditEntry, error)
+	GetAll(context.Context, AuditQuery) (*AuditPage, error)
+}
+
+type auditRepository struct {
+	db *dialectDB
+}
+
+func NewAuditRepository(db *sql.DB, dialect *Dialect) auditRepositoryInterface {
+	return &auditRepository{db: newDialectDB(db, dialect)}
+}
+
+const auditColumns = "id, actor, action, document_id, request_id, before_hash, after_hash, prev_hash, hash, created_at"
+
+func scanAuditEntry(scanner interface{ Scan(...interface{}) error }) (*AuditEntry, error) {
+	var e AuditEntry
+	if err := scanner.Scan(&e.ID, &e.Actor, &e.Action, &e.DocumentID, &e.RequestID, &e.BeforeHash, &e.AfterHash, &e.PrevHash, &e.Hash, &e.CreatedAt); err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+// Append chains an entry onto the last one of the log in a transaction of its
+// own.
+func (r *auditRepository) Append(ctx context.Context, entry AuditEntry) (_ *AuditEntry, err error) {
+	ctx, done := startOperation(ctx, "auditRepository.Append")
+	defer done(&err)
+
+	var appended *AuditEntry
+	err = inTx(ctx, r.db, func(tx *dialectTx) error {
+		appended, err = appendAudit(ctx, tx, entry)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return appended, nil
+}
+
+// writeAudit records action on the document of an id in the audit log within
+// tx, when ctx has an auditor (see WithAuditor).
+func writeAudit(ctx context.Context, tx *dialectTx, action string, id int64, before, after *Document) error {
+	entry, ok := NewAuditEntry(ctx, action, id, before, after)
+	if !ok {
+		return nil
+	}
+	_, err := appendAudit(ctx, tx, entry)
+	return err
+}
+
+// writeAuditUnchanged records like writeAudit an action that leaves the
+// document of an id as it was, such as a signature. The document is read
+// within tx, which fails with sql.ErrNoRows once it is in the trash.
+func writeAuditUnchanged(ctx context.Context, tx *dialectTx, action string, id int64) error {
+	if !audited(ctx) {
+		return nil
+	}
+	doc, err := getDocument(ctx, tx, id)
+	if err != nil {
+		return err
+	}
+	return writeAudit(ctx, tx, action, id, doc, doc)
+}
+
+// audited reports whether the changes made with ctx are recorded in the audit
+// log.
+func audited(ctx context.Context) bool {
+	_, ok := NewAuditEntry(ctx, "", 0, nil, nil)
+	return ok
+}
+
+// appendAudit chains an entry onto the last one of the log within tx, setting
+// its PrevHash, CreatedAt and Hash. The single row of audit_head holds the hash
+// of the last entry; appendAudit updates it before reading it, which keeps it
+// locked until tx ends, so that entries chain in the order their transactions
+// commit. The unique prev_hash column still refuses an entry chained onto one
+// that already has a successor.
+func appendAudit(ctx context.Context, tx *dialectTx, entry AuditEntry) (*AuditEntry, error) {
+	lockStmt, err := tx.PrepareContext(ctx, "UPDATE audit_head SET entries = entries + 1 WHERE id = 1")
+	if err != nil {
+		return nil, err
+	}
+	defer lockStmt.Close()
+
+	if _, err := lockStmt.ExecContext(ctx); err != nil {
+		return nil, err
+	}
+
+	headStmt, err := tx.PrepareContext(ctx, "SELECT hash FROM audit_head WHERE id = 1")
+	if err != nil {
+		return nil, err
+	}
+	defer headStmt.Close()
+
+	if err := headStmt.QueryRowContext(ctx).Scan(&entry.PrevHash); err != nil {
+		return nil, err
+	}
+	entry.CreatedAt = now().UTC().Truncate(time.Microsecond)
+	entry.Hash = entry.ComputeHash()
+	entry.ID, err = tx.dialect.insert(ctx, tx, "INSERT INTO audit_log(actor, action, document_id, request_id, before_hash, after_hash, prev_hash, hash, created_at) VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?)",
+		entry.Actor, entry.Action, entry.DocumentID, entry.RequestID, entry.BeforeHash, entry.AfterHash, entry.PrevHash, entry.Hash, entry.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	stmt, err := tx.PrepareContext(ctx, "UPDATE audit_head SET hash = ? WHERE id = 1")
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	if _, err := stmt.ExecContext(ctx, entry.Hash); err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+func (r *auditRepository) GetAll(ctx context.Context, query AuditQuery) (_ *AuditPage, err error) {
+	ctx, done := startOperation(ctx, "auditRepository.GetAll")
+	defer done(&err)
+
+	if err := query.Validate(); err != nil {
+		return nil, err
+	}
+	where := ""
+	args := make([]interface{}, 0)
+	if query.Actor != "" {
+		where = appendCondition(where, "actor = ?")
+		args = append(args, query.Actor)
+	}
+	if query.Action != "" {
+		where = appendCondition(where, "action = ?")
+		args = append(args, query.Action)
+	}
+	if query.DocumentID != 0 {
+		where = appendCondition(where, "document_id = ?")
+		args = append(args, query.DocumentID)
+	}
+	if !query.Since.IsZero() {
+		where = appendCondition(where, "created_at >= ?")
+		args = append(args, query.Since.UTC())
+	}
+	if !query.Until.IsZero() {
+		where = appendCondition(where, "created_at <= ?")
+		args = append(args, query.Until.UTC())
+	}
+
+	countStmt, err := r.db.PrepareContext(ctx, "SELECT COUNT(*) FROM audit_log"+where)
+	if err != nil {
+		return nil, err
+	}
+	defer countStmt.Close()
+
+	page := AuditPage{Entries: make([]*AuditEntry, 0)}
+	if err := countStmt.QueryRowContext(ctx, args...).Scan(&page.Total); err != nil {
+		return nil, err
+	}
+
+	if query.Cursor != 0 {
+		where = appendCondition(where, "id > ?")
+		args = append(args, query.Cursor)
+	}
+	args = append(args, query.Limit)
+
+	stmt, err := r.db.PrepareContext(ctx, "SELECT "+auditColumns+" FROM audit_log"+where+" ORDER BY id LIMIT ?")
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		e, err := scanAuditEntry(rows)
+		if err != nil {
+			return nil, err
+		}
+		page.Entries = append(page.Entries, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(page.Entries) == query.Limit {
+		page.NextCursor = EncodeCursor(page.Entries[len(page.Entries)-1].ID)
+	}
+	return &page, nil
+}
@@ -0,0 +1,347 @@
+package model
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"github.com/DATA-DOG/go-sqlmock"
+	"testing"
+	"time"
+)
+
+// expectAudit expects the statements appendAudit issues to chain an entry onto
+// head.
+func expectAudit(mock sqlmock.Sqlmock, head string, args ...driver.Value) {
+	mock.ExpectPrepare("UPDATE audit_head SET entries = entries \\+ 1").ExpectExec().
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectPrepare("SELECT hash FROM audit_head").ExpectQuery().
+		WillReturnRows(sqlmock.NewRows([]string{"hash"}).AddRow(head))
+	mock.ExpectPrepare("INSERT INTO audit_log").ExpectExec().
+		WithArgs(append(args, head, sqlmock.AnyArg(), sqlmock.AnyArg())...).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectPrepare("UPDATE audit_head SET hash").ExpectExec().
+		WithArgs(sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(0, 1))
+}
+
+func TestAuditRepository_Append(t *testing.T) {
+	db, mock := NewMock()
+	defer db.Close()
+
+	r := NewAuditRepository(db, MySQL)
+	entry := AuditEntry{Actor: "alice", Action: AuditUpdate, DocumentID: 1, RequestID: "req-1", BeforeHash: "before", AfterHash: "after"}
+	for _, head := range []string{"", "h1"} {
+		mock.ExpectBegin()
+		expectAudit(mock, head, "alice", AuditUpdate, 1, "req-1", "before", "after")
+		mock.ExpectCommit()
+
+		got, err := r.Append(context.Background(), entry)
+		if err != nil {
+			t.Fatalf("Append() error = %v", err)
+		}
+		if got.PrevHash != head || got.Hash != got.ComputeHash() || got.CreatedAt.IsZero() {
+			t.Errorf("Append() = %+v, want an entry chained onto %q", got, head)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+func TestAuditRepository_Append_Failed(t *testing.T) {
+	db, mock := NewMock()
+	defer db.Close()
+
+	r := NewAuditRepository(db, MySQL)
+	failure := errors.New("disk full")
+	mock.ExpectBegin()
+	mock.ExpectPrepare("UPDATE audit_head SET entries").ExpectExec().
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectPrepare("SELECT hash FROM audit_head").ExpectQuery().
+		WillReturnRows(sqlmock.NewRows([]string{"hash"}).AddRow("h1"))
+	mock.ExpectPrepare("INSERT INTO audit_log").ExpectExec().WillReturnError(failure)
+	mock.ExpectRollback()
+
+	if _, err := r.Append(context.Background(), AuditEntry{Actor: "alice", Action: AuditRead}); err != failure {
+		t.Errorf("Append() error = %v, want %v", err, failure)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestAuditRepositories(t *testing.T) {
+	db := openSQLite(t)
+	repositories := map[string]auditRepositoryInterface{
+		"Memory": NewMemoryAuditRepository(),
+		"SQLite": NewAuditRepository(db, SQLite),
+	}
+	for name, r := range repositories {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			for _, e := range []AuditEntry{
+				{Actor: "alice", Action: AuditCreate, DocumentID: 1, AfterHash: "a1"},
+				{Actor: "bob", Action: AuditRead, DocumentID: 1, BeforeHash: "a1", AfterHash: "a1"},
+				{Actor: "alice", Action: AuditList},
+				{Actor: "alice", Action: AuditDelete, DocumentID: 1, BeforeHash: "a1"},
+			} {
+				if _, err := r.Append(ctx, e); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			all, err := r.GetAll(ctx, AuditQuery{})
+			if err != nil || len(all.Entries) != 4 {
+				t.Fatalf("GetAll() = %v, %v, want 4 entries", all, err)
+			}
+			verification := AuditVerification{Valid: true}
+			for _, e := range all.Entries {
+				if !verification.Check(e) {
+					t.Fatalf("Check(%+v) failed: %s", e, verification.Reason)
+				}
+			}
+			if verification.Head != all.Entries[3].Hash {
+				t.Errorf("Head = %q, want the hash of the last entry", verification.Head)
+			}
+
+			page, err := r.GetAll(ctx, AuditQuery{Actor: "alice", DocumentID: 1, Limit: 1})
+			if err != nil || len(page.Entries) != 1 || page.Entries[0].Action != AuditCreate || page.Total != 2 || page.NextCursor == "" {
+				t.Fatalf("GetAll() first page = %v, %v", page, err)
+			}
+			cursor, _ := DecodeCursor(page.NextCursor)
+			page, err = r.GetAll(ctx, AuditQuery{Actor: "alice", DocumentID: 1, Limit: 1, Cursor: cursor})
+			if err != nil || len(page.Entries) != 1 || page.Entries[0].Action != AuditDelete {
+				t.Fatalf("GetAll() second page = %v, %v", page, err)
+			}
+			page, err = r.GetAll(ctx, AuditQuery{Action: AuditRead, Since: all.Entries[1].CreatedAt, Until: all.Entries[1].CreatedAt})
+			if err != nil || len(page.Entries) != 1 || page.Entries[0].Actor != "bob" {
+				t.Errorf("GetAll() reads at %v = %v, %v", all.Entries[1].CreatedAt, page, err)
+			}
+			if _, err := r.GetAll(ctx, AuditQuery{Action: "approve"}); err != AuditActionInvalidValue {
+				t.Errorf("GetAll() error = %v, want %v", err, AuditActionInvalidValue)
+			}
+		})
+	}
+}
+
+func TestAuditVerification_Tampered(t *testing.T) {
+	db := openSQLite(t)
+	r := NewAuditRepository(db, SQLite)
+	ctx := context.Background()
+	for _, actor := range []string{"alice", "bob", "carol"} {
+		if _, err := r.Append(ctx, AuditEntry{Actor: actor, Action: AuditRead, DocumentID: 1}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := db.Exec("UPDATE audit_log SET actor = 'mallory' WHERE id = 2"); err != nil {
+		t.Fatal(err)
+	}
+
+	all, err := r.GetAll(ctx, AuditQuery{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	verification := AuditVerification{Valid: true}
+	for _, e := range all.Entries {
+		if !verification.Check(e) {
+			break
+		}
+	}
+	if verification.Valid || verification.BrokenAt != 2 || verification.Entries != 1 {
+		t.Errorf("verification = %+v, want broken at entry 2", verification)
+	}
+}
+
+// TestSQLite_AuditedChanges has the changes made with an auditor recorded in
+// their own transaction, so that a change whose entry cannot be appended is
+// not made either.
+func TestSQLite_AuditedChanges(t *testing.T) {
+	db := openSQLite(t)
+	documents, auditLog := NewDocumentRepository(db, SQLite), NewAuditRepository(db, SQLite)
+	ctx := WithAuditor(context.Background(), "alice", "req-1")
+
+	created, err := documents.Create(ctx, Document{Title: "title", Signee: "alice"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	before := *created
+	created.Title = "new title"
+	updated, err := documents.Update(ctx, *created)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := documents.Delete(ctx, updated.ID, updated.Version); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := documents.RestoreDeleted(ctx, updated.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := auditLog.Append(context.Background(), AuditEntry{Actor: "bob", Action: AuditRead, DocumentID: updated.ID}); err != nil {
+		t.Fatal(err)
+	}
+
+	page, err := auditLog.GetAll(context.Background(), AuditQuery{})
+	if err != nil || len(page.Entries) != 5 {
+		t.Fatalf("GetAll() = %+v, %v, want 5 entries", page, err)
+	}
+	verification := AuditVerification{Valid: true}
+	for i, action := range []string{AuditCreate, AuditUpdate, AuditDelete, AuditRestore, AuditRead} {
+		e := page.Entries[i]
+		if e.Action != action || !verification.Check(e) {
+			t.Errorf("entry %d = %+v, want a chained %s", i+1, e, action)
+		}
+	}
+	if e := page.Entries[1]; e.Actor != "alice" || e.RequestID != "req-1" || e.BeforeHash != before.Digest() || e.AfterHash != updated.Digest() {
+		t.Errorf("update entry = %+v", e)
+	}
+
+	last, err := documents.GetLastSequence(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec("DROP TABLE audit_head"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := documents.Create(ctx, Document{Title: "unaudited", Signee: "alice"}); err == nil {
+		t.Fatal("Create() without an audit log succeeded")
+	}
+	if got, err := documents.GetLastSequence(context.Background()); err != nil || got != last {
+		t.Errorf("GetLastSequence() = %d, %v, want the unaudited change rolled back to %d", got, err, last)
+	}
+}
+
+// TestAuditedBatch has the operations of a batch recorded in its transaction:
+// those that fail, and every one of an atomic batch that fails, are not.
+func TestAuditedBatch(t *testing.T) {
+	db := openSQLite(t)
+	memoryDocuments, _, _, _, _, memoryAudit := NewMemoryRepositories()
+	repositories := map[string]struct {
+		documents documentRepositoryInterface
+		audit     auditRepositoryInterface
+	}{
+		"Memory": {memoryDocuments, memoryAudit},
+		"SQLite": {NewDocumentRepository(db, SQLite), NewAuditRepository(db, SQLite)},
+	}
+	for name, r := range repositories {
+		t.Run(name, func(t *testing.T) {
+			ctx := WithAuditor(context.Background(), "alice", "req-1")
+			results, err := r.documents.Batch(ctx, []BatchOperation{
+				{Op: BatchCreate, Document: Document{Title: "first", Signee: "alice"}},
+				{Op: BatchDelete, Document: Document{ID: 42, Version: 1}},
+				{Op: BatchCreate, Document: Document{Title: "second", Signee: "alice"}},
+			}, BatchBestEffort)
+			if err != nil || results[1].Err == nil {
+				t.Fatalf("Batch() = %+v, %v", results, err)
+			}
+			if _, err := r.documents.Batch(ctx, []BatchOperation{
+				{Op: BatchCreate, Document: Document{Title: "third", Signee: "alice"}},
+				{Op: BatchUpdate, Document: Document{ID: results[0].Document.ID, Title: "first", Signee: "alice", Version: 7}},
+			}, BatchAtomic); err == nil {
+				t.Fatal("Batch() of a stale update succeeded")
+			}
+
+			page, err := r.audit.GetAll(context.Background(), AuditQuery{})
+			if err != nil || len(page.Entries) != 2 {
+				t.Fatalf("GetAll() = %+v, %v, want the two creates of the first batch", page, err)
+			}
+			verification := AuditVerification{Valid: true}
+			for i, e := range page.Entries {
+				doc := results[2*i].Document
+				if e.Action != AuditCreate || e.DocumentID != doc.ID || e.AfterHash != doc.Digest() || !verification.Check(e) {
+					t.Errorf("entry %d = %+v, want the chained create of %+v", i+1, e, doc)
+				}
+			}
+		})
+	}
+}
+
+// TestAuditedSigningAndPurge has signatures, declines and purges recorded in
+// their own transaction, like the changes of documents.
+func TestAuditedSigningAndPurge(t *testing.T) {
+	db := openSQLite(t)
+	memoryDocuments, memorySignatures, memorySignees, _, _, memoryAudit := NewMemoryRepositories()
+	repositories := map[string]struct {
+		documents  documentRepositoryInterface
+		signatures signatureRepositoryInterface
+		signees    signeeRepositoryInterface
+		audit      auditRepositoryInterface
+	}{
+		"Memory": {memoryDocuments, memorySignatures, memorySignees, memoryAudit},
+		"SQLite": {NewDocumentRepository(db, SQLite), NewSignatureRepository(db, SQLite), NewSigneeRepository(db, SQLite), NewAuditRepository(db, SQLite)},
+	}
+	for name, r := range repositories {
+		t.Run(name, func(t *testing.T) {
+			ctx := WithAuditor(context.Background(), "alice", "req-1")
+			doc, err := r.documents.Create(context.Background(), Document{Title: "signed", Signee: "alice"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := r.signees.SetSignees(context.Background(), SigningWorkflow{DocumentID: doc.ID, Mode: SigningParallel, Signees: []*Signee{
+				{Position: 1, Name: "alice", Status: SigneePending},
+				{Position: 2, Name: "bob", Status: SigneePending},
+			}}); err != nil {
+				t.Fatal(err)
+			}
+			workflow, err := r.signees.GetWorkflow(context.Background(), doc.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			key, err := r.signatures.CreateKey(context.Background(), SigneeKey{Signee: "alice", PublicKey: make([]byte, 32)})
+			if err != nil {
+				t.Fatal(err)
+			}
+			signedAt := time.Now().UTC()
+			alice := *workflow.Signees[0]
+			alice.Status, alice.SignedAt = SigneeSigned, &signedAt
+			sig := Signature{DocumentID: doc.ID, KeyID: key.ID, Signee: "alice", Signature: make([]byte, 64), Digest: doc.Digest()}
+			if _, err := r.signatures.CreateSignature(ctx, sig, &alice); err != nil {
+				t.Fatal(err)
+			}
+			bob := *workflow.Signees[1]
+			bob.Status, bob.Reason, bob.DeclinedAt = SigneeDeclined, "wrong amount", &signedAt
+			if err := r.signees.UpdateStatus(ctx, bob); err != nil {
+				t.Fatal(err)
+			}
+			if err := r.documents.Delete(context.Background(), doc.ID, doc.Version); err != nil {
+				t.Fatal(err)
+			}
+			if err := r.documents.Purge(ctx, doc.ID); err != nil {
+				t.Fatal(err)
+			}
+			expired, err := r.documents.Create(context.Background(), Document{Title: "expired", Signee: "alice"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := r.documents.Delete(context.Background(), expired.ID, expired.Version); err != nil {
+				t.Fatal(err)
+			}
+			if purged, err := r.documents.PurgeDeletedBefore(ctx, time.Now().Add(time.Hour)); err != nil || purged != 1 {
+				t.Fatalf("PurgeDeletedBefore() = %d, %v", purged, err)
+			}
+			if purged, err := r.documents.PurgeDeletedBefore(ctx, time.Now().Add(time.Hour)); err != nil || purged != 0 {
+				t.Fatalf("PurgeDeletedBefore() of an empty trash = %d, %v", purged, err)
+			}
+
+			page, err := r.audit.GetAll(context.Background(), AuditQuery{})
+			if err != nil || len(page.Entries) != 4 {
+				t.Fatalf("GetAll() = %+v, %v, want 4 entries", page, err)
+			}
+			verification := AuditVerification{Valid: true}
+			for i, want := range []struct {
+				action string
+				id     int64
+				before string
+				after  string
+			}{
+				{AuditSign, doc.ID, doc.Digest(), doc.Digest()},
+				{AuditDecline, doc.ID, doc.Digest(), doc.Digest()},
+				{AuditPurge, doc.ID, doc.Digest(), ""},
+				{AuditPurge, 0, "", ""},
+			} {
+				e := page.Entries[i]
+				if e.Action != want.action || e.DocumentID != want.id || e.BeforeHash != want.before || e.AfterHash != want.after || e.Actor != "alice" || !verification.Check(e) {
+					t.Errorf("entry %d = %+v, want a chained %s of %d", i+1, e, want.action, want.id)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,113 @@
+package model
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	RoleAdmin = "admin"
+
+	AuthAPIKey = "api_key"
+	AuthJWT    = "jwt"
+
+	// APIKeySubjectPrefix starts the subjects of the API keys, which tokens
+	// may not take, so that a key never shares a subject with a user.
+	APIKeySubjectPrefix = "apikey:"
+	// AdminSubject is the subject of ADMIN_API_KEY, which is not stored.
+	AdminSubject = APIKeySubjectPrefix + RoleAdmin
+)
+
+var (
+	UnauthenticatedValue   = newError(KindUnauthenticated, "unauthenticated", "request is not authenticated, expect a valid X-API-Key header or bearer token")
+	ForbiddenValue         = newError(KindForbidden, "forbidden", "principal is not allowed to perform this action")
+	APIKeyNameInvalidValue = newError(KindValidation, "api_key_name_invalid", "name had empty value, expect a valid one")
+	RolesInvalidValue      = newError(KindValidation, "roles_invalid", "roles had invalid value, expect names without commas or spaces")
+)
+
+// Principal is who a request was authenticated as, through an API key or a
+// bearer token. Groups are matched by the document grants given to groups.
+type Principal struct {
+	Subject string   `json:"subject"`
+	Roles   []string `json:"roles"`
+	Groups  []string `json:"groups,omitempty"`
+	Method  string   `json:"method"`
+}
+
+func (p *Principal) HasRole(role string) bool {
+	for _, r := range p.Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+type principalKey struct{}
+
+func WithPrincipal(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// PrincipalFrom returns the principal of ctx, or nil when it carries none.
+func PrincipalFrom(ctx context.Context) *Principal {
+	p, _ := ctx.Value(principalKey{}).(*Principal)
+	return p
+}
+
+// APIKey authenticates whoever holds Key as the subject of its id with Roles;
+// Name only describes it. Only the hex
+// SHA-256 of the key is stored; Key itself is returned once, on creation.
+type APIKey struct {
+	ID        int64      `json:"id"`
+	Name      string     `json:"name"`
+	Roles     []string   `json:"roles"`
+	Key       string     `json:"key,omitempty"`
+	Hash      string     `json:"-"`
+	CreatedAt time.Time  `json:"created_at"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+}
+
+func (k *APIKey) Validate() error {
+	k.Name = strings.TrimSpace(k.Name)
+	if k.Name == "" {
+		return APIKeyNameInvalidValue
+	}
+	if k.Roles == nil {
+		k.Roles = []string{}
+	}
+	for _, role := range k.Roles {
+		if role == "" || strings.ContainsAny(role, ", \t\n") {
+			return RolesInvalidValue
+		}
+	}
+	return nil
+}
+
+// APIKeySubject is the subject an API key authenticates as. Unlike its name,
+// its id is unique.
+func APIKeySubject(id int64) string {
+	return APIKeySubjectPrefix + strconv.FormatInt(id, 10)
+}
+
+// HashAPIKey returns the hash an API key is stored and looked up by. The keys
+// are random enough for a plain SHA-256 to resist brute force.
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+func joinRoles(roles []string) string {
+	return strings.Join(roles, ",")
+}
+
+func splitRoles(roles string) []string {
+	if roles == "" {
+		return []string{}
+	}
+	return strings.Split(roles, ",")
+}
@@ -0,0 +1,100 @@
+package model
+
+import (
+	"fmt"
+)
+
+var (
+	BatchModeInvalidValue      = newError(KindInvalid, "batch_mode_invalid", "mode had invalid value, expect atomic or best_effort")
+	BatchSizeInvalidValue      = newError(KindInvalid, "batch_size_invalid", "operations had invalid length, expect between 1 and 1000 operations")
+	BatchOperationInvalidValue = newError(KindInvalid, "batch_operation_invalid", "op had invalid value, expect create, update or delete")
+	BatchIDInvalidValue        = newError(KindInvalid, "batch_id_invalid", "id had invalid value, expect the id of an existing document")
+)
+
+const MaxBatchSize = 1000
+
+type BatchOp string
+
+const (
+	BatchCreate BatchOp = "create"
+	BatchUpdate BatchOp = "update"
+	BatchDelete BatchOp = "delete"
+)
+
+// BatchMode tells whether a batch is applied all-or-nothing or keeps the
+// operations that succeeded when others fail.
+type BatchMode string
+
+const (
+	BatchAtomic     BatchMode = "atomic"
+	BatchBestEffort BatchMode = "best_effort"
+)
+
+// BatchOperation is one operation of a batch. Updates and deletes must carry
+// the version the document is expected to be at.
+type BatchOperation struct {
+	Op BatchOp `json:"op"`
+	Document
+}
+
+type Batch struct {
+	Mode       BatchMode        `json:"mode"`
+	Operations []BatchOperation `json:"operations"`
+}
+
+// BatchResult is the outcome of one operation of a batch. Document is nil for
+// deletes and failed operations.
+type BatchResult struct {
+	Document *Document
+	Err      error
+}
+
+// BatchOperationError reports the operation that made an atomic batch fail.
+type BatchOperationError struct {
+	Index int
+	Err   error
+}
+
+func (e *BatchOperationError) Error() string {
+	return fmt.Sprintf("operation %d: %v", e.Index, e.Err)
+}
+
+func (e *BatchOperationError) Unwrap() error {
+	return e.Err
+}
+
+// Validate checks the mode and size of a batch, defaulting the mode to atomic.
+func (b *Batch) Validate() error {
+	if b.Mode == "" {
+		b.Mode = BatchAtomic
+	}
+	if b.Mode != BatchAtomic && b.Mode != BatchBestEffort {
+		return BatchModeInvalidValue
+	}
+	if len(b.Operations) == 0 || len(b.Operations) > MaxBatchSize {
+		return BatchSizeInvalidValue
+	}
+	return nil
+}
+
+// Validate checks a single operation of a batch.
+func (o *BatchOperation) Validate() error {
+	switch o.Op {
+	case BatchCreate:
+		return o.Document.Validate()
+	case BatchUpdate:
+		if err := o.Document.Validate(); err != nil {
+			return err
+		}
+	case BatchDelete:
+	default:
+		return BatchOperationInvalidValue
+	}
+	if o.ID <= 0 {
+		return BatchIDInvalidValue
+	}
+	if o.Version <= 0 {
+		return PreconditionRequiredValue
+	}
+	return nil
+}
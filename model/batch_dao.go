@@ -0,0 +1,71 @@
+package model
+
+import "context"
+
+// Batch applies the operations of a batch in a single transaction. In atomic
+// mode the first failing operation rolls back the whole batch and is returned
+// as a *BatchOperationError. In best-effort mode every operation runs under
+// its own savepoint, so a failure only undoes that operation and is reported
+// in its result.
+func (r *documentRepository) Batch(ctx context.Context, ops []BatchOperation, mode BatchMode) (_ []BatchResult, err error) {
+	ctx, done := startOperation(ctx, "documentRepository.Batch")
+	defer done(&err)
+
+	results := make([]BatchResult, len(ops))
+	err = inChangeTx(ctx, r.db, func(tx *dialectTx) error {
+		for i, op := range ops {
+			if mode == BatchAtomic {
+				doc, err := applyBatchOperation(ctx, tx, op)
+				if err != nil {
+					return &BatchOperationError{Index: i, Err: err}
+				}
+				results[i].Document = doc
+				continue
+			}
+
+			if _, err := tx.ExecContext(ctx, "SAVEPOINT batch_operation"); err != nil {
+				return err
+			}
+			doc, err := applyBatchOperation(ctx, tx, op)
+			if err != nil {
+				if _, rollbackErr := tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT batch_operation"); rollbackErr != nil {
+					return rollbackErr
+				}
+				results[i].Err = err
+				continue
+			}
+			if _, err := tx.ExecContext(ctx, "RELEASE SAVEPOINT batch_operation"); err != nil {
+				return err
+			}
+			results[i].Document = doc
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+func applyBatchOperation(ctx context.Context, tx *dialectTx, op BatchOperation) (*Document, error) {
+	switch op.Op {
+	case BatchCreate:
+		return createDocument(ctx, tx, op.Document)
+	case BatchUpdate:
+		current, err := getDocument(ctx, tx, op.ID)
+		if err != nil {
+			return nil, err
+		}
+		if current.Version != op.Version {
+			return nil, VersionMismatchValue
+		}
+		doc := op.Document
+		if err := updateDocument(ctx, tx, &doc); err != nil {
+			return nil, err
+		}
+		return &doc, nil
+	case BatchDelete:
+		return nil, deleteDocument(ctx, tx, op.ID, op.Version)
+	}
+	return nil, BatchOperationInvalidValue
+}
@@ -0,0 +1,82 @@
+package model
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/DATA-DOG/go-sqlmock"
+	"reflect"
+	"testing"
+)
+
+func TestDocumentRepository_Batch_Atomic(t *testing.T) {
+	db, mock := NewMock()
+	defer db.Close()
+	r := NewDocumentRepository(db, MySQL)
+
+	contentBytes, _ := json.Marshal(Content{})
+	expectChangeTx(mock)
+	mock.ExpectPrepare("INSERT INTO documents").ExpectExec().
+		WithArgs("title", string(contentBytes), "signee", "").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	expectRevision(mock, 1, 1, RevisionCreate, "title", contentBytes, "signee")
+	expectEvent(mock, 1, EventDocumentCreated)
+	mock.ExpectPrepare("SELECT (.+) FROM documents").ExpectQuery().WithArgs(2).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "title", "content", "signee", "owner", "version", "deleted_at"}).
+			AddRow(2, "other", contentBytes, "signee", "alice", 3, nil))
+	mock.ExpectRollback()
+
+	results, err := r.Batch(context.Background(), []BatchOperation{
+		{Op: BatchCreate, Document: Document{Title: "title", Signee: "signee"}},
+		{Op: BatchUpdate, Document: Document{ID: 2, Title: "other", Signee: "signee", Version: 2}},
+	}, BatchAtomic)
+	if results != nil {
+		t.Errorf("Batch() = %v, want nil", results)
+	}
+	var opErr *BatchOperationError
+	if !errors.As(err, &opErr) || opErr.Index != 1 || !errors.Is(err, VersionMismatchValue) {
+		t.Errorf("Batch() error = %v, want operation 1 to mismatch", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Batch() %v", err)
+	}
+}
+
+func TestDocumentRepository_Batch_BestEffort(t *testing.T) {
+	db, mock := NewMock()
+	defer db.Close()
+	r := NewDocumentRepository(db, MySQL)
+
+	contentBytes, _ := json.Marshal(Content{})
+	expectChangeTx(mock)
+	mock.ExpectExec("SAVEPOINT batch_operation").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectPrepare("SELECT (.+) FROM documents").ExpectQuery().WithArgs(2).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "title", "content", "signee", "owner", "version", "deleted_at"}))
+	mock.ExpectExec("ROLLBACK TO SAVEPOINT batch_operation").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("SAVEPOINT batch_operation").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectPrepare("INSERT INTO documents").ExpectExec().
+		WithArgs("title", string(contentBytes), "signee", "").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	expectRevision(mock, 1, 1, RevisionCreate, "title", contentBytes, "signee")
+	expectEvent(mock, 1, EventDocumentCreated)
+	mock.ExpectExec("RELEASE SAVEPOINT batch_operation").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+
+	results, err := r.Batch(context.Background(), []BatchOperation{
+		{Op: BatchDelete, Document: Document{ID: 2, Version: 1}},
+		{Op: BatchCreate, Document: Document{Title: "title", Signee: "signee"}},
+	}, BatchBestEffort)
+	if err != nil {
+		t.Fatalf("Batch() error = %v", err)
+	}
+	want := []BatchResult{
+		{Err: results[0].Err},
+		{Document: &Document{ID: 1, Title: "title", Signee: "signee", Version: 1}},
+	}
+	if !reflect.DeepEqual(results, want) || results[0].Err == nil {
+		t.Errorf("Batch() = %v, want %v", results, want)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Batch() %v", err)
+	}
+}
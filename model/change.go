@@ -0,0 +1,109 @@
+package model
+
+import (
+	"time"
+)
+
+// The kinds of change the change feed reports. Restoring a document from the
+// trash is an update, since it brings back a document a reader last saw
+// deleted.
+const (
+	ChangeCreate = "create"
+	ChangeUpdate = "update"
+	ChangeDelete = "delete"
+)
+
+const (
+	// MaxChangeWait bounds how long a read of the change feed may wait for
+	// new changes, below the write timeout of the server.
+	MaxChangeWait = 10 * time.Second
+)
+
+var (
+	WaitInvalidValue          = newError(KindInvalid, "wait_invalid", "wait had invalid value, expect a duration of at most 10s such as 5s")
+	EventFilterInvalidValue   = newError(KindInvalid, "event_filter_invalid", "document_id had invalid value, expect a positive integer")
+	StreamingUnsupportedValue = newError(KindInternal, "streaming_unsupported", "streaming is not supported by the connection")
+)
+
+// Change is an entry of the change feed. Sequence increases with every change
+// to any document, so that a reader resumes after the last one it saw.
+// Document is the document after the change, nil for a deletion, while Signee
+// is the signee of the document either way.
+type Change struct {
+	Sequence   int64     `json:"sequence"`
+	Operation  string    `json:"operation"`
+	DocumentID int64     `json:"document_id"`
+	Revision   int64     `json:"revision"`
+	Signee     string    `json:"signee"`
+	CreatedAt  time.Time `json:"created_at"`
+	Document   *Document `json:"document,omitempty"`
+}
+
+// ChangeQuery describes a page of the change feed: the changes after the
+// Since sequence, waiting up to Wait for one when there is none yet.
+type ChangeQuery struct {
+	Since int64
+	Limit int
+	Wait  time.Duration
+}
+
+type ChangePage struct {
+	Changes    []*Change
+	NextCursor string
+}
+
+// ChangeFilter selects the changes of one document, of the documents of one
+// signee, or both. The zero value selects every change.
+type ChangeFilter struct {
+	DocumentID int64
+	Signee     string
+}
+
+// Matches reports whether the filter selects c.
+func (f ChangeFilter) Matches(c *Change) bool {
+	if f.DocumentID != 0 && c.DocumentID != f.DocumentID {
+		return false
+	}
+	if f.Signee != "" && c.Signee != f.Signee {
+		return false
+	}
+	return true
+}
+
+// Validate fills in the default limit and checks the wait.
+func (q *ChangeQuery) Validate() error {
+	if q.Limit == 0 {
+		q.Limit = DefaultListLimit
+	}
+	if q.Limit < 0 || q.Limit > MaxListLimit {
+		return LimitInvalidValue
+	}
+	if q.Since < 0 {
+		return CursorInvalidValue
+	}
+	if q.Wait < 0 || q.Wait > MaxChangeWait {
+		return WaitInvalidValue
+	}
+	return nil
+}
+
+// newChange is the change a revision records, under the given sequence.
+func newChange(sequence int64, rev *Revision) *Change {
+	change := &Change{
+		Sequence:   sequence,
+		Operation:  rev.Operation,
+		DocumentID: rev.ID,
+		Revision:   rev.Revision,
+		Signee:     rev.Signee,
+		CreatedAt:  rev.CreatedAt,
+	}
+	switch rev.Operation {
+	case RevisionDelete:
+		return change
+	case RevisionRestore:
+		change.Operation = ChangeUpdate
+	}
+	doc := rev.Document
+	change.Document = &doc
+	return change
+}
@@ -0,0 +1,44 @@
+package model_test
+
+import (
+	_ "github.com/go-sql-driver/mysql"
+	_ "github.com/lib/pq"
+	"os"
+	"path/filepath"
+	"precisely/model"
+	"precisely/model/repositorytest"
+	"testing"
+)
+
+func TestMemoryRepository_Conformance(t *testing.T) {
+	repositorytest.TestDocumentRepository(t, func(t *testing.T) model.DocumentRepositoryInterface {
+		r, _, _, _, _, _ := model.NewMemoryRepositories()
+		return r
+	})
+}
+
+func TestSQLiteRepository_Conformance(t *testing.T) {
+	repositorytest.TestDocumentRepository(t, func(t *testing.T) model.DocumentRepositoryInterface {
+		dsn := model.SQLite.DataSourceName("", "", "", "", filepath.Join(t.TempDir(), "precisely.db"))
+		return model.NewDocumentRepository(model.OpenTestDB(t, model.SQLite, dsn), model.SQLite)
+	})
+}
+
+// The MySQL and PostgreSQL suites run against the databases named by
+// TEST_MYSQL_DSN and TEST_POSTGRES_DSN, whose tables they drop.
+func TestMySQLRepository_Conformance(t *testing.T) {
+	testSQLRepository(t, model.MySQL, os.Getenv("TEST_MYSQL_DSN"))
+}
+
+func TestPostgresRepository_Conformance(t *testing.T) {
+	testSQLRepository(t, model.Postgres, os.Getenv("TEST_POSTGRES_DSN"))
+}
+
+func testSQLRepository(t *testing.T, dialect *model.Dialect, dsn string) {
+	if dsn == "" {
+		t.Skipf("no %s database to test against", dialect.Driver)
+	}
+	repositorytest.TestDocumentRepository(t, func(t *testing.T) model.DocumentRepositoryInterface {
+		return model.NewDocumentRepository(model.OpenTestDB(t, dialect, dsn), dialect)
+	})
+}
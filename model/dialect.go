@@ -0,0 +1,275 @@
+package model
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.7.0"
+	"go.opentelemetry.io/otel/trace"
+	"net/url"
+	"precisely/tracing"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Dialect describes how the repositories talk to one kind of database. Queries
+// are written with ? placeholders and rebound for dialects that number them.
+type Dialect struct {
+	// Driver is the database/sql driver name.
+	Driver string
+	// numbered placeholders are written $1, $2, ... instead of ?.
+	numbered bool
+	// returning dialects report inserted ids through RETURNING id rather than
+	// LastInsertId.
+	returning bool
+	// like is the case-insensitive LIKE operator.
+	like string
+	// system names the database in traces.
+	system string
+	dsn    func(username, password, port, host, database string) string
+}
+
+var (
+	MySQL = &Dialect{
+		Driver: "mysql",
+		like:   "LIKE",
+		system: "mysql",
+		dsn: func(username, password, port, host, database string) string {
+			return fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?parseTime=true",
+				username, password, host, port, database)
+		},
+	}
+	// Postgres connects without TLS unless MS_DB carries its own query
+	// string, e.g. "precisely?sslmode=verify-full".
+	Postgres = &Dialect{
+		Driver:    "postgres",
+		numbered:  true,
+		returning: true,
+		like:      "ILIKE",
+		system:    "postgresql",
+		dsn: func(username, password, port, host, database string) string {
+			u := url.URL{
+				Scheme: "postgres",
+				User:   url.UserPassword(username, password),
+				Host:   host + ":" + port,
+				Path:   database,
+			}
+			if i := strings.Index(database, "?"); i >= 0 {
+				u.Path, u.RawQuery = database[:i], database[i+1:]
+			} else {
+				u.RawQuery = "sslmode=disable"
+			}
+			return u.String()
+		},
+	}
+	// SQLite keeps the database in the file named by MS_DB and ignores the
+	// other connection settings.
+	SQLite = &Dialect{
+		Driver: "sqlite3",
+		like:   "LIKE",
+		system: "sqlite",
+		dsn: func(username, password, port, host, database string) string {
+			return "file:" + database + "?_foreign_keys=on&_busy_timeout=5000&_journal_mode=WAL&_txlock=immediate"
+		},
+	}
+)
+
+// dialects maps the accepted DRIVER settings to their dialect.
+var dialects = map[string]*Dialect{
+	"mysql":      MySQL,
+	"postgres":   Postgres,
+	"postgresql": Postgres,
+	"sqlite3":    SQLite,
+	"sqlite":     SQLite,
+}
+
+// DialectFor returns the dialect of a DRIVER setting.
+func DialectFor(driver string) (*Dialect, error) {
+	if d, ok := dialects[driver]; ok {
+		return d, nil
+	}
+	return nil, fmt.Errorf("unsupported DRIVER %q, expect mysql, postgres or sqlite3", driver)
+}
+
+// DataSourceName builds the connection string of the dialect.
+func (d *Dialect) DataSourceName(username, password, port, host, database string) string {
+	return d.dsn(username, password, port, host, database)
+}
+
+// Rebind rewrites the ? placeholders of a query for the dialect.
+func (d *Dialect) Rebind(query string) string {
+	if !d.numbered {
+		return query
+	}
+	var b strings.Builder
+	n := 0
+	quoted := false
+	for _, c := range query {
+		switch {
+		case c == '\'':
+			quoted = !quoted
+		case c == '?' && !quoted:
+			n++
+			b.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(c)
+	}
+	return b.String()
+}
+
+// OperationTimeout bounds each operation of the SQL repositories when
+// positive, on top of the deadline of the context it is given.
+var OperationTimeout time.Duration
+
+// startOperation starts the span of one repository operation and derives its
+// context. The returned done function ends both and, when the operation failed
+// because the context ended, reports the context's error however the driver
+// worded the failure.
+func startOperation(ctx context.Context, name string) (context.Context, func(*error)) {
+	ctx, span := tracing.Start(ctx, name)
+	cancel := func() {}
+	if OperationTimeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, OperationTimeout)
+	}
+	return ctx, func(err *error) {
+		if *err != nil && ctx.Err() != nil && !errors.Is(*err, ctx.Err()) {
+			*err = fmt.Errorf("%w: %v", ctx.Err(), *err)
+		}
+		if *err != nil && !errors.Is(*err, sql.ErrNoRows) {
+			span.RecordError(*err)
+			span.SetStatus(codes.Error, (*err).Error())
+		}
+		cancel()
+		span.End()
+	}
+}
+
+// startStatement starts the span of one SQL statement, named after its verb.
+func (d *Dialect) startStatement(ctx context.Context, query string) (context.Context, trace.Span) {
+	name := "SQL"
+	if fields := strings.Fields(query); len(fields) > 0 {
+		name = strings.ToUpper(fields[0])
+	}
+	return tracing.Start(ctx, name, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(
+		semconv.DBSystemKey.String(d.system),
+		semconv.DBStatementKey.String(query),
+	))
+}
+
+func endStatement(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+type preparer interface {
+	PrepareContext(context.Context, string) (*dialectStmt, error)
+}
+
+// insert runs an INSERT written without RETURNING and returns the id of the
+// new row.
+func (d *Dialect) insert(ctx context.Context, p preparer, query string, args ...interface{}) (int64, error) {
+	if d.returning {
+		query += " RETURNING id"
+	}
+	stmt, err := p.PrepareContext(ctx, query)
+	if err != nil {
+		return 0, err
+	}
+	defer stmt.Close()
+
+	var id int64
+	if d.returning {
+		err = stmt.QueryRowContext(ctx, args...).Scan(&id)
+		return id, translateError(err)
+	}
+	result, err := stmt.ExecContext(ctx, args...)
+	if err != nil {
+		return 0, err
+	}
+	return result.LastInsertId()
+}
+
+// dialectDB and dialectTx rebind the queries they prepare or execute, and
+// trace them along with dialectStmt, which also reports unique violations as
+// the Errors of their columns.
+type dialectDB struct {
+	*sql.DB
+	dialect *Dialect
+}
+
+func newDialectDB(db *sql.DB, dialect *Dialect) *dialectDB {
+	return &dialectDB{DB: db, dialect: dialect}
+}
+
+func (db *dialectDB) PrepareContext(ctx context.Context, query string) (*dialectStmt, error) {
+	query = db.dialect.Rebind(query)
+	stmt, err := db.DB.PrepareContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	return &dialectStmt{Stmt: stmt, dialect: db.dialect, query: query}, nil
+}
+
+func (db *dialectDB) BeginTx(ctx context.Context) (*dialectTx, error) {
+	tx, err := db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &dialectTx{Tx: tx, dialect: db.dialect}, nil
+}
+
+type dialectTx struct {
+	*sql.Tx
+	dialect *Dialect
+}
+
+func (tx *dialectTx) PrepareContext(ctx context.Context, query string) (*dialectStmt, error) {
+	query = tx.dialect.Rebind(query)
+	stmt, err := tx.Tx.PrepareContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	return &dialectStmt{Stmt: stmt, dialect: tx.dialect, query: query}, nil
+}
+
+func (tx *dialectTx) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	query = tx.dialect.Rebind(query)
+	ctx, span := tx.dialect.startStatement(ctx, query)
+	result, err := tx.Tx.ExecContext(ctx, query, args...)
+	endStatement(span, err)
+	return result, translateError(err)
+}
+
+type dialectStmt struct {
+	*sql.Stmt
+	dialect *Dialect
+	query   string
+}
+
+func (s *dialectStmt) ExecContext(ctx context.Context, args ...interface{}) (sql.Result, error) {
+	ctx, span := s.dialect.startStatement(ctx, s.query)
+	result, err := s.Stmt.ExecContext(ctx, args...)
+	endStatement(span, err)
+	return result, translateError(err)
+}
+
+func (s *dialectStmt) QueryContext(ctx context.Context, args ...interface{}) (*sql.Rows, error) {
+	ctx, span := s.dialect.startStatement(ctx, s.query)
+	rows, err := s.Stmt.QueryContext(ctx, args...)
+	endStatement(span, err)
+	return rows, err
+}
+
+// QueryRowContext ends its span once the query ran, leaving errors to Scan.
+func (s *dialectStmt) QueryRowContext(ctx context.Context, args ...interface{}) *sql.Row {
+	ctx, span := s.dialect.startStatement(ctx, s.query)
+	defer span.End()
+	return s.Stmt.QueryRowContext(ctx, args...)
+}
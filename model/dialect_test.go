@@ -0,0 +1,244 @@
+package model
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	_ "github.com/mattn/go-sqlite3"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.7.0"
+	"go.opentelemetry.io/otel/trace"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDialect_Rebind(t *testing.T) {
+	query := "SELECT id FROM documents WHERE title LIKE ? ESCAPE '?' AND id > ? LIMIT ?"
+	if got := MySQL.Rebind(query); got != query {
+		t.Errorf("MySQL.Rebind() = %q", got)
+	}
+	want := "SELECT id FROM documents WHERE title LIKE $1 ESCAPE '?' AND id > $2 LIMIT $3"
+	if got := Postgres.Rebind(query); got != want {
+		t.Errorf("Postgres.Rebind() = %q, want %q", got, want)
+	}
+}
+
+func TestDialect_DataSourceName(t *testing.T) {
+	tests := []struct {
+		dialect  *Dialect
+		database string
+		want     string
+	}{
+		{MySQL, "precisely", "user:p@ss@tcp(db:3306)/precisely?parseTime=true"},
+		{Postgres, "precisely", "postgres://user:p%40ss@db:3306/precisely?sslmode=disable"},
+		{Postgres, "precisely?sslmode=verify-full", "postgres://user:p%40ss@db:3306/precisely?sslmode=verify-full"},
+		{SQLite, "precisely.db", "file:precisely.db?_foreign_keys=on&_busy_timeout=5000&_journal_mode=WAL&_txlock=immediate"},
+	}
+	for _, tt := range tests {
+		if got := tt.dialect.DataSourceName("user", "p@ss", "3306", "db", tt.database); got != tt.want {
+			t.Errorf("%s DataSourceName() = %q, want %q", tt.dialect.Driver, got, tt.want)
+		}
+	}
+	if _, err := DialectFor("oracle"); err == nil {
+		t.Error("DialectFor(oracle) error = nil")
+	}
+}
+
+// OpenTestDB connects to a test database of the given dialect and recreates
+// its tables from the migrations of the dialect. Everything in the database
+// is dropped first.
+func OpenTestDB(t *testing.T, dialect *Dialect, dsn string) *sql.DB {
+	t.Helper()
+	db, err := sql.Open(dialect.Driver, dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	for _, table := range []string{"audit_head", "sequences", "idempotency_keys", "outbox", "webhook_deliveries", "webhooks", "audit_log", "document_grants", "api_keys", "document_signatures", "signee_keys", "document_signees", "document_revisions", "documents"} {
+		if _, err := db.Exec("DROP TABLE IF EXISTS " + table); err != nil {
+			t.Fatalf("dropping %s: %v", table, err)
+		}
+	}
+
+	dir := map[*Dialect]string{MySQL: "mysql", Postgres: "postgres", SQLite: "sqlite"}[dialect]
+	migrations, err := filepath.Glob(filepath.Join("..", "db", "migration", dir, "*.up.sql"))
+	if err != nil || len(migrations) == 0 {
+		t.Fatalf("no %s migrations: %v", dir, err)
+	}
+	sort.Strings(migrations)
+	for _, migration := range migrations {
+		content, err := ioutil.ReadFile(migration)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, statement := range strings.Split(string(content), ";") {
+			if strings.TrimSpace(statement) == "" {
+				continue
+			}
+			if _, err := db.Exec(statement); err != nil {
+				t.Fatalf("%s: %v", migration, err)
+			}
+		}
+	}
+	return db
+}
+
+func openSQLite(t *testing.T) *sql.DB {
+	return OpenTestDB(t, SQLite, SQLite.DataSourceName("", "", "", "", filepath.Join(t.TempDir(), "precisely.db")))
+}
+
+func TestSQLite_Spans(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	defer otel.SetTracerProvider(trace.NewNoopTracerProvider())
+
+	db := openSQLite(t)
+	if _, err := NewDocumentRepository(db, SQLite).Create(context.Background(), Document{Title: "title", Signee: "alice"}); err != nil {
+		t.Fatal(err)
+	}
+
+	spans := recorder.Ended()
+	operation := spans[len(spans)-1]
+	if operation.Name() != "documentRepository.Create" {
+		t.Fatalf("last span = %q, want documentRepository.Create", operation.Name())
+	}
+	var inserts int
+	for _, span := range spans[:len(spans)-1] {
+		if span.Parent().SpanID() != operation.SpanContext().SpanID() {
+			t.Errorf("span %q is not a child of the operation", span.Name())
+		}
+		attributes := attribute.NewSet(span.Attributes()...)
+		if system, _ := attributes.Value(semconv.DBSystemKey); system.AsString() != "sqlite" {
+			t.Errorf("span %q has db.system %q", span.Name(), system.AsString())
+		}
+		statement, _ := attributes.Value(semconv.DBStatementKey)
+		if span.Name() == "INSERT" && strings.HasPrefix(statement.AsString(), "INSERT INTO documents") {
+			inserts++
+		}
+	}
+	if inserts != 1 {
+		t.Errorf("got %d INSERT INTO documents spans in %d spans, want 1", inserts, len(spans))
+	}
+}
+
+func TestSQLite_Signing(t *testing.T) {
+	db := openSQLite(t)
+	doc, err := NewDocumentRepository(db, SQLite).Create(context.Background(), Document{Title: "title", Signee: "alice"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	signees := NewSigneeRepository(db, SQLite)
+	workflow, err := signees.SetSignees(context.Background(), SigningWorkflow{
+		DocumentID: doc.ID,
+		Mode:       SigningOrdered,
+		Signees: []*Signee{
+			{Position: 1, Name: "alice", Status: SigneePending},
+			{Position: 2, Name: "bob", Status: SigneePending},
+		},
+	})
+	if err != nil || workflow.Signees[1].ID == 0 {
+		t.Fatalf("SetSignees() = %+v, %v", workflow, err)
+	}
+	if workflow, err = signees.GetWorkflow(context.Background(), doc.ID); err != nil {
+		t.Fatalf("GetWorkflow() error = %v", err)
+	}
+
+	signatures := NewSignatureRepository(db, SQLite)
+	key, err := signatures.CreateKey(context.Background(), SigneeKey{Signee: "alice", PublicKey: make([]byte, 32)})
+	if err != nil || key.ID == 0 {
+		t.Fatalf("CreateKey() = %+v, %v", key, err)
+	}
+	signedAt := time.Now().UTC()
+	alice := *workflow.Signees[0]
+	alice.Status, alice.SignedAt = SigneeSigned, &signedAt
+	sig := Signature{DocumentID: doc.ID, KeyID: key.ID, Signee: "alice", Signature: make([]byte, 64), Digest: doc.Digest()}
+	if _, err := signatures.CreateSignature(context.Background(), sig, &alice); err != nil {
+		t.Errorf("CreateSignature() error = %v", err)
+	}
+	if _, err := signatures.CreateSignature(context.Background(), sig, &alice); !errors.Is(err, SigneeStatusConflict) {
+		t.Errorf("CreateSignature() of a signee who signed error = %v, want %v", err, SigneeStatusConflict)
+	}
+	got, err := signatures.GetSignatures(context.Background(), doc.ID)
+	if err != nil || len(got) != 1 || got[0].KeyID != key.ID {
+		t.Errorf("GetSignatures() = %+v, %v, want the signature stored once", got, err)
+	}
+	replaced := SigningWorkflow{DocumentID: doc.ID, Mode: SigningParallel, Signees: []*Signee{{Position: 1, Name: "carol", Status: SigneePending}}}
+	if _, err := signees.SetSignees(context.Background(), replaced); !errors.Is(err, SigneesLockedValue) {
+		t.Errorf("SetSignees() once a signee signed error = %v, want %v", err, SigneesLockedValue)
+	}
+
+	bob := *workflow.Signees[1]
+	bob.Status, bob.SignedAt = SigneeSigned, &signedAt
+	if err := signees.UpdateStatus(context.Background(), bob); err != nil {
+		t.Errorf("UpdateStatus() error = %v", err)
+	}
+	workflow, err = signees.GetWorkflow(context.Background(), doc.ID)
+	if err != nil || workflow.Mode != SigningOrdered || workflow.Signees[0].SignedAt == nil || workflow.State != SigningCompleted {
+		t.Errorf("GetWorkflow() = %+v, %v", workflow, err)
+	}
+}
+
+// TestSQLite_ChangesInCommitOrder has a second transaction write while the
+// first still holds its change uncommitted: readers of the feed see neither
+// until they commit, then both in the order they committed.
+func TestSQLite_ChangesInCommitOrder(t *testing.T) {
+	db := openSQLite(t)
+	r := NewDocumentRepository(db, SQLite).(*documentRepository)
+	first, err := r.Create(context.Background(), Document{Title: "first", Signee: "alice"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	since, err := r.GetLastSequence(context.Background())
+	if err != nil || since == 0 {
+		t.Fatalf("GetLastSequence() = %d, %v", since, err)
+	}
+
+	written, commit := make(chan struct{}), make(chan struct{})
+	errs := make(chan error, 2)
+	go func() {
+		errs <- inTx(context.Background(), r.db, func(tx *dialectTx) error {
+			first.Title = "updated"
+			if err := updateDocument(context.Background(), tx, first); err != nil {
+				return err
+			}
+			close(written)
+			<-commit
+			return nil
+		})
+	}()
+	<-written
+	go func() {
+		_, err := r.Create(context.Background(), Document{Title: "second", Signee: "bob"})
+		errs <- err
+	}()
+
+	if changes, err := r.GetChanges(context.Background(), since, 10); err != nil || len(changes) != 0 {
+		t.Errorf("GetChanges() before commit = %+v, %v, want none", changes, err)
+	}
+	close(commit)
+	for i := 0; i < 2; i++ {
+		if err := <-errs; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	changes, err := r.GetChanges(context.Background(), since, 10)
+	if err != nil || len(changes) != 2 {
+		t.Fatalf("GetChanges() = %+v, %v, want both changes", changes, err)
+	}
+	if changes[0].DocumentID != first.ID || changes[1].Document.Title != "second" || changes[1].Sequence <= changes[0].Sequence {
+		t.Errorf("GetChanges() = %+v, %+v, want the update before the create", changes[0], changes[1])
+	}
+	if last, err := r.GetLastSequence(context.Background()); err != nil || last != changes[1].Sequence {
+		t.Errorf("GetLastSequence() = %d, %v, want %d", last, err, changes[1].Sequence)
+	}
+}
@@ -1,21 +1,66 @@
 package model
 
 import (
+	"database/sql/driver"
+	"encoding/base64"
 	"encoding/json"
-	"errors"
+	"fmt"
+	"strconv"
 	"strings"
+	"time"
 )
 
 var (
-	TitleInvalidValue  = errors.New("title had empty value, expect a valid one")
-	SigneeInvalidValue = errors.New("signee had empty value, expect a valid one")
+	TitleInvalidValue         = newError(KindValidation, "title_invalid", "title had empty value, expect a valid one")
+	SigneeInvalidValue        = newError(KindValidation, "signee_invalid", "signee had empty value, expect a valid one")
+	LimitInvalidValue         = newError(KindInvalid, "limit_invalid", "limit had invalid value, expect an integer between 1 and 500")
+	OffsetInvalidValue        = newError(KindInvalid, "offset_invalid", "offset had invalid value, expect a non-negative integer")
+	CursorInvalidValue        = newError(KindInvalid, "cursor_invalid", "cursor had invalid value, expect one returned by a previous listing sorted by id")
+	SortInvalidValue          = newError(KindInvalid, "sort_invalid", "sort had invalid value, expect a comma separated list of id, title, signee or deleted_at")
+	FilterInvalidValue        = newError(KindInvalid, "filter_invalid", "filter had invalid value, expect exact, prefix or contains on title or signee")
+	VersionMismatchValue      = newError(KindPreconditionFailed, "version_mismatch", "document was modified, expect its current ETag in If-Match")
+	PreconditionRequiredValue = newError(KindPreconditionRequired, "precondition_required", "If-Match header is required, expect the ETag of the document")
+	PatchTypeInvalidValue     = newError(KindUnsupportedMediaType, "patch_type_invalid", "patch had unsupported content type, expect application/merge-patch+json or application/json-patch+json")
+	PatchInvalidValue         = newError(KindValidation, "patch_invalid", "patch could not be applied to the title, content and signee of the document")
+	DuplicateTitleValue       = newError(KindConflict, "duplicate_title", "title had duplicated value, expect a unique one")
+)
+
+const (
+	DefaultListLimit = 50
+	MaxListLimit     = 500
+)
+
+type FilterOperator string
+
+const (
+	FilterExact    FilterOperator = "exact"
+	FilterPrefix   FilterOperator = "prefix"
+	FilterContains FilterOperator = "contains"
+)
+
+// sortableColumns and filterableColumns whitelist the fields a listing may be
+// ordered or filtered by, mapping them to their column names.
+var (
+	sortableColumns = map[string]string{
+		"id":         "id",
+		"title":      "title",
+		"signee":     "signee",
+		"deleted_at": "deleted_at",
+	}
+	filterableColumns = map[string]string{
+		"title":  "title",
+		"signee": "signee",
+	}
 )
 
 type Document struct {
-	ID      int64   `json:"id"`
-	Title   string  `json:"title"`
-	Content Content `json:"content"`
-	Signee  string  `json:"signee"`
+	ID        int64      `json:"id"`
+	Title     string     `json:"title"`
+	Content   Content    `json:"content"`
+	Signee    string     `json:"signee"`
+	Owner     string     `json:"owner,omitempty"`
+	Version   int64      `json:"version,omitempty"`
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
 }
 
 type Content struct {
@@ -23,9 +68,53 @@ type Content struct {
 	Data   string `json:"data"`
 }
 
+type Filter struct {
+	Field    string
+	Operator FilterOperator
+	Value    string
+}
+
+type SortField struct {
+	Field string
+	Desc  bool
+}
+
+// ListOptions describes a page of documents. Cursor, when set, is the id of the
+// last document of the previous page and takes the place of Offset. Trashed
+// lists the documents in the trash instead of the live ones. VisibleTo, when
+// set, restricts the listing to the documents the principal owns or was
+// granted a role on.
+type ListOptions struct {
+	VisibleTo *Principal
+	Trashed   bool
+	Limit     int
+	Offset    int
+	Cursor    int64
+	Filters   []Filter
+	Sort      []SortField
+}
+
+type DocumentPage struct {
+	Documents  []*Document
+	Total      int64
+	NextCursor string
+}
+
 func (c *Content) Scan(src interface{}) error {
-	val := src.([]uint8)
-	return json.Unmarshal(val, &c)
+	switch val := src.(type) {
+	case []uint8:
+		return json.Unmarshal(val, &c)
+	case string:
+		return json.Unmarshal([]byte(val), &c)
+	}
+	return fmt.Errorf("content had unexpected type %T", src)
+}
+
+// Value stores content as JSON text, which every dialect accepts for its json
+// or text column.
+func (c Content) Value() (driver.Value, error) {
+	val, err := json.Marshal(c)
+	return string(val), err
 }
 
 func (d *Document) Validate() error {
@@ -39,3 +128,88 @@ func (d *Document) Validate() error {
 	}
 	return nil
 }
+
+// ParseSort parses a sort expression such as "title,-id", where a leading "-"
+// orders the field descending.
+func ParseSort(expr string) ([]SortField, error) {
+	var fields []SortField
+	for _, part := range strings.Split(expr, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		field := SortField{Field: part}
+		if strings.HasPrefix(part, "-") {
+			field = SortField{Field: part[1:], Desc: true}
+		}
+		if _, ok := sortableColumns[field.Field]; !ok {
+			return nil, SortInvalidValue
+		}
+		fields = append(fields, field)
+	}
+	return fields, nil
+}
+
+func EncodeCursor(id int64) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatInt(id, 10)))
+}
+
+func DecodeCursor(cursor string) (int64, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, CursorInvalidValue
+	}
+	id, err := strconv.ParseInt(string(raw), 10, 64)
+	if err != nil || id <= 0 {
+		return 0, CursorInvalidValue
+	}
+	return id, nil
+}
+
+// Validate checks the options against the whitelisted fields and fills in the
+// default limit and the id tie-breaker that keeps the ordering stable.
+func (o *ListOptions) Validate() error {
+	if o.Limit == 0 {
+		o.Limit = DefaultListLimit
+	}
+	if o.Limit < 0 || o.Limit > MaxListLimit {
+		return LimitInvalidValue
+	}
+	if o.Offset < 0 {
+		return OffsetInvalidValue
+	}
+	for _, f := range o.Filters {
+		if _, ok := filterableColumns[f.Field]; !ok {
+			return FilterInvalidValue
+		}
+		switch f.Operator {
+		case FilterExact, FilterPrefix, FilterContains:
+		default:
+			return FilterInvalidValue
+		}
+	}
+	for _, s := range o.Sort {
+		if _, ok := sortableColumns[s.Field]; !ok {
+			return SortInvalidValue
+		}
+	}
+	hasID := false
+	for _, s := range o.Sort {
+		if s.Field == "id" {
+			hasID = true
+		}
+	}
+	if !hasID {
+		o.Sort = append(o.Sort, SortField{Field: "id"})
+	}
+	if o.Cursor != 0 && (o.Offset != 0 || !o.keyedByID()) {
+		return CursorInvalidValue
+	}
+	return nil
+}
+
+// keyedByID reports whether the listing is ordered by id alone, the only
+// ordering that supports keyset pagination.
+func (o *ListOptions) keyedByID() bool {
+	return len(o.Sort) == 1 && o.Sort[0].Field == "id"
+}
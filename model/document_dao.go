@@ -1,142 +1,542 @@
 package model
 
 import (
+	"context"
 	"database/sql"
-	"encoding/json"
-	"fmt"
-	"log"
+	"github.com/sirupsen/logrus"
+	"strings"
+	"time"
 )
 
 var (
 	DocumentRepository documentRepositoryInterface = &documentRepository{}
 )
 
+// DocumentRepositoryInterface names the document repository outside of this
+// package, for backends and the conformance suite in model/repositorytest.
+type DocumentRepositoryInterface = documentRepositoryInterface
+
 type documentRepositoryInterface interface {
-	Get(int64) (*Document, error)
-	Create(Document) (*Document, error)
-	Update(Document) (*Document, error)
-	Delete(int64) error
-	GetAll() ([]*Document, error)
+	Get(context.Context, int64) (*Document, error)
+	Create(context.Context, Document) (*Document, error)
+	Update(context.Context, Document) (*Document, error)
+	Delete(context.Context, int64, int64) error
+	GetAll(context.Context, ListOptions) (*DocumentPage, error)
+	GetTrashed(context.Context, int64) (*Document, error)
+	GetRevisions(context.Context, int64) ([]*Revision, error)
+	GetRevision(context.Context, int64, int64) (*Revision, error)
+	GetAsOf(context.Context, int64, time.Time) (*Document, error)
+	GetChanges(context.Context, int64, int) ([]*Change, error)
+	GetLastSequence(context.Context) (int64, error)
+	RestoreDeleted(context.Context, int64) (*Document, error)
+	Purge(context.Context, int64) error
+	PurgeDeletedBefore(context.Context, time.Time) (int64, error)
+	Batch(context.Context, []BatchOperation, BatchMode) ([]BatchResult, error)
 	Init(string, string, string, string, string, string) *sql.DB
 }
 
 type documentRepository struct {
-	db *sql.DB
+	db *dialectDB
 }
 
-func NewDocumentRepository(db *sql.DB) documentRepositoryInterface {
-	return &documentRepository{db: db}
+func NewDocumentRepository(db *sql.DB, dialect *Dialect) documentRepositoryInterface {
+	return &documentRepository{db: newDialectDB(db, dialect)}
 }
 
+// Init connects to the database of the given driver, one of mysql, postgres
+// or sqlite3.
 func (r *documentRepository) Init(driver, username, password, port, host, database string) *sql.DB {
-	var err error
-	psqlInfo := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s",
-		username, password, host, port, database)
-	r.db, err = sql.Open(driver, psqlInfo)
+	dialect, err := DialectFor(driver)
 	if err != nil {
-		log.Fatalf("failed opening connection to mysql: %v", err)
+		logrus.WithError(err).Fatal("unsupported database driver")
 	}
-	err = r.db.Ping()
+	db, err := sql.Open(dialect.Driver, dialect.DataSourceName(username, password, port, host, database))
 	if err != nil {
-		log.Fatal(err)
+		logrus.WithError(err).WithField("driver", dialect.Driver).Fatal("failed opening connection")
 	}
-	return r.db
-}
-
-func (r *documentRepository) Get(id int64) (*Document, error) {
-	stmt, err := r.db.Prepare("SELECT * FROM documents WHERE id = ?")
+	err = db.Ping()
 	if err != nil {
-		return nil, err
+		logrus.WithError(err).WithField("driver", dialect.Driver).Fatal("failed reaching the database")
 	}
-	defer stmt.Close()
+	r.db = newDialectDB(db, dialect)
+	return db
+}
 
+const documentColumns = "id, title, content, signee, owner, version, deleted_at"
+
+func scanDocument(scanner interface{ Scan(...interface{}) error }) (*Document, error) {
 	var doc Document
-	result := stmt.QueryRow(id)
-	if err := result.Scan(&doc.ID, &doc.Title, &doc.Content, &doc.Signee); err != nil {
+	if err := scanner.Scan(&doc.ID, &doc.Title, &doc.Content, &doc.Signee, &doc.Owner, &doc.Version, &doc.DeletedAt); err != nil {
 		return nil, err
 	}
 	return &doc, nil
 }
 
-func (r *documentRepository) Create(newDoc Document) (*Document, error) {
-	stmt, err := r.db.Prepare("INSERT INTO documents(title, content, signee) VALUES(?, ?, ?);")
+// Get returns a document unless it does not exist or is in the trash.
+func (r *documentRepository) Get(ctx context.Context, id int64) (_ *Document, err error) {
+	ctx, done := startOperation(ctx, "documentRepository.Get")
+	defer done(&err)
+
+	stmt, err := r.db.PrepareContext(ctx, "SELECT "+documentColumns+" FROM documents WHERE id = ? AND deleted_at IS NULL")
 	if err != nil {
 		return nil, err
 	}
-
 	defer stmt.Close()
 
-	contentJson, _ := json.Marshal(newDoc.Content)
-	insertResult, err := stmt.Exec(newDoc.Title, contentJson, newDoc.Signee)
+	return scanDocument(stmt.QueryRowContext(ctx, id))
+}
+
+func (r *documentRepository) Create(ctx context.Context, newDoc Document) (_ *Document, err error) {
+	ctx, done := startOperation(ctx, "documentRepository.Create")
+	defer done(&err)
+
+	err = inChangeTx(ctx, r.db, func(tx *dialectTx) error {
+		created, err := createDocument(ctx, tx, newDoc)
+		if err != nil {
+			return err
+		}
+		newDoc = *created
+		return nil
+	})
 	if err != nil {
 		return nil, err
 	}
-	id, err := insertResult.LastInsertId()
+	return &newDoc, nil
+}
+
+// Update overwrites a document as long as it is still at upDoc.Version, and
+// fails with VersionMismatchValue otherwise.
+func (r *documentRepository) Update(ctx context.Context, upDoc Document) (_ *Document, err error) {
+	ctx, done := startOperation(ctx, "documentRepository.Update")
+	defer done(&err)
+
+	err = inChangeTx(ctx, r.db, func(tx *dialectTx) error {
+		return updateDocument(ctx, tx, &upDoc)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &upDoc, nil
+}
+
+// inTx runs fn in a transaction, committing when it succeeds and rolling back
+// otherwise.
+func inTx(ctx context.Context, db *dialectDB, fn func(*dialectTx) error) error {
+	tx, err := db.BeginTx(ctx)
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+func createDocument(ctx context.Context, tx *dialectTx, newDoc Document) (*Document, error) {
+	id, err := tx.dialect.insert(ctx, tx, "INSERT INTO documents(title, content, signee, owner) VALUES(?, ?, ?, ?)",
+		newDoc.Title, newDoc.Content, newDoc.Signee, newDoc.Owner)
 	if err != nil {
 		return nil, err
 	}
 	newDoc.ID = id
+	newDoc.Version = 1
 
+	if err := writeRevision(ctx, tx, RevisionCreate, newDoc); err != nil {
+		return nil, err
+	}
+	if err := writeEvent(ctx, tx, EventDocumentCreated, newDoc); err != nil {
+		return nil, err
+	}
+	if err := writeAudit(ctx, tx, AuditCreate, newDoc.ID, nil, &newDoc); err != nil {
+		return nil, err
+	}
 	return &newDoc, nil
 }
 
-func (r *documentRepository) Update(upDoc Document) (*Document, error) {
+func updateDocument(ctx context.Context, tx *dialectTx, upDoc *Document) error {
+	var before *Document
+	if audited(ctx) {
+		var err error
+		if before, err = getDocument(ctx, tx, upDoc.ID); err == sql.ErrNoRows {
+			return VersionMismatchValue
+		} else if err != nil {
+			return err
+		}
+	}
 
-	stmt, err := r.db.Prepare("UPDATE documents SET title = ?, content = ?, signee = ? WHERE id = ?")
+	stmt, err := tx.PrepareContext(ctx, "UPDATE documents SET title = ?, content = ?, signee = ?, version = version + 1 WHERE id = ? AND version = ? AND deleted_at IS NULL")
 	if err != nil {
-		return nil, err
+		return err
 	}
 
 	defer stmt.Close()
-	contentJson, _ := json.Marshal(upDoc.Content)
-	result, err := stmt.Exec(
+	result, err := stmt.ExecContext(ctx,
 		upDoc.Title,
-		contentJson,
+		upDoc.Content,
 		upDoc.Signee,
-		upDoc.ID)
+		upDoc.ID,
+		upDoc.Version)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return VersionMismatchValue
+	}
+	upDoc.Version++
+	if err := writeRevision(ctx, tx, RevisionUpdate, *upDoc); err != nil {
+		return err
+	}
+	if err := writeEvent(ctx, tx, EventDocumentUpdated, *upDoc); err != nil {
+		return err
+	}
+	return writeAudit(ctx, tx, AuditUpdate, upDoc.ID, before, upDoc)
+}
+
+// getDocument returns a document outside the trash as seen by tx.
+func getDocument(ctx context.Context, tx *dialectTx, id int64) (*Document, error) {
+	stmt, err := tx.PrepareContext(ctx, "SELECT "+documentColumns+" FROM documents WHERE id = ? AND deleted_at IS NULL")
 	if err != nil {
 		return nil, err
 	}
-	if _, err := result.RowsAffected(); err != nil {
+	defer stmt.Close()
+
+	return scanDocument(stmt.QueryRowContext(ctx, id))
+}
+
+// getTrashedDocument returns a document in the trash as seen by tx.
+func getTrashedDocument(ctx context.Context, tx *dialectTx, id int64) (*Document, error) {
+	stmt, err := tx.PrepareContext(ctx, "SELECT "+documentColumns+" FROM documents WHERE id = ? AND deleted_at IS NOT NULL")
+	if err != nil {
 		return nil, err
 	}
-	return &upDoc, nil
+	defer stmt.Close()
+
+	return scanDocument(stmt.QueryRowContext(ctx, id))
 }
 
-func (r *documentRepository) GetAll() ([]*Document, error) {
-	stmt, err := r.db.Prepare("SELECT * FROM documents")
+// deleteDocument moves a document at the given version to the trash.
+func deleteDocument(ctx context.Context, tx *dialectTx, id, version int64) error {
+	doc, err := getDocument(ctx, tx, id)
+	if err != nil {
+		return err
+	}
+	if doc.Version != version {
+		return VersionMismatchValue
+	}
+
+	stmt, err := tx.PrepareContext(ctx, "UPDATE documents SET deleted_at = ?, version = version + 1 WHERE id = ? AND version = ?")
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	result, err := stmt.ExecContext(ctx, now().UTC(), id, version)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return VersionMismatchValue
+	}
+	if err := writeRevision(ctx, tx, RevisionDelete, *doc); err != nil {
+		return err
+	}
+	if err := writeEvent(ctx, tx, EventDocumentDeleted, *doc); err != nil {
+		return err
+	}
+	return writeAudit(ctx, tx, AuditDelete, id, doc, nil)
+}
+
+func (r *documentRepository) GetAll(ctx context.Context, opts ListOptions) (_ *DocumentPage, err error) {
+	ctx, done := startOperation(ctx, "documentRepository.GetAll")
+	defer done(&err)
+
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+	where, args := buildListFilter(r.db.dialect, opts)
+	if opts.VisibleTo != nil {
+		condition, visibleArgs := buildVisibility(opts.VisibleTo)
+		where = appendCondition(where, condition)
+		args = append(args, visibleArgs...)
+	}
+	if opts.Trashed {
+		where = appendCondition(where, "deleted_at IS NOT NULL")
+	} else {
+		where = appendCondition(where, "deleted_at IS NULL")
+	}
+
+	countStmt, err := r.db.PrepareContext(ctx, "SELECT COUNT(*) FROM documents"+where)
+	if err != nil {
+		return nil, err
+	}
+	defer countStmt.Close()
+
+	var page DocumentPage
+	if err := countStmt.QueryRowContext(ctx, args...).Scan(&page.Total); err != nil {
+		return nil, err
+	}
+
+	if opts.Cursor != 0 {
+		op := ">"
+		if opts.Sort[0].Desc {
+			op = "<"
+		}
+		where = appendCondition(where, "id "+op+" ?")
+		args = append(args, opts.Cursor)
+	}
+	query := "SELECT " + documentColumns + " FROM documents" + where + buildListOrder(opts) + " LIMIT ? OFFSET ?"
+	args = append(args, opts.Limit, opts.Offset)
+
+	stmt, err := r.db.PrepareContext(ctx, query)
 	if err != nil {
 		return nil, err
 	}
 	defer stmt.Close()
 
-	rows, err := stmt.Query()
+	rows, err := stmt.QueryContext(ctx, args...)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	results := make([]*Document, 0)
+	page.Documents = make([]*Document, 0)
 
 	for rows.Next() {
-		var doc Document
-		if getError := rows.Scan(&doc.ID, &doc.Title, &doc.Content, &doc.Signee); getError != nil {
+		doc, getError := scanDocument(rows)
+		if getError != nil {
 			return nil, getError
 		}
-		results = append(results, &doc)
+		page.Documents = append(page.Documents, doc)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if opts.keyedByID() && len(page.Documents) == opts.Limit {
+		page.NextCursor = EncodeCursor(page.Documents[len(page.Documents)-1].ID)
 	}
-	return results, nil
+	return &page, nil
 }
 
-func (r *documentRepository) Delete(id int64) error {
-	stmt, err := r.db.Prepare("DELETE FROM documents WHERE id = ?")
+func buildListFilter(dialect *Dialect, opts ListOptions) (string, []interface{}) {
+	where := ""
+	args := make([]interface{}, 0, len(opts.Filters))
+	for _, f := range opts.Filters {
+		column := filterableColumns[f.Field]
+		switch f.Operator {
+		case FilterExact:
+			where = appendCondition(where, column+" = ?")
+			args = append(args, f.Value)
+		case FilterPrefix:
+			where = appendCondition(where, column+" "+dialect.like+" ? ESCAPE '!'")
+			args = append(args, escapeLike(f.Value)+"%")
+		case FilterContains:
+			where = appendCondition(where, column+" "+dialect.like+" ? ESCAPE '!'")
+			args = append(args, "%"+escapeLike(f.Value)+"%")
+		}
+	}
+	return where, args
+}
+
+// buildVisibility matches the documents p owns or holds a grant on, directly
+// or through one of its groups.
+func buildVisibility(p *Principal) (string, []interface{}) {
+	grantees := "grantee_type = ? AND grantee = ?"
+	args := []interface{}{p.Subject, GranteeUser, p.Subject}
+	if len(p.Groups) > 0 {
+		grantees += " OR grantee_type = ? AND grantee IN (?" + strings.Repeat(", ?", len(p.Groups)-1) + ")"
+		args = append(args, GranteeGroup)
+		for _, group := range p.Groups {
+			args = append(args, group)
+		}
+	}
+	return "(owner = ? OR id IN (SELECT document_id FROM document_grants WHERE " + grantees + "))", args
+}
+
+func buildListOrder(opts ListOptions) string {
+	terms := make([]string, 0, len(opts.Sort))
+	for _, s := range opts.Sort {
+		term := sortableColumns[s.Field]
+		if s.Desc {
+			term += " DESC"
+		}
+		terms = append(terms, term)
+	}
+	return " ORDER BY " + strings.Join(terms, ", ")
+}
+
+func appendCondition(where, condition string) string {
+	if where == "" {
+		return " WHERE " + condition
+	}
+	return where + " AND " + condition
+}
+
+// likeEscaper escapes LIKE wildcards with "!", which unlike a backslash needs
+// no quoting in any dialect.
+var likeEscaper = strings.NewReplacer("!", "!!", "%", "!%", "_", "!_")
+
+func escapeLike(value string) string {
+	return likeEscaper.Replace(value)
+}
+
+func (r *documentRepository) Delete(ctx context.Context, id, version int64) (err error) {
+	ctx, done := startOperation(ctx, "documentRepository.Delete")
+	defer done(&err)
+
+	return inChangeTx(ctx, r.db, func(tx *dialectTx) error {
+		return deleteDocument(ctx, tx, id, version)
+	})
+}
+
+// GetTrashed returns a document in the trash.
+func (r *documentRepository) GetTrashed(ctx context.Context, id int64) (_ *Document, err error) {
+	ctx, done := startOperation(ctx, "documentRepository.GetTrashed")
+	defer done(&err)
+
+	stmt, err := r.db.PrepareContext(ctx, "SELECT "+documentColumns+" FROM documents WHERE id = ? AND deleted_at IS NOT NULL")
 	if err != nil {
-		return err
+		return nil, err
 	}
 	defer stmt.Close()
 
-	if _, err := stmt.Exec(id); err != nil {
-		return err
+	return scanDocument(stmt.QueryRowContext(ctx, id))
+}
+
+// RestoreDeleted takes a document out of the trash.
+func (r *documentRepository) RestoreDeleted(ctx context.Context, id int64) (_ *Document, err error) {
+	ctx, done := startOperation(ctx, "documentRepository.RestoreDeleted")
+	defer done(&err)
+
+	var doc *Document
+	err = inChangeTx(ctx, r.db, func(tx *dialectTx) error {
+		var err error
+		if doc, err = getTrashedDocument(ctx, tx, id); err != nil {
+			return err
+		}
+		trashed := *doc
+
+		stmt, err := tx.PrepareContext(ctx, "UPDATE documents SET deleted_at = NULL, version = version + 1 WHERE id = ?")
+		if err != nil {
+			return err
+		}
+		defer stmt.Close()
+
+		if _, err := stmt.ExecContext(ctx, id); err != nil {
+			return err
+		}
+		doc.DeletedAt = nil
+		doc.Version++
+		if err := writeRevision(ctx, tx, RevisionRestore, *doc); err != nil {
+			return err
+		}
+		if err := writeEvent(ctx, tx, EventDocumentUpdated, *doc); err != nil {
+			return err
+		}
+		return writeAudit(ctx, tx, AuditRestore, id, &trashed, doc)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// Purge permanently deletes a document in the trash along with its signees
+// and grants. Its revisions are kept.
+func (r *documentRepository) Purge(ctx context.Context, id int64) (err error) {
+	ctx, done := startOperation(ctx, "documentRepository.Purge")
+	defer done(&err)
+
+	return inTx(ctx, r.db, func(tx *dialectTx) error {
+		var trashed *Document
+		if audited(ctx) {
+			var err error
+			if trashed, err = getTrashedDocument(ctx, tx, id); err != nil {
+				return err
+			}
+		}
+
+		stmt, err := tx.PrepareContext(ctx, "DELETE FROM documents WHERE id = ? AND deleted_at IS NOT NULL")
+		if err != nil {
+			return err
+		}
+		defer stmt.Close()
+
+		result, err := stmt.ExecContext(ctx, id)
+		if err != nil {
+			return err
+		}
+		affected, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if affected == 0 {
+			return sql.ErrNoRows
+		}
+		if err := purgeDependents(ctx, tx, "document_id = ?", id); err != nil {
+			return err
+		}
+		return writeAudit(ctx, tx, AuditPurge, id, trashed, nil)
+	})
+}
+
+// PurgeDeletedBefore permanently deletes the documents trashed before the
+// given time and returns how many were purged. A purge that deleted documents
+// is audited as a single entry without a document.
+func (r *documentRepository) PurgeDeletedBefore(ctx context.Context, before time.Time) (_ int64, err error) {
+	ctx, done := startOperation(ctx, "documentRepository.PurgeDeletedBefore")
+	defer done(&err)
+
+	var purged int64
+	err = inTx(ctx, r.db, func(tx *dialectTx) error {
+		if err := purgeDependents(ctx, tx, "document_id IN (SELECT id FROM documents WHERE deleted_at < ?)", before.UTC()); err != nil {
+			return err
+		}
+
+		stmt, err := tx.PrepareContext(ctx, "DELETE FROM documents WHERE deleted_at < ?")
+		if err != nil {
+			return err
+		}
+		defer stmt.Close()
+
+		result, err := stmt.ExecContext(ctx, before.UTC())
+		if err != nil {
+			return err
+		}
+		if purged, err = result.RowsAffected(); err != nil || purged == 0 {
+			return err
+		}
+		return writeAudit(ctx, tx, AuditPurge, 0, nil, nil)
+	})
+	return purged, err
+}
+
+// purgeDependents deletes the signees and grants of the documents matching
+// condition.
+func purgeDependents(ctx context.Context, tx *dialectTx, condition string, args ...interface{}) error {
+	for _, table := range []string{"document_signees", "document_grants"} {
+		if err := purgeFrom(ctx, tx, table, condition, args...); err != nil {
+			return err
+		}
 	}
 	return nil
 }
+
+func purgeFrom(ctx context.Context, tx *dialectTx, table, condition string, args ...interface{}) error {
+	stmt, err := tx.PrepareContext(ctx, "DELETE FROM "+table+" WHERE "+condition)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	_, err = stmt.ExecContext(ctx, args...)
+	return err
+}
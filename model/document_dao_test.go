@@ -1,13 +1,16 @@
 package model
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
 	"errors"
 	"github.com/DATA-DOG/go-sqlmock"
 	"log"
 	"reflect"
+	"regexp"
 	"testing"
+	"time"
 )
 
 func NewMock() (*sql.DB, sqlmock.Sqlmock) {
@@ -22,7 +25,7 @@ func TestDocumentRepository_Get(t *testing.T) {
 	db, mock := NewMock()
 	defer db.Close()
 
-	r := NewDocumentRepository(db)
+	r := NewDocumentRepository(db, MySQL)
 
 	tests := []struct {
 		name    string
@@ -38,8 +41,8 @@ func TestDocumentRepository_Get(t *testing.T) {
 			id:   1,
 			mock: func() {
 				contentBytes, _ := json.Marshal(Content{Header: "header", Data: "data"})
-				rows := sqlmock.NewRows([]string{"id", "title", "content", "signee"}).
-					AddRow(1, "Document 1", contentBytes, "Signee 1")
+				rows := sqlmock.NewRows([]string{"id", "title", "content", "signee", "owner", "version", "deleted_at"}).
+					AddRow(1, "Document 1", contentBytes, "Signee 1", "alice", 1, nil)
 				mock.ExpectPrepare("SELECT (.+) FROM documents").ExpectQuery().WithArgs(1).
 					WillReturnRows(rows)
 			},
@@ -48,6 +51,8 @@ func TestDocumentRepository_Get(t *testing.T) {
 				Title:   "Document 1",
 				Content: Content{Header: "header", Data: "data"},
 				Signee:  "Signee 1",
+				Owner:   "alice",
+				Version: 1,
 			},
 		},
 		{
@@ -55,7 +60,7 @@ func TestDocumentRepository_Get(t *testing.T) {
 			r:    r,
 			id:   1,
 			mock: func() {
-				rows := sqlmock.NewRows([]string{"id", "title", "content", "signee"})
+				rows := sqlmock.NewRows([]string{"id", "title", "content", "signee", "owner", "version", "deleted_at"})
 				mock.ExpectPrepare("SELECT (.+) FROM documents").ExpectQuery().WithArgs(1).
 					WillReturnRows(rows)
 			},
@@ -66,7 +71,7 @@ func TestDocumentRepository_Get(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			tt.mock()
-			got, err := tt.r.Get(tt.id)
+			got, err := tt.r.Get(context.Background(), tt.id)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("Get() error new = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -78,10 +83,27 @@ func TestDocumentRepository_Get(t *testing.T) {
 	}
 }
 
+func TestDocumentRepository_Get_OperationTimeout(t *testing.T) {
+	db, mock := NewMock()
+	defer db.Close()
+
+	OperationTimeout = 10 * time.Millisecond
+	defer func() { OperationTimeout = 0 }()
+
+	rows := sqlmock.NewRows([]string{"id", "title", "content", "signee", "owner", "version", "deleted_at"})
+	mock.ExpectPrepare("SELECT (.+) FROM documents").ExpectQuery().WithArgs(1).
+		WillDelayFor(time.Second).WillReturnRows(rows)
+
+	_, err := NewDocumentRepository(db, MySQL).Get(context.Background(), 1)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Get() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+}
+
 func TestDocumentRepository_Create(t *testing.T) {
 	db, mock := NewMock()
 	defer db.Close()
-	r := NewDocumentRepository(db)
+	r := NewDocumentRepository(db, MySQL)
 	tests := []struct {
 		name    string
 		r       documentRepositoryInterface
@@ -100,12 +122,17 @@ func TestDocumentRepository_Create(t *testing.T) {
 					Data:   "data",
 				},
 				Signee: "signee",
+				Owner:  "alice",
 			},
 			mock: func() {
 				contentBytes, _ := json.Marshal(Content{Header: "header", Data: "data"})
+				expectChangeTx(mock)
 				mock.ExpectPrepare("INSERT INTO documents").ExpectExec().
-					WithArgs("title", contentBytes, "signee").
+					WithArgs("title", string(contentBytes), "signee", "alice").
 					WillReturnResult(sqlmock.NewResult(1, 1))
+				expectRevision(mock, 1, 1, RevisionCreate, "title", contentBytes, "signee")
+				expectEvent(mock, 1, EventDocumentCreated)
+				mock.ExpectCommit()
 			},
 
 			want: &Document{
@@ -115,7 +142,9 @@ func TestDocumentRepository_Create(t *testing.T) {
 					Header: "header",
 					Data:   "data",
 				},
-				Signee: "signee",
+				Owner:   "alice",
+				Signee:  "signee",
+				Version: 1,
 			},
 		},
 		{
@@ -127,7 +156,9 @@ func TestDocumentRepository_Create(t *testing.T) {
 			},
 			mock: func() {
 				contentBytes, _ := json.Marshal(Content{Header: "header", Data: "data"})
-				mock.ExpectPrepare("INSERT INTO messages").ExpectExec().WithArgs("title", contentBytes, "signee").WillReturnError(errors.New("empty content"))
+				expectChangeTx(mock)
+				mock.ExpectPrepare("INSERT INTO documents").ExpectExec().WithArgs("title", string(contentBytes), "signee", "").WillReturnError(errors.New("empty content"))
+				mock.ExpectRollback()
 			},
 			wantErr: true,
 		},
@@ -135,7 +166,7 @@ func TestDocumentRepository_Create(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			tt.mock()
-			got, err := tt.r.Create(tt.doc)
+			got, err := tt.r.Create(context.Background(), tt.doc)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("Create() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -151,7 +182,7 @@ func TestDocumentRepository_Update(t *testing.T) {
 	db, mock := NewMock()
 	defer db.Close()
 
-	r := NewDocumentRepository(db)
+	r := NewDocumentRepository(db, MySQL)
 
 	tests := []struct {
 		name    string
@@ -171,13 +202,18 @@ func TestDocumentRepository_Update(t *testing.T) {
 					Header: "header",
 					Data:   "data",
 				},
-				Signee: "signee",
+				Signee:  "signee",
+				Version: 1,
 			},
 			mock: func() {
 				contentBytes, _ := json.Marshal(Content{Header: "header", Data: "data"})
+				expectChangeTx(mock)
 				mock.ExpectPrepare("UPDATE documents").ExpectExec().
-					WithArgs("title", contentBytes, "signee", 1).
+					WithArgs("title", string(contentBytes), "signee", 1, 1).
 					WillReturnResult(sqlmock.NewResult(0, 1))
+				expectRevision(mock, 1, 2, RevisionUpdate, "title", contentBytes, "signee")
+				expectEvent(mock, 1, EventDocumentUpdated)
+				mock.ExpectCommit()
 			},
 			want: &Document{
 				ID:    1,
@@ -186,7 +222,8 @@ func TestDocumentRepository_Update(t *testing.T) {
 					Header: "header",
 					Data:   "data",
 				},
-				Signee: "signee",
+				Signee:  "signee",
+				Version: 2,
 			},
 		},
 		{
@@ -199,13 +236,35 @@ func TestDocumentRepository_Update(t *testing.T) {
 					Header: "header",
 					Data:   "data",
 				},
-				Signee: "signee",
+				Signee:  "signee",
+				Version: 1,
 			},
 			mock: func() {
 				contentBytes, _ := json.Marshal(Content{Header: "header", Data: "data"})
+				expectChangeTx(mock)
 				mock.ExpectPrepare("UPDATE documents").ExpectExec().
-					WithArgs("update title", contentBytes, "update signee", 0).
+					WithArgs("title", string(contentBytes), "signee", 1, 1).
 					WillReturnError(errors.New("invalid update id"))
+				mock.ExpectRollback()
+			},
+			wantErr: true,
+		},
+		{
+			name: "Version mismatch",
+			r:    r,
+			doc: Document{
+				ID:      1,
+				Title:   "title",
+				Signee:  "signee",
+				Version: 1,
+			},
+			mock: func() {
+				contentBytes, _ := json.Marshal(Content{})
+				expectChangeTx(mock)
+				mock.ExpectPrepare("UPDATE documents").ExpectExec().
+					WithArgs("title", string(contentBytes), "signee", 1, 1).
+					WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectRollback()
 			},
 			wantErr: true,
 		},
@@ -213,7 +272,7 @@ func TestDocumentRepository_Update(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			tt.mock()
-			got, err := tt.r.Update(tt.doc)
+			got, err := tt.r.Update(context.Background(), tt.doc)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("Update() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -229,54 +288,149 @@ func TestDocumentRepository_GetAll(t *testing.T) {
 	db, mock := NewMock()
 	defer db.Close()
 
-	r := NewDocumentRepository(db)
+	r := NewDocumentRepository(db, MySQL)
+
+	contentBytes, _ := json.Marshal(Content{
+		Header: "header",
+		Data:   "data",
+	})
+	columns := []string{"id", "title", "content", "signee", "owner", "version", "deleted_at"}
 
 	tests := []struct {
 		name    string
 		r       documentRepositoryInterface
+		opts    ListOptions
 		mock    func()
-		want    []*Document
+		want    *DocumentPage
 		wantErr bool
 	}{
 		{
 			name: "Ok",
 			r:    r,
 			mock: func() {
-				contentBytes, _ := json.Marshal(Content{
-					Header: "header",
-					Data:   "data",
-				})
-				rows := sqlmock.NewRows([]string{"id", "title", "content", "signee"}).
-					AddRow(1, "first title", contentBytes, "first signee").
-					AddRow(2, "second title", contentBytes, "second signee")
-				mock.ExpectPrepare("SELECT (.+) FROM documents").ExpectQuery().WillReturnRows(rows)
+				mock.ExpectPrepare(regexp.QuoteMeta("SELECT COUNT(*) FROM documents WHERE deleted_at IS NULL")).ExpectQuery().
+					WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
+				rows := sqlmock.NewRows(columns).
+					AddRow(1, "first title", contentBytes, "first signee", "alice", 1, nil).
+					AddRow(2, "second title", contentBytes, "second signee", "alice", 1, nil)
+				mock.ExpectPrepare(regexp.QuoteMeta("SELECT id, title, content, signee, owner, version, deleted_at FROM documents WHERE deleted_at IS NULL ORDER BY id LIMIT ? OFFSET ?")).
+					ExpectQuery().WithArgs(DefaultListLimit, 0).WillReturnRows(rows)
+			},
+			want: &DocumentPage{
+				Documents: []*Document{
+					{
+						ID:    1,
+						Title: "first title",
+						Content: Content{
+							Header: "header",
+							Data:   "data",
+						},
+						Signee:  "first signee",
+						Owner:   "alice",
+						Version: 1,
+					},
+					{
+						ID:    2,
+						Title: "second title",
+						Content: Content{
+							Header: "header",
+							Data:   "data",
+						},
+						Signee:  "second signee",
+						Owner:   "alice",
+						Version: 1,
+					},
+				},
+				Total: 2,
+			},
+		},
+		{
+			name: "Filtered and sorted",
+			r:    r,
+			opts: ListOptions{
+				Limit:  1,
+				Offset: 1,
+				Filters: []Filter{
+					{Field: "title", Operator: FilterContains, Value: "50%"},
+					{Field: "signee", Operator: FilterPrefix, Value: "bob"},
+				},
+				Sort: []SortField{{Field: "title", Desc: true}},
+			},
+			mock: func() {
+				mock.ExpectPrepare(regexp.QuoteMeta("SELECT COUNT(*) FROM documents WHERE title LIKE ? ESCAPE '!' AND signee LIKE ? ESCAPE '!' AND deleted_at IS NULL")).ExpectQuery().
+					WithArgs(`%50!%%`, "bob%").
+					WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(3))
+				rows := sqlmock.NewRows(columns).
+					AddRow(7, "50% off", contentBytes, "bobby", "alice", 1, nil)
+				mock.ExpectPrepare(regexp.QuoteMeta("SELECT id, title, content, signee, owner, version, deleted_at FROM documents WHERE title LIKE ? ESCAPE '!' AND signee LIKE ? ESCAPE '!' AND deleted_at IS NULL ORDER BY title DESC, id LIMIT ? OFFSET ?")).
+					ExpectQuery().WithArgs(`%50!%%`, "bob%", 1, 1).WillReturnRows(rows)
 			},
-			want: []*Document{
-				{
-					ID:    1,
-					Title: "first title",
-					Content: Content{
-						Header: "header",
-						Data:   "data",
+			want: &DocumentPage{
+				Documents: []*Document{
+					{
+						ID:    7,
+						Title: "50% off",
+						Content: Content{
+							Header: "header",
+							Data:   "data",
+						},
+						Signee:  "bobby",
+						Owner:   "alice",
+						Version: 1,
 					},
-					Signee: "first signee",
 				},
-				{
-					ID:    2,
-					Title: "second title",
-					Content: Content{
-						Header: "header",
-						Data:   "data",
+				Total: 3,
+			},
+		},
+		{
+			name: "Cursor",
+			r:    r,
+			opts: ListOptions{
+				Limit:  1,
+				Cursor: 5,
+				Sort:   []SortField{{Field: "id", Desc: true}},
+			},
+			mock: func() {
+				mock.ExpectPrepare(regexp.QuoteMeta("SELECT COUNT(*) FROM documents WHERE deleted_at IS NULL")).ExpectQuery().
+					WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(9))
+				rows := sqlmock.NewRows(columns).
+					AddRow(4, "fourth title", contentBytes, "fourth signee", "alice", 1, nil)
+				mock.ExpectPrepare(regexp.QuoteMeta("SELECT id, title, content, signee, owner, version, deleted_at FROM documents WHERE deleted_at IS NULL AND id < ? ORDER BY id DESC LIMIT ? OFFSET ?")).
+					ExpectQuery().WithArgs(5, 1, 0).WillReturnRows(rows)
+			},
+			want: &DocumentPage{
+				Documents: []*Document{
+					{
+						ID:    4,
+						Title: "fourth title",
+						Content: Content{
+							Header: "header",
+							Data:   "data",
+						},
+						Signee:  "fourth signee",
+						Owner:   "alice",
+						Version: 1,
 					},
-					Signee: "second signee",
 				},
+				Total:      9,
+				NextCursor: EncodeCursor(4),
 			},
 		},
+		{
+			name: "Cursor with non id sort",
+			r:    r,
+			opts: ListOptions{
+				Cursor: 5,
+				Sort:   []SortField{{Field: "title"}},
+			},
+			mock:    func() {},
+			wantErr: true,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			tt.mock()
-			got, err := tt.r.GetAll()
+			got, err := tt.r.GetAll(context.Background(), tt.opts)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("GetAll() error new = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -292,7 +446,7 @@ func TestDocumentRepository_Delete(t *testing.T) {
 	db, mock := NewMock()
 	defer db.Close()
 
-	r := NewDocumentRepository(db)
+	r := NewDocumentRepository(db, MySQL)
 
 	tests := []struct {
 		name    string
@@ -307,8 +461,17 @@ func TestDocumentRepository_Delete(t *testing.T) {
 			r:    r,
 			id:   1,
 			mock: func() {
-				mock.ExpectPrepare("DELETE FROM documents").ExpectExec().
-					WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 1))
+				contentBytes, _ := json.Marshal(Content{Header: "header", Data: "data"})
+				expectChangeTx(mock)
+				rows := sqlmock.NewRows([]string{"id", "title", "content", "signee", "owner", "version", "deleted_at"}).
+					AddRow(1, "title", contentBytes, "signee", "alice", 1, nil)
+				mock.ExpectPrepare("SELECT (.+) FROM documents").ExpectQuery().WithArgs(1).
+					WillReturnRows(rows)
+				mock.ExpectPrepare("UPDATE documents SET deleted_at").ExpectExec().
+					WithArgs(sqlmock.AnyArg(), 1, 1).WillReturnResult(sqlmock.NewResult(0, 1))
+				expectRevision(mock, 1, 3, RevisionDelete, "title", contentBytes, "signee")
+				expectEvent(mock, 1, EventDocumentDeleted)
+				mock.ExpectCommit()
 			},
 			wantErr: false,
 		},
@@ -317,8 +480,10 @@ func TestDocumentRepository_Delete(t *testing.T) {
 			r:    r,
 			id:   1,
 			mock: func() {
-				mock.ExpectPrepare("DELETE FROM documents").ExpectExec().
-					WithArgs(100).WillReturnResult(sqlmock.NewResult(0, 0))
+				expectChangeTx(mock)
+				mock.ExpectPrepare("SELECT (.+) FROM documents").ExpectQuery().WithArgs(1).
+					WillReturnRows(sqlmock.NewRows([]string{"id", "title", "content", "signee", "owner", "version", "deleted_at"}))
+				mock.ExpectRollback()
 			},
 			wantErr: true,
 		},
@@ -326,11 +491,138 @@ func TestDocumentRepository_Delete(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			tt.mock()
-			err := tt.r.Delete(tt.id)
+			err := tt.r.Delete(context.Background(), tt.id, 1)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("Delete() error new = %v, wantErr %v", err, tt.wantErr)
 				return
 			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("Delete() %v", err)
+			}
+		})
+	}
+}
+
+func TestDocumentRepository_GetAll_Trashed(t *testing.T) {
+	db, mock := NewMock()
+	defer db.Close()
+
+	r := NewDocumentRepository(db, MySQL)
+	deletedAt := time.Date(2021, 12, 1, 10, 0, 0, 0, time.UTC)
+	contentBytes, _ := json.Marshal(Content{Header: "header", Data: "data"})
+
+	mock.ExpectPrepare(regexp.QuoteMeta("SELECT COUNT(*) FROM documents WHERE deleted_at IS NOT NULL")).ExpectQuery().
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+	mock.ExpectPrepare(regexp.QuoteMeta("SELECT id, title, content, signee, owner, version, deleted_at FROM documents WHERE deleted_at IS NOT NULL ORDER BY deleted_at DESC, id LIMIT ? OFFSET ?")).
+		ExpectQuery().WithArgs(DefaultListLimit, 0).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "title", "content", "signee", "owner", "version", "deleted_at"}).
+			AddRow(3, "trashed", contentBytes, "signee", "alice", 1, deletedAt))
+
+	got, err := r.GetAll(context.Background(), ListOptions{Trashed: true, Sort: []SortField{{Field: "deleted_at", Desc: true}}})
+	if err != nil {
+		t.Fatalf("GetAll() error = %v", err)
+	}
+	if len(got.Documents) != 1 || got.Documents[0].DeletedAt == nil || !got.Documents[0].DeletedAt.Equal(deletedAt) {
+		t.Errorf("GetAll() = %v", got.Documents)
+	}
+}
+
+func TestDocumentRepository_RestoreDeleted(t *testing.T) {
+	db, mock := NewMock()
+	defer db.Close()
+
+	r := NewDocumentRepository(db, MySQL)
+	deletedAt := time.Date(2021, 12, 1, 10, 0, 0, 0, time.UTC)
+	contentBytes, _ := json.Marshal(Content{Header: "header", Data: "data"})
+
+	expectChangeTx(mock)
+	mock.ExpectPrepare("SELECT (.+) FROM documents WHERE id = (.+) AND deleted_at IS NOT NULL").ExpectQuery().WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "title", "content", "signee", "owner", "version", "deleted_at"}).
+			AddRow(1, "title", contentBytes, "signee", "alice", 1, deletedAt))
+	mock.ExpectPrepare("UPDATE documents SET deleted_at = NULL").ExpectExec().WithArgs(1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	expectRevision(mock, 1, 4, RevisionRestore, "title", contentBytes, "signee")
+	expectEvent(mock, 1, EventDocumentUpdated)
+	mock.ExpectCommit()
+
+	got, err := r.RestoreDeleted(context.Background(), 1)
+	if err != nil {
+		t.Fatalf("RestoreDeleted() error = %v", err)
+	}
+	if got.DeletedAt != nil || got.Title != "title" {
+		t.Errorf("RestoreDeleted() = %v", got)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestDocumentRepository_Purge(t *testing.T) {
+	db, mock := NewMock()
+	defer db.Close()
+
+	r := NewDocumentRepository(db, MySQL)
+
+	tests := []struct {
+		name    string
+		mock    func()
+		wantErr error
+	}{
+		{
+			name: "Ok",
+			mock: func() {
+				mock.ExpectBegin()
+				mock.ExpectPrepare("DELETE FROM documents WHERE id = (.+) AND deleted_at IS NOT NULL").ExpectExec().
+					WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectPrepare("DELETE FROM document_signees").ExpectExec().
+					WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 2))
+				mock.ExpectPrepare("DELETE FROM document_grants").ExpectExec().
+					WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			},
+		},
+		{
+			name: "Not in trash",
+			mock: func() {
+				mock.ExpectBegin()
+				mock.ExpectPrepare("DELETE FROM documents WHERE id = (.+) AND deleted_at IS NOT NULL").ExpectExec().
+					WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectRollback()
+			},
+			wantErr: sql.ErrNoRows,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mock()
+			if err := r.Purge(context.Background(), 1); err != tt.wantErr {
+				t.Errorf("Purge() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Error(err)
+			}
 		})
 	}
 }
+
+func TestDocumentRepository_PurgeDeletedBefore(t *testing.T) {
+	db, mock := NewMock()
+	defer db.Close()
+
+	r := NewDocumentRepository(db, MySQL)
+	before := time.Date(2021, 12, 1, 10, 0, 0, 0, time.UTC)
+
+	mock.ExpectBegin()
+	mock.ExpectPrepare("DELETE FROM document_signees WHERE document_id IN").ExpectExec().
+		WithArgs(before).WillReturnResult(sqlmock.NewResult(0, 4))
+	mock.ExpectPrepare("DELETE FROM document_grants WHERE document_id IN").ExpectExec().
+		WithArgs(before).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectPrepare("DELETE FROM documents WHERE deleted_at <").ExpectExec().
+		WithArgs(before).WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+
+	purged, err := r.PurgeDeletedBefore(context.Background(), before)
+	if err != nil || purged != 2 {
+		t.Errorf("PurgeDeletedBefore() = %v, %v", purged, err)
+	}
+}
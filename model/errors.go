@@ -0,0 +1,166 @@
+package model
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"github.com/go-sql-driver/mysql"
+	"github.com/lib/pq"
+	"github.com/mattn/go-sqlite3"
+	"net"
+	"strings"
+)
+
+// ErrorKind is the class of an Error, which decides the status it is answered
+// with.
+type ErrorKind string
+
+const (
+	// KindInvalid is a malformed request, such as a query parameter that does
+	// not parse.
+	KindInvalid ErrorKind = "invalid"
+	// KindValidation is a well-formed request for an entity that is not
+	// valid.
+	KindValidation           ErrorKind = "validation"
+	KindNotFound             ErrorKind = "not_found"
+	KindConflict             ErrorKind = "conflict"
+	KindPreconditionFailed   ErrorKind = "precondition_failed"
+	KindPreconditionRequired ErrorKind = "precondition_required"
+	KindUnsupportedMediaType ErrorKind = "unsupported_media_type"
+	KindUnauthenticated      ErrorKind = "unauthenticated"
+	KindForbidden            ErrorKind = "forbidden"
+	// KindUnavailable is a failure expected to pass, such as the database
+	// being unreachable or the server shutting down.
+	KindUnavailable ErrorKind = "unavailable"
+	KindTimeout     ErrorKind = "timeout"
+	KindCanceled    ErrorKind = "canceled"
+	KindInternal    ErrorKind = "internal"
+)
+
+// Error is an error of the domain. Code names it to clients and, unlike
+// Message, never changes. Err is the error it stands for if any, such as the
+// one of the driver, which clients are not shown.
+type Error struct {
+	Kind    ErrorKind
+	Code    string
+	Message string
+	Err     error
+}
+
+func newError(kind ErrorKind, code, message string) *Error {
+	return &Error{Kind: kind, Code: code, Message: message}
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Is matches the errors of the same code, so that an Error standing for
+// another error still matches the value it was made from.
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Code == e.Code
+}
+
+// ErrorCode returns Code, for the packages that answer errors without knowing
+// of Error.
+func (e *Error) ErrorCode() string {
+	return e.Code
+}
+
+// standFor returns a copy of e standing for err.
+func (e *Error) standFor(err error) *Error {
+	c := *e
+	c.Err = err
+	return &c
+}
+
+var (
+	NotFoundValue    = newError(KindNotFound, "not_found", "resource was not found, expect the id of an existing one")
+	ConflictValue    = newError(KindConflict, "conflict", "resource conflicts with an existing one, expect unique values")
+	UnavailableValue = newError(KindUnavailable, "unavailable", "database is unavailable, expect to retry later")
+	TimeoutValue     = newError(KindTimeout, "timeout", "request did not complete in time, expect to retry later")
+	CanceledValue    = newError(KindCanceled, "canceled", "request was canceled by the client")
+	InternalValue    = newError(KindInternal, "internal", "internal server error, expect it to be logged under the request_id")
+	BodyInvalidValue = newError(KindInvalid, "body_invalid", "request body had invalid value, expect a JSON document of the expected fields")
+	IDInvalidValue   = newError(KindInvalid, "id_invalid", "path had invalid id, expect a positive 64-bit integer")
+)
+
+// ErrorOf returns the Error that err is, or else the one it stands for: not
+// found for sql.ErrNoRows, a conflict for a unique violation, unavailable when
+// the database cannot be reached, a timeout or cancellation when the context
+// ended, and InternalValue for anything else.
+func ErrorOf(err error) *Error {
+	var e *Error
+	if errors.As(err, &e) {
+		return e
+	}
+	if e, ok := translateError(err).(*Error); ok {
+		return e
+	}
+	var opErr *net.OpError
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return NotFoundValue.standFor(err)
+	case errors.Is(err, context.DeadlineExceeded):
+		return TimeoutValue.standFor(err)
+	case errors.Is(err, context.Canceled):
+		return CanceledValue.standFor(err)
+	case errors.Is(err, driver.ErrBadConn), errors.Is(err, sql.ErrConnDone), errors.As(err, &opErr):
+		return UnavailableValue.standFor(err)
+	}
+	return InternalValue.standFor(err)
+}
+
+// uniqueErrors are the Errors of the columns whose values must be unique;
+// other unique violations are answered as ConflictValue.
+var uniqueErrors = []struct {
+	column string
+	err    *Error
+}{
+	{"title", DuplicateTitleValue},
+	{"public_key", DuplicatePublicKeyValue},
+}
+
+// translateError turns a unique violation reported by any of the drivers into
+// the Error of its column, and leaves other errors as they are.
+func translateError(err error) error {
+	constraint, ok := uniqueViolation(err)
+	if !ok {
+		return err
+	}
+	for _, u := range uniqueErrors {
+		if strings.Contains(constraint, u.column) {
+			return u.err.standFor(err)
+		}
+	}
+	return ConflictValue.standFor(err)
+}
+
+// uniqueViolation reports whether err is a unique violation, along with the
+// name of the violated key, constraint or columns as the driver gives it.
+func uniqueViolation(err error) (string, bool) {
+	var myErr *mysql.MySQLError
+	if errors.As(err, &myErr) && myErr.Number == 1062 {
+		// Duplicate entry 'a' for key 'documents.title'
+		if i := strings.LastIndex(myErr.Message, " for key "); i >= 0 {
+			return myErr.Message[i:], true
+		}
+		return myErr.Message, true
+	}
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+		return pqErr.Constraint, true
+	}
+	var sqliteErr sqlite3.Error
+	if errors.As(err, &sqliteErr) && sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique {
+		// UNIQUE constraint failed: documents.title
+		return sqliteErr.Error(), true
+	}
+	return "", false
+}
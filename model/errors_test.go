@@ -0,0 +1,95 @@
+package model
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"github.com/go-sql-driver/mysql"
+	"github.com/lib/pq"
+	"github.com/mattn/go-sqlite3"
+	"testing"
+)
+
+func TestErrorOf(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want *Error
+	}{
+		{name: "Error", err: TitleInvalidValue, want: TitleInvalidValue},
+		{name: "Wrapped error", err: &BatchOperationError{Index: 1, Err: VersionMismatchValue}, want: VersionMismatchValue},
+		{name: "No rows", err: sql.ErrNoRows, want: NotFoundValue},
+		{name: "Deadline", err: fmt.Errorf("%w: lock wait", context.DeadlineExceeded), want: TimeoutValue},
+		{name: "Canceled", err: context.Canceled, want: CanceledValue},
+		{name: "Bad connection", err: driver.ErrBadConn, want: UnavailableValue},
+		{name: "Duplicate title", err: &mysql.MySQLError{Number: 1062, Message: "Duplicate entry 'title' for key 'documents.title'"}, want: DuplicateTitleValue},
+		{name: "Other driver error", err: &mysql.MySQLError{Number: 1205, Message: "Lock wait timeout exceeded"}, want: InternalValue},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ErrorOf(tt.err)
+			if got.Code != tt.want.Code || got.Kind != tt.want.Kind || !errors.Is(got, tt.want) {
+				t.Errorf("ErrorOf() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestErrorOf_KeepsCause(t *testing.T) {
+	cause := &mysql.MySQLError{Number: 1205, Message: "Lock wait timeout exceeded"}
+	got := ErrorOf(cause)
+	if got.Error() != InternalValue.Error() {
+		t.Errorf("Error() = %q, want the message of InternalValue", got.Error())
+	}
+	var myErr *mysql.MySQLError
+	if !errors.As(got, &myErr) || myErr != cause {
+		t.Errorf("ErrorOf() = %+v, want it to wrap the driver error", got)
+	}
+	if InternalValue.Err != nil {
+		t.Error("ErrorOf() modified InternalValue")
+	}
+}
+
+func TestTranslateError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want error
+	}{
+		{name: "MySQL title", err: &mysql.MySQLError{Number: 1062, Message: "Duplicate entry 'title' for key 'title'"}, want: DuplicateTitleValue},
+		{name: "MySQL value naming a column", err: &mysql.MySQLError{Number: 1062, Message: "Duplicate entry 'title' for key 'document_grants.document_id'"}, want: ConflictValue},
+		{name: "Postgres title", err: &pq.Error{Code: "23505", Constraint: "documents_title_key"}, want: DuplicateTitleValue},
+		{name: "Postgres public key", err: &pq.Error{Code: "23505", Constraint: "signee_keys_public_key_key"}, want: DuplicatePublicKeyValue},
+		{name: "SQLite without a column", err: sqlite3.Error{Code: sqlite3.ErrConstraint, ExtendedCode: sqlite3.ErrConstraintUnique}, want: ConflictValue},
+		{name: "Postgres foreign key", err: &pq.Error{Code: "23503", Constraint: "documents_title_fkey"}},
+		{name: "Nil"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := translateError(tt.err)
+			if tt.want == nil {
+				if got != tt.err {
+					t.Errorf("translateError() = %v, want %v", got, tt.err)
+				}
+				return
+			}
+			if !errors.Is(got, tt.want) || !errors.Is(got, tt.err) {
+				t.Errorf("translateError() = %v, want %v standing for %v", got, tt.want, tt.err)
+			}
+		})
+	}
+}
+
+func TestDocumentRepository_DuplicateTitle(t *testing.T) {
+	r := NewDocumentRepository(openSQLite(t), SQLite)
+	if _, err := r.Create(context.Background(), Document{Title: "title", Signee: "signee"}); err != nil {
+		t.Fatal(err)
+	}
+	_, err := r.Create(context.Background(), Document{Title: "title", Signee: "signee"})
+	var sqliteErr sqlite3.Error
+	if !errors.Is(err, DuplicateTitleValue) || ErrorOf(err).Kind != KindConflict || !errors.As(err, &sqliteErr) {
+		t.Errorf("Create() of a taken title error = %v, want %v standing for the driver error", err, DuplicateTitleValue)
+	}
+}
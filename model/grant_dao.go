@@ -0,0 +1,130 @@
+package model
+
+import (
+	"context"
+	"database/sql"
+)
+
+var (
+	GrantRepository grantRepositoryInterface = &grantRepository{}
+)
+
+type grantRepositoryInterface interface {
+	GetAll(context.Context, int64) ([]*Grant, error)
+	Put(context.Context, Grant) (*Grant, error)
+	Delete(context.Context, int64, int64) error
+}
+
+type grantRepository struct {
+	db *dialectDB
+}
+
+func NewGrantRepository(db *sql.DB, dialect *Dialect) grantRepositoryInterface {
+	return &grantRepository{db: newDialectDB(db, dialect)}
+}
+
+const grantColumns = "id, document_id, grantee_type, grantee, role, created_at"
+
+func scanGrant(scanner interface{ Scan(...interface{}) error }) (*Grant, error) {
+	var g Grant
+	if err := scanner.Scan(&g.ID, &g.DocumentID, &g.GranteeType, &g.Grantee, &g.Role, &g.CreatedAt); err != nil {
+		return nil, err
+	}
+	return &g, nil
+}
+
+// GetAll lists the grants of a document.
+func (r *grantRepository) GetAll(ctx context.Context, documentID int64) (_ []*Grant, err error) {
+	ctx, done := startOperation(ctx, "grantRepository.GetAll")
+	defer done(&err)
+
+	stmt, err := r.db.PrepareContext(ctx, "SELECT "+grantColumns+" FROM document_grants WHERE document_id = ? ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx, documentID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := make([]*Grant, 0)
+	for rows.Next() {
+		g, err := scanGrant(rows)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, g)
+	}
+	return results, rows.Err()
+}
+
+// Put grants a role on a document, replacing the role the grantee already
+// held on it if any.
+func (r *grantRepository) Put(ctx context.Context, grant Grant) (_ *Grant, err error) {
+	ctx, done := startOperation(ctx, "grantRepository.Put")
+	defer done(&err)
+
+	err = inTx(ctx, r.db, func(tx *dialectTx) error {
+		getStmt, err := tx.PrepareContext(ctx, "SELECT "+grantColumns+" FROM document_grants WHERE document_id = ? AND grantee_type = ? AND grantee = ?")
+		if err != nil {
+			return err
+		}
+		defer getStmt.Close()
+
+		current, err := scanGrant(getStmt.QueryRowContext(ctx, grant.DocumentID, grant.GranteeType, grant.Grantee))
+		if err == sql.ErrNoRows {
+			grant.CreatedAt = now().UTC()
+			grant.ID, err = tx.dialect.insert(ctx, tx, "INSERT INTO document_grants(document_id, grantee_type, grantee, role, created_at) VALUES(?, ?, ?, ?, ?)",
+				grant.DocumentID, grant.GranteeType, grant.Grantee, grant.Role, grant.CreatedAt)
+			return err
+		}
+		if err != nil {
+			return err
+		}
+
+		stmt, err := tx.PrepareContext(ctx, "UPDATE document_grants SET role = ? WHERE id = ?")
+		if err != nil {
+			return err
+		}
+		defer stmt.Close()
+
+		if _, err := stmt.ExecContext(ctx, grant.Role, current.ID); err != nil {
+			return err
+		}
+		grant.ID, grant.CreatedAt = current.ID, current.CreatedAt
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &grant, nil
+}
+
+// Delete revokes a grant of a document, or fails with sql.ErrNoRows when the
+// document holds no such grant.
+func (r *grantRepository) Delete(ctx context.Context, documentID, id int64) (err error) {
+	ctx, done := startOperation(ctx, "grantRepository.Delete")
+	defer done(&err)
+
+	stmt, err := r.db.PrepareContext(ctx, "DELETE FROM document_grants WHERE id = ? AND document_id = ?")
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	result, err := stmt.ExecContext(ctx, id, documentID)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
@@ -0,0 +1,151 @@
+package model
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/DATA-DOG/go-sqlmock"
+	"testing"
+	"time"
+)
+
+var grantRows = []string{"id", "document_id", "grantee_type", "grantee", "role", "created_at"}
+
+func TestGrantRepository_Put(t *testing.T) {
+	db, mock := NewMock()
+	defer db.Close()
+
+	r := NewGrantRepository(db, MySQL)
+	created := time.Date(2022, 1, 10, 9, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name   string
+		mock   func()
+		wantID int64
+	}{
+		{
+			name: "New grantee",
+			mock: func() {
+				mock.ExpectBegin()
+				mock.ExpectPrepare("SELECT (.+) FROM document_grants WHERE document_id = \\? AND grantee_type = \\? AND grantee = \\?").
+					ExpectQuery().WithArgs(1, GranteeUser, "bob").WillReturnRows(sqlmock.NewRows(grantRows))
+				mock.ExpectPrepare("INSERT INTO document_grants").ExpectExec().
+					WithArgs(1, GranteeUser, "bob", RoleEditor, sqlmock.AnyArg()).
+					WillReturnResult(sqlmock.NewResult(3, 1))
+				mock.ExpectCommit()
+			},
+			wantID: 3,
+		},
+		{
+			name: "Replaced role",
+			mock: func() {
+				mock.ExpectBegin()
+				mock.ExpectPrepare("SELECT (.+) FROM document_grants").ExpectQuery().WithArgs(1, GranteeUser, "bob").
+					WillReturnRows(sqlmock.NewRows(grantRows).AddRow(2, 1, GranteeUser, "bob", RoleViewer, created))
+				mock.ExpectPrepare("UPDATE document_grants SET role = \\? WHERE id = \\?").ExpectExec().
+					WithArgs(RoleEditor, 2).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			},
+			wantID: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mock()
+			got, err := r.Put(context.Background(), Grant{DocumentID: 1, GranteeType: GranteeUser, Grantee: "bob", Role: RoleEditor})
+			if err != nil {
+				t.Fatalf("Put() error = %v", err)
+			}
+			if got.ID != tt.wantID || got.Role != RoleEditor || got.CreatedAt.IsZero() {
+				t.Errorf("Put() = %v, want id %d", got, tt.wantID)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestGrantRepository_Delete(t *testing.T) {
+	db, mock := NewMock()
+	defer db.Close()
+
+	r := NewGrantRepository(db, MySQL)
+	mock.ExpectPrepare("DELETE FROM document_grants WHERE id = \\? AND document_id = \\?").ExpectExec().WithArgs(2, 1).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	if err := r.Delete(context.Background(), 1, 2); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("Delete() error = %v, want %v", err, sql.ErrNoRows)
+	}
+}
+
+func TestGrantRepositories(t *testing.T) {
+	documents, _, _, grants, _, _ := NewMemoryRepositories()
+	db := openSQLite(t)
+	repositories := map[string]struct {
+		documents documentRepositoryInterface
+		grants    grantRepositoryInterface
+	}{
+		"Memory": {documents, grants},
+		"SQLite": {NewDocumentRepository(db, SQLite), NewGrantRepository(db, SQLite)},
+	}
+	for name, r := range repositories {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			var ids []int64
+			for _, owner := range []string{"alice", "bob", "carol"} {
+				doc, err := r.documents.Create(ctx, Document{Title: "owned by " + owner, Signee: "signee", Owner: owner})
+				if err != nil {
+					t.Fatal(err)
+				}
+				ids = append(ids, doc.ID)
+			}
+			if _, err := r.grants.Put(ctx, Grant{DocumentID: ids[1], GranteeType: GranteeUser, Grantee: "alice", Role: RoleViewer}); err != nil {
+				t.Fatal(err)
+			}
+			replaced, err := r.grants.Put(ctx, Grant{DocumentID: ids[1], GranteeType: GranteeUser, Grantee: "alice", Role: RoleEditor})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := r.grants.Put(ctx, Grant{DocumentID: ids[2], GranteeType: GranteeGroup, Grantee: "legal", Role: RoleSigner}); err != nil {
+				t.Fatal(err)
+			}
+			got, err := r.grants.GetAll(ctx, ids[1])
+			if err != nil || len(got) != 1 || got[0].ID != replaced.ID || got[0].Role != RoleEditor {
+				t.Fatalf("GetAll() = %v, %v, want the replaced grant only", got, err)
+			}
+
+			visible := func(p *Principal) int64 {
+				page, err := r.documents.GetAll(ctx, ListOptions{VisibleTo: p})
+				if err != nil {
+					t.Fatal(err)
+				}
+				return page.Total
+			}
+			if n := visible(&Principal{Subject: "alice"}); n != 2 {
+				t.Errorf("GetAll() visible to alice = %d, want 2", n)
+			}
+			if n := visible(&Principal{Subject: "alice", Groups: []string{"sales", "legal"}}); n != 3 {
+				t.Errorf("GetAll() visible to alice in legal = %d, want 3", n)
+			}
+			if n := visible(&Principal{Subject: "dave"}); n != 0 {
+				t.Errorf("GetAll() visible to dave = %d, want 0", n)
+			}
+
+			if err := r.grants.Delete(ctx, ids[1], replaced.ID); err != nil {
+				t.Fatal(err)
+			}
+			if err := r.grants.Delete(ctx, ids[1], replaced.ID); !errors.Is(err, sql.ErrNoRows) {
+				t.Errorf("Delete() twice error = %v", err)
+			}
+			if err := r.documents.Delete(ctx, ids[2], 1); err != nil {
+				t.Fatal(err)
+			}
+			if err := r.documents.Purge(ctx, ids[2]); err != nil {
+				t.Fatal(err)
+			}
+			if got, err := r.grants.GetAll(ctx, ids[2]); err != nil || len(got) != 0 {
+				t.Errorf("GetAll() of a purged document = %v, %v", got, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,20 @@
+package model
+
+// SchemaVersion is the migration the repositories are written against, the
+// highest one of every directory of db/migration.
+const SchemaVersion = 14
+
+var (
+	DrainingValue              = newError(KindUnavailable, "draining", "server is shutting down, expect it to stop serving requests")
+	SchemaVersionMismatchValue = newError(KindUnavailable, "schema_version_mismatch", "database schema is not at the expected migration, expect migrate up to have run")
+)
+
+// NotReady returns the error a failed readiness check is answered with: err
+// when it is already unavailable, and otherwise UnavailableValue standing for
+// it, so that clients are never shown the message of the driver.
+func NotReady(err error) error {
+	if ErrorOf(err).Kind == KindUnavailable {
+		return err
+	}
+	return UnavailableValue.standFor(err)
+}
@@ -0,0 +1,47 @@
+package model
+
+import (
+	"context"
+	"database/sql"
+)
+
+var (
+	HealthRepository healthRepositoryInterface = &healthRepository{}
+)
+
+type healthRepositoryInterface interface {
+	Ping(context.Context) error
+	// SchemaVersion returns the migration the database is at and whether that
+	// migration failed halfway.
+	SchemaVersion(context.Context) (int64, bool, error)
+}
+
+type healthRepository struct {
+	db *dialectDB
+}
+
+func NewHealthRepository(db *sql.DB, dialect *Dialect) healthRepositoryInterface {
+	return &healthRepository{db: newDialectDB(db, dialect)}
+}
+
+func (r *healthRepository) Ping(ctx context.Context) (err error) {
+	ctx, done := startOperation(ctx, "healthRepository.Ping")
+	defer done(&err)
+
+	return r.db.PingContext(ctx)
+}
+
+// SchemaVersion reads the table golang-migrate keeps its state in.
+func (r *healthRepository) SchemaVersion(ctx context.Context) (version int64, dirty bool, err error) {
+	ctx, done := startOperation(ctx, "healthRepository.SchemaVersion")
+	defer done(&err)
+
+	stmt, err := r.db.PrepareContext(ctx, "SELECT version, dirty FROM schema_migrations")
+	if err != nil {
+		return 0, false, err
+	}
+	defer stmt.Close()
+
+	err = stmt.QueryRowContext(ctx).Scan(&version, &dirty)
+	return version, dirty, err
+}
@@ -0,0 +1,47 @@
+package model
+
+import (
+	"context"
+	"github.com/DATA-DOG/go-sqlmock"
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestHealthRepository_SchemaVersion(t *testing.T) {
+	db, mock := NewMock()
+	defer db.Close()
+
+	mock.ExpectPrepare("SELECT version, dirty FROM schema_migrations").ExpectQuery().
+		WillReturnRows(sqlmock.NewRows([]string{"version", "dirty"}).AddRow(6, false))
+
+	version, dirty, err := NewHealthRepository(db, MySQL).SchemaVersion(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if version != 6 || dirty {
+		t.Errorf("SchemaVersion() = %d, %t, want 6, false", version, dirty)
+	}
+}
+
+// TestSchemaVersion keeps SchemaVersion in step with the migrations.
+func TestSchemaVersion(t *testing.T) {
+	for _, dir := range []string{"mysql", "postgres", "sqlite"} {
+		files, err := ioutil.ReadDir(filepath.Join("..", "db", "migration", dir))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var latest int64
+		for _, f := range files {
+			v, err := strconv.ParseInt(strings.SplitN(f.Name(), "_", 2)[0], 10, 64)
+			if err == nil && v > latest {
+				latest = v
+			}
+		}
+		if latest != SchemaVersion {
+			t.Errorf("latest %s migration is %d, SchemaVersion is %d", dir, latest, SchemaVersion)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package model
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"time"
+)
+
+// MaxIdempotencyKeyLength bounds the Idempotency-Key header.
+const MaxIdempotencyKeyLength = 255
+
+var (
+	IdempotencyKeyInvalidValue    = newError(KindInvalid, "idempotency_key_invalid", "Idempotency-Key header had invalid value, expect 1 to 255 characters")
+	IdempotencyKeyReusedValue     = newError(KindValidation, "idempotency_key_reused", "Idempotency-Key was used with another request, expect a new key for a new request")
+	IdempotencyKeyInProgressValue = newError(KindConflict, "idempotency_key_in_progress", "a request with this Idempotency-Key is in progress, expect to retry once it completed")
+)
+
+// replayedHeaders are the response headers an idempotency record keeps.
+var replayedHeaders = []string{"Content-Type", "ETag", "Location"}
+
+// IdempotencyRecord is the request a client made under an idempotency key,
+// scoped to the subject of its principal, and the response it got. StatusCode
+// is zero while the request is in progress. The record may be replaced once
+// ExpiresAt passed: soon after it was taken when the request never completed,
+// and after the retention of responses otherwise.
+type IdempotencyRecord struct {
+	ID          int64       `json:"id"`
+	Scope       string      `json:"scope"`
+	Key         string      `json:"key"`
+	Fingerprint string      `json:"fingerprint"`
+	StatusCode  int         `json:"status_code"`
+	Header      http.Header `json:"header,omitempty"`
+	Body        []byte      `json:"body,omitempty"`
+	CreatedAt   time.Time   `json:"created_at"`
+	ExpiresAt   time.Time   `json:"expires_at"`
+}
+
+// RequestFingerprint is the hex SHA-256 of the method, path and body of a
+// request, which a retry under the same key must match.
+func RequestFingerprint(method, path string, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(method + " " + path + "\n"))
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Completed reports whether the record holds the response of its request.
+func (r *IdempotencyRecord) Completed() bool {
+	return r.StatusCode != 0
+}
+
+// Complete records the response of the request, kept until expiresAt.
+func (r *IdempotencyRecord) Complete(statusCode int, header http.Header, body []byte, expiresAt time.Time) {
+	r.StatusCode = statusCode
+	r.Header = make(http.Header)
+	for _, name := range replayedHeaders {
+		for _, value := range header.Values(name) {
+			r.Header.Add(name, value)
+		}
+	}
+	r.Body = append([]byte(nil), body...)
+	r.ExpiresAt = expiresAt
+}
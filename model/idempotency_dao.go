@@ -0,0 +1,175 @@
+package model
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+)
+
+var (
+	IdempotencyRepository idempotencyRepositoryInterface = &idempotencyRepository{}
+)
+
+// idempotencyRepositoryInterface keeps the requests made under idempotency
+// keys and their responses.
+type idempotencyRepositoryInterface interface {
+	// Reserve takes the key of record for its request unless a record of the
+	// key that has not expired exists, which it returns instead. The boolean
+	// reports whether the key was taken.
+	Reserve(context.Context, IdempotencyRecord) (*IdempotencyRecord, bool, error)
+	Complete(context.Context, IdempotencyRecord) error
+	Release(context.Context, int64) error
+	DeleteExpiredBefore(context.Context, time.Time) (int64, error)
+}
+
+type idempotencyRepository struct {
+	db *dialectDB
+}
+
+func NewIdempotencyRepository(db *sql.DB, dialect *Dialect) idempotencyRepositoryInterface {
+	return &idempotencyRepository{db: newDialectDB(db, dialect)}
+}
+
+const idempotencyColumns = "id, scope, idempotency_key, fingerprint, status_code, header, body, created_at, expires_at"
+
+func scanIdempotencyRecord(scanner interface{ Scan(...interface{}) error }) (*IdempotencyRecord, error) {
+	var record IdempotencyRecord
+	var header string
+	var body string
+	if err := scanner.Scan(&record.ID, &record.Scope, &record.Key, &record.Fingerprint, &record.StatusCode, &header, &body, &record.CreatedAt, &record.ExpiresAt); err != nil {
+		return nil, err
+	}
+	if header != "" {
+		if err := json.Unmarshal([]byte(header), &record.Header); err != nil {
+			return nil, err
+		}
+	}
+	if body != "" {
+		record.Body = []byte(body)
+	}
+	return &record, nil
+}
+
+func encodeHeader(record IdempotencyRecord) (string, error) {
+	if len(record.Header) == 0 {
+		return "", nil
+	}
+	header, err := json.Marshal(record.Header)
+	return string(header), err
+}
+
+func (r *idempotencyRepository) Reserve(ctx context.Context, record IdempotencyRecord) (_ *IdempotencyRecord, _ bool, err error) {
+	ctx, done := startOperation(ctx, "idempotencyRepository.Reserve")
+	defer done(&err)
+
+	var existing *IdempotencyRecord
+	err = inTx(ctx, r.db, func(tx *dialectTx) error {
+		found, err := getIdempotencyRecord(ctx, tx, record.Scope, record.Key)
+		if err == nil && found.ExpiresAt.After(now()) {
+			existing = found
+			return nil
+		}
+		if err == nil {
+			if _, err := tx.ExecContext(ctx, "DELETE FROM idempotency_keys WHERE id = ?", found.ID); err != nil {
+				return err
+			}
+		} else if err != sql.ErrNoRows {
+			return err
+		}
+
+		header, err := encodeHeader(record)
+		if err != nil {
+			return err
+		}
+		record.CreatedAt = now().UTC()
+		record.ID, err = tx.dialect.insert(ctx, tx, "INSERT INTO idempotency_keys(scope, idempotency_key, fingerprint, status_code, header, body, created_at, expires_at) VALUES(?, ?, ?, ?, ?, ?, ?, ?)",
+			record.Scope, record.Key, record.Fingerprint, record.StatusCode, header, string(record.Body), record.CreatedAt, record.ExpiresAt.UTC())
+		return err
+	})
+	if err != nil {
+		// A concurrent request may have taken the key first.
+		if found, getErr := getIdempotencyRecord(ctx, r.db, record.Scope, record.Key); getErr == nil {
+			return found, false, nil
+		}
+		return nil, false, err
+	}
+	if existing != nil {
+		return existing, false, nil
+	}
+	return &record, true, nil
+}
+
+func getIdempotencyRecord(ctx context.Context, p preparer, scope, key string) (*IdempotencyRecord, error) {
+	stmt, err := p.PrepareContext(ctx, "SELECT "+idempotencyColumns+" FROM idempotency_keys WHERE scope = ? AND idempotency_key = ?")
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	return scanIdempotencyRecord(stmt.QueryRowContext(ctx, scope, key))
+}
+
+// Complete records the response of the request of a reserved key.
+func (r *idempotencyRepository) Complete(ctx context.Context, record IdempotencyRecord) (err error) {
+	ctx, done := startOperation(ctx, "idempotencyRepository.Complete")
+	defer done(&err)
+
+	header, err := encodeHeader(record)
+	if err != nil {
+		return err
+	}
+	stmt, err := r.db.PrepareContext(ctx, "UPDATE idempotency_keys SET status_code = ?, header = ?, body = ?, expires_at = ? WHERE id = ?")
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	result, err := stmt.ExecContext(ctx, record.StatusCode, header, string(record.Body), record.ExpiresAt.UTC(), record.ID)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// Release frees a reserved key whose request failed, so that it may be
+// retried under the same key.
+func (r *idempotencyRepository) Release(ctx context.Context, id int64) (err error) {
+	ctx, done := startOperation(ctx, "idempotencyRepository.Release")
+	defer done(&err)
+
+	stmt, err := r.db.PrepareContext(ctx, "DELETE FROM idempotency_keys WHERE id = ?")
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	_, err = stmt.ExecContext(ctx, id)
+	return err
+}
+
+// DeleteExpiredBefore deletes the records that expired before the given time
+// and returns how many were deleted.
+func (r *idempotencyRepository) DeleteExpiredBefore(ctx context.Context, before time.Time) (_ int64, err error) {
+	ctx, done := startOperation(ctx, "idempotencyRepository.DeleteExpiredBefore")
+	defer done(&err)
+
+	stmt, err := r.db.PrepareContext(ctx, "DELETE FROM idempotency_keys WHERE expires_at < ?")
+	if err != nil {
+		return 0, err
+	}
+	defer stmt.Close()
+
+	result, err := stmt.ExecContext(ctx, before.UTC())
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
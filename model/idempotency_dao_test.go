@@ -0,0 +1,93 @@
+package model
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestIdempotencyRecord_Complete(t *testing.T) {
+	r := IdempotencyRecord{}
+	if r.Completed() {
+		t.Fatal("Completed() of a new record = true")
+	}
+	header := http.Header{"Content-Type": {"application/json"}, "Etag": {`"1"`}, "X-Request-Id": {"r1"}}
+	r.Complete(http.StatusCreated, header, []byte(`{"id":1}`), time.Now())
+	if !r.Completed() || r.StatusCode != http.StatusCreated || string(r.Body) != `{"id":1}` {
+		t.Errorf("Complete() = %+v", r)
+	}
+	if len(r.Header) != 2 || r.Header.Get("ETag") != `"1"` || r.Header.Get("X-Request-Id") != "" {
+		t.Errorf("Complete() header = %v, want Content-Type and ETag only", r.Header)
+	}
+}
+
+func TestRequestFingerprint(t *testing.T) {
+	fingerprint := RequestFingerprint("POST", "/documents", []byte(`{"title":"a"}`))
+	if len(fingerprint) != 64 || fingerprint != RequestFingerprint("POST", "/documents", []byte(`{"title":"a"}`)) {
+		t.Errorf("RequestFingerprint() = %q, want a stable SHA-256", fingerprint)
+	}
+	for _, other := range []string{
+		RequestFingerprint("PATCH", "/documents", []byte(`{"title":"a"}`)),
+		RequestFingerprint("POST", "/documents:batch", []byte(`{"title":"a"}`)),
+		RequestFingerprint("POST", "/documents", []byte(`{"title":"b"}`)),
+	} {
+		if other == fingerprint {
+			t.Errorf("RequestFingerprint() of another request = %q", other)
+		}
+	}
+}
+
+func TestIdempotencyRepositories(t *testing.T) {
+	repositories := map[string]idempotencyRepositoryInterface{
+		"Memory": NewMemoryIdempotencyRepository(),
+		"SQLite": NewIdempotencyRepository(openSQLite(t), SQLite),
+	}
+	for name, r := range repositories {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			record := IdempotencyRecord{Scope: "alice", Key: "k1", Fingerprint: "f1", ExpiresAt: time.Now().Add(time.Minute)}
+			reserved, taken, err := r.Reserve(ctx, record)
+			if err != nil || !taken || reserved.ID == 0 {
+				t.Fatalf("Reserve() = %+v, %v, %v, want the key taken", reserved, taken, err)
+			}
+			existing, taken, err := r.Reserve(ctx, record)
+			if err != nil || taken || existing.ID != reserved.ID || existing.Completed() {
+				t.Fatalf("Reserve() again = %+v, %v, %v, want the record in progress", existing, taken, err)
+			}
+			other := record
+			other.Scope = "bob"
+			if _, taken, err := r.Reserve(ctx, other); err != nil || !taken {
+				t.Errorf("Reserve() in another scope = %v, %v, want the key taken", taken, err)
+			}
+
+			reserved.Complete(http.StatusCreated, http.Header{"Content-Type": {"application/json"}}, []byte(`{"id":1}`), time.Now().Add(time.Hour))
+			if err := r.Complete(ctx, *reserved); err != nil {
+				t.Fatal(err)
+			}
+			existing, taken, err = r.Reserve(ctx, record)
+			if err != nil || taken || existing.StatusCode != http.StatusCreated || string(existing.Body) != `{"id":1}` || existing.Header.Get("Content-Type") != "application/json" {
+				t.Fatalf("Reserve() of a completed key = %+v, %v, %v", existing, taken, err)
+			}
+
+			if err := r.Release(ctx, reserved.ID); err != nil {
+				t.Fatal(err)
+			}
+			if _, taken, err := r.Reserve(ctx, record); err != nil || !taken {
+				t.Errorf("Reserve() of a released key = %v, %v, want the key taken", taken, err)
+			}
+
+			expired := IdempotencyRecord{Scope: "alice", Key: "k2", Fingerprint: "f2", ExpiresAt: time.Now().Add(-time.Second)}
+			if _, _, err := r.Reserve(ctx, expired); err != nil {
+				t.Fatal(err)
+			}
+			expired.Fingerprint = "f3"
+			if replaced, taken, err := r.Reserve(ctx, expired); err != nil || !taken || replaced.Fingerprint != "f3" {
+				t.Errorf("Reserve() of an expired key = %+v, %v, %v, want the key taken", replaced, taken, err)
+			}
+			if deleted, err := r.DeleteExpiredBefore(ctx, time.Now()); err != nil || deleted != 1 {
+				t.Errorf("DeleteExpiredBefore() = %d, %v, want 1", deleted, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,1186 @@
+package model
+
+import (
+	"context"
+	"database/sql"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// memoryStore holds the tables behind the in-memory repositories. Titles are
+// compared case-insensitively, like the default MySQL collation.
+type memoryStore struct {
+	mu           sync.RWMutex
+	lastID       map[string]int64
+	documents    map[int64]Document
+	signingModes map[int64]string
+	revisions    map[int64][]Revision
+	signees      map[int64][]Signee
+	grants       map[int64][]Grant
+	keys         []SigneeKey
+	signatures   []Signature
+	outbox       []OutboxEvent
+	audit        []AuditEntry
+}
+
+// NewMemoryRepositories returns document, signature, signee, grant, outbox and
+// audit repositories sharing one in-memory store, with the same semantics as
+// the SQL ones.
+func NewMemoryRepositories() (documentRepositoryInterface, signatureRepositoryInterface, signeeRepositoryInterface, grantRepositoryInterface, outboxRepositoryInterface, auditRepositoryInterface) {
+	store := newMemoryStore()
+	return &memoryDocumentRepository{store}, &memorySignatureRepository{store}, &memorySigneeRepository{store}, &memoryGrantRepository{store}, &memoryOutboxRepository{store}, &memoryAuditRepository{store}
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{
+		lastID:       make(map[string]int64),
+		documents:    make(map[int64]Document),
+		signingModes: make(map[int64]string),
+		revisions:    make(map[int64][]Revision),
+		signees:      make(map[int64][]Signee),
+		grants:       make(map[int64][]Grant),
+	}
+}
+
+func (s *memoryStore) nextID(table string) int64 {
+	s.lastID[table]++
+	return s.lastID[table]
+}
+
+// clone copies the store so that a failed atomic batch can be undone.
+func (s *memoryStore) clone() *memoryStore {
+	c := &memoryStore{
+		lastID:       make(map[string]int64, len(s.lastID)),
+		documents:    make(map[int64]Document, len(s.documents)),
+		signingModes: make(map[int64]string, len(s.signingModes)),
+		revisions:    make(map[int64][]Revision, len(s.revisions)),
+		signees:      make(map[int64][]Signee, len(s.signees)),
+		grants:       make(map[int64][]Grant, len(s.grants)),
+		keys:         append([]SigneeKey(nil), s.keys...),
+		signatures:   append([]Signature(nil), s.signatures...),
+		outbox:       append([]OutboxEvent(nil), s.outbox...),
+		audit:        append([]AuditEntry(nil), s.audit...),
+	}
+	for k, v := range s.lastID {
+		c.lastID[k] = v
+	}
+	for k, v := range s.documents {
+		c.documents[k] = v
+	}
+	for k, v := range s.signingModes {
+		c.signingModes[k] = v
+	}
+	for k, v := range s.revisions {
+		c.revisions[k] = append([]Revision(nil), v...)
+	}
+	for k, v := range s.signees {
+		c.signees[k] = append([]Signee(nil), v...)
+	}
+	for k, v := range s.grants {
+		c.grants[k] = append([]Grant(nil), v...)
+	}
+	return c
+}
+
+func (s *memoryStore) restoreFrom(c *memoryStore) {
+	s.lastID, s.documents, s.signingModes = c.lastID, c.documents, c.signingModes
+	s.revisions, s.signees, s.grants, s.keys, s.signatures = c.revisions, c.signees, c.grants, c.keys, c.signatures
+	s.outbox, s.audit = c.outbox, c.audit
+}
+
+func (s *memoryStore) titleTaken(title string, except int64) bool {
+	for id, doc := range s.documents {
+		if id != except && strings.EqualFold(doc.Title, title) {
+			return true
+		}
+	}
+	return false
+}
+
+// active returns a document outside the trash.
+func (s *memoryStore) active(id int64) (Document, error) {
+	doc, ok := s.documents[id]
+	if !ok || doc.DeletedAt != nil {
+		return Document{}, sql.ErrNoRows
+	}
+	return doc, nil
+}
+
+func (s *memoryStore) writeRevision(operation string, doc Document) {
+	revisions := s.revisions[doc.ID]
+	s.revisions[doc.ID] = append(revisions, Revision{
+		Document:  Document{ID: doc.ID, Title: doc.Title, Content: doc.Content, Signee: doc.Signee},
+		Revision:  int64(len(revisions) + 1),
+		Operation: operation,
+		CreatedAt: now().UTC(),
+		sequence:  s.nextID("document_revisions"),
+	})
+}
+
+// writeEvent records an event along with the change it reports, under the
+// lock of the store. Its payload cannot fail to marshal.
+func (s *memoryStore) writeEvent(eventType string, doc Document) {
+	event, _ := NewOutboxEvent(eventType, doc, "", now().UTC())
+	s.appendEvent(event)
+}
+
+func (s *memoryStore) appendEvent(event OutboxEvent) OutboxEvent {
+	event.ID = s.nextID("outbox")
+	s.outbox = append(s.outbox, event)
+	return event
+}
+
+// writeAudit records action on the document of an id in the audit log under
+// the lock of the store, when ctx has an auditor.
+func (s *memoryStore) writeAudit(ctx context.Context, action string, id int64, before, after *Document) {
+	if entry, ok := NewAuditEntry(ctx, action, id, before, after); ok {
+		s.appendAudit(entry)
+	}
+}
+
+// auditedDocument returns the document of an id outside the trash for the
+// entry of an action that leaves it as it was, or nil when ctx has no auditor.
+func (s *memoryStore) auditedDocument(ctx context.Context, id int64) (*Document, error) {
+	if !audited(ctx) {
+		return nil, nil
+	}
+	doc, ok := s.documents[id]
+	if !ok || doc.DeletedAt != nil {
+		return nil, sql.ErrNoRows
+	}
+	return &doc, nil
+}
+
+func (s *memoryStore) appendAudit(entry AuditEntry) AuditEntry {
+	entry.ID = int64(len(s.audit) + 1)
+	entry.PrevHash = ""
+	if len(s.audit) > 0 {
+		entry.PrevHash = s.audit[len(s.audit)-1].Hash
+	}
+	entry.CreatedAt = now().UTC().Truncate(time.Microsecond)
+	entry.Hash = entry.ComputeHash()
+	s.audit = append(s.audit, entry)
+	return entry
+}
+
+func (s *memoryStore) create(ctx context.Context, newDoc Document) (*Document, error) {
+	if s.titleTaken(newDoc.Title, 0) {
+		return nil, DuplicateTitleValue
+	}
+	newDoc.ID = s.nextID("documents")
+	newDoc.Version = 1
+	newDoc.DeletedAt = nil
+	s.documents[newDoc.ID] = newDoc
+	s.signingModes[newDoc.ID] = SigningParallel
+	s.writeRevision(RevisionCreate, newDoc)
+	s.writeEvent(EventDocumentCreated, newDoc)
+	s.writeAudit(ctx, AuditCreate, newDoc.ID, nil, &newDoc)
+	return &newDoc, nil
+}
+
+func (s *memoryStore) update(ctx context.Context, upDoc Document) (*Document, error) {
+	current, err := s.active(upDoc.ID)
+	if err != nil || current.Version != upDoc.Version {
+		return nil, VersionMismatchValue
+	}
+	if s.titleTaken(upDoc.Title, upDoc.ID) {
+		return nil, DuplicateTitleValue
+	}
+	before := current
+	current.Title, current.Content, current.Signee = upDoc.Title, upDoc.Content, upDoc.Signee
+	current.Version++
+	s.documents[current.ID] = current
+	s.writeRevision(RevisionUpdate, current)
+	s.writeEvent(EventDocumentUpdated, current)
+
+	upDoc.Version = current.Version
+	s.writeAudit(ctx, AuditUpdate, upDoc.ID, &before, &upDoc)
+	return &upDoc, nil
+}
+
+func (s *memoryStore) delete(ctx context.Context, id, version int64) error {
+	doc, err := s.active(id)
+	if err != nil {
+		return err
+	}
+	if doc.Version != version {
+		return VersionMismatchValue
+	}
+	s.writeRevision(RevisionDelete, doc)
+	s.writeEvent(EventDocumentDeleted, doc)
+	s.writeAudit(ctx, AuditDelete, id, &doc, nil)
+	deletedAt := now().UTC()
+	doc.DeletedAt = &deletedAt
+	doc.Version++
+	s.documents[id] = doc
+	return nil
+}
+
+func (s *memoryStore) purge(id int64) {
+	delete(s.documents, id)
+	delete(s.signingModes, id)
+	delete(s.signees, id)
+	delete(s.grants, id)
+}
+
+// visibleTo reports whether p owns doc or holds a grant on it.
+func (s *memoryStore) visibleTo(p *Principal, doc Document) bool {
+	if doc.Owner != "" && doc.Owner == p.Subject {
+		return true
+	}
+	for i := range s.grants[doc.ID] {
+		if p.matches(&s.grants[doc.ID][i]) {
+			return true
+		}
+	}
+	return false
+}
+
+type memoryDocumentRepository struct {
+	store *memoryStore
+}
+
+// Init has nothing to connect to and returns a nil database.
+func (r *memoryDocumentRepository) Init(string, string, string, string, string, string) *sql.DB {
+	return nil
+}
+
+func (r *memoryDocumentRepository) Get(ctx context.Context, id int64) (*Document, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	doc, err := r.store.active(id)
+	if err != nil {
+		return nil, err
+	}
+	return &doc, nil
+}
+
+func (r *memoryDocumentRepository) Create(ctx context.Context, newDoc Document) (*Document, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	return r.store.create(ctx, newDoc)
+}
+
+func (r *memoryDocumentRepository) Update(ctx context.Context, upDoc Document) (*Document, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	return r.store.update(ctx, upDoc)
+}
+
+func (r *memoryDocumentRepository) Delete(ctx context.Context, id, version int64) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	return r.store.delete(ctx, id, version)
+}
+
+func (r *memoryDocumentRepository) GetAll(ctx context.Context, opts ListOptions) (*DocumentPage, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	matched := make([]Document, 0)
+	for _, doc := range r.store.documents {
+		if (doc.DeletedAt != nil) == opts.Trashed && matchesFilters(doc, opts.Filters) &&
+			(opts.VisibleTo == nil || r.store.visibleTo(opts.VisibleTo, doc)) {
+			matched = append(matched, doc)
+		}
+	}
+	sort.Slice(matched, func(i, j int) bool {
+		return lessDocument(matched[i], matched[j], opts.Sort)
+	})
+
+	page := DocumentPage{Total: int64(len(matched)), Documents: make([]*Document, 0)}
+	skipped := 0
+	for i := range matched {
+		doc := matched[i]
+		if opts.Cursor != 0 && (opts.Sort[0].Desc && doc.ID >= opts.Cursor || !opts.Sort[0].Desc && doc.ID <= opts.Cursor) {
+			continue
+		}
+		if skipped < opts.Offset {
+			skipped++
+			continue
+		}
+		if len(page.Documents) == opts.Limit {
+			break
+		}
+		page.Documents = append(page.Documents, &doc)
+	}
+	if opts.keyedByID() && len(page.Documents) == opts.Limit {
+		page.NextCursor = EncodeCursor(page.Documents[len(page.Documents)-1].ID)
+	}
+	return &page, nil
+}
+
+func matchesFilters(doc Document, filters []Filter) bool {
+	for _, f := range filters {
+		value := doc.Title
+		if f.Field == "signee" {
+			value = doc.Signee
+		}
+		value, want := strings.ToLower(value), strings.ToLower(f.Value)
+		switch f.Operator {
+		case FilterExact:
+			if value != want {
+				return false
+			}
+		case FilterPrefix:
+			if !strings.HasPrefix(value, want) {
+				return false
+			}
+		case FilterContains:
+			if !strings.Contains(value, want) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// lessDocument orders documents like ORDER BY on the given fields, with NULL
+// deletion times first.
+func lessDocument(a, b Document, fields []SortField) bool {
+	for _, f := range fields {
+		c := 0
+		switch f.Field {
+		case "id":
+			c = compareInt64(a.ID, b.ID)
+		case "title":
+			c = strings.Compare(strings.ToLower(a.Title), strings.ToLower(b.Title))
+		case "signee":
+			c = strings.Compare(strings.ToLower(a.Signee), strings.ToLower(b.Signee))
+		case "deleted_at":
+			c = compareTime(a.DeletedAt, b.DeletedAt)
+		}
+		if c != 0 {
+			return (c < 0) != f.Desc
+		}
+	}
+	return false
+}
+
+func compareInt64(a, b int64) int {
+	if a < b {
+		return -1
+	} else if a > b {
+		return 1
+	}
+	return 0
+}
+
+func compareTime(a, b *time.Time) int {
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return -1
+	case b == nil:
+		return 1
+	case a.Before(*b):
+		return -1
+	case a.After(*b):
+		return 1
+	}
+	return 0
+}
+
+func (r *memoryDocumentRepository) GetRevisions(ctx context.Context, id int64) ([]*Revision, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	revisions := r.store.revisions[id]
+	if len(revisions) == 0 {
+		return nil, sql.ErrNoRows
+	}
+	results := make([]*Revision, len(revisions))
+	for i := range revisions {
+		rev := revisions[i]
+		results[i] = &rev
+	}
+	return results, nil
+}
+
+func (r *memoryDocumentRepository) GetRevision(ctx context.Context, id, revision int64) (*Revision, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	revisions := r.store.revisions[id]
+	if revision < 1 || revision > int64(len(revisions)) {
+		return nil, sql.ErrNoRows
+	}
+	rev := revisions[revision-1]
+	return &rev, nil
+}
+
+func (r *memoryDocumentRepository) GetAsOf(ctx context.Context, id int64, asOf time.Time) (*Document, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	revisions := r.store.revisions[id]
+	for i := len(revisions) - 1; i >= 0; i-- {
+		if revisions[i].CreatedAt.After(asOf) {
+			continue
+		}
+		if revisions[i].Operation == RevisionDelete {
+			return nil, sql.ErrNoRows
+		}
+		doc := revisions[i].Document
+		return &doc, nil
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (r *memoryDocumentRepository) GetChanges(ctx context.Context, since int64, limit int) ([]*Change, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	changes := make([]*Change, 0)
+	for _, revisions := range r.store.revisions {
+		for i := range revisions {
+			if revisions[i].sequence > since {
+				changes = append(changes, newChange(revisions[i].sequence, &revisions[i]))
+			}
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Sequence < changes[j].Sequence })
+	if len(changes) > limit {
+		changes = changes[:limit]
+	}
+	return changes, nil
+}
+
+func (r *memoryDocumentRepository) GetLastSequence(ctx context.Context) (int64, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	return r.store.lastID["document_revisions"], nil
+}
+
+func (r *memoryDocumentRepository) GetTrashed(ctx context.Context, id int64) (*Document, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	doc, ok := r.store.documents[id]
+	if !ok || doc.DeletedAt == nil {
+		return nil, sql.ErrNoRows
+	}
+	return &doc, nil
+}
+
+func (r *memoryDocumentRepository) RestoreDeleted(ctx context.Context, id int64) (*Document, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	doc, ok := r.store.documents[id]
+	if !ok || doc.DeletedAt == nil {
+		return nil, sql.ErrNoRows
+	}
+	trashed := doc
+	doc.DeletedAt = nil
+	doc.Version++
+	r.store.documents[id] = doc
+	r.store.writeRevision(RevisionRestore, doc)
+	r.store.writeEvent(EventDocumentUpdated, doc)
+	r.store.writeAudit(ctx, AuditRestore, id, &trashed, &doc)
+	return &doc, nil
+}
+
+func (r *memoryDocumentRepository) Purge(ctx context.Context, id int64) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	doc, ok := r.store.documents[id]
+	if !ok || doc.DeletedAt == nil {
+		return sql.ErrNoRows
+	}
+	r.store.purge(id)
+	r.store.writeAudit(ctx, AuditPurge, id, &doc, nil)
+	return nil
+}
+
+func (r *memoryDocumentRepository) PurgeDeletedBefore(ctx context.Context, before time.Time) (int64, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	var purged int64
+	for id, doc := range r.store.documents {
+		if doc.DeletedAt != nil && doc.DeletedAt.Before(before) {
+			r.store.purge(id)
+			purged++
+		}
+	}
+	if purged > 0 {
+		r.store.writeAudit(ctx, AuditPurge, 0, nil, nil)
+	}
+	return purged, nil
+}
+
+// Batch applies every operation under one lock. An atomic batch works on a
+// copy of the store that only replaces it once every operation succeeded.
+func (r *memoryDocumentRepository) Batch(ctx context.Context, ops []BatchOperation, mode BatchMode) ([]BatchResult, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	store := r.store
+	if mode == BatchAtomic {
+		store = r.store.clone()
+	}
+	results := make([]BatchResult, len(ops))
+	for i, op := range ops {
+		var err error
+		switch op.Op {
+		case BatchCreate:
+			results[i].Document, err = store.create(ctx, op.Document)
+		case BatchUpdate:
+			if _, err = store.active(op.ID); err == nil {
+				results[i].Document, err = store.update(ctx, op.Document)
+			}
+		case BatchDelete:
+			err = store.delete(ctx, op.ID, op.Version)
+		default:
+			err = BatchOperationInvalidValue
+		}
+		if err != nil {
+			if mode == BatchAtomic {
+				return nil, &BatchOperationError{Index: i, Err: err}
+			}
+			results[i] = BatchResult{Err: err}
+		}
+	}
+	if mode == BatchAtomic {
+		r.store.restoreFrom(store)
+	}
+	return results, nil
+}
+
+type memorySignatureRepository struct {
+	store *memoryStore
+}
+
+func (r *memorySignatureRepository) CreateKey(ctx context.Context, key SigneeKey) (*SigneeKey, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	for _, k := range r.store.keys {
+		if string(k.PublicKey) == string(key.PublicKey) {
+			return nil, DuplicatePublicKeyValue
+		}
+	}
+	key.ID = r.store.nextID("signee_keys")
+	key.CreatedAt = now().UTC()
+	r.store.keys = append(r.store.keys, key)
+	return &key, nil
+}
+
+func (r *memorySignatureRepository) GetKey(ctx context.Context, id int64) (*SigneeKey, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	for _, key := range r.store.keys {
+		if key.ID == id {
+			return &key, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (r *memorySignatureRepository) GetKeys(ctx context.Context, signee string) ([]*SigneeKey, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	results := make([]*SigneeKey, 0)
+	for i := range r.store.keys {
+		if key := r.store.keys[i]; signee == "" || key.Signee == signee {
+			results = append(results, &key)
+		}
+	}
+	return results, nil
+}
+
+func (r *memorySignatureRepository) CreateSignature(ctx context.Context, sig Signature, signee *Signee) (*Signature, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	doc, err := r.store.auditedDocument(ctx, sig.DocumentID)
+	if err != nil {
+		return nil, err
+	}
+	if signee != nil {
+		if err := r.store.updateStatus(*signee); err != nil {
+			return nil, err
+		}
+	}
+	sig.ID = r.store.nextID("document_signatures")
+	sig.CreatedAt = now().UTC()
+	r.store.signatures = append(r.store.signatures, sig)
+	r.store.writeAudit(ctx, AuditSign, sig.DocumentID, doc, doc)
+	return &sig, nil
+}
+
+func (r *memorySignatureRepository) GetSignatures(ctx context.Context, documentID int64) ([]*Signature, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	results := make([]*Signature, 0)
+	for i := range r.store.signatures {
+		if sig := r.store.signatures[i]; sig.DocumentID == documentID {
+			results = append(results, &sig)
+		}
+	}
+	return results, nil
+}
+
+type memorySigneeRepository struct {
+	store *memoryStore
+}
+
+func (r *memorySigneeRepository) GetWorkflow(ctx context.Context, documentID int64) (*SigningWorkflow, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	if _, err := r.store.active(documentID); err != nil {
+		return nil, err
+	}
+	workflow := SigningWorkflow{DocumentID: documentID, Mode: r.store.signingModes[documentID], Signees: make([]*Signee, 0)}
+	for i := range r.store.signees[documentID] {
+		s := r.store.signees[documentID][i]
+		workflow.Signees = append(workflow.Signees, &s)
+	}
+	sort.SliceStable(workflow.Signees, func(i, j int) bool {
+		return workflow.Signees[i].Position < workflow.Signees[j].Position
+	})
+	workflow.RefreshState()
+	return &workflow, nil
+}
+
+func (r *memorySigneeRepository) SetSignees(ctx context.Context, workflow SigningWorkflow) (*SigningWorkflow, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if doc, ok := r.store.documents[workflow.DocumentID]; !ok || doc.DeletedAt != nil {
+		return nil, sql.ErrNoRows
+	}
+	for _, s := range r.store.signees[workflow.DocumentID] {
+		if s.Status != SigneePending {
+			return nil, SigneesLockedValue
+		}
+	}
+	r.store.signingModes[workflow.DocumentID] = workflow.Mode
+	signees := make([]Signee, 0, len(workflow.Signees))
+	for _, s := range workflow.Signees {
+		s.ID = r.store.nextID("document_signees")
+		stored := *s
+		stored.DocumentID = workflow.DocumentID
+		signees = append(signees, stored)
+	}
+	r.store.signees[workflow.DocumentID] = signees
+	workflow.RefreshState()
+	return &workflow, nil
+}
+
+func (r *memorySigneeRepository) UpdateStatus(ctx context.Context, s Signee) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	doc, err := r.store.auditedDocument(ctx, s.DocumentID)
+	if err != nil {
+		return err
+	}
+	if err := r.store.updateStatus(s); err != nil {
+		return err
+	}
+	r.store.writeAudit(ctx, signeeAuditAction(s.Status), s.DocumentID, doc, doc)
+	return nil
+}
+
+func (s *memoryStore) updateStatus(signee Signee) error {
+	signees := s.signees[signee.DocumentID]
+	for i := range signees {
+		if signees[i].ID != signee.ID || signees[i].Status != SigneePending {
+			continue
+		}
+		signees[i].Status, signees[i].Reason = signee.Status, signee.Reason
+		signees[i].SignedAt, signees[i].DeclinedAt = signee.SignedAt, signee.DeclinedAt
+		return nil
+	}
+	return SigneeStatusConflict
+}
+
+type memoryGrantRepository struct {
+	store *memoryStore
+}
+
+func (r *memoryGrantRepository) GetAll(ctx context.Context, documentID int64) ([]*Grant, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	results := make([]*Grant, 0, len(r.store.grants[documentID]))
+	for i := range r.store.grants[documentID] {
+		g := r.store.grants[documentID][i]
+		results = append(results, &g)
+	}
+	return results, nil
+}
+
+func (r *memoryGrantRepository) Put(ctx context.Context, grant Grant) (*Grant, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	grants := r.store.grants[grant.DocumentID]
+	for i := range grants {
+		if grants[i].GranteeType == grant.GranteeType && grants[i].Grantee == grant.Grantee {
+			grants[i].Role = grant.Role
+			g := grants[i]
+			return &g, nil
+		}
+	}
+	grant.ID = r.store.nextID("document_grants")
+	grant.CreatedAt = now().UTC()
+	r.store.grants[grant.DocumentID] = append(grants, grant)
+	return &grant, nil
+}
+
+func (r *memoryGrantRepository) Delete(ctx context.Context, documentID, id int64) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	grants := r.store.grants[documentID]
+	for i := range grants {
+		if grants[i].ID == id {
+			r.store.grants[documentID] = append(grants[:i:i], grants[i+1:]...)
+			return nil
+		}
+	}
+	return sql.ErrNoRows
+}
+
+// NewMemoryAPIKeyRepository returns an API key repository keeping its keys in
+// the process.
+func NewMemoryAPIKeyRepository() apiKeyRepositoryInterface {
+	return &memoryAPIKeyRepository{}
+}
+
+type memoryAPIKeyRepository struct {
+	mu   sync.RWMutex
+	keys []APIKey
+}
+
+func (r *memoryAPIKeyRepository) Create(ctx context.Context, key APIKey) (*APIKey, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key.ID = int64(len(r.keys) + 1)
+	key.Key = ""
+	key.Roles = append([]string{}, key.Roles...)
+	key.CreatedAt = now().UTC()
+	r.keys = append(r.keys, key)
+	return &key, nil
+}
+
+func (r *memoryAPIKeyRepository) GetByHash(ctx context.Context, hash string) (*APIKey, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, key := range r.keys {
+		if key.Hash == hash && key.RevokedAt == nil {
+			return &key, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (r *memoryAPIKeyRepository) GetAll(ctx context.Context) ([]*APIKey, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	results := make([]*APIKey, 0, len(r.keys))
+	for i := range r.keys {
+		key := r.keys[i]
+		results = append(results, &key)
+	}
+	return results, nil
+}
+
+func (r *memoryAPIKeyRepository) Revoke(ctx context.Context, id int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if id < 1 || id > int64(len(r.keys)) || r.keys[id-1].RevokedAt != nil {
+		return sql.ErrNoRows
+	}
+	revokedAt := now().UTC()
+	r.keys[id-1].RevokedAt = &revokedAt
+	return nil
+}
+
+// NewMemoryAuditRepository returns an audit repository keeping its log in the
+// process, apart from any document repository.
+func NewMemoryAuditRepository() auditRepositoryInterface {
+	return &memoryAuditRepository{newMemoryStore()}
+}
+
+type memoryAuditRepository struct {
+	store *memoryStore
+}
+
+func (r *memoryAuditRepository) Append(ctx context.Context, entry AuditEntry) (*AuditEntry, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	entry = r.store.appendAudit(entry)
+	return &entry, nil
+}
+
+func (r *memoryAuditRepository) GetAll(ctx context.Context, query AuditQuery) (*AuditPage, error) {
+	if err := query.Validate(); err != nil {
+		return nil, err
+	}
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	page := AuditPage{Entries: make([]*AuditEntry, 0)}
+	for _, e := range r.store.audit {
+		if (query.Actor != "" && e.Actor != query.Actor) ||
+			(query.Action != "" && e.Action != query.Action) ||
+			(query.DocumentID != 0 && e.DocumentID != query.DocumentID) ||
+			(!query.Since.IsZero() && e.CreatedAt.Before(query.Since)) ||
+			(!query.Until.IsZero() && e.CreatedAt.After(query.Until)) {
+			continue
+		}
+		page.Total++
+		if e.ID > query.Cursor && len(page.Entries) < query.Limit {
+			e := e
+			page.Entries = append(page.Entries, &e)
+		}
+	}
+	if len(page.Entries) == query.Limit {
+		page.NextCursor = EncodeCursor(page.Entries[len(page.Entries)-1].ID)
+	}
+	return &page, nil
+}
+
+// NewMemoryWebhookRepository returns a webhook repository keeping its webhooks
+// and deliveries in the process.
+func NewMemoryWebhookRepository() webhookRepositoryInterface {
+	return &memoryWebhookRepository{}
+}
+
+type memoryWebhookRepository struct {
+	mu             sync.RWMutex
+	webhooks       []Webhook
+	deliveries     []Delivery
+	lastWebhookID  int64
+	lastDeliveryID int64
+}
+
+func (r *memoryWebhookRepository) Create(ctx context.Context, webhook Webhook) (*Webhook, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.lastWebhookID++
+	webhook.ID = r.lastWebhookID
+	webhook.Events = append([]string{}, webhook.Events...)
+	webhook.CreatedAt = now().UTC()
+	r.webhooks = append(r.webhooks, webhook)
+	return &webhook, nil
+}
+
+func (r *memoryWebhookRepository) Get(ctx context.Context, id int64) (*Webhook, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, w := range r.webhooks {
+		if w.ID == id {
+			return &w, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (r *memoryWebhookRepository) GetAll(ctx context.Context) ([]*Webhook, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	results := make([]*Webhook, 0, len(r.webhooks))
+	for i := range r.webhooks {
+		w := r.webhooks[i]
+		results = append(results, &w)
+	}
+	return results, nil
+}
+
+func (r *memoryWebhookRepository) Update(ctx context.Context, webhook Webhook) (*Webhook, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i := range r.webhooks {
+		if r.webhooks[i].ID == webhook.ID {
+			r.webhooks[i].URL = webhook.URL
+			r.webhooks[i].Events = append([]string{}, webhook.Events...)
+			r.webhooks[i].Disabled = webhook.Disabled
+			updated := r.webhooks[i]
+			return &updated, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (r *memoryWebhookRepository) Delete(ctx context.Context, id int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i := range r.webhooks {
+		if r.webhooks[i].ID == id {
+			r.webhooks = append(r.webhooks[:i:i], r.webhooks[i+1:]...)
+			kept := r.deliveries[:0:0]
+			for _, d := range r.deliveries {
+				if d.WebhookID != id {
+					kept = append(kept, d)
+				}
+			}
+			r.deliveries = kept
+			return nil
+		}
+	}
+	return sql.ErrNoRows
+}
+
+func (r *memoryWebhookRepository) CreateDeliveries(ctx context.Context, deliveries []Delivery) ([]*Delivery, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	created := make([]*Delivery, 0, len(deliveries))
+	for _, d := range deliveries {
+		r.lastDeliveryID++
+		d.ID = r.lastDeliveryID
+		d.CreatedAt = now().UTC()
+		r.deliveries = append(r.deliveries, d)
+		created = append(created, &d)
+	}
+	return created, nil
+}
+
+func (r *memoryWebhookRepository) GetDelivery(ctx context.Context, id int64) (*Delivery, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, d := range r.deliveries {
+		if d.ID == id {
+			return &d, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (r *memoryWebhookRepository) GetDeliveries(ctx context.Context, query DeliveryQuery) (*DeliveryPage, error) {
+	if err := query.Validate(); err != nil {
+		return nil, err
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	page := DeliveryPage{Deliveries: make([]*Delivery, 0)}
+	for i := len(r.deliveries) - 1; i >= 0; i-- {
+		d := r.deliveries[i]
+		if (query.WebhookID != 0 && d.WebhookID != query.WebhookID) ||
+			(query.Status != "" && d.Status != query.Status) {
+			continue
+		}
+		page.Total++
+		if (query.Cursor != 0 && d.ID >= query.Cursor) || len(page.Deliveries) == query.Limit {
+			continue
+		}
+		page.Deliveries = append(page.Deliveries, &d)
+	}
+	if len(page.Deliveries) == query.Limit {
+		page.NextCursor = EncodeCursor(page.Deliveries[len(page.Deliveries)-1].ID)
+	}
+	return &page, nil
+}
+
+func (r *memoryWebhookRepository) ClaimDue(ctx context.Context, lease time.Duration, limit int) ([]*Delivery, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	at := now().UTC()
+	leased := at.Add(lease)
+	claimed := make([]*Delivery, 0)
+	for i := range r.deliveries {
+		d := &r.deliveries[i]
+		if len(claimed) == limit {
+			break
+		}
+		if d.Status != DeliveryPending || d.NextAttemptAt == nil || d.NextAttemptAt.After(at) {
+			continue
+		}
+		d.NextAttemptAt = &leased
+		claim := *d
+		claimed = append(claimed, &claim)
+	}
+	return claimed, nil
+}
+
+func (r *memoryWebhookRepository) UpdateDelivery(ctx context.Context, delivery Delivery) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i := range r.deliveries {
+		if r.deliveries[i].ID == delivery.ID {
+			r.deliveries[i] = delivery
+			return nil
+		}
+	}
+	return sql.ErrNoRows
+}
+
+// NewMemoryHealthRepository returns a health repository for the in-memory
+// store, which is always reachable and at SchemaVersion.
+type memoryOutboxRepository struct {
+	store *memoryStore
+}
+
+func (r *memoryOutboxRepository) Append(ctx context.Context, event OutboxEvent) (*OutboxEvent, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	event = r.store.appendEvent(event)
+	return &event, nil
+}
+
+func (r *memoryOutboxRepository) ClaimDue(ctx context.Context, lease time.Duration, limit int) ([]*OutboxEvent, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	at := now().UTC()
+	leased := at.Add(lease)
+	claimed := make([]*OutboxEvent, 0)
+	for i := range r.store.outbox {
+		e := &r.store.outbox[i]
+		if len(claimed) == limit {
+			break
+		}
+		if e.NextAttemptAt == nil || e.NextAttemptAt.After(at) {
+			continue
+		}
+		e.NextAttemptAt = &leased
+		claim := *e
+		claim.DeliveredTo = append([]string(nil), e.DeliveredTo...)
+		claimed = append(claimed, &claim)
+	}
+	return claimed, nil
+}
+
+func (r *memoryOutboxRepository) Update(ctx context.Context, event OutboxEvent) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	for i := range r.store.outbox {
+		if r.store.outbox[i].ID == event.ID {
+			r.store.outbox[i] = event
+			return nil
+		}
+	}
+	return sql.ErrNoRows
+}
+
+func (r *memoryOutboxRepository) DeleteDeliveredBefore(ctx context.Context, before time.Time) (int64, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	kept := r.store.outbox[:0]
+	for _, e := range r.store.outbox {
+		if e.DeliveredAt == nil || !e.DeliveredAt.Before(before) {
+			kept = append(kept, e)
+		}
+	}
+	deleted := int64(len(r.store.outbox) - len(kept))
+	r.store.outbox = kept
+	return deleted, nil
+}
+
+func NewMemoryHealthRepository() healthRepositoryInterface {
+	return memoryHealthRepository{}
+}
+
+type memoryHealthRepository struct{}
+
+func (memoryHealthRepository) Ping(ctx context.Context) error {
+	return nil
+}
+
+func (memoryHealthRepository) SchemaVersion(ctx context.Context) (int64, bool, error) {
+	return SchemaVersion, false, nil
+}
+
+func NewMemoryIdempotencyRepository() idempotencyRepositoryInterface {
+	return &memoryIdempotencyRepository{records: make(map[[2]string]IdempotencyRecord)}
+}
+
+// memoryIdempotencyRepository keys its records by scope and key.
+type memoryIdempotencyRepository struct {
+	mu      sync.Mutex
+	lastID  int64
+	records map[[2]string]IdempotencyRecord
+}
+
+func (r *memoryIdempotencyRepository) Reserve(ctx context.Context, record IdempotencyRecord) (*IdempotencyRecord, bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	id := [2]string{record.Scope, record.Key}
+	if existing, ok := r.records[id]; ok && existing.ExpiresAt.After(now()) {
+		return &existing, false, nil
+	}
+	r.lastID++
+	record.ID = r.lastID
+	record.CreatedAt = now().UTC()
+	r.records[id] = record
+	return &record, true, nil
+}
+
+func (r *memoryIdempotencyRepository) Complete(ctx context.Context, record IdempotencyRecord) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for id, existing := range r.records {
+		if existing.ID == record.ID {
+			existing.StatusCode, existing.Header, existing.Body, existing.ExpiresAt = record.StatusCode, record.Header, record.Body, record.ExpiresAt
+			r.records[id] = existing
+			return nil
+		}
+	}
+	return sql.ErrNoRows
+}
+
+func (r *memoryIdempotencyRepository) Release(ctx context.Context, recordID int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for id, existing := range r.records {
+		if existing.ID == recordID {
+			delete(r.records, id)
+		}
+	}
+	return nil
+}
+
+func (r *memoryIdempotencyRepository) DeleteExpiredBefore(ctx context.Context, before time.Time) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var deleted int64
+	for id, existing := range r.records {
+		if existing.ExpiresAt.Before(before) {
+			delete(r.records, id)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
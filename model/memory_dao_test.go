@@ -0,0 +1,31 @@
+package model
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+)
+
+func TestMemoryRepository_Concurrent(t *testing.T) {
+	r, _, signees, _, _, _ := NewMemoryRepositories()
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			doc, err := r.Create(context.Background(), Document{Title: fmt.Sprint("title ", i), Signee: "signee"})
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			r.GetAll(context.Background(), ListOptions{})
+			signees.SetSignees(context.Background(), SigningWorkflow{DocumentID: doc.ID, Mode: SigningParallel, Signees: []*Signee{{Name: "alice", Status: SigneePending}}})
+			signees.GetWorkflow(context.Background(), doc.ID)
+		}(i)
+	}
+	wg.Wait()
+	if page, _ := r.GetAll(context.Background(), ListOptions{}); page.Total != 50 {
+		t.Errorf("GetAll() total = %d, want 50", page.Total)
+	}
+}
@@ -0,0 +1,109 @@
+package model
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+)
+
+// The document lifecycle events, which the outbox records and webhooks
+// subscribe to.
+const (
+	EventDocumentCreated = "document.created"
+	EventDocumentUpdated = "document.updated"
+	EventDocumentDeleted = "document.deleted"
+	EventDocumentSigned  = "document.signed"
+)
+
+var documentEvents = map[string]bool{
+	EventDocumentCreated: true,
+	EventDocumentUpdated: true,
+	EventDocumentDeleted: true,
+	EventDocumentSigned:  true,
+}
+
+// Event is the JSON payload of an outbox event, which the sinks and webhooks
+// receive. Document is the document after the change, or before it for a
+// deletion; Signee is who signed it.
+type Event struct {
+	ID        string    `json:"id"`
+	Type      string    `json:"type"`
+	CreatedAt time.Time `json:"created_at"`
+	Document  *Document `json:"document"`
+	Signee    string    `json:"signee,omitempty"`
+}
+
+// OutboxEvent is an event written to the outbox along with the change it
+// reports, which the relay then hands to every sink. NextAttemptAt is set
+// until every sink received it, DeliveredTo names those that did so far and
+// Error is why the last attempt failed.
+type OutboxEvent struct {
+	ID            int64           `json:"id"`
+	EventID       string          `json:"event_id"`
+	Event         string          `json:"event"`
+	DocumentID    int64           `json:"document_id"`
+	Payload       json.RawMessage `json:"payload"`
+	Attempts      int             `json:"attempts"`
+	NextAttemptAt *time.Time      `json:"next_attempt_at,omitempty"`
+	DeliveredTo   []string        `json:"delivered_to,omitempty"`
+	Error         string          `json:"error,omitempty"`
+	CreatedAt     time.Time       `json:"created_at"`
+	DeliveredAt   *time.Time      `json:"delivered_at,omitempty"`
+}
+
+// NewOutboxEvent is the event reporting a change of doc at the given time,
+// due at once.
+func NewOutboxEvent(eventType string, doc Document, signee string, at time.Time) (OutboxEvent, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return OutboxEvent{}, err
+	}
+	event := Event{
+		ID:        hex.EncodeToString(id),
+		Type:      eventType,
+		CreatedAt: at,
+		Document:  &doc,
+		Signee:    signee,
+	}
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return OutboxEvent{}, err
+	}
+	return OutboxEvent{
+		EventID:       event.ID,
+		Event:         eventType,
+		DocumentID:    doc.ID,
+		Payload:       payload,
+		NextAttemptAt: &at,
+		CreatedAt:     at,
+	}, nil
+}
+
+// DeliveredToSink reports whether the named sink already received the event.
+func (e *OutboxEvent) DeliveredToSink(sink string) bool {
+	for _, name := range e.DeliveredTo {
+		if name == sink {
+			return true
+		}
+	}
+	return false
+}
+
+// Succeed records an attempt after which every sink received the event.
+func (e *OutboxEvent) Succeed(now time.Time) {
+	e.Attempts++
+	e.Error = ""
+	e.NextAttemptAt = nil
+	e.DeliveredAt = &now
+}
+
+// Fail records an attempt after which some sink has yet to receive the event,
+// and schedules the next one like for webhook deliveries. The outbox never
+// gives up on an event.
+func (e *OutboxEvent) Fail(err string, now time.Time, base, maxBackoff time.Duration) {
+	e.Attempts++
+	e.Error = truncateError(err)
+	next := now.Add(backoff(e.Attempts, base, maxBackoff))
+	e.NextAttemptAt = &next
+}
@@ -0,0 +1,179 @@
+package model
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+)
+
+var (
+	OutboxRepository outboxRepositoryInterface = &outboxRepository{}
+)
+
+// outboxRepositoryInterface relays the outbox. The document repositories
+// write to it themselves, within the transaction of each change.
+type outboxRepositoryInterface interface {
+	Append(context.Context, OutboxEvent) (*OutboxEvent, error)
+	ClaimDue(context.Context, time.Duration, int) ([]*OutboxEvent, error)
+	Update(context.Context, OutboxEvent) error
+	DeleteDeliveredBefore(context.Context, time.Time) (int64, error)
+}
+
+type outboxRepository struct {
+	db *dialectDB
+}
+
+func NewOutboxRepository(db *sql.DB, dialect *Dialect) outboxRepositoryInterface {
+	return &outboxRepository{db: newDialectDB(db, dialect)}
+}
+
+const outboxColumns = "id, event_id, event, document_id, payload, attempts, next_attempt_at, delivered_to, last_error, created_at, delivered_at"
+
+func scanOutboxEvent(scanner interface{ Scan(...interface{}) error }) (*OutboxEvent, error) {
+	var e OutboxEvent
+	var payload []byte
+	var deliveredTo string
+	if err := scanner.Scan(&e.ID, &e.EventID, &e.Event, &e.DocumentID, &payload, &e.Attempts, &e.NextAttemptAt, &deliveredTo, &e.Error, &e.CreatedAt, &e.DeliveredAt); err != nil {
+		return nil, err
+	}
+	e.Payload = payload
+	if deliveredTo != "" {
+		e.DeliveredTo = strings.Split(deliveredTo, ",")
+	}
+	return &e, nil
+}
+
+// writeEvent records the event reporting a change of doc within the
+// transaction making it.
+func writeEvent(ctx context.Context, tx *dialectTx, eventType string, doc Document) error {
+	event, err := NewOutboxEvent(eventType, doc, "", now().UTC())
+	if err != nil {
+		return err
+	}
+	_, err = insertOutboxEvent(ctx, tx.dialect, tx, event)
+	return err
+}
+
+func insertOutboxEvent(ctx context.Context, dialect *Dialect, p preparer, e OutboxEvent) (int64, error) {
+	return dialect.insert(ctx, p, "INSERT INTO outbox(event_id, event, document_id, payload, next_attempt_at, created_at) VALUES(?, ?, ?, ?, ?, ?)",
+		e.EventID, e.Event, e.DocumentID, string(e.Payload), e.NextAttemptAt, e.CreatedAt)
+}
+
+// Append records an event outside of a document transaction, for changes
+// that the document repository does not make.
+func (r *outboxRepository) Append(ctx context.Context, event OutboxEvent) (_ *OutboxEvent, err error) {
+	ctx, done := startOperation(ctx, "outboxRepository.Append")
+	defer done(&err)
+
+	event.ID, err = insertOutboxEvent(ctx, r.db.dialect, r.db, event)
+	if err != nil {
+		return nil, err
+	}
+	return &event, nil
+}
+
+// ClaimDue returns up to limit events due for an attempt in the order they
+// were written, after pushing that attempt back by lease so that no other
+// relay claims them meanwhile.
+func (r *outboxRepository) ClaimDue(ctx context.Context, lease time.Duration, limit int) (_ []*OutboxEvent, err error) {
+	ctx, done := startOperation(ctx, "outboxRepository.ClaimDue")
+	defer done(&err)
+
+	claimed := make([]*OutboxEvent, 0)
+	err = inTx(ctx, r.db, func(tx *dialectTx) error {
+		at := now().UTC()
+		stmt, err := tx.PrepareContext(ctx, "SELECT "+outboxColumns+" FROM outbox WHERE next_attempt_at <= ? ORDER BY id LIMIT ?")
+		if err != nil {
+			return err
+		}
+		defer stmt.Close()
+
+		rows, err := stmt.QueryContext(ctx, at, limit)
+		if err != nil {
+			return err
+		}
+		due := make([]*OutboxEvent, 0)
+		for rows.Next() {
+			e, err := scanOutboxEvent(rows)
+			if err != nil {
+				rows.Close()
+				return err
+			}
+			due = append(due, e)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		claimStmt, err := tx.PrepareContext(ctx, "UPDATE outbox SET next_attempt_at = ? WHERE id = ? AND next_attempt_at <= ?")
+		if err != nil {
+			return err
+		}
+		defer claimStmt.Close()
+
+		leased := at.Add(lease)
+		for _, e := range due {
+			result, err := claimStmt.ExecContext(ctx, leased, e.ID, at)
+			if err != nil {
+				return err
+			}
+			if affected, err := result.RowsAffected(); err != nil {
+				return err
+			} else if affected == 1 {
+				e.NextAttemptAt = &leased
+				claimed = append(claimed, e)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return claimed, nil
+}
+
+// Update records the outcome of an attempt.
+func (r *outboxRepository) Update(ctx context.Context, e OutboxEvent) (err error) {
+	ctx, done := startOperation(ctx, "outboxRepository.Update")
+	defer done(&err)
+
+	stmt, err := r.db.PrepareContext(ctx, "UPDATE outbox SET attempts = ?, next_attempt_at = ?, delivered_to = ?, last_error = ?, delivered_at = ? WHERE id = ?")
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	result, err := stmt.ExecContext(ctx, e.Attempts, e.NextAttemptAt, strings.Join(e.DeliveredTo, ","), e.Error, e.DeliveredAt, e.ID)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// DeleteDeliveredBefore deletes the events every sink received before the
+// given time and returns how many were deleted.
+func (r *outboxRepository) DeleteDeliveredBefore(ctx context.Context, before time.Time) (_ int64, err error) {
+	ctx, done := startOperation(ctx, "outboxRepository.DeleteDeliveredBefore")
+	defer done(&err)
+
+	stmt, err := r.db.PrepareContext(ctx, "DELETE FROM outbox WHERE delivered_at < ?")
+	if err != nil {
+		return 0, err
+	}
+	defer stmt.Close()
+
+	result, err := stmt.ExecContext(ctx, before.UTC())
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
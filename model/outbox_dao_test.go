@@ -0,0 +1,107 @@
+package model
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/DATA-DOG/go-sqlmock"
+	"testing"
+	"time"
+)
+
+// expectEvent expects the statement writeEvent issues for an event.
+func expectEvent(mock sqlmock.Sqlmock, documentID int64, event string) {
+	mock.ExpectPrepare("INSERT INTO outbox").ExpectExec().
+		WithArgs(sqlmock.AnyArg(), event, documentID, sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+}
+
+func TestOutboxEvent_Fail(t *testing.T) {
+	at := time.Date(2022, 1, 10, 9, 0, 0, 0, time.UTC)
+	e := OutboxEvent{Attempts: 2, DeliveredTo: []string{"log"}}
+	e.Fail("file: disk full", at, time.Second, time.Minute)
+	if e.Attempts != 3 || e.Error != "file: disk full" || e.NextAttemptAt == nil || !e.NextAttemptAt.Equal(at.Add(4*time.Second)) {
+		t.Errorf("Fail() = %+v, want the next attempt in 4s", e)
+	}
+	if !e.DeliveredToSink("log") || e.DeliveredToSink("file") {
+		t.Errorf("DeliveredTo = %v, want log only", e.DeliveredTo)
+	}
+	e.Succeed(at)
+	if e.NextAttemptAt != nil || e.DeliveredAt == nil || e.Error != "" {
+		t.Errorf("Succeed() = %+v, want delivered", e)
+	}
+}
+
+func TestOutboxRepositories(t *testing.T) {
+	db := openSQLite(t)
+	memoryDocuments, _, _, _, memoryOutbox, _ := NewMemoryRepositories()
+	repositories := map[string]struct {
+		documents documentRepositoryInterface
+		outbox    outboxRepositoryInterface
+	}{
+		"Memory": {memoryDocuments, memoryOutbox},
+		"SQLite": {NewDocumentRepository(db, SQLite), NewOutboxRepository(db, SQLite)},
+	}
+	for name, r := range repositories {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			doc, err := r.documents.Create(ctx, Document{Title: "title", Signee: "signee", Owner: "owner"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			doc.Title = "new title"
+			if doc, err = r.documents.Update(ctx, *doc); err != nil {
+				t.Fatal(err)
+			}
+			if err := r.documents.Delete(ctx, doc.ID, doc.Version); err != nil {
+				t.Fatal(err)
+			}
+			// A batch rolled back writes no event.
+			_, err = r.documents.Batch(ctx, []BatchOperation{
+				{Op: BatchCreate, Document: Document{Title: "other", Owner: "owner"}},
+				{Op: BatchCreate, Document: Document{Title: "other", Owner: "owner"}},
+			}, BatchAtomic)
+			if err == nil {
+				t.Fatal("Batch() of duplicate titles succeeded")
+			}
+
+			claimed, err := r.outbox.ClaimDue(ctx, time.Minute, 10)
+			if err != nil || len(claimed) != 3 {
+				t.Fatalf("ClaimDue() = %v, %v, want 3 events", claimed, err)
+			}
+			for i, want := range []string{EventDocumentCreated, EventDocumentUpdated, EventDocumentDeleted} {
+				var event Event
+				if err := json.Unmarshal(claimed[i].Payload, &event); err != nil {
+					t.Fatal(err)
+				}
+				if claimed[i].Event != want || event.Type != want || event.ID != claimed[i].EventID || event.Document.ID != doc.ID || claimed[i].DocumentID != doc.ID {
+					t.Errorf("event %d = %+v, %+v, want %s of document %d", i, claimed[i], event, want, doc.ID)
+				}
+			}
+			if again, err := r.outbox.ClaimDue(ctx, time.Minute, 10); err != nil || len(again) != 0 {
+				t.Errorf("ClaimDue() while leased = %v, %v, want none", again, err)
+			}
+
+			at := now().UTC()
+			claimed[0].Succeed(at)
+			claimed[1].DeliveredTo = []string{"log"}
+			claimed[1].Fail("http: 503 Service Unavailable", at, -time.Second, -time.Second)
+			for _, e := range claimed[:2] {
+				if err := r.outbox.Update(ctx, *e); err != nil {
+					t.Fatal(err)
+				}
+			}
+			retried, err := r.outbox.ClaimDue(ctx, time.Minute, 10)
+			if err != nil || len(retried) != 1 || retried[0].ID != claimed[1].ID || !retried[0].DeliveredToSink("log") || retried[0].Attempts != 1 {
+				t.Fatalf("ClaimDue() after a failure = %v, %v, want the failed event", retried, err)
+			}
+
+			if deleted, err := r.outbox.DeleteDeliveredBefore(ctx, at.Add(time.Second)); err != nil || deleted != 1 {
+				t.Errorf("DeleteDeliveredBefore() = %d, %v, want 1", deleted, err)
+			}
+			appended, err := r.outbox.Append(ctx, OutboxEvent{EventID: "e1", Event: EventDocumentSigned, DocumentID: doc.ID, Payload: []byte(`{}`), NextAttemptAt: &at, CreatedAt: at})
+			if err != nil || appended.ID <= claimed[2].ID {
+				t.Errorf("Append() = %v, %v", appended, err)
+			}
+		})
+	}
+}
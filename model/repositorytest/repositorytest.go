@@ -0,0 +1,533 @@
+// Package repositorytest checks that an implementation of the document
+// repository behaves like the MySQL one, so that every backend can prove its
+// conformance by running the same suite against itself.
+package repositorytest
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"precisely/model"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+// TestDocumentRepository runs the conformance suite. newRepository must return
+// a repository over an empty store each time it is called.
+func TestDocumentRepository(t *testing.T, newRepository func(t *testing.T) model.DocumentRepositoryInterface) {
+	tests := []struct {
+		name string
+		test func(*testing.T, model.DocumentRepositoryInterface)
+	}{
+		{"CreateAndGet", testCreateAndGet},
+		{"ContentRoundTrip", testContentRoundTrip},
+		{"NotFound", testNotFound},
+		{"Update", testUpdate},
+		{"UniqueTitle", testUniqueTitle},
+		{"Trash", testTrash},
+		{"GetAll", testGetAll},
+		{"Revisions", testRevisions},
+		{"Changes", testChanges},
+		{"Batch", testBatch},
+		{"ConcurrentWriters", testConcurrentWriters},
+		{"FollowChanges", testFollowChanges},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.test(t, newRepository(t))
+		})
+	}
+}
+
+func create(t *testing.T, r model.DocumentRepositoryInterface, title string) *model.Document {
+	t.Helper()
+	doc, err := r.Create(context.Background(), model.Document{Title: title, Content: model.Content{Header: "header", Data: "data"}, Signee: "signee", Owner: "owner"})
+	if err != nil {
+		t.Fatalf("Create(%q) error = %v", title, err)
+	}
+	return doc
+}
+
+func testCreateAndGet(t *testing.T, r model.DocumentRepositoryInterface) {
+	first := create(t, r, "first")
+	second := create(t, r, "second")
+	if first.ID <= 0 || second.ID <= first.ID {
+		t.Errorf("Create() ids = %d, %d, want increasing positive ids", first.ID, second.ID)
+	}
+	if first.Version != 1 {
+		t.Errorf("Create() version = %d, want 1", first.Version)
+	}
+	if first.Owner != "owner" {
+		t.Errorf("Create() owner = %q, want %q", first.Owner, "owner")
+	}
+
+	got, err := r.Get(context.Background(), second.ID)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, second) {
+		t.Errorf("Get() = %+v, want %+v", got, second)
+	}
+}
+
+func testContentRoundTrip(t *testing.T, r model.DocumentRepositoryInterface) {
+	for i, content := range []model.Content{
+		{},
+		{Header: `quote " and <html> & ünïcödé ✓`, Data: "line\nbreak, tab\t and \\ backslash"},
+		{Header: `{"looks": "like json"}`, Data: "null"},
+	} {
+		doc, err := r.Create(context.Background(), model.Document{Title: fmt.Sprint("content ", i), Content: content, Signee: "signee"})
+		if err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+		got, err := r.Get(context.Background(), doc.ID)
+		if err != nil {
+			t.Fatalf("Get() error = %v", err)
+		}
+		if got.Content != content {
+			t.Errorf("Get() content = %+v, want %+v", got.Content, content)
+		}
+	}
+}
+
+func testNotFound(t *testing.T, r model.DocumentRepositoryInterface) {
+	doc := create(t, r, "title")
+	missing := doc.ID + 100
+
+	if _, err := r.Get(context.Background(), missing); err != sql.ErrNoRows {
+		t.Errorf("Get() error = %v, want %v", err, sql.ErrNoRows)
+	}
+	if err := r.Delete(context.Background(), missing, 1); err != sql.ErrNoRows {
+		t.Errorf("Delete() error = %v, want %v", err, sql.ErrNoRows)
+	}
+	if _, err := r.Update(context.Background(), model.Document{ID: missing, Title: "other", Signee: "signee", Version: 1}); err != model.VersionMismatchValue {
+		t.Errorf("Update() error = %v, want %v", err, model.VersionMismatchValue)
+	}
+	if _, err := r.GetRevisions(context.Background(), missing); err != sql.ErrNoRows {
+		t.Errorf("GetRevisions() error = %v, want %v", err, sql.ErrNoRows)
+	}
+	if _, err := r.GetRevision(context.Background(), doc.ID, 2); err != sql.ErrNoRows {
+		t.Errorf("GetRevision() error = %v, want %v", err, sql.ErrNoRows)
+	}
+	if _, err := r.RestoreDeleted(context.Background(), doc.ID); err != sql.ErrNoRows {
+		t.Errorf("RestoreDeleted() of a live document error = %v, want %v", err, sql.ErrNoRows)
+	}
+	if err := r.Purge(context.Background(), doc.ID); err != sql.ErrNoRows {
+		t.Errorf("Purge() of a live document error = %v, want %v", err, sql.ErrNoRows)
+	}
+}
+
+func testUpdate(t *testing.T, r model.DocumentRepositoryInterface) {
+	doc := create(t, r, "title")
+	doc.Title, doc.Content.Data = "new title", "new data"
+	updated, err := r.Update(context.Background(), *doc)
+	if err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if updated.Version != 2 {
+		t.Errorf("Update() version = %d, want 2", updated.Version)
+	}
+	if _, err := r.Update(context.Background(), *doc); err != model.VersionMismatchValue {
+		t.Errorf("Update() at a stale version error = %v, want %v", err, model.VersionMismatchValue)
+	}
+
+	got, err := r.Get(context.Background(), doc.ID)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if got.Title != "new title" || got.Content.Data != "new data" || got.Version != 2 {
+		t.Errorf("Get() = %+v, want the update", got)
+	}
+}
+
+func testUniqueTitle(t *testing.T, r model.DocumentRepositoryInterface) {
+	first := create(t, r, "first")
+	second := create(t, r, "second")
+
+	if _, err := r.Create(context.Background(), model.Document{Title: "first", Signee: "signee"}); !errors.Is(err, model.DuplicateTitleValue) {
+		t.Errorf("Create() with a taken title error = %v, want %v", err, model.DuplicateTitleValue)
+	}
+	second.Title = "first"
+	if _, err := r.Update(context.Background(), *second); !errors.Is(err, model.DuplicateTitleValue) {
+		t.Errorf("Update() to a taken title error = %v, want %v", err, model.DuplicateTitleValue)
+	}
+	if got, _ := r.Get(context.Background(), second.ID); got == nil || got.Title != "second" || got.Version != 1 {
+		t.Errorf("Get() after a conflicting update = %+v", got)
+	}
+
+	if err := r.Delete(context.Background(), first.ID, first.Version); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if _, err := r.Create(context.Background(), model.Document{Title: "first", Signee: "signee"}); !errors.Is(err, model.DuplicateTitleValue) {
+		t.Errorf("Create() with the title of a trashed document error = %v, want %v", err, model.DuplicateTitleValue)
+	}
+	page, err := r.GetAll(context.Background(), model.ListOptions{})
+	if err != nil || page.Total != 1 {
+		t.Errorf("GetAll() after conflicts = %+v, %v, want 1 document", page, err)
+	}
+}
+
+func testTrash(t *testing.T, r model.DocumentRepositoryInterface) {
+	doc := create(t, r, "title")
+	if err := r.Delete(context.Background(), doc.ID, 2); err != model.VersionMismatchValue {
+		t.Errorf("Delete() at a wrong version error = %v, want %v", err, model.VersionMismatchValue)
+	}
+	if err := r.Delete(context.Background(), doc.ID, 1); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if _, err := r.Get(context.Background(), doc.ID); err != sql.ErrNoRows {
+		t.Errorf("Get() of a trashed document error = %v, want %v", err, sql.ErrNoRows)
+	}
+	trashed, err := r.GetTrashed(context.Background(), doc.ID)
+	if err != nil || trashed.DeletedAt == nil || trashed.Owner != doc.Owner {
+		t.Errorf("GetTrashed() = %+v, %v", trashed, err)
+	}
+	trash, err := r.GetAll(context.Background(), model.ListOptions{Trashed: true})
+	if err != nil || trash.Total != 1 || trash.Documents[0].DeletedAt == nil || trash.Documents[0].Version != 2 {
+		t.Fatalf("GetAll() trashed = %+v, %v", trash, err)
+	}
+
+	restored, err := r.RestoreDeleted(context.Background(), doc.ID)
+	if err != nil || restored.DeletedAt != nil || restored.Version != 3 {
+		t.Fatalf("RestoreDeleted() = %+v, %v", restored, err)
+	}
+	if _, err := r.Get(context.Background(), doc.ID); err != nil {
+		t.Errorf("Get() of a restored document error = %v", err)
+	}
+	if _, err := r.GetTrashed(context.Background(), doc.ID); err != sql.ErrNoRows {
+		t.Errorf("GetTrashed() of a restored document error = %v, want %v", err, sql.ErrNoRows)
+	}
+
+	if err := r.Delete(context.Background(), doc.ID, 3); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if err := r.Purge(context.Background(), doc.ID); err != nil {
+		t.Fatalf("Purge() error = %v", err)
+	}
+	if _, err := r.RestoreDeleted(context.Background(), doc.ID); err != sql.ErrNoRows {
+		t.Errorf("RestoreDeleted() of a purged document error = %v, want %v", err, sql.ErrNoRows)
+	}
+	if _, err := r.GetRevisions(context.Background(), doc.ID); err != nil {
+		t.Errorf("GetRevisions() of a purged document error = %v, want its revisions kept", err)
+	}
+	create(t, r, "title")
+}
+
+func testGetAll(t *testing.T, r model.DocumentRepositoryInterface) {
+	ids := make(map[string]int64)
+	for _, title := range []string{"banana", "apple", "cherry", "50% off", "apple_pie"} {
+		ids[title] = create(t, r, title).ID
+	}
+
+	page, err := r.GetAll(context.Background(), model.ListOptions{Sort: []model.SortField{{Field: "title", Desc: true}}})
+	if err != nil {
+		t.Fatalf("GetAll() error = %v", err)
+	}
+	if got := titles(page); got != "[cherry banana apple_pie apple 50% off]" || page.Total != 5 {
+		t.Errorf("GetAll() sorted by title = %s, total %d", got, page.Total)
+	}
+
+	filters := []struct {
+		filter model.Filter
+		want   string
+	}{
+		{model.Filter{Field: "title", Operator: model.FilterExact, Value: "apple"}, "[apple]"},
+		{model.Filter{Field: "title", Operator: model.FilterPrefix, Value: "APP"}, "[apple apple_pie]"},
+		{model.Filter{Field: "title", Operator: model.FilterPrefix, Value: "apple_"}, "[apple_pie]"},
+		{model.Filter{Field: "title", Operator: model.FilterContains, Value: "0%"}, "[50% off]"},
+		{model.Filter{Field: "signee", Operator: model.FilterExact, Value: "nobody"}, "[]"},
+	}
+	for _, f := range filters {
+		page, err := r.GetAll(context.Background(), model.ListOptions{Filters: []model.Filter{f.filter}, Sort: []model.SortField{{Field: "title"}}})
+		if err != nil {
+			t.Fatalf("GetAll() error = %v", err)
+		}
+		if got := titles(page); got != f.want {
+			t.Errorf("GetAll() %s %s %q = %s, want %s", f.filter.Field, f.filter.Operator, f.filter.Value, got, f.want)
+		}
+	}
+
+	page, err = r.GetAll(context.Background(), model.ListOptions{Limit: 2, Offset: 1, Sort: []model.SortField{{Field: "title"}}})
+	if err != nil || titles(page) != "[apple apple_pie]" || page.Total != 5 {
+		t.Errorf("GetAll() limit 2 offset 1 = %+v, %v", page, err)
+	}
+
+	var seen []int64
+	opts := model.ListOptions{Limit: 2, Sort: []model.SortField{{Field: "id", Desc: true}}}
+	for {
+		page, err := r.GetAll(context.Background(), opts)
+		if err != nil {
+			t.Fatalf("GetAll() error = %v", err)
+		}
+		for _, doc := range page.Documents {
+			seen = append(seen, doc.ID)
+		}
+		if page.NextCursor == "" {
+			break
+		}
+		if opts.Cursor, err = model.DecodeCursor(page.NextCursor); err != nil {
+			t.Fatalf("DecodeCursor() error = %v", err)
+		}
+	}
+	want := []int64{ids["apple_pie"], ids["50% off"], ids["cherry"], ids["apple"], ids["banana"]}
+	if !reflect.DeepEqual(seen, want) {
+		t.Errorf("GetAll() by cursor = %v, want %v", seen, want)
+	}
+}
+
+func titles(page *model.DocumentPage) string {
+	titles := make([]string, 0, len(page.Documents))
+	for _, doc := range page.Documents {
+		titles = append(titles, doc.Title)
+	}
+	return fmt.Sprint(titles)
+}
+
+func testRevisions(t *testing.T, r model.DocumentRepositoryInterface) {
+	doc := create(t, r, "title")
+	doc.Content.Data = "new data"
+	if _, err := r.Update(context.Background(), *doc); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if err := r.Delete(context.Background(), doc.ID, 2); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+
+	revisions, err := r.GetRevisions(context.Background(), doc.ID)
+	if err != nil {
+		t.Fatalf("GetRevisions() error = %v", err)
+	}
+	operations := make([]string, 0, len(revisions))
+	for i, rev := range revisions {
+		if rev.Revision != int64(i+1) || rev.ID != doc.ID {
+			t.Errorf("GetRevisions()[%d] = %+v", i, rev)
+		}
+		operations = append(operations, rev.Operation)
+	}
+	if fmt.Sprint(operations) != "[create update delete]" {
+		t.Errorf("GetRevisions() operations = %v", operations)
+	}
+
+	rev, err := r.GetRevision(context.Background(), doc.ID, 1)
+	if err != nil || rev.Content.Data != "data" {
+		t.Errorf("GetRevision() = %+v, %v", rev, err)
+	}
+	asOf, err := r.GetAsOf(context.Background(), doc.ID, revisions[1].CreatedAt)
+	if err != nil || asOf.Content.Data != "new data" {
+		t.Errorf("GetAsOf() the update = %+v, %v", asOf, err)
+	}
+	if _, err := r.GetAsOf(context.Background(), doc.ID, revisions[2].CreatedAt); err != sql.ErrNoRows {
+		t.Errorf("GetAsOf() the delete error = %v, want %v", err, sql.ErrNoRows)
+	}
+	if _, err := r.GetAsOf(context.Background(), doc.ID, revisions[0].CreatedAt.Add(-1e9)); err != sql.ErrNoRows {
+		t.Errorf("GetAsOf() before creation error = %v, want %v", err, sql.ErrNoRows)
+	}
+}
+
+func testChanges(t *testing.T, r model.DocumentRepositoryInterface) {
+	if last, err := r.GetLastSequence(context.Background()); err != nil || last != 0 {
+		t.Errorf("GetLastSequence() of an empty store = %d, %v, want 0", last, err)
+	}
+	doc := create(t, r, "title")
+	other := create(t, r, "other")
+	doc.Title = "new title"
+	if _, err := r.Update(context.Background(), *doc); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if err := r.Delete(context.Background(), other.ID, 1); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if _, err := r.RestoreDeleted(context.Background(), other.ID); err != nil {
+		t.Fatalf("RestoreDeleted() error = %v", err)
+	}
+
+	changes, err := r.GetChanges(context.Background(), 0, 10)
+	if err != nil {
+		t.Fatalf("GetChanges() error = %v", err)
+	}
+	var got []string
+	for i, change := range changes {
+		if i > 0 && change.Sequence <= changes[i-1].Sequence {
+			t.Errorf("GetChanges()[%d] sequence = %d, want more than %d", i, change.Sequence, changes[i-1].Sequence)
+		}
+		if (change.Document == nil) != (change.Operation == model.ChangeDelete) {
+			t.Errorf("GetChanges()[%d] = %+v, want a document unless deleted", i, change)
+		}
+		got = append(got, fmt.Sprintf("%s %d", change.Operation, change.DocumentID))
+	}
+	want := fmt.Sprintf("[create %d create %d update %d delete %d update %d]", doc.ID, other.ID, doc.ID, other.ID, other.ID)
+	if fmt.Sprint(got) != want {
+		t.Errorf("GetChanges() = %v, want %s", got, want)
+	}
+	if changes[2].Document.Title != "new title" {
+		t.Errorf("GetChanges()[2] title = %q, want %q", changes[2].Document.Title, "new title")
+	}
+
+	resumed, err := r.GetChanges(context.Background(), changes[1].Sequence, 2)
+	if err != nil || len(resumed) != 2 || resumed[0].Sequence != changes[2].Sequence || resumed[1].Sequence != changes[3].Sequence {
+		t.Errorf("GetChanges() after %d = %+v, %v", changes[1].Sequence, resumed, err)
+	}
+	if rest, err := r.GetChanges(context.Background(), changes[4].Sequence, 10); err != nil || len(rest) != 0 {
+		t.Errorf("GetChanges() after the last = %+v, %v", rest, err)
+	}
+	if changes[3].Signee != "signee" {
+		t.Errorf("GetChanges()[3] signee = %q, want the signee of the deleted document", changes[3].Signee)
+	}
+	if last, err := r.GetLastSequence(context.Background()); err != nil || last != changes[4].Sequence {
+		t.Errorf("GetLastSequence() = %d, %v, want %d", last, err, changes[4].Sequence)
+	}
+}
+
+func testBatch(t *testing.T, r model.DocumentRepositoryInterface) {
+	doc := create(t, r, "title")
+
+	_, err := r.Batch(context.Background(), []model.BatchOperation{
+		{Op: model.BatchCreate, Document: model.Document{Title: "rolled back", Signee: "signee"}},
+		{Op: model.BatchDelete, Document: model.Document{ID: doc.ID, Version: 2}},
+	}, model.BatchAtomic)
+	var opErr *model.BatchOperationError
+	if !errors.As(err, &opErr) || opErr.Index != 1 || !errors.Is(err, model.VersionMismatchValue) {
+		t.Errorf("Batch() atomic error = %v, want operation 1 to mismatch", err)
+	}
+	if page, _ := r.GetAll(context.Background(), model.ListOptions{}); page == nil || page.Total != 1 {
+		t.Errorf("GetAll() after a failed atomic batch = %+v, want 1 document", page)
+	}
+
+	results, err := r.Batch(context.Background(), []model.BatchOperation{
+		{Op: model.BatchCreate, Document: model.Document{Title: "title", Signee: "signee"}},
+		{Op: model.BatchCreate, Document: model.Document{Title: "kept", Signee: "signee"}},
+		{Op: model.BatchUpdate, Document: model.Document{ID: doc.ID, Title: "renamed", Signee: "signee", Version: 1}},
+		{Op: model.BatchDelete, Document: model.Document{ID: doc.ID + 100, Version: 1}},
+	}, model.BatchBestEffort)
+	if err != nil {
+		t.Fatalf("Batch() best effort error = %v", err)
+	}
+	if results[0].Err == nil || results[1].Document == nil || results[2].Document == nil || results[2].Document.Version != 2 || results[3].Err != sql.ErrNoRows {
+		t.Errorf("Batch() best effort = %+v", results)
+	}
+	page, err := r.GetAll(context.Background(), model.ListOptions{Sort: []model.SortField{{Field: "title"}}})
+	if err != nil || titles(page) != "[kept renamed]" {
+		t.Errorf("GetAll() after a best effort batch = %+v, %v", page, err)
+	}
+}
+
+func testConcurrentWriters(t *testing.T, r model.DocumentRepositoryInterface) {
+	const writers = 10
+	doc := create(t, r, "contended")
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	created := make(map[int64]bool)
+	var updated, sameTitle int
+	for i := 0; i < writers; i++ {
+		wg.Add(3)
+		go func(i int) {
+			defer wg.Done()
+			d, err := r.Create(context.Background(), model.Document{Title: fmt.Sprint("writer ", i), Signee: "signee"})
+			if err != nil {
+				t.Errorf("Create() error = %v", err)
+				return
+			}
+			mu.Lock()
+			created[d.ID] = true
+			mu.Unlock()
+		}(i)
+		go func(i int) {
+			defer wg.Done()
+			_, err := r.Update(context.Background(), model.Document{ID: doc.ID, Title: "contended", Content: model.Content{Data: fmt.Sprint(i)}, Signee: "signee", Version: 1})
+			if err == nil {
+				mu.Lock()
+				updated++
+				mu.Unlock()
+			} else if err != model.VersionMismatchValue {
+				t.Errorf("Update() error = %v", err)
+			}
+		}(i)
+		go func() {
+			defer wg.Done()
+			if _, err := r.Create(context.Background(), model.Document{Title: "same title", Signee: "signee"}); err == nil {
+				mu.Lock()
+				sameTitle++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if len(created) != writers {
+		t.Errorf("concurrent Create() made %d distinct documents, want %d", len(created), writers)
+	}
+	if updated != 1 {
+		t.Errorf("concurrent Update() at one version succeeded %d times, want 1", updated)
+	}
+	if sameTitle != 1 {
+		t.Errorf("concurrent Create() of one title succeeded %d times, want 1", sameTitle)
+	}
+	if got, err := r.Get(context.Background(), doc.ID); err != nil || got.Version != 2 {
+		t.Errorf("Get() after concurrent updates = %+v, %v", got, err)
+	}
+}
+
+// testFollowChanges follows the change feed from a cursor, the way the change
+// hub and indexers do, while writers commit concurrently: a change committed
+// behind the cursor would never be seen.
+func testFollowChanges(t *testing.T, r model.DocumentRepositoryInterface) {
+	const writers = 10
+	var wg sync.WaitGroup
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			doc, err := r.Create(context.Background(), model.Document{Title: fmt.Sprint("writer ", i), Signee: "signee"})
+			if err != nil {
+				t.Errorf("Create() error = %v", err)
+				return
+			}
+			doc.Title = fmt.Sprint("written ", i)
+			if _, err := r.Update(context.Background(), *doc); err != nil {
+				t.Errorf("Update() error = %v", err)
+			}
+		}(i)
+	}
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	seen := make(map[int64]bool)
+	var cursor int64
+	for finished := false; ; {
+		changes, err := r.GetChanges(context.Background(), cursor, 3)
+		if err != nil {
+			t.Fatalf("GetChanges() error = %v", err)
+		}
+		for _, change := range changes {
+			seen[change.Sequence] = true
+			cursor = change.Sequence
+		}
+		if len(changes) > 0 {
+			continue
+		}
+		if finished {
+			break
+		}
+		select {
+		case <-done:
+			finished = true
+		default:
+		}
+	}
+
+	all, err := r.GetChanges(context.Background(), 0, 10*writers)
+	if err != nil || len(all) != 2*writers {
+		t.Fatalf("GetChanges() = %d changes, %v, want %d", len(all), err, 2*writers)
+	}
+	for _, change := range all {
+		if !seen[change.Sequence] {
+			t.Errorf("change %d was committed behind the cursor of its follower", change.Sequence)
+		}
+	}
+}
@@ -0,0 +1,29 @@
+package model
+
+import (
+	"time"
+)
+
+const (
+	RevisionCreate  = "create"
+	RevisionUpdate  = "update"
+	RevisionDelete  = "delete"
+	RevisionRestore = "restore"
+)
+
+var (
+	RevisionInvalidValue = newError(KindValidation, "revision_invalid", "revision records a deletion, expect a revision holding content")
+	AsOfInvalidValue     = newError(KindInvalid, "as_of_invalid", "asOf had invalid value, expect an RFC 3339 timestamp")
+)
+
+// Revision is a snapshot of a document taken by every create, update, delete
+// and restore from the trash. The embedded document holds the state after the
+// operation, or the last state before it for a deletion. The in-memory
+// repository keeps the sequence of the revision in the change feed alongside.
+type Revision struct {
+	Revision  int64     `json:"revision"`
+	Operation string    `json:"operation"`
+	CreatedAt time.Time `json:"created_at"`
+	Document
+	sequence int64
+}
@@ -0,0 +1,225 @@
+package model
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// now is the clock revisions are stamped with, replaceable in tests.
+var now = time.Now
+
+const revisionColumns = "revision, operation, created_at, document_id, title, content, signee"
+
+func scanRevision(scanner interface{ Scan(...interface{}) error }) (*Revision, error) {
+	var rev Revision
+	if err := scanner.Scan(&rev.Revision, &rev.Operation, &rev.CreatedAt, &rev.ID, &rev.Title, &rev.Content, &rev.Signee); err != nil {
+		return nil, err
+	}
+	return &rev, nil
+}
+
+// revisionSequence names the row of the sequences table the change feed is
+// numbered from.
+const revisionSequence = "document_revisions"
+
+// lockSequence takes the row of the named sequence for the rest of tx without
+// issuing a value.
+func lockSequence(ctx context.Context, tx *dialectTx, name string) error {
+	stmt, err := tx.PrepareContext(ctx, "UPDATE sequences SET last_issued = last_issued WHERE name = ?")
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	_, err = stmt.ExecContext(ctx, name)
+	return err
+}
+
+// inChangeTx runs fn in a transaction that holds the revision sequence from
+// its start. Every transaction writing revisions takes the sequence before any
+// document, so that none waits for it while holding a document another one
+// is waiting for.
+func inChangeTx(ctx context.Context, db *dialectDB, fn func(*dialectTx) error) error {
+	return inTx(ctx, db, func(tx *dialectTx) error {
+		if err := lockSequence(ctx, tx, revisionSequence); err != nil {
+			return err
+		}
+		return fn(tx)
+	})
+}
+
+// nextSequence issues the next value of the named sequence within tx. The row
+// it increments stays locked until tx ends, so a transaction issued a value
+// commits or rolls back before the next value is issued: values become
+// visible in the order they were issued, and a reader that has seen one has
+// seen every committed value below it.
+func nextSequence(ctx context.Context, tx *dialectTx, name string) (int64, error) {
+	updateStmt, err := tx.PrepareContext(ctx, "UPDATE sequences SET last_issued = last_issued + 1 WHERE name = ?")
+	if err != nil {
+		return 0, err
+	}
+	defer updateStmt.Close()
+
+	if _, err := updateStmt.ExecContext(ctx, name); err != nil {
+		return 0, err
+	}
+
+	stmt, err := tx.PrepareContext(ctx, "SELECT last_issued FROM sequences WHERE name = ?")
+	if err != nil {
+		return 0, err
+	}
+	defer stmt.Close()
+
+	var value int64
+	err = stmt.QueryRowContext(ctx, name).Scan(&value)
+	return value, err
+}
+
+// writeRevision appends the next revision of doc within tx, numbered in the
+// change feed by the revision sequence.
+func writeRevision(ctx context.Context, tx *dialectTx, operation string, doc Document) error {
+	nextStmt, err := tx.PrepareContext(ctx, "SELECT COALESCE(MAX(revision), 0) + 1 FROM document_revisions WHERE document_id = ?")
+	if err != nil {
+		return err
+	}
+	defer nextStmt.Close()
+
+	var revision int64
+	if err := nextStmt.QueryRowContext(ctx, doc.ID).Scan(&revision); err != nil {
+		return err
+	}
+
+	sequence, err := nextSequence(ctx, tx, revisionSequence)
+	if err != nil {
+		return err
+	}
+
+	stmt, err := tx.PrepareContext(ctx, "INSERT INTO document_revisions(document_id, revision, operation, title, content, signee, created_at, sequence) VALUES(?, ?, ?, ?, ?, ?, ?, ?)")
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	_, err = stmt.ExecContext(ctx, doc.ID, revision, operation, doc.Title, doc.Content, doc.Signee, now().UTC(), sequence)
+	return err
+}
+
+func (r *documentRepository) GetRevisions(ctx context.Context, id int64) (_ []*Revision, err error) {
+	ctx, done := startOperation(ctx, "documentRepository.GetRevisions")
+	defer done(&err)
+
+	stmt, err := r.db.PrepareContext(ctx, "SELECT "+revisionColumns+" FROM document_revisions WHERE document_id = ? ORDER BY revision")
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := make([]*Revision, 0)
+	for rows.Next() {
+		rev, err := scanRevision(rows)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, rev)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(results) == 0 {
+		return nil, sql.ErrNoRows
+	}
+	return results, nil
+}
+
+func (r *documentRepository) GetRevision(ctx context.Context, id, revision int64) (_ *Revision, err error) {
+	ctx, done := startOperation(ctx, "documentRepository.GetRevision")
+	defer done(&err)
+
+	stmt, err := r.db.PrepareContext(ctx, "SELECT "+revisionColumns+" FROM document_revisions WHERE document_id = ? AND revision = ?")
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	return scanRevision(stmt.QueryRowContext(ctx, id, revision))
+}
+
+// GetAsOf returns the document as it stood at the given time, or
+// sql.ErrNoRows when it did not exist yet or had been deleted by then.
+func (r *documentRepository) GetAsOf(ctx context.Context, id int64, asOf time.Time) (_ *Document, err error) {
+	ctx, done := startOperation(ctx, "documentRepository.GetAsOf")
+	defer done(&err)
+
+	stmt, err := r.db.PrepareContext(ctx, "SELECT "+revisionColumns+" FROM document_revisions WHERE document_id = ? AND created_at <= ? ORDER BY revision DESC LIMIT 1")
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	rev, err := scanRevision(stmt.QueryRowContext(ctx, id, asOf.UTC()))
+	if err != nil {
+		return nil, err
+	}
+	if rev.Operation == RevisionDelete {
+		return nil, sql.ErrNoRows
+	}
+	return &rev.Document, nil
+}
+
+// GetChanges returns up to limit changes after the given sequence in sequence
+// order. Sequences are issued in commit order (see nextSequence), so a change
+// committed after this call is never numbered below the ones it returns.
+func (r *documentRepository) GetChanges(ctx context.Context, since int64, limit int) (_ []*Change, err error) {
+	ctx, done := startOperation(ctx, "documentRepository.GetChanges")
+	defer done(&err)
+
+	stmt, err := r.db.PrepareContext(ctx, "SELECT sequence, "+revisionColumns+" FROM document_revisions WHERE sequence > ? ORDER BY sequence LIMIT ?")
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx, since, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	changes := make([]*Change, 0)
+	for rows.Next() {
+		var sequence int64
+		var rev Revision
+		if err := rows.Scan(&sequence, &rev.Revision, &rev.Operation, &rev.CreatedAt, &rev.ID, &rev.Title, &rev.Content, &rev.Signee); err != nil {
+			return nil, err
+		}
+		changes = append(changes, newChange(sequence, &rev))
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return changes, nil
+}
+
+// GetLastSequence returns the sequence of the latest change, zero when there
+// is none.
+func (r *documentRepository) GetLastSequence(ctx context.Context) (_ int64, err error) {
+	ctx, done := startOperation(ctx, "documentRepository.GetLastSequence")
+	defer done(&err)
+
+	stmt, err := r.db.PrepareContext(ctx, "SELECT COALESCE(MAX(sequence), 0) FROM document_revisions")
+	if err != nil {
+		return 0, err
+	}
+	defer stmt.Close()
+
+	var sequence int64
+	err = stmt.QueryRowContext(ctx).Scan(&sequence)
+	return sequence, err
+}
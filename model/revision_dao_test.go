@@ -0,0 +1,146 @@
+package model
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"github.com/DATA-DOG/go-sqlmock"
+	"reflect"
+	"testing"
+	"time"
+)
+
+var revisionRows = []string{"revision", "operation", "created_at", "document_id", "title", "content", "signee"}
+
+// expectChangeTx expects the start of a transaction that writes revisions.
+func expectChangeTx(mock sqlmock.Sqlmock) {
+	mock.ExpectBegin()
+	mock.ExpectPrepare("UPDATE sequences SET last_issued = last_issued WHERE").ExpectExec().WithArgs(revisionSequence).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+}
+
+// expectRevision expects the statements writeRevision issues for a revision.
+func expectRevision(mock sqlmock.Sqlmock, id, revision int64, operation, title string, content []byte, signee string) {
+	mock.ExpectPrepare("SELECT (.+) FROM document_revisions").ExpectQuery().WithArgs(id).
+		WillReturnRows(sqlmock.NewRows([]string{"revision"}).AddRow(revision))
+	mock.ExpectPrepare("UPDATE sequences SET last_issued = last_issued \\+ 1").ExpectExec().WithArgs(revisionSequence).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectPrepare("SELECT last_issued FROM sequences").ExpectQuery().WithArgs(revisionSequence).
+		WillReturnRows(sqlmock.NewRows([]string{"last_issued"}).AddRow(41))
+	mock.ExpectPrepare("INSERT INTO document_revisions").ExpectExec().
+		WithArgs(id, revision, operation, title, string(content), signee, sqlmock.AnyArg(), 41).
+		WillReturnResult(sqlmock.NewResult(revision, 1))
+}
+
+func TestDocumentRepository_GetRevisions(t *testing.T) {
+	db, mock := NewMock()
+	defer db.Close()
+
+	r := NewDocumentRepository(db, MySQL)
+	contentBytes, _ := json.Marshal(Content{Header: "header", Data: "data"})
+	created := time.Date(2021, 12, 1, 10, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		mock    func()
+		want    []*Revision
+		wantErr error
+	}{
+		{
+			name: "Ok",
+			mock: func() {
+				rows := sqlmock.NewRows(revisionRows).
+					AddRow(1, RevisionCreate, created, 1, "title", contentBytes, "signee").
+					AddRow(2, RevisionUpdate, created.Add(time.Hour), 1, "new title", contentBytes, "signee")
+				mock.ExpectPrepare("SELECT (.+) FROM document_revisions").ExpectQuery().WithArgs(1).
+					WillReturnRows(rows)
+			},
+			want: []*Revision{
+				{
+					Revision:  1,
+					Operation: RevisionCreate,
+					CreatedAt: created,
+					Document:  Document{ID: 1, Title: "title", Content: Content{Header: "header", Data: "data"}, Signee: "signee"},
+				},
+				{
+					Revision:  2,
+					Operation: RevisionUpdate,
+					CreatedAt: created.Add(time.Hour),
+					Document:  Document{ID: 1, Title: "new title", Content: Content{Header: "header", Data: "data"}, Signee: "signee"},
+				},
+			},
+		},
+		{
+			name: "Document Not Found",
+			mock: func() {
+				mock.ExpectPrepare("SELECT (.+) FROM document_revisions").ExpectQuery().WithArgs(1).
+					WillReturnRows(sqlmock.NewRows(revisionRows))
+			},
+			wantErr: sql.ErrNoRows,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mock()
+			got, err := r.GetRevisions(context.Background(), 1)
+			if err != tt.wantErr {
+				t.Errorf("GetRevisions() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if err == nil && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetRevisions() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDocumentRepository_GetAsOf(t *testing.T) {
+	db, mock := NewMock()
+	defer db.Close()
+
+	r := NewDocumentRepository(db, MySQL)
+	contentBytes, _ := json.Marshal(Content{Header: "header", Data: "data"})
+	asOf := time.Date(2021, 12, 1, 12, 0, 0, 0, time.FixedZone("ICT", 7*3600))
+
+	tests := []struct {
+		name    string
+		row     []driver.Value
+		want    *Document
+		wantErr error
+	}{
+		{
+			name: "Ok",
+			row:  []driver.Value{2, RevisionUpdate, asOf, 1, "title", contentBytes, "signee"},
+			want: &Document{ID: 1, Title: "title", Content: Content{Header: "header", Data: "data"}, Signee: "signee"},
+		},
+		{
+			name:    "Deleted by then",
+			row:     []driver.Value{3, RevisionDelete, asOf, 1, "title", contentBytes, "signee"},
+			wantErr: sql.ErrNoRows,
+		},
+		{
+			name:    "Not created yet",
+			wantErr: sql.ErrNoRows,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rows := sqlmock.NewRows(revisionRows)
+			if tt.row != nil {
+				rows.AddRow(tt.row...)
+			}
+			mock.ExpectPrepare("SELECT (.+) FROM document_revisions WHERE document_id = (.+) AND created_at <= (.+)").
+				ExpectQuery().WithArgs(1, asOf.UTC()).WillReturnRows(rows)
+
+			got, err := r.GetAsOf(context.Background(), 1, asOf)
+			if err != tt.wantErr {
+				t.Errorf("GetAsOf() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if err == nil && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetAsOf() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,84 @@
+package model
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+	"time"
+)
+
+var (
+	PublicKeyInvalidValue   = newError(KindValidation, "public_key_invalid", "public_key had invalid value, expect a base64 encoded 32 bytes Ed25519 public key")
+	SignatureInvalidValue   = newError(KindValidation, "signature_invalid", "signature does not verify against the document content with the given key")
+	SigneeMismatchValue     = newError(KindValidation, "signee_mismatch", "key is not registered to the signee of the document")
+	KeyInvalidValue         = newError(KindValidation, "key_invalid", "key_id had invalid value, expect the id of a registered key")
+	DuplicatePublicKeyValue = newError(KindConflict, "duplicate_public_key", "public key had duplicated value, expect one not registered yet")
+)
+
+// SigneeKey is an Ed25519 public key registered to a signee. []byte fields
+// are base64 encoded in JSON.
+type SigneeKey struct {
+	ID        int64     `json:"id"`
+	Signee    string    `json:"signee"`
+	PublicKey []byte    `json:"public_key"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Signature is a detached Ed25519 signature over the canonical payload of a
+// document. Digest is the hex SHA-256 of the payload that was signed.
+type Signature struct {
+	ID         int64     `json:"id"`
+	DocumentID int64     `json:"document_id"`
+	KeyID      int64     `json:"key_id"`
+	Signee     string    `json:"signee"`
+	Signature  []byte    `json:"signature"`
+	Digest     string    `json:"digest"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+type SignatureVerification struct {
+	Signature
+	Valid bool `json:"valid"`
+}
+
+type Verification struct {
+	DocumentID int64                    `json:"document_id"`
+	Digest     string                   `json:"digest"`
+	Valid      bool                     `json:"valid"`
+	Signatures []*SignatureVerification `json:"signatures"`
+}
+
+func (k *SigneeKey) Validate() error {
+	k.Signee = strings.TrimSpace(k.Signee)
+	if k.Signee == "" {
+		return SigneeInvalidValue
+	}
+	if len(k.PublicKey) != ed25519.PublicKeySize {
+		return PublicKeyInvalidValue
+	}
+	return nil
+}
+
+// CanonicalPayload is the byte sequence signees sign: the compact JSON object
+// {"title":...,"content":{"header":...,"data":...}} with keys in that order
+// and no HTML escaping.
+func (d *Document) CanonicalPayload() []byte {
+	payload := struct {
+		Title   string  `json:"title"`
+		Content Content `json:"content"`
+	}{d.Title, d.Content}
+
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	encoder.Encode(payload)
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n"))
+}
+
+func (d *Document) Digest() string {
+	sum := sha256.Sum256(d.CanonicalPayload())
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,145 @@
+package model
+
+import (
+	"context"
+	"database/sql"
+)
+
+var (
+	SignatureRepository signatureRepositoryInterface = &signatureRepository{}
+)
+
+type signatureRepositoryInterface interface {
+	CreateKey(context.Context, SigneeKey) (*SigneeKey, error)
+	GetKey(context.Context, int64) (*SigneeKey, error)
+	GetKeys(context.Context, string) ([]*SigneeKey, error)
+	CreateSignature(context.Context, Signature, *Signee) (*Signature, error)
+	GetSignatures(context.Context, int64) ([]*Signature, error)
+}
+
+type signatureRepository struct {
+	db *dialectDB
+}
+
+func NewSignatureRepository(db *sql.DB, dialect *Dialect) signatureRepositoryInterface {
+	return &signatureRepository{db: newDialectDB(db, dialect)}
+}
+
+func (r *signatureRepository) CreateKey(ctx context.Context, key SigneeKey) (_ *SigneeKey, err error) {
+	ctx, done := startOperation(ctx, "signatureRepository.CreateKey")
+	defer done(&err)
+
+	key.CreatedAt = now().UTC()
+	key.ID, err = r.db.dialect.insert(ctx, r.db, "INSERT INTO signee_keys(signee, public_key, created_at) VALUES(?, ?, ?)",
+		key.Signee, key.PublicKey, key.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+func (r *signatureRepository) GetKey(ctx context.Context, id int64) (_ *SigneeKey, err error) {
+	ctx, done := startOperation(ctx, "signatureRepository.GetKey")
+	defer done(&err)
+
+	stmt, err := r.db.PrepareContext(ctx, "SELECT id, signee, public_key, created_at FROM signee_keys WHERE id = ?")
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	var key SigneeKey
+	if err := stmt.QueryRowContext(ctx, id).Scan(&key.ID, &key.Signee, &key.PublicKey, &key.CreatedAt); err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+// GetKeys lists the keys registered to signee, or every key when it is empty.
+func (r *signatureRepository) GetKeys(ctx context.Context, signee string) (_ []*SigneeKey, err error) {
+	ctx, done := startOperation(ctx, "signatureRepository.GetKeys")
+	defer done(&err)
+
+	query := "SELECT id, signee, public_key, created_at FROM signee_keys"
+	var args []interface{}
+	if signee != "" {
+		query += " WHERE signee = ?"
+		args = append(args, signee)
+	}
+	stmt, err := r.db.PrepareContext(ctx, query+" ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := make([]*SigneeKey, 0)
+	for rows.Next() {
+		var key SigneeKey
+		if err := rows.Scan(&key.ID, &key.Signee, &key.PublicKey, &key.CreatedAt); err != nil {
+			return nil, err
+		}
+		results = append(results, &key)
+	}
+	return results, rows.Err()
+}
+
+// CreateSignature stores a signature and, when signee is set, records that
+// they signed within the same transaction, along with its audit entry. It
+// fails with SigneeStatusConflict when the signee is no longer pending.
+func (r *signatureRepository) CreateSignature(ctx context.Context, sig Signature, signee *Signee) (_ *Signature, err error) {
+	ctx, done := startOperation(ctx, "signatureRepository.CreateSignature")
+	defer done(&err)
+
+	sig.CreatedAt = now().UTC()
+	err = inTx(ctx, r.db, func(tx *dialectTx) error {
+		if signee != nil {
+			if err := updateSigneeStatus(ctx, tx, *signee); err != nil {
+				return err
+			}
+		}
+		id, err := tx.dialect.insert(ctx, tx, "INSERT INTO document_signatures(document_id, key_id, signee, signature, digest, created_at) VALUES(?, ?, ?, ?, ?, ?)",
+			sig.DocumentID, sig.KeyID, sig.Signee, sig.Signature, sig.Digest, sig.CreatedAt)
+		if err != nil {
+			return err
+		}
+		sig.ID = id
+		return writeAuditUnchanged(ctx, tx, AuditSign, sig.DocumentID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &sig, nil
+}
+
+func (r *signatureRepository) GetSignatures(ctx context.Context, documentID int64) (_ []*Signature, err error) {
+	ctx, done := startOperation(ctx, "signatureRepository.GetSignatures")
+	defer done(&err)
+
+	stmt, err := r.db.PrepareContext(ctx, "SELECT id, document_id, key_id, signee, signature, digest, created_at FROM document_signatures WHERE document_id = ? ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx, documentID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := make([]*Signature, 0)
+	for rows.Next() {
+		var sig Signature
+		if err := rows.Scan(&sig.ID, &sig.DocumentID, &sig.KeyID, &sig.Signee, &sig.Signature, &sig.Digest, &sig.CreatedAt); err != nil {
+			return nil, err
+		}
+		results = append(results, &sig)
+	}
+	return results, rows.Err()
+}
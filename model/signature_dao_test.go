@@ -0,0 +1,64 @@
+package model
+
+import (
+	"context"
+	"github.com/DATA-DOG/go-sqlmock"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestDocument_CanonicalPayload(t *testing.T) {
+	doc := Document{
+		ID:      1,
+		Title:   "<Terms & Conditions>",
+		Content: Content{Header: "header", Data: "data"},
+		Signee:  "signee",
+	}
+	want := `{"title":"<Terms & Conditions>","content":{"header":"header","data":"data"}}`
+	if got := string(doc.CanonicalPayload()); got != want {
+		t.Errorf("CanonicalPayload() = %v, want %v", got, want)
+	}
+}
+
+func TestSignatureRepository_CreateKey(t *testing.T) {
+	db, mock := NewMock()
+	defer db.Close()
+
+	r := NewSignatureRepository(db, MySQL)
+	publicKey := make([]byte, 32)
+	mock.ExpectPrepare("INSERT INTO signee_keys").ExpectExec().
+		WithArgs("signee", publicKey, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(3, 1))
+
+	got, err := r.CreateKey(context.Background(), SigneeKey{Signee: "signee", PublicKey: publicKey})
+	if err != nil {
+		t.Fatalf("CreateKey() error = %v", err)
+	}
+	if got.ID != 3 || got.Signee != "signee" || got.CreatedAt.IsZero() {
+		t.Errorf("CreateKey() = %v", got)
+	}
+}
+
+func TestSignatureRepository_GetSignatures(t *testing.T) {
+	db, mock := NewMock()
+	defer db.Close()
+
+	r := NewSignatureRepository(db, MySQL)
+	created := time.Date(2021, 12, 1, 10, 0, 0, 0, time.UTC)
+	rows := sqlmock.NewRows([]string{"id", "document_id", "key_id", "signee", "signature", "digest", "created_at"}).
+		AddRow(1, 7, 3, "signee", []byte("sig"), "digest", created)
+	mock.ExpectPrepare("SELECT (.+) FROM document_signatures").ExpectQuery().WithArgs(7).
+		WillReturnRows(rows)
+
+	got, err := r.GetSignatures(context.Background(), 7)
+	if err != nil {
+		t.Fatalf("GetSignatures() error = %v", err)
+	}
+	want := []*Signature{
+		{ID: 1, DocumentID: 7, KeyID: 3, Signee: "signee", Signature: []byte("sig"), Digest: "digest", CreatedAt: created},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("GetSignatures() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,136 @@
+package model
+
+import (
+	"strings"
+	"time"
+)
+
+const (
+	SigningOrdered  = "ordered"
+	SigningParallel = "parallel"
+
+	SigneePending  = "pending"
+	SigneeSigned   = "signed"
+	SigneeDeclined = "declined"
+
+	SigningDraft     = "draft"
+	SigningPending   = "pending"
+	SigningCompleted = "completed"
+	SigningDeclined  = "declined"
+)
+
+// MaxReasonLength bounds the reason a signee gives for declining, in
+// characters.
+const MaxReasonLength = 255
+
+var (
+	SigningModeInvalidValue = newError(KindValidation, "signing_mode_invalid", "mode had invalid value, expect ordered or parallel")
+	SigneesInvalidValue     = newError(KindValidation, "signees_invalid", "signees had invalid value, expect a non-empty list of distinct names")
+	ReasonInvalidValue      = newError(KindValidation, "reason_invalid", "reason had invalid value, expect at most 255 characters")
+	SigneesLockedValue      = newError(KindConflict, "signees_locked", "signees can no longer change once a signee has signed or declined")
+	SigneeStatusConflict    = newError(KindConflict, "signee_status_conflict", "signee has already signed or declined")
+	SigneeOutOfTurnValue    = newError(KindConflict, "signee_out_of_turn", "signee must wait for the signees before them in ordered signing")
+	SigningDeclinedValue    = newError(KindConflict, "signing_declined", "signing was declined by a signee")
+)
+
+// Signee is a party of the signing workflow of a document. Position orders
+// the signees when the workflow is ordered.
+type Signee struct {
+	ID         int64      `json:"id"`
+	DocumentID int64      `json:"document_id"`
+	Position   int        `json:"position"`
+	Name       string     `json:"signee"`
+	Status     string     `json:"status"`
+	Reason     string     `json:"reason,omitempty"`
+	SignedAt   *time.Time `json:"signed_at,omitempty"`
+	DeclinedAt *time.Time `json:"declined_at,omitempty"`
+}
+
+// SigningWorkflow is the list of signees of a document. State is draft
+// without signees, declined once any signee declined, completed once all of
+// them signed and pending otherwise.
+type SigningWorkflow struct {
+	DocumentID int64     `json:"document_id"`
+	Mode       string    `json:"mode"`
+	State      string    `json:"state"`
+	Signees    []*Signee `json:"signees"`
+}
+
+func (w *SigningWorkflow) Validate() error {
+	if w.Mode == "" {
+		w.Mode = SigningParallel
+	}
+	if w.Mode != SigningOrdered && w.Mode != SigningParallel {
+		return SigningModeInvalidValue
+	}
+	if len(w.Signees) == 0 {
+		return SigneesInvalidValue
+	}
+	seen := make(map[string]bool)
+	for i, s := range w.Signees {
+		s.Name = strings.TrimSpace(s.Name)
+		if s.Name == "" || seen[s.Name] {
+			return SigneesInvalidValue
+		}
+		seen[s.Name] = true
+		s.DocumentID = w.DocumentID
+		s.Position = i + 1
+		s.Status = SigneePending
+	}
+	return nil
+}
+
+func (w *SigningWorkflow) Signee(id int64) *Signee {
+	for _, s := range w.Signees {
+		if s.ID == id {
+			return s
+		}
+	}
+	return nil
+}
+
+func (w *SigningWorkflow) SigneeNamed(name string) *Signee {
+	for _, s := range w.Signees {
+		if s.Name == name {
+			return s
+		}
+	}
+	return nil
+}
+
+// CheckTurn reports whether s may sign now: it must still be pending, nobody
+// may have declined, and in ordered signing every signee before it has signed.
+func (w *SigningWorkflow) CheckTurn(s *Signee) error {
+	if s.Status != SigneePending {
+		return SigneeStatusConflict
+	}
+	if w.State == SigningDeclined {
+		return SigningDeclinedValue
+	}
+	if w.Mode == SigningOrdered {
+		for _, other := range w.Signees {
+			if other.Position < s.Position && other.Status != SigneeSigned {
+				return SigneeOutOfTurnValue
+			}
+		}
+	}
+	return nil
+}
+
+// RefreshState derives State from the statuses of the signees.
+func (w *SigningWorkflow) RefreshState() {
+	if len(w.Signees) == 0 {
+		w.State = SigningDraft
+		return
+	}
+	w.State = SigningCompleted
+	for _, s := range w.Signees {
+		if s.Status == SigneeDeclined {
+			w.State = SigningDeclined
+			return
+		}
+		if s.Status != SigneeSigned {
+			w.State = SigningPending
+		}
+	}
+}
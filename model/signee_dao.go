@@ -0,0 +1,186 @@
+package model
+
+import (
+	"context"
+	"database/sql"
+)
+
+var (
+	SigneeRepository signeeRepositoryInterface = &signeeRepository{}
+)
+
+type signeeRepositoryInterface interface {
+	GetWorkflow(context.Context, int64) (*SigningWorkflow, error)
+	SetSignees(context.Context, SigningWorkflow) (*SigningWorkflow, error)
+	UpdateStatus(context.Context, Signee) error
+}
+
+type signeeRepository struct {
+	db *dialectDB
+}
+
+func NewSigneeRepository(db *sql.DB, dialect *Dialect) signeeRepositoryInterface {
+	return &signeeRepository{db: newDialectDB(db, dialect)}
+}
+
+// GetWorkflow returns the signing workflow of a document, or sql.ErrNoRows
+// when the document does not exist.
+func (r *signeeRepository) GetWorkflow(ctx context.Context, documentID int64) (_ *SigningWorkflow, err error) {
+	ctx, done := startOperation(ctx, "signeeRepository.GetWorkflow")
+	defer done(&err)
+
+	modeStmt, err := r.db.PrepareContext(ctx, "SELECT signing_mode FROM documents WHERE id = ? AND deleted_at IS NULL")
+	if err != nil {
+		return nil, err
+	}
+	defer modeStmt.Close()
+
+	workflow := SigningWorkflow{DocumentID: documentID}
+	if err := modeStmt.QueryRowContext(ctx, documentID).Scan(&workflow.Mode); err != nil {
+		return nil, err
+	}
+
+	stmt, err := r.db.PrepareContext(ctx, "SELECT id, document_id, position, signee, status, reason, signed_at, declined_at FROM document_signees WHERE document_id = ? ORDER BY position")
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx, documentID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	workflow.Signees = make([]*Signee, 0)
+	for rows.Next() {
+		var s Signee
+		if err := rows.Scan(&s.ID, &s.DocumentID, &s.Position, &s.Name, &s.Status, &s.Reason, &s.SignedAt, &s.DeclinedAt); err != nil {
+			return nil, err
+		}
+		workflow.Signees = append(workflow.Signees, &s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	workflow.RefreshState()
+	return &workflow, nil
+}
+
+// SetSignees replaces the signees and signing mode of a document outside the
+// trash as long as none of the current signees has signed or declined. Only
+// pending signees are deleted, and the replacement is rolled back when any
+// other remains, so that a signature or decline committing meanwhile is never
+// lost.
+func (r *signeeRepository) SetSignees(ctx context.Context, workflow SigningWorkflow) (_ *SigningWorkflow, err error) {
+	ctx, done := startOperation(ctx, "signeeRepository.SetSignees")
+	defer done(&err)
+
+	err = inTx(ctx, r.db, func(tx *dialectTx) error {
+		modeStmt, err := tx.PrepareContext(ctx, "UPDATE documents SET signing_mode = ? WHERE id = ? AND deleted_at IS NULL")
+		if err != nil {
+			return err
+		}
+		defer modeStmt.Close()
+
+		result, err := modeStmt.ExecContext(ctx, workflow.Mode, workflow.DocumentID)
+		if err != nil {
+			return err
+		}
+		affected, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if affected == 0 {
+			// MySQL counts the rows changed rather than matched, so a mode
+			// left as it was is told apart from a missing document by reading
+			// the document.
+			if _, err := getDocument(ctx, tx, workflow.DocumentID); err != nil {
+				return err
+			}
+		}
+
+		deleteStmt, err := tx.PrepareContext(ctx, "DELETE FROM document_signees WHERE document_id = ? AND status = ?")
+		if err != nil {
+			return err
+		}
+		defer deleteStmt.Close()
+
+		if _, err := deleteStmt.ExecContext(ctx, workflow.DocumentID, SigneePending); err != nil {
+			return err
+		}
+
+		countStmt, err := tx.PrepareContext(ctx, "SELECT COUNT(*) FROM document_signees WHERE document_id = ?")
+		if err != nil {
+			return err
+		}
+		defer countStmt.Close()
+
+		var acted int
+		if err := countStmt.QueryRowContext(ctx, workflow.DocumentID).Scan(&acted); err != nil {
+			return err
+		}
+		if acted > 0 {
+			return SigneesLockedValue
+		}
+
+		for _, s := range workflow.Signees {
+			s.ID, err = tx.dialect.insert(ctx, tx, "INSERT INTO document_signees(document_id, position, signee, status) VALUES(?, ?, ?, ?)",
+				workflow.DocumentID, s.Position, s.Name, s.Status)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	workflow.RefreshState()
+	return &workflow, nil
+}
+
+// UpdateStatus records that a pending signee signed or declined, along with
+// its audit entry. It fails with SigneeStatusConflict when the signee is no
+// longer pending.
+func (r *signeeRepository) UpdateStatus(ctx context.Context, s Signee) (err error) {
+	ctx, done := startOperation(ctx, "signeeRepository.UpdateStatus")
+	defer done(&err)
+
+	return inTx(ctx, r.db, func(tx *dialectTx) error {
+		if err := updateSigneeStatus(ctx, tx, s); err != nil {
+			return err
+		}
+		return writeAuditUnchanged(ctx, tx, signeeAuditAction(s.Status), s.DocumentID)
+	})
+}
+
+// signeeAuditAction is the audit action recording that a signee took a
+// status.
+func signeeAuditAction(status string) string {
+	if status == SigneeDeclined {
+		return AuditDecline
+	}
+	return AuditSign
+}
+
+func updateSigneeStatus(ctx context.Context, p preparer, s Signee) error {
+	stmt, err := p.PrepareContext(ctx, "UPDATE document_signees SET status = ?, reason = ?, signed_at = ?, declined_at = ? WHERE id = ? AND document_id = ? AND status = ?")
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	result, err := stmt.ExecContext(ctx, s.Status, s.Reason, s.SignedAt, s.DeclinedAt, s.ID, s.DocumentID, SigneePending)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return SigneeStatusConflict
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+package model
+
+import (
+	"context"
+	"database/sql"
+	"github.com/DATA-DOG/go-sqlmock"
+	"testing"
+	"time"
+)
+
+var signeeRows = []string{"id", "document_id", "position", "signee", "status", "reason", "signed_at", "declined_at"}
+
+func TestSigneeRepository_GetWorkflow(t *testing.T) {
+	db, mock := NewMock()
+	defer db.Close()
+
+	r := NewSigneeRepository(db, MySQL)
+	signedAt := time.Date(2021, 12, 1, 10, 0, 0, 0, time.UTC)
+	mock.ExpectPrepare("SELECT signing_mode FROM documents").ExpectQuery().WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"signing_mode"}).AddRow(SigningOrdered))
+	mock.ExpectPrepare("SELECT (.+) FROM document_signees").ExpectQuery().WithArgs(1).
+		WillReturnRows(sqlmock.NewRows(signeeRows).
+			AddRow(1, 1, 1, "alice", SigneeSigned, "", signedAt, nil).
+			AddRow(2, 1, 2, "bob", SigneePending, "", nil, nil))
+
+	got, err := r.GetWorkflow(context.Background(), 1)
+	if err != nil {
+		t.Fatalf("GetWorkflow() error = %v", err)
+	}
+	if got.Mode != SigningOrdered || got.State != SigningPending || len(got.Signees) != 2 {
+		t.Errorf("GetWorkflow() = %+v", got)
+	}
+	if got.Signees[0].SignedAt == nil || !got.Signees[0].SignedAt.Equal(signedAt) || got.Signees[1].SignedAt != nil {
+		t.Errorf("GetWorkflow() signed_at = %v, %v", got.Signees[0].SignedAt, got.Signees[1].SignedAt)
+	}
+}
+
+func TestSigneeRepository_SetSignees_Locked(t *testing.T) {
+	db, mock := NewMock()
+	defer db.Close()
+
+	r := NewSigneeRepository(db, MySQL)
+	mock.ExpectBegin()
+	mock.ExpectPrepare("UPDATE documents SET signing_mode (.+) AND deleted_at IS NULL").ExpectExec().WithArgs(SigningParallel, 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectPrepare("DELETE FROM document_signees WHERE document_id = (.+) AND status = ").ExpectExec().WithArgs(1, SigneePending).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectPrepare("SELECT COUNT(.+) FROM document_signees").ExpectQuery().WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+	mock.ExpectRollback()
+
+	_, err := r.SetSignees(context.Background(), SigningWorkflow{DocumentID: 1, Mode: SigningParallel, Signees: []*Signee{{Name: "alice"}}})
+	if err != SigneesLockedValue {
+		t.Errorf("SetSignees() error = %v, want %v", err, SigneesLockedValue)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestSigneeRepository_SetSignees_Trashed(t *testing.T) {
+	db, mock := NewMock()
+	defer db.Close()
+
+	r := NewSigneeRepository(db, MySQL)
+	mock.ExpectBegin()
+	mock.ExpectPrepare("UPDATE documents SET signing_mode (.+) AND deleted_at IS NULL").ExpectExec().WithArgs(SigningParallel, 1).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectPrepare("SELECT (.+) FROM documents WHERE id = (.+) AND deleted_at IS NULL").ExpectQuery().WithArgs(1).
+		WillReturnError(sql.ErrNoRows)
+	mock.ExpectRollback()
+
+	_, err := r.SetSignees(context.Background(), SigningWorkflow{DocumentID: 1, Mode: SigningParallel, Signees: []*Signee{{Name: "alice"}}})
+	if err != sql.ErrNoRows {
+		t.Errorf("SetSignees() error = %v, want %v", err, sql.ErrNoRows)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestSigneeRepository_UpdateStatus_Conflict(t *testing.T) {
+	db, mock := NewMock()
+	defer db.Close()
+
+	r := NewSigneeRepository(db, MySQL)
+	mock.ExpectBegin()
+	mock.ExpectPrepare("UPDATE document_signees").ExpectExec().
+		WithArgs(SigneeSigned, "", sqlmock.AnyArg(), nil, 2, 1, SigneePending).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectRollback()
+
+	signedAt := time.Now()
+	err := r.UpdateStatus(context.Background(), Signee{ID: 2, DocumentID: 1, Status: SigneeSigned, SignedAt: &signedAt})
+	if err != SigneeStatusConflict {
+		t.Errorf("UpdateStatus() error = %v, want %v", err, SigneeStatusConflict)
+	}
+}
@@ -0,0 +1,196 @@
+package model
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// The states of a delivery. A pending delivery is attempted again until it
+// succeeds or runs out of attempts, when it is dead-lettered.
+const (
+	DeliveryPending   = "pending"
+	DeliverySucceeded = "succeeded"
+	DeliveryDead      = "dead"
+)
+
+var deliveryStatuses = map[string]bool{
+	DeliveryPending:   true,
+	DeliverySucceeded: true,
+	DeliveryDead:      true,
+}
+
+// MaxWebhookURLLength bounds the URL of a webhook, in characters.
+const MaxWebhookURLLength = 2048
+
+// maxDeliveryError bounds the error message kept with a failed attempt, in
+// bytes.
+const maxDeliveryError = 1024
+
+// truncateError cuts err to maxDeliveryError bytes without splitting a
+// character.
+func truncateError(err string) string {
+	if len(err) <= maxDeliveryError {
+		return err
+	}
+	cut := maxDeliveryError
+	for cut > 0 && !utf8.RuneStart(err[cut]) {
+		cut--
+	}
+	return err[:cut]
+}
+
+var (
+	WebhookURLInvalidValue     = newError(KindValidation, "webhook_url_invalid", "url had invalid value, expect an absolute http or https URL of at most 2048 characters")
+	WebhookEventsInvalidValue  = newError(KindValidation, "webhook_events_invalid", "events had invalid value, expect one or more of document.created, document.updated, document.deleted or document.signed")
+	DeliveryStatusInvalidValue = newError(KindInvalid, "delivery_status_invalid", "status had invalid value, expect pending, succeeded or dead")
+)
+
+// Webhook subscribes a URL to document events. Secret signs the deliveries and
+// is only returned when the webhook is created.
+type Webhook struct {
+	ID        int64     `json:"id"`
+	URL       string    `json:"url"`
+	Events    []string  `json:"events"`
+	Disabled  bool      `json:"disabled"`
+	Secret    string    `json:"secret,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (w *Webhook) Validate() error {
+	w.URL = strings.TrimSpace(w.URL)
+	if utf8.RuneCountInString(w.URL) > MaxWebhookURLLength {
+		return WebhookURLInvalidValue
+	}
+	u, err := url.Parse(w.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return WebhookURLInvalidValue
+	}
+	if len(w.Events) == 0 {
+		return WebhookEventsInvalidValue
+	}
+	for _, event := range w.Events {
+		if !documentEvents[event] {
+			return WebhookEventsInvalidValue
+		}
+	}
+	return nil
+}
+
+// Subscribed reports whether the webhook is enabled and receives event.
+func (w *Webhook) Subscribed(event string) bool {
+	if w.Disabled {
+		return false
+	}
+	for _, e := range w.Events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// Delivery is the delivery of one event to one webhook. NextAttemptAt is set
+// while it is pending, ResponseCode and Error describe the last attempt.
+type Delivery struct {
+	ID            int64           `json:"id"`
+	WebhookID     int64           `json:"webhook_id"`
+	EventID       string          `json:"event_id"`
+	Event         string          `json:"event"`
+	Payload       json.RawMessage `json:"payload"`
+	Status        string          `json:"status"`
+	Attempts      int             `json:"attempts"`
+	NextAttemptAt *time.Time      `json:"next_attempt_at,omitempty"`
+	ResponseCode  int             `json:"response_code,omitempty"`
+	Error         string          `json:"error,omitempty"`
+	CreatedAt     time.Time       `json:"created_at"`
+	DeliveredAt   *time.Time      `json:"delivered_at,omitempty"`
+}
+
+// Succeed records a successful attempt.
+func (d *Delivery) Succeed(code int, now time.Time) {
+	d.Attempts++
+	d.Status = DeliverySucceeded
+	d.ResponseCode = code
+	d.Error = ""
+	d.NextAttemptAt = nil
+	d.DeliveredAt = &now
+}
+
+// Fail records a failed attempt and schedules the next one after a backoff
+// that starts at base and doubles with every attempt, up to maxBackoff, or
+// dead-letters the delivery once it made maxAttempts.
+func (d *Delivery) Fail(code int, err string, now time.Time, base, maxBackoff time.Duration, maxAttempts int) {
+	d.Attempts++
+	d.ResponseCode = code
+	d.Error = truncateError(err)
+	if d.Attempts >= maxAttempts {
+		d.Status = DeliveryDead
+		d.NextAttemptAt = nil
+		return
+	}
+	next := now.Add(backoff(d.Attempts, base, maxBackoff))
+	d.Status = DeliveryPending
+	d.NextAttemptAt = &next
+}
+
+// backoff is the wait after the given number of failed attempts: base after
+// the first one, doubled after every other one up to maxBackoff.
+func backoff(attempts int, base, maxBackoff time.Duration) time.Duration {
+	wait := base
+	for i := 1; i < attempts && wait < maxBackoff; i++ {
+		wait *= 2
+	}
+	if wait > maxBackoff {
+		wait = maxBackoff
+	}
+	return wait
+}
+
+// DeliveryQuery describes a page of deliveries, newest first. WebhookID and
+// Status are ignored when empty and Cursor, when set, is the id of the last
+// delivery of the previous page. The Total of a page counts every delivery
+// matching the filters, whatever the cursor.
+type DeliveryQuery struct {
+	WebhookID int64
+	Status    string
+	Cursor    int64
+	Limit     int
+}
+
+type DeliveryPage struct {
+	Deliveries []*Delivery
+	Total      int64
+	NextCursor string
+}
+
+func (q *DeliveryQuery) Validate() error {
+	if q.Limit == 0 {
+		q.Limit = DefaultListLimit
+	}
+	if q.Limit < 0 || q.Limit > MaxListLimit {
+		return LimitInvalidValue
+	}
+	if q.Status != "" && !deliveryStatuses[q.Status] {
+		return DeliveryStatusInvalidValue
+	}
+	return nil
+}
+
+// SignWebhookPayload is the signature of a delivery made at timestamp, in Unix
+// seconds: "t=<timestamp>,v1=<hex HMAC-SHA256 of "<timestamp>.<payload>">"
+// keyed with the secret of the webhook. Covering the timestamp lets receivers
+// reject replayed deliveries.
+func SignWebhookPayload(secret string, timestamp int64, payload []byte) string {
+	t := strconv.FormatInt(timestamp, 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(t + "."))
+	mac.Write(payload)
+	return "t=" + t + ",v1=" + hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,363 @@
+package model
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+)
+
+var (
+	WebhookRepository webhookRepositoryInterface = &webhookRepository{}
+)
+
+type webhookRepositoryInterface interface {
+	Create(context.Context, Webhook) (*Webhook, error)
+	Get(context.Context, int64) (*Webhook, error)
+	GetAll(context.Context) ([]*Webhook, error)
+	Update(context.Context, Webhook) (*Webhook, error)
+	Delete(context.Context, int64) error
+	CreateDeliveries(context.Context, []Delivery) ([]*Delivery, error)
+	GetDelivery(context.Context, int64) (*Delivery, error)
+	GetDeliveries(context.Context, DeliveryQuery) (*DeliveryPage, error)
+	ClaimDue(context.Context, time.Duration, int) ([]*Delivery, error)
+	UpdateDelivery(context.Context, Delivery) error
+}
+
+type webhookRepository struct {
+	db *dialectDB
+}
+
+func NewWebhookRepository(db *sql.DB, dialect *Dialect) webhookRepositoryInterface {
+	return &webhookRepository{db: newDialectDB(db, dialect)}
+}
+
+const (
+	webhookColumns  = "id, url, secret, events, disabled, created_at"
+	deliveryColumns = "id, webhook_id, event_id, event, payload, status, attempts, next_attempt_at, response_code, last_error, created_at, delivered_at"
+)
+
+func scanWebhook(scanner interface{ Scan(...interface{}) error }) (*Webhook, error) {
+	var w Webhook
+	var events string
+	if err := scanner.Scan(&w.ID, &w.URL, &w.Secret, &events, &w.Disabled, &w.CreatedAt); err != nil {
+		return nil, err
+	}
+	w.Events = strings.Split(events, ",")
+	return &w, nil
+}
+
+func scanDelivery(scanner interface{ Scan(...interface{}) error }) (*Delivery, error) {
+	var d Delivery
+	var payload []byte
+	if err := scanner.Scan(&d.ID, &d.WebhookID, &d.EventID, &d.Event, &payload, &d.Status, &d.Attempts, &d.NextAttemptAt, &d.ResponseCode, &d.Error, &d.CreatedAt, &d.DeliveredAt); err != nil {
+		return nil, err
+	}
+	d.Payload = payload
+	return &d, nil
+}
+
+func (r *webhookRepository) Create(ctx context.Context, webhook Webhook) (_ *Webhook, err error) {
+	ctx, done := startOperation(ctx, "webhookRepository.Create")
+	defer done(&err)
+
+	webhook.CreatedAt = now().UTC()
+	webhook.ID, err = r.db.dialect.insert(ctx, r.db, "INSERT INTO webhooks(url, secret, events, disabled, created_at) VALUES(?, ?, ?, ?, ?)",
+		webhook.URL, webhook.Secret, strings.Join(webhook.Events, ","), webhook.Disabled, webhook.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &webhook, nil
+}
+
+func (r *webhookRepository) Get(ctx context.Context, id int64) (_ *Webhook, err error) {
+	ctx, done := startOperation(ctx, "webhookRepository.Get")
+	defer done(&err)
+
+	stmt, err := r.db.PrepareContext(ctx, "SELECT "+webhookColumns+" FROM webhooks WHERE id = ?")
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	return scanWebhook(stmt.QueryRowContext(ctx, id))
+}
+
+func (r *webhookRepository) GetAll(ctx context.Context) (_ []*Webhook, err error) {
+	ctx, done := startOperation(ctx, "webhookRepository.GetAll")
+	defer done(&err)
+
+	stmt, err := r.db.PrepareContext(ctx, "SELECT "+webhookColumns+" FROM webhooks ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := make([]*Webhook, 0)
+	for rows.Next() {
+		w, err := scanWebhook(rows)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, w)
+	}
+	return results, rows.Err()
+}
+
+// Update changes the URL, events and disabled flag of a webhook, keeping its
+// secret.
+func (r *webhookRepository) Update(ctx context.Context, webhook Webhook) (_ *Webhook, err error) {
+	ctx, done := startOperation(ctx, "webhookRepository.Update")
+	defer done(&err)
+
+	var current *Webhook
+	err = inTx(ctx, r.db, func(tx *dialectTx) error {
+		getStmt, err := tx.PrepareContext(ctx, "SELECT "+webhookColumns+" FROM webhooks WHERE id = ?")
+		if err != nil {
+			return err
+		}
+		defer getStmt.Close()
+
+		if current, err = scanWebhook(getStmt.QueryRowContext(ctx, webhook.ID)); err != nil {
+			return err
+		}
+
+		stmt, err := tx.PrepareContext(ctx, "UPDATE webhooks SET url = ?, events = ?, disabled = ? WHERE id = ?")
+		if err != nil {
+			return err
+		}
+		defer stmt.Close()
+
+		_, err = stmt.ExecContext(ctx, webhook.URL, strings.Join(webhook.Events, ","), webhook.Disabled, webhook.ID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	current.URL, current.Events, current.Disabled = webhook.URL, webhook.Events, webhook.Disabled
+	return current, nil
+}
+
+// Delete removes a webhook along with its deliveries.
+func (r *webhookRepository) Delete(ctx context.Context, id int64) (err error) {
+	ctx, done := startOperation(ctx, "webhookRepository.Delete")
+	defer done(&err)
+
+	return inTx(ctx, r.db, func(tx *dialectTx) error {
+		if err := purgeFrom(ctx, tx, "webhook_deliveries", "webhook_id = ?", id); err != nil {
+			return err
+		}
+		stmt, err := tx.PrepareContext(ctx, "DELETE FROM webhooks WHERE id = ?")
+		if err != nil {
+			return err
+		}
+		defer stmt.Close()
+
+		result, err := stmt.ExecContext(ctx, id)
+		if err != nil {
+			return err
+		}
+		affected, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if affected == 0 {
+			return sql.ErrNoRows
+		}
+		return nil
+	})
+}
+
+// CreateDeliveries queues deliveries, all at once or none.
+func (r *webhookRepository) CreateDeliveries(ctx context.Context, deliveries []Delivery) (_ []*Delivery, err error) {
+	ctx, done := startOperation(ctx, "webhookRepository.CreateDeliveries")
+	defer done(&err)
+
+	created := make([]*Delivery, 0, len(deliveries))
+	err = inTx(ctx, r.db, func(tx *dialectTx) error {
+		for _, d := range deliveries {
+			d.CreatedAt = now().UTC()
+			d.ID, err = tx.dialect.insert(ctx, tx, "INSERT INTO webhook_deliveries(webhook_id, event_id, event, payload, status, attempts, next_attempt_at, created_at) VALUES(?, ?, ?, ?, ?, ?, ?, ?)",
+				d.WebhookID, d.EventID, d.Event, string(d.Payload), d.Status, d.Attempts, d.NextAttemptAt, d.CreatedAt)
+			if err != nil {
+				return err
+			}
+			d := d
+			created = append(created, &d)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return created, nil
+}
+
+func (r *webhookRepository) GetDelivery(ctx context.Context, id int64) (_ *Delivery, err error) {
+	ctx, done := startOperation(ctx, "webhookRepository.GetDelivery")
+	defer done(&err)
+
+	stmt, err := r.db.PrepareContext(ctx, "SELECT "+deliveryColumns+" FROM webhook_deliveries WHERE id = ?")
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	return scanDelivery(stmt.QueryRowContext(ctx, id))
+}
+
+func (r *webhookRepository) GetDeliveries(ctx context.Context, query DeliveryQuery) (_ *DeliveryPage, err error) {
+	ctx, done := startOperation(ctx, "webhookRepository.GetDeliveries")
+	defer done(&err)
+
+	if err := query.Validate(); err != nil {
+		return nil, err
+	}
+	where := ""
+	args := make([]interface{}, 0)
+	if query.WebhookID != 0 {
+		where = appendCondition(where, "webhook_id = ?")
+		args = append(args, query.WebhookID)
+	}
+	if query.Status != "" {
+		where = appendCondition(where, "status = ?")
+		args = append(args, query.Status)
+	}
+
+	countStmt, err := r.db.PrepareContext(ctx, "SELECT COUNT(*) FROM webhook_deliveries"+where)
+	if err != nil {
+		return nil, err
+	}
+	defer countStmt.Close()
+
+	page := DeliveryPage{Deliveries: make([]*Delivery, 0)}
+	if err := countStmt.QueryRowContext(ctx, args...).Scan(&page.Total); err != nil {
+		return nil, err
+	}
+
+	if query.Cursor != 0 {
+		where = appendCondition(where, "id < ?")
+		args = append(args, query.Cursor)
+	}
+	args = append(args, query.Limit)
+
+	stmt, err := r.db.PrepareContext(ctx, "SELECT "+deliveryColumns+" FROM webhook_deliveries"+where+" ORDER BY id DESC LIMIT ?")
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		d, err := scanDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+		page.Deliveries = append(page.Deliveries, d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(page.Deliveries) == query.Limit {
+		page.NextCursor = EncodeCursor(page.Deliveries[len(page.Deliveries)-1].ID)
+	}
+	return &page, nil
+}
+
+// ClaimDue returns up to limit pending deliveries whose next attempt is due,
+// after pushing that attempt back by lease so that no other dispatcher claims
+// them meanwhile. A dispatcher that dies while delivering thereby lets another
+// retry once the lease ran out.
+func (r *webhookRepository) ClaimDue(ctx context.Context, lease time.Duration, limit int) (_ []*Delivery, err error) {
+	ctx, done := startOperation(ctx, "webhookRepository.ClaimDue")
+	defer done(&err)
+
+	claimed := make([]*Delivery, 0)
+	err = inTx(ctx, r.db, func(tx *dialectTx) error {
+		at := now().UTC()
+		stmt, err := tx.PrepareContext(ctx, "SELECT "+deliveryColumns+" FROM webhook_deliveries WHERE status = ? AND next_attempt_at <= ? ORDER BY next_attempt_at LIMIT ?")
+		if err != nil {
+			return err
+		}
+		defer stmt.Close()
+
+		rows, err := stmt.QueryContext(ctx, DeliveryPending, at, limit)
+		if err != nil {
+			return err
+		}
+		due := make([]*Delivery, 0)
+		for rows.Next() {
+			d, err := scanDelivery(rows)
+			if err != nil {
+				rows.Close()
+				return err
+			}
+			due = append(due, d)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		claimStmt, err := tx.PrepareContext(ctx, "UPDATE webhook_deliveries SET next_attempt_at = ? WHERE id = ? AND status = ? AND next_attempt_at <= ?")
+		if err != nil {
+			return err
+		}
+		defer claimStmt.Close()
+
+		leased := at.Add(lease)
+		for _, d := range due {
+			result, err := claimStmt.ExecContext(ctx, leased, d.ID, DeliveryPending, at)
+			if err != nil {
+				return err
+			}
+			if affected, err := result.RowsAffected(); err != nil {
+				return err
+			} else if affected == 1 {
+				d.NextAttemptAt = &leased
+				claimed = append(claimed, d)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return claimed, nil
+}
+
+// UpdateDelivery records the outcome of an attempt.
+func (r *webhookRepository) UpdateDelivery(ctx context.Context, d Delivery) (err error) {
+	ctx, done := startOperation(ctx, "webhookRepository.UpdateDelivery")
+	defer done(&err)
+
+	stmt, err := r.db.PrepareContext(ctx, "UPDATE webhook_deliveries SET status = ?, attempts = ?, next_attempt_at = ?, response_code = ?, last_error = ?, delivered_at = ? WHERE id = ?")
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	result, err := stmt.ExecContext(ctx, d.Status, d.Attempts, d.NextAttemptAt, d.ResponseCode, d.Error, d.DeliveredAt, d.ID)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
@@ -0,0 +1,165 @@
+package model
+
+import (
+	"context"
+	"database/sql"
+	"github.com/DATA-DOG/go-sqlmock"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+func TestDelivery_Fail(t *testing.T) {
+	at := time.Date(2022, 1, 10, 9, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		attempts int
+		want     time.Duration
+		dead     bool
+	}{
+		{name: "First attempt", attempts: 0, want: 30 * time.Second},
+		{name: "Third attempt", attempts: 2, want: 2 * time.Minute},
+		{name: "Capped", attempts: 6, want: 10 * time.Minute},
+		{name: "Out of attempts", attempts: 7, dead: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := Delivery{Status: DeliveryPending, Attempts: tt.attempts}
+			d.Fail(503, "503 Service Unavailable", at, 30*time.Second, 10*time.Minute, 8)
+			if d.Attempts != tt.attempts+1 || d.ResponseCode != 503 || d.Error != "503 Service Unavailable" {
+				t.Errorf("Fail() = %+v", d)
+			}
+			if tt.dead {
+				if d.Status != DeliveryDead || d.NextAttemptAt != nil {
+					t.Errorf("Fail() = %+v, want dead", d)
+				}
+				return
+			}
+			if d.Status != DeliveryPending || d.NextAttemptAt == nil || !d.NextAttemptAt.Equal(at.Add(tt.want)) {
+				t.Errorf("Fail() next attempt = %v, want %v", d.NextAttemptAt, at.Add(tt.want))
+			}
+		})
+	}
+}
+
+func TestDelivery_Fail_TruncatesError(t *testing.T) {
+	var d Delivery
+	d.Fail(0, "x"+strings.Repeat("é", maxDeliveryError), time.Now(), time.Second, time.Minute, 3)
+	if len(d.Error) != maxDeliveryError-1 || !utf8.ValidString(d.Error) {
+		t.Errorf("Fail() error of %d bytes, valid %t, want the last whole character before %d bytes", len(d.Error), utf8.ValidString(d.Error), maxDeliveryError)
+	}
+}
+
+func TestSignWebhookPayload(t *testing.T) {
+	got := SignWebhookPayload("whsec_test", 1642000000, []byte(`{"id":"e1"}`))
+	want := "t=1642000000,v1=f5b0d4140e9b02f82fe03af263d0830bf9f0dd13a1bd9161e8e491989ca62575"
+	if got != want {
+		t.Errorf("SignWebhookPayload() = %q, want %q", got, want)
+	}
+}
+
+func TestWebhookRepository_ClaimDue(t *testing.T) {
+	db, mock := NewMock()
+	defer db.Close()
+
+	r := NewWebhookRepository(db, MySQL)
+	at := time.Now().UTC()
+	columns := []string{"id", "webhook_id", "event_id", "event", "payload", "status", "attempts", "next_attempt_at", "response_code", "last_error", "created_at", "delivered_at"}
+	mock.ExpectBegin()
+	mock.ExpectPrepare("SELECT (.+) FROM webhook_deliveries WHERE status = \\? AND next_attempt_at <= \\?").ExpectQuery().
+		WithArgs(DeliveryPending, sqlmock.AnyArg(), 20).
+		WillReturnRows(sqlmock.NewRows(columns).
+			AddRow(1, 1, "e1", EventDocumentCreated, `{}`, DeliveryPending, 0, at, 0, "", at, nil).
+			AddRow(2, 1, "e2", EventDocumentUpdated, `{}`, DeliveryPending, 1, at, 500, "500 Internal Server Error", at, nil))
+	claim := mock.ExpectPrepare("UPDATE webhook_deliveries SET next_attempt_at")
+	claim.ExpectExec().WithArgs(sqlmock.AnyArg(), 1, DeliveryPending, sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(0, 1))
+	// Another dispatcher claimed the second delivery in the meantime.
+	claim.ExpectExec().WithArgs(sqlmock.AnyArg(), 2, DeliveryPending, sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+
+	claimed, err := r.ClaimDue(context.Background(), time.Minute, 20)
+	if err != nil {
+		t.Fatalf("ClaimDue() error = %v", err)
+	}
+	if len(claimed) != 1 || claimed[0].ID != 1 || !claimed[0].NextAttemptAt.After(at) {
+		t.Errorf("ClaimDue() = %v, want delivery 1 leased", claimed)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestWebhookRepositories(t *testing.T) {
+	db := openSQLite(t)
+	repositories := map[string]webhookRepositoryInterface{
+		"Memory": NewMemoryWebhookRepository(),
+		"SQLite": NewWebhookRepository(db, SQLite),
+	}
+	for name, r := range repositories {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			webhook, err := r.Create(ctx, Webhook{URL: "https://example.com/hook", Secret: "whsec_test", Events: []string{EventDocumentCreated}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			webhook.Events = []string{EventDocumentCreated, EventDocumentSigned}
+			if _, err := r.Update(ctx, *webhook); err != nil {
+				t.Fatal(err)
+			}
+			got, err := r.Get(ctx, webhook.ID)
+			if err != nil || got.Secret != "whsec_test" || len(got.Events) != 2 {
+				t.Fatalf("Get() = %+v, %v, want the updated webhook with its secret", got, err)
+			}
+			if _, err := r.Update(ctx, Webhook{ID: webhook.ID + 1, URL: "https://example.com", Events: []string{EventDocumentCreated}}); err != sql.ErrNoRows {
+				t.Errorf("Update() of an unknown webhook error = %v, want %v", err, sql.ErrNoRows)
+			}
+
+			due, later := now().UTC().Add(-time.Second), now().UTC().Add(time.Hour)
+			deliveries, err := r.CreateDeliveries(ctx, []Delivery{
+				{WebhookID: webhook.ID, EventID: "e1", Event: EventDocumentCreated, Payload: []byte(`{"id":"e1"}`), Status: DeliveryPending, NextAttemptAt: &due},
+				{WebhookID: webhook.ID, EventID: "e2", Event: EventDocumentSigned, Payload: []byte(`{"id":"e2"}`), Status: DeliveryPending, NextAttemptAt: &later},
+			})
+			if err != nil || len(deliveries) != 2 {
+				t.Fatalf("CreateDeliveries() = %v, %v", deliveries, err)
+			}
+
+			claimed, err := r.ClaimDue(ctx, time.Minute, 10)
+			if err != nil || len(claimed) != 1 || claimed[0].EventID != "e1" || string(claimed[0].Payload) != `{"id":"e1"}` {
+				t.Fatalf("ClaimDue() = %v, %v, want the due delivery", claimed, err)
+			}
+			if again, err := r.ClaimDue(ctx, time.Minute, 10); err != nil || len(again) != 0 {
+				t.Errorf("ClaimDue() while leased = %v, %v, want none", again, err)
+			}
+
+			claimed[0].Fail(0, "connection refused", now().UTC(), time.Second, time.Minute, 1)
+			if err := r.UpdateDelivery(ctx, *claimed[0]); err != nil {
+				t.Fatal(err)
+			}
+			dead, err := r.GetDeliveries(ctx, DeliveryQuery{Status: DeliveryDead})
+			if err != nil || len(dead.Deliveries) != 1 || dead.Deliveries[0].Error != "connection refused" || dead.Total != 1 {
+				t.Fatalf("GetDeliveries() dead = %v, %v", dead, err)
+			}
+
+			page, err := r.GetDeliveries(ctx, DeliveryQuery{WebhookID: webhook.ID, Limit: 1})
+			if err != nil || len(page.Deliveries) != 1 || page.Deliveries[0].EventID != "e2" || page.Total != 2 || page.NextCursor == "" {
+				t.Fatalf("GetDeliveries() first page = %v, %v", page, err)
+			}
+			cursor, _ := DecodeCursor(page.NextCursor)
+			page, err = r.GetDeliveries(ctx, DeliveryQuery{WebhookID: webhook.ID, Limit: 1, Cursor: cursor})
+			if err != nil || len(page.Deliveries) != 1 || page.Deliveries[0].EventID != "e1" {
+				t.Fatalf("GetDeliveries() second page = %v, %v", page, err)
+			}
+
+			if err := r.Delete(ctx, webhook.ID); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := r.GetDelivery(ctx, deliveries[0].ID); err != sql.ErrNoRows {
+				t.Errorf("GetDelivery() after Delete() error = %v, want %v", err, sql.ErrNoRows)
+			}
+			if err := r.Delete(ctx, webhook.ID); err != sql.ErrNoRows {
+				t.Errorf("Delete() twice error = %v, want %v", err, sql.ErrNoRows)
+			}
+		})
+	}
+}
@@ -0,0 +1,41 @@
+// Package response records what is written to a response, for the middlewares
+// that report on requests once they are served.
+package response
+
+import "net/http"
+
+// Recorder remembers the status code, the number of body bytes and the error
+// of the response written through it.
+type Recorder struct {
+	http.ResponseWriter
+	Code  int
+	Bytes int
+	Err   error
+}
+
+// NewRecorder wraps w, assuming the status is 200 until another one is
+// written.
+func NewRecorder(w http.ResponseWriter) *Recorder {
+	return &Recorder{ResponseWriter: w, Code: http.StatusOK}
+}
+
+func (r *Recorder) WriteHeader(code int) {
+	r.Code = code
+	r.ResponseWriter.WriteHeader(code)
+}
+
+func (r *Recorder) Write(b []byte) (int, error) {
+	n, err := r.ResponseWriter.Write(b)
+	r.Bytes += n
+	return n, err
+}
+
+func (r *Recorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+// RecordError keeps the error the response answers, which the handlers do
+// not write to the response itself.
+func (r *Recorder) RecordError(err error) {
+	r.Err = err
+}
@@ -0,0 +1,34 @@
+package response
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRecorder_Nested(t *testing.T) {
+	rr := httptest.NewRecorder()
+	outer := NewRecorder(rr)
+	inner := NewRecorder(outer)
+
+	inner.WriteHeader(http.StatusCreated)
+	inner.Write([]byte("created"))
+	inner.RecordError(errors.New("unused"))
+
+	for _, r := range []*Recorder{outer, inner} {
+		assert.Equal(t, http.StatusCreated, r.Code)
+		assert.Equal(t, len("created"), r.Bytes)
+	}
+	assert.Nil(t, outer.Err, "errors are recorded by whoever hands them out")
+	assert.EqualError(t, inner.Err, "unused")
+	assert.Equal(t, outer, inner.Unwrap())
+	assert.Equal(t, "created", rr.Body.String())
+}
+
+func TestNewRecorder_DefaultsToOK(t *testing.T) {
+	r := NewRecorder(httptest.NewRecorder())
+	r.Write([]byte("{}"))
+	assert.Equal(t, http.StatusOK, r.Code)
+}
@@ -0,0 +1,68 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"precisely/model"
+)
+
+// authorize fails unless the principal of ctx may perform action on doc. A
+// principal that may not even read doc is told it does not exist, so that
+// document ids cannot be probed.
+func authorize(ctx context.Context, doc *model.Document, action model.Action) error {
+	p := model.PrincipalFrom(ctx)
+	if p == nil {
+		return model.ForbiddenValue
+	}
+	if p.Can(doc, nil, action) {
+		return nil
+	}
+	grants, err := model.GrantRepository.GetAll(ctx, doc.ID)
+	if err != nil {
+		return err
+	}
+	if p.Can(doc, grants, action) {
+		return nil
+	}
+	if p.Can(doc, grants, model.ActionRead) {
+		return model.ForbiddenValue
+	}
+	return sql.ErrNoRows
+}
+
+// authorizeID is authorize for the document of an id, which admins need not
+// load.
+func authorizeID(ctx context.Context, id int64, action model.Action) error {
+	if p := model.PrincipalFrom(ctx); p != nil && p.HasRole(model.RoleAdmin) {
+		return nil
+	}
+	doc, err := model.DocumentRepository.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+	return authorize(ctx, doc, action)
+}
+
+// actAs fails unless the principal of ctx is the signee of the given name, or
+// an admin. Sharing a document with a signer lets them sign it for themselves
+// only, whoever owns it.
+func actAs(ctx context.Context, signee string) error {
+	p := model.PrincipalFrom(ctx)
+	if p == nil || !p.HasRole(model.RoleAdmin) && p.Subject != signee {
+		return model.ForbiddenValue
+	}
+	return nil
+}
+
+// visibleTo is the principal a listing must be restricted to, nil for admins
+// who see every document.
+func visibleTo(ctx context.Context) (*model.Principal, error) {
+	p := model.PrincipalFrom(ctx)
+	if p == nil {
+		return nil, model.ForbiddenValue
+	}
+	if p.HasRole(model.RoleAdmin) {
+		return nil, nil
+	}
+	return p, nil
+}
@@ -0,0 +1,97 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"precisely/model"
+	"precisely/tracing"
+)
+
+// apiKeyPrefix marks the API keys of the service, which helps spotting them in
+// leaked configuration.
+const apiKeyPrefix = "prk_"
+
+var (
+	APIKeyService apiKeyServiceInterface = &apiKeyService{}
+)
+
+type apiKeyService struct{}
+
+type apiKeyServiceInterface interface {
+	Create(context.Context, model.APIKey) (*model.APIKey, error)
+	GetAll(context.Context) ([]*model.APIKey, error)
+	Revoke(context.Context, int64) error
+	Authenticate(context.Context, string) (*model.Principal, error)
+}
+
+// requireRole fails with ForbiddenValue unless the principal of ctx has role.
+func requireRole(ctx context.Context, role string) error {
+	if p := model.PrincipalFrom(ctx); p == nil || !p.HasRole(role) {
+		return model.ForbiddenValue
+	}
+	return nil
+}
+
+// Create generates a new API key, returned in Key this once.
+func (s *apiKeyService) Create(ctx context.Context, key model.APIKey) (*model.APIKey, error) {
+	ctx, span := tracing.Start(ctx, "apiKeyService.Create")
+	defer span.End()
+
+	if err := requireRole(ctx, model.RoleAdmin); err != nil {
+		return nil, err
+	}
+	if err := key.Validate(); err != nil {
+		return nil, err
+	}
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, err
+	}
+	plain := apiKeyPrefix + base64.RawURLEncoding.EncodeToString(secret)
+	key.Hash = model.HashAPIKey(plain)
+	created, err := model.APIKeyRepository.Create(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	created.Key = plain
+	return created, nil
+}
+
+func (s *apiKeyService) GetAll(ctx context.Context) ([]*model.APIKey, error) {
+	ctx, span := tracing.Start(ctx, "apiKeyService.GetAll")
+	defer span.End()
+
+	if err := requireRole(ctx, model.RoleAdmin); err != nil {
+		return nil, err
+	}
+	return model.APIKeyRepository.GetAll(ctx)
+}
+
+func (s *apiKeyService) Revoke(ctx context.Context, id int64) error {
+	ctx, span := tracing.Start(ctx, "apiKeyService.Revoke")
+	defer span.End()
+
+	if err := requireRole(ctx, model.RoleAdmin); err != nil {
+		return err
+	}
+	return model.APIKeyRepository.Revoke(ctx, id)
+}
+
+// Authenticate returns the principal of an API key, named after its id, or
+// UnauthenticatedValue when the key is unknown or revoked.
+func (s *apiKeyService) Authenticate(ctx context.Context, plain string) (*model.Principal, error) {
+	ctx, span := tracing.Start(ctx, "apiKeyService.Authenticate")
+	defer span.End()
+
+	key, err := model.APIKeyRepository.GetByHash(ctx, model.HashAPIKey(plain))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, model.UnauthenticatedValue
+		}
+		return nil, err
+	}
+	return &model.Principal{Subject: model.APIKeySubject(key.ID), Roles: key.Roles, Method: model.AuthAPIKey}, nil
+}
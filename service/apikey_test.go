@@ -0,0 +1,101 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"github.com/stretchr/testify/assert"
+	"precisely/model"
+	"strings"
+	"testing"
+)
+
+var (
+	createAPIKeyDAO    func(key model.APIKey) (*model.APIKey, error)
+	getAPIKeyByHashDAO func(hash string) (*model.APIKey, error)
+	getAPIKeysDAO      func() ([]*model.APIKey, error)
+	revokeAPIKeyDAO    func(id int64) error
+)
+
+type apiKeyDBMock struct{}
+
+func (m *apiKeyDBMock) Create(_ context.Context, key model.APIKey) (*model.APIKey, error) {
+	return createAPIKeyDAO(key)
+}
+
+func (m *apiKeyDBMock) GetByHash(_ context.Context, hash string) (*model.APIKey, error) {
+	return getAPIKeyByHashDAO(hash)
+}
+
+func (m *apiKeyDBMock) GetAll(_ context.Context) ([]*model.APIKey, error) {
+	return getAPIKeysDAO()
+}
+
+func (m *apiKeyDBMock) Revoke(_ context.Context, id int64) error {
+	return revokeAPIKeyDAO(id)
+}
+
+var admin = model.WithPrincipal(context.Background(), &model.Principal{Subject: "root", Roles: []string{model.RoleAdmin}})
+
+func TestAPIKeyService_Create(t *testing.T) {
+	model.APIKeyRepository = &apiKeyDBMock{}
+	var stored model.APIKey
+	createAPIKeyDAO = func(key model.APIKey) (*model.APIKey, error) {
+		stored = key
+		key.ID = 1
+		return &key, nil
+	}
+
+	created, err := APIKeyService.Create(admin, model.APIKey{Name: " ci ", Roles: []string{"writer"}})
+	assert.Nil(t, err)
+	assert.True(t, strings.HasPrefix(created.Key, apiKeyPrefix))
+	assert.Equal(t, "ci", created.Name)
+	assert.Equal(t, model.HashAPIKey(created.Key), stored.Hash)
+	assert.Empty(t, stored.Key)
+}
+
+func TestAPIKeyService_Create_Forbidden(t *testing.T) {
+	model.APIKeyRepository = &apiKeyDBMock{}
+	writer := model.WithPrincipal(context.Background(), &model.Principal{Subject: "ci", Roles: []string{"writer"}})
+	for _, ctx := range []context.Context{context.Background(), writer} {
+		_, err := APIKeyService.Create(ctx, model.APIKey{Name: "ci"})
+		assert.Equal(t, model.ForbiddenValue, err)
+		assert.Equal(t, model.ForbiddenValue, APIKeyService.Revoke(ctx, 1))
+	}
+}
+
+func TestAPIKeyService_Create_InvalidRoles(t *testing.T) {
+	model.APIKeyRepository = &apiKeyDBMock{}
+	_, err := APIKeyService.Create(admin, model.APIKey{Name: "ci", Roles: []string{"reader,writer"}})
+	assert.Equal(t, model.RolesInvalidValue, err)
+}
+
+func TestAPIKeyService_Authenticate(t *testing.T) {
+	model.APIKeyRepository = &apiKeyDBMock{}
+	getAPIKeyByHashDAO = func(hash string) (*model.APIKey, error) {
+		if hash == model.HashAPIKey("prk_valid") {
+			return &model.APIKey{ID: 1, Name: "ci", Roles: []string{"writer"}}, nil
+		}
+		return nil, sql.ErrNoRows
+	}
+
+	principal, err := APIKeyService.Authenticate(context.Background(), "prk_valid")
+	assert.Nil(t, err)
+	assert.Equal(t, &model.Principal{Subject: "apikey:1", Roles: []string{"writer"}, Method: model.AuthAPIKey}, principal)
+
+	_, err = APIKeyService.Authenticate(context.Background(), "prk_unknown")
+	assert.Equal(t, model.UnauthenticatedValue, err)
+}
+
+func TestAPIKeyService_Authenticate_NamedLikeAUser(t *testing.T) {
+	model.APIKeyRepository = &apiKeyDBMock{}
+	getAPIKeyByHashDAO = func(hash string) (*model.APIKey, error) {
+		return &model.APIKey{ID: 7, Name: "alice", Roles: []string{}}, nil
+	}
+
+	principal, err := APIKeyService.Authenticate(context.Background(), "prk_alice")
+	assert.Nil(t, err)
+	assert.Equal(t, "apikey:7", principal.Subject)
+	doc := &model.Document{ID: 1, Owner: "alice"}
+	grants := []*model.Grant{{DocumentID: 1, GranteeType: model.GranteeUser, Grantee: "alice", Role: model.RoleEditor}}
+	assert.False(t, principal.Can(doc, grants, model.ActionRead), "a key named alice must not act as alice")
+}
@@ -0,0 +1,82 @@
+package service
+
+import (
+	"context"
+	"precisely/logging"
+	"precisely/model"
+	"precisely/tracing"
+)
+
+var (
+	AuditService auditServiceInterface = &auditService{}
+)
+
+type auditService struct{}
+
+// auditServiceInterface reads the audit log, which only admins may do.
+type auditServiceInterface interface {
+	GetAll(context.Context, model.AuditQuery) (*model.AuditPage, error)
+	Verify(context.Context) (*model.AuditVerification, error)
+}
+
+// auditing has the repositories record the changes to documents made with
+// the returned context in the audit log, as done by the principal of ctx,
+// within the transaction of each change.
+func auditing(ctx context.Context) context.Context {
+	actor := ""
+	if p := model.PrincipalFrom(ctx); p != nil {
+		actor = p.Subject
+	}
+	return model.WithAuditor(ctx, actor, logging.RequestID(ctx))
+}
+
+// audit records an operation the principal of ctx performed on the document
+// of an id, zero for a listing, given the document before and after it, nil
+// when it did not exist. The entry is appended once the operation succeeded,
+// and failing to append it fails the operation: audit is meant for reads.
+func audit(ctx context.Context, action string, id int64, before, after *model.Document) error {
+	entry, _ := model.NewAuditEntry(auditing(ctx), action, id, before, after)
+	_, err := model.AuditRepository.Append(ctx, entry)
+	return err
+}
+
+func (s *auditService) GetAll(ctx context.Context, query model.AuditQuery) (*model.AuditPage, error) {
+	ctx, span := tracing.Start(ctx, "auditService.GetAll")
+	defer span.End()
+
+	if err := requireRole(ctx, model.RoleAdmin); err != nil {
+		return nil, err
+	}
+	if err := query.Validate(); err != nil {
+		return nil, err
+	}
+	return model.AuditRepository.GetAll(ctx, query)
+}
+
+// Verify walks the whole audit log in order and reports the first entry that
+// does not link to the one before it or whose hash does not match its content.
+func (s *auditService) Verify(ctx context.Context) (*model.AuditVerification, error) {
+	ctx, span := tracing.Start(ctx, "auditService.Verify")
+	defer span.End()
+
+	if err := requireRole(ctx, model.RoleAdmin); err != nil {
+		return nil, err
+	}
+	result := &model.AuditVerification{Valid: true}
+	query := model.AuditQuery{Limit: model.MaxListLimit}
+	for {
+		page, err := model.AuditRepository.GetAll(ctx, query)
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range page.Entries {
+			if !result.Check(entry) {
+				return result, nil
+			}
+		}
+		if page.NextCursor == "" {
+			return result, nil
+		}
+		query.Cursor = page.Entries[len(page.Entries)-1].ID
+	}
+}
@@ -0,0 +1,163 @@
+package service
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"precisely/model"
+	"testing"
+)
+
+// TestMain keeps the audit log, the outbox and the webhook deliveries the
+// services write in memory.
+func TestMain(m *testing.M) {
+	model.AuditRepository = model.NewMemoryAuditRepository()
+	model.WebhookRepository = model.NewMemoryWebhookRepository()
+	_, _, _, _, model.OutboxRepository, _ = model.NewMemoryRepositories()
+	os.Exit(m.Run())
+}
+
+var getAuditDAO func(query model.AuditQuery) (*model.AuditPage, error)
+
+type auditDBMock struct{}
+
+func (m *auditDBMock) Append(_ context.Context, entry model.AuditEntry) (*model.AuditEntry, error) {
+	return &entry, nil
+}
+
+func (m *auditDBMock) GetAll(_ context.Context, query model.AuditQuery) (*model.AuditPage, error) {
+	return getAuditDAO(query)
+}
+
+// chain links entries the way the audit repositories do.
+func chain(entries ...model.AuditEntry) []*model.AuditEntry {
+	chained := make([]*model.AuditEntry, 0, len(entries))
+	prev := ""
+	for i := range entries {
+		e := entries[i]
+		e.ID = int64(i + 1)
+		e.PrevHash = prev
+		e.Hash = e.ComputeHash()
+		prev = e.Hash
+		chained = append(chained, &e)
+	}
+	return chained
+}
+
+// TestDocumentService_Audit has the changes recorded by the repository within
+// their transaction and the reads by the service.
+func TestDocumentService_Audit(t *testing.T) {
+	documents, grants := model.DocumentRepository, model.GrantRepository
+	defer func() {
+		model.DocumentRepository, model.GrantRepository = documents, grants
+		model.AuditRepository = model.NewMemoryAuditRepository()
+	}()
+	model.DocumentRepository, _, _, model.GrantRepository, _, model.AuditRepository = model.NewMemoryRepositories()
+
+	created, err := DocumentService.Create(as("alice"), model.Document{Title: "title", Signee: "signee"})
+	assert.Nil(t, err)
+	updated, err := DocumentService.Update(as("alice"), model.Document{ID: created.ID, Title: "new title", Signee: "signee"})
+	assert.Nil(t, err)
+	_, err = DocumentService.Get(as("alice"), created.ID)
+	assert.Nil(t, err)
+	_, err = DocumentService.Get(as("dave"), created.ID)
+	assert.NotNil(t, err)
+
+	page, err := AuditService.GetAll(admin, model.AuditQuery{DocumentID: created.ID})
+	assert.Nil(t, err)
+	if assert.Len(t, page.Entries, 3) {
+		assert.Equal(t, "alice", page.Entries[0].Actor)
+		assert.Equal(t, model.AuditCreate, page.Entries[0].Action)
+		assert.Equal(t, "", page.Entries[0].BeforeHash)
+		assert.Equal(t, created.Digest(), page.Entries[0].AfterHash)
+		assert.Equal(t, model.AuditUpdate, page.Entries[1].Action)
+		assert.Equal(t, created.Digest(), page.Entries[1].BeforeHash)
+		assert.Equal(t, updated.Digest(), page.Entries[1].AfterHash)
+		assert.Equal(t, model.AuditRead, page.Entries[2].Action)
+	}
+
+	verification, err := AuditService.Verify(admin)
+	assert.Nil(t, err)
+	assert.Equal(t, &model.AuditVerification{Valid: true, Entries: 3, Head: page.Entries[2].Hash}, verification)
+}
+
+// TestDocumentService_AuditPurge has a purge recorded by the repository within
+// its transaction, as done by the principal who purged.
+func TestDocumentService_AuditPurge(t *testing.T) {
+	documents, grants := model.DocumentRepository, model.GrantRepository
+	defer func() {
+		model.DocumentRepository, model.GrantRepository = documents, grants
+		model.AuditRepository = model.NewMemoryAuditRepository()
+	}()
+	model.DocumentRepository, _, _, model.GrantRepository, _, model.AuditRepository = model.NewMemoryRepositories()
+
+	created, err := DocumentService.Create(as("alice"), model.Document{Title: "title", Signee: "signee"})
+	assert.Nil(t, err)
+	assert.Nil(t, DocumentService.Delete(as("alice"), created.ID, created.Version))
+	assert.Nil(t, DocumentService.Purge(as("alice"), created.ID))
+
+	page, err := AuditService.GetAll(admin, model.AuditQuery{Action: model.AuditPurge})
+	assert.Nil(t, err)
+	if assert.Len(t, page.Entries, 1) {
+		assert.Equal(t, "alice", page.Entries[0].Actor)
+		assert.Equal(t, created.ID, page.Entries[0].DocumentID)
+		assert.Equal(t, created.Digest(), page.Entries[0].BeforeHash)
+		assert.Equal(t, "", page.Entries[0].AfterHash)
+	}
+}
+
+func TestAuditService_Verify(t *testing.T) {
+	model.AuditRepository = &auditDBMock{}
+	defer func() { model.AuditRepository = model.NewMemoryAuditRepository() }()
+
+	entries := chain(
+		model.AuditEntry{Actor: "alice", Action: model.AuditCreate, DocumentID: 1, AfterHash: "a"},
+		model.AuditEntry{Actor: "bob", Action: model.AuditUpdate, DocumentID: 1, BeforeHash: "a", AfterHash: "b"},
+		model.AuditEntry{Actor: "alice", Action: model.AuditDelete, DocumentID: 1, BeforeHash: "b"},
+	)
+	tampered := *entries[1]
+	tampered.AfterHash = "c"
+	relinked := chain(model.AuditEntry{Actor: "bob", Action: model.AuditUpdate, DocumentID: 1})[0]
+	relinked.ID = 2
+
+	tests := []struct {
+		name    string
+		entries []*model.AuditEntry
+		want    *model.AuditVerification
+	}{
+		{"Intact", entries, &model.AuditVerification{Valid: true, Entries: 3, Head: entries[2].Hash}},
+		{"Empty", nil, &model.AuditVerification{Valid: true}},
+		{"Changed entry", []*model.AuditEntry{entries[0], &tampered, entries[2]},
+			&model.AuditVerification{Entries: 1, Head: entries[0].Hash, BrokenAt: 2, Reason: "hash does not match the content of the entry"}},
+		{"Removed entry", []*model.AuditEntry{entries[0], entries[2]},
+			&model.AuditVerification{Entries: 1, Head: entries[0].Hash, BrokenAt: 3, Reason: "prev_hash does not match the hash of the previous entry"}},
+		{"Replaced entry", []*model.AuditEntry{entries[0], relinked},
+			&model.AuditVerification{Entries: 1, Head: entries[0].Hash, BrokenAt: 2, Reason: "prev_hash does not match the hash of the previous entry"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			getAuditDAO = func(query model.AuditQuery) (*model.AuditPage, error) {
+				page := &model.AuditPage{Entries: make([]*model.AuditEntry, 0)}
+				for _, e := range tt.entries {
+					if e.ID > query.Cursor && len(page.Entries) < 2 {
+						page.Entries = append(page.Entries, e)
+					}
+				}
+				if len(page.Entries) == 2 {
+					page.NextCursor = model.EncodeCursor(page.Entries[1].ID)
+				}
+				return page, nil
+			}
+			got, err := AuditService.Verify(admin)
+			assert.Nil(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestAuditService_Forbidden(t *testing.T) {
+	_, err := AuditService.GetAll(as("alice"), model.AuditQuery{})
+	assert.Equal(t, model.ForbiddenValue, err)
+	_, err = AuditService.Verify(as("alice"))
+	assert.Equal(t, model.ForbiddenValue, err)
+}
@@ -0,0 +1,79 @@
+package service
+
+import (
+	"context"
+	"precisely/model"
+	"precisely/tracing"
+)
+
+// Batch validates, authorizes and applies a batch of operations. In atomic
+// mode an invalid or forbidden operation fails the whole batch before anything
+// is applied, while in best-effort mode it is only reported in its own result.
+func (s *documentService) Batch(ctx context.Context, batch model.Batch) ([]model.BatchResult, error) {
+	ctx, span := tracing.Start(ctx, "documentService.Batch")
+	defer span.End()
+
+	if err := batch.Validate(); err != nil {
+		return nil, err
+	}
+
+	results := make([]model.BatchResult, len(batch.Operations))
+	valid := make([]model.BatchOperation, 0, len(batch.Operations))
+	indexes := make([]int, 0, len(batch.Operations))
+	for i, op := range batch.Operations {
+		err := op.Validate()
+		if err == nil {
+			err = authorizeOperation(ctx, &op)
+		}
+		if err != nil {
+			if batch.Mode == model.BatchAtomic {
+				return nil, &model.BatchOperationError{Index: i, Err: err}
+			}
+			results[i].Err = err
+			continue
+		}
+		valid = append(valid, op)
+		indexes = append(indexes, i)
+	}
+	if len(valid) == 0 {
+		return results, nil
+	}
+
+	applied, err := model.DocumentRepository.Batch(auditing(ctx), valid, batch.Mode)
+	if err != nil {
+		if opErr, ok := err.(*model.BatchOperationError); ok {
+			return nil, &model.BatchOperationError{Index: indexes[opErr.Index], Err: opErr.Err}
+		}
+		return nil, err
+	}
+	ChangeHub.Publish()
+	for i, result := range applied {
+		results[indexes[i]] = result
+	}
+	return results, nil
+}
+
+// authorizeOperation checks that the principal of ctx may apply op, like the
+// single document operations do, and sets the owner op writes.
+func authorizeOperation(ctx context.Context, op *model.BatchOperation) error {
+	switch op.Op {
+	case model.BatchCreate:
+		p := model.PrincipalFrom(ctx)
+		if p == nil {
+			return model.ForbiddenValue
+		}
+		op.Owner = p.Subject
+	case model.BatchUpdate, model.BatchDelete:
+		current, err := model.DocumentRepository.Get(ctx, op.ID)
+		if err != nil {
+			return err
+		}
+		op.Owner = current.Owner
+		action := model.ActionWrite
+		if op.Op == model.BatchDelete {
+			action = model.ActionDelete
+		}
+		return authorize(ctx, current, action)
+	}
+	return nil
+}
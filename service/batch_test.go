@@ -0,0 +1,66 @@
+package service
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"precisely/model"
+	"testing"
+)
+
+func TestDocumentService_Batch_BestEffort(t *testing.T) {
+	model.DocumentRepository = &dBMock{}
+	getMessageDAO = func(id int64) (*model.Document, error) {
+		return &model.Document{ID: id, Title: "title", Signee: "signee", Owner: "alice", Version: 2}, nil
+	}
+	var applied []model.BatchOperation
+	batchMessageDAO = func(ops []model.BatchOperation, mode model.BatchMode) ([]model.BatchResult, error) {
+		applied = ops
+		assert.Equal(t, model.BatchBestEffort, mode)
+		return []model.BatchResult{
+			{Document: &model.Document{ID: 1, Title: "title", Signee: "signee", Version: 1}},
+			{},
+		}, nil
+	}
+
+	results, err := DocumentService.Batch(admin, model.Batch{
+		Mode: model.BatchBestEffort,
+		Operations: []model.BatchOperation{
+			{Op: model.BatchCreate, Document: model.Document{Title: " title ", Signee: "signee"}},
+			{Op: model.BatchUpdate, Document: model.Document{ID: 1, Signee: "signee", Version: 1}},
+			{Op: model.BatchDelete, Document: model.Document{ID: 2}},
+			{Op: model.BatchDelete, Document: model.Document{ID: 3, Version: 2}},
+		},
+	})
+	assert.Nil(t, err)
+	assert.Len(t, applied, 2)
+	assert.Equal(t, "title", applied[0].Title)
+	assert.Equal(t, "root", applied[0].Owner)
+	assert.EqualValues(t, 3, applied[1].ID)
+	assert.Equal(t, "alice", applied[1].Owner)
+	assert.EqualValues(t, 1, results[0].Document.ID)
+	assert.Equal(t, model.TitleInvalidValue, results[1].Err)
+	assert.Equal(t, model.PreconditionRequiredValue, results[2].Err)
+	assert.Equal(t, model.BatchResult{}, results[3])
+}
+
+func TestDocumentService_Batch_Atomic(t *testing.T) {
+	model.DocumentRepository = &dBMock{}
+	batchMessageDAO = func(ops []model.BatchOperation, mode model.BatchMode) ([]model.BatchResult, error) {
+		return nil, errors.New("batch must not reach the repository")
+	}
+
+	results, err := DocumentService.Batch(admin, model.Batch{
+		Operations: []model.BatchOperation{
+			{Op: model.BatchCreate, Document: model.Document{Title: "title", Signee: "signee"}},
+			{Op: "upsert"},
+		},
+	})
+	assert.Nil(t, results)
+	assert.EqualError(t, err, "operation 1: "+model.BatchOperationInvalidValue.Error())
+	assert.True(t, errors.Is(err, model.BatchOperationInvalidValue))
+
+	_, err = DocumentService.Batch(admin, model.Batch{Mode: "eventually"})
+	assert.Equal(t, model.BatchModeInvalidValue, err)
+	_, err = DocumentService.Batch(admin, model.Batch{})
+	assert.Equal(t, model.BatchSizeInvalidValue, err)
+}
@@ -0,0 +1,137 @@
+package service
+
+import (
+	"context"
+	"precisely/model"
+	"precisely/tracing"
+	"time"
+)
+
+// ChangePollInterval is how often a read of the change feed waiting for new
+// changes looks for them.
+var ChangePollInterval = 250 * time.Millisecond
+
+// GetChanges reads the change feed as an admin, since it carries every
+// document. When there is no change after query.Since yet, it waits up to
+// query.Wait for one. The next cursor resumes after the last change returned,
+// or where the read started when there was none.
+func (s *documentService) GetChanges(ctx context.Context, query model.ChangeQuery) (*model.ChangePage, error) {
+	ctx, span := tracing.Start(ctx, "documentService.GetChanges")
+	defer span.End()
+
+	if err := requireRole(ctx, model.RoleAdmin); err != nil {
+		return nil, err
+	}
+	if err := query.Validate(); err != nil {
+		return nil, err
+	}
+	deadline := time.Now().Add(query.Wait)
+	for {
+		changes, err := model.DocumentRepository.GetChanges(ctx, query.Since, query.Limit)
+		if err != nil {
+			return nil, err
+		}
+		if len(changes) > 0 || !time.Now().Before(deadline) {
+			return newChangePage(query.Since, changes), nil
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(ChangePollInterval):
+		}
+	}
+}
+
+// StreamChanges hands send the changes matching filter as an admin, after the
+// since sequence or, when it is zero, from now on. send is first called with
+// nil once the stream is subscribed. It returns once ctx is done or send
+// fails. Should send fall behind the hub, the stream catches up from the
+// change feed and subscribes again.
+func (s *documentService) StreamChanges(ctx context.Context, since int64, filter model.ChangeFilter, send func(*model.Change) error) error {
+	ctx, span := tracing.Start(ctx, "documentService.StreamChanges")
+	defer span.End()
+
+	if err := requireRole(ctx, model.RoleAdmin); err != nil {
+		return err
+	}
+	subscribed := false
+	for {
+		sub, err := ChangeHub.Subscribe(ctx, filter)
+		if err != nil {
+			return err
+		}
+		if since == 0 {
+			since = sub.Since
+		}
+		if !subscribed {
+			if err := send(nil); err != nil {
+				ChangeHub.Unsubscribe(sub)
+				return err
+			}
+			subscribed = true
+		}
+		since, err = replayChanges(ctx, since, filter, send)
+		if err == nil {
+			since, err = streamSubscription(ctx, sub, since, send)
+		}
+		ChangeHub.Unsubscribe(sub)
+		if err != nil || !sub.Lagged() || ctx.Err() != nil {
+			return err
+		}
+	}
+}
+
+// replayChanges hands send the changes matching filter after since from the
+// change feed, and returns the sequence of the last change it read.
+func replayChanges(ctx context.Context, since int64, filter model.ChangeFilter, send func(*model.Change) error) (int64, error) {
+	for {
+		changes, err := model.DocumentRepository.GetChanges(ctx, since, model.MaxListLimit)
+		if err != nil {
+			return since, err
+		}
+		for _, change := range changes {
+			if filter.Matches(change) {
+				if err := send(change); err != nil {
+					return since, err
+				}
+			}
+			since = change.Sequence
+		}
+		if len(changes) < model.MaxListLimit {
+			return since, nil
+		}
+	}
+}
+
+// streamSubscription hands send the changes of sub after since, which the
+// replay may already have sent, until ctx is done or sub is closed.
+func streamSubscription(ctx context.Context, sub *Subscription, since int64, send func(*model.Change) error) (int64, error) {
+	for {
+		select {
+		case <-ctx.Done():
+			return since, nil
+		case change, ok := <-sub.C:
+			if !ok {
+				return since, nil
+			}
+			if change.Sequence <= since {
+				continue
+			}
+			if err := send(change); err != nil {
+				return since, err
+			}
+			since = change.Sequence
+		}
+	}
+}
+
+func newChangePage(since int64, changes []*model.Change) *model.ChangePage {
+	page := &model.ChangePage{Changes: changes}
+	if len(changes) > 0 {
+		since = changes[len(changes)-1].Sequence
+	}
+	if since > 0 {
+		page.NextCursor = model.EncodeCursor(since)
+	}
+	return page
+}
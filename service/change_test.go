@@ -0,0 +1,107 @@
+package service
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"precisely/model"
+	"testing"
+	"time"
+)
+
+func TestDocumentService_GetChanges_Success(t *testing.T) {
+	model.DocumentRepository = &dBMock{}
+	getChangesMessageDAO = func(since int64, limit int) ([]*model.Change, error) {
+		assert.EqualValues(t, 3, since)
+		assert.EqualValues(t, model.DefaultListLimit, limit)
+		return []*model.Change{
+			{Sequence: 4, Operation: model.ChangeCreate, DocumentID: 2},
+			{Sequence: 7, Operation: model.ChangeDelete, DocumentID: 1},
+		}, nil
+	}
+	page, err := DocumentService.GetChanges(admin, model.ChangeQuery{Since: 3})
+	assert.Nil(t, err)
+	assert.EqualValues(t, 2, len(page.Changes))
+	assert.EqualValues(t, model.EncodeCursor(7), page.NextCursor)
+}
+
+func TestDocumentService_GetChanges_WaitsForChanges(t *testing.T) {
+	model.DocumentRepository = &dBMock{}
+	ChangePollInterval = time.Millisecond
+	polls := 0
+	getChangesMessageDAO = func(since int64, limit int) ([]*model.Change, error) {
+		polls++
+		if polls < 3 {
+			return []*model.Change{}, nil
+		}
+		return []*model.Change{{Sequence: 6, Operation: model.ChangeUpdate, DocumentID: 1}}, nil
+	}
+	page, err := DocumentService.GetChanges(admin, model.ChangeQuery{Since: 5, Wait: time.Second})
+	assert.Nil(t, err)
+	assert.EqualValues(t, 3, polls)
+	assert.EqualValues(t, 1, len(page.Changes))
+	assert.EqualValues(t, model.EncodeCursor(6), page.NextCursor)
+}
+
+func TestDocumentService_GetChanges_WaitElapses(t *testing.T) {
+	model.DocumentRepository = &dBMock{}
+	ChangePollInterval = time.Millisecond
+	getChangesMessageDAO = func(since int64, limit int) ([]*model.Change, error) {
+		return []*model.Change{}, nil
+	}
+	page, err := DocumentService.GetChanges(admin, model.ChangeQuery{Since: 5, Wait: 10 * time.Millisecond})
+	assert.Nil(t, err)
+	assert.EqualValues(t, 0, len(page.Changes))
+	assert.EqualValues(t, model.EncodeCursor(5), page.NextCursor)
+}
+
+func TestDocumentService_GetChanges_Forbidden(t *testing.T) {
+	model.DocumentRepository = &dBMock{}
+	page, err := DocumentService.GetChanges(as("bob"), model.ChangeQuery{})
+	assert.Nil(t, page)
+	assert.Equal(t, model.ForbiddenValue, err)
+}
+
+func TestDocumentService_GetChanges_InvalidWait(t *testing.T) {
+	model.DocumentRepository = &dBMock{}
+	page, err := DocumentService.GetChanges(admin, model.ChangeQuery{Wait: time.Minute})
+	assert.Nil(t, page)
+	assert.Equal(t, model.WaitInvalidValue, err)
+}
+
+func TestDocumentService_StreamChanges_ResumesAndFilters(t *testing.T) {
+	changes := []*model.Change{
+		{Sequence: 1, DocumentID: 1},
+		{Sequence: 2, DocumentID: 2},
+		{Sequence: 3, DocumentID: 1},
+	}
+	feedMock(&changes)
+	ChangeHub = newChangeHub()
+
+	ctx, cancel := context.WithCancel(admin)
+	var sent []int64
+	err := DocumentService.StreamChanges(ctx, 1, model.ChangeFilter{DocumentID: 1}, func(change *model.Change) error {
+		if change == nil {
+			sent = append(sent, 0)
+			return nil
+		}
+		sent = append(sent, change.Sequence)
+		if change.Sequence == 3 {
+			changes = append(changes, &model.Change{Sequence: 4, DocumentID: 2}, &model.Change{Sequence: 5, DocumentID: 1})
+			go ChangeHub.fanOut(context.Background())
+		}
+		if change.Sequence == 5 {
+			cancel()
+		}
+		return nil
+	})
+	assert.Nil(t, err)
+	assert.EqualValues(t, []int64{0, 3, 5}, sent)
+}
+
+func TestDocumentService_StreamChanges_Forbidden(t *testing.T) {
+	err := DocumentService.StreamChanges(as("bob"), 0, model.ChangeFilter{}, func(*model.Change) error {
+		t.Error("send called")
+		return nil
+	})
+	assert.Equal(t, model.ForbiddenValue, err)
+}
@@ -1,7 +1,10 @@
 package service
 
 import (
+	"context"
 	"precisely/model"
+	"precisely/tracing"
+	"time"
 )
 
 var (
@@ -11,43 +14,139 @@ var (
 type documentService struct{}
 
 type documentServiceInterface interface {
-	Get(int64) (*model.Document, error)
-	Create(model.Document) (*model.Document, error)
-	Update(model.Document) (*model.Document, error)
-	Delete(int64) error
-	GetAll() ([]*model.Document, error)
+	Get(context.Context, int64) (*model.Document, error)
+	Create(context.Context, model.Document) (*model.Document, error)
+	Update(context.Context, model.Document) (*model.Document, error)
+	Patch(context.Context, int64, int64, string, []byte) (*model.Document, error)
+	Delete(context.Context, int64, int64) error
+	GetAll(context.Context, model.ListOptions) (*model.DocumentPage, error)
+	GetRevisions(context.Context, int64) ([]*model.Revision, error)
+	GetRevision(context.Context, int64, int64) (*model.Revision, error)
+	GetAsOf(context.Context, int64, time.Time) (*model.Document, error)
+	GetChanges(context.Context, model.ChangeQuery) (*model.ChangePage, error)
+	StreamChanges(context.Context, int64, model.ChangeFilter, func(*model.Change) error) error
+	Restore(context.Context, int64, int64, int64) (*model.Document, error)
+	RestoreDeleted(context.Context, int64) (*model.Document, error)
+	Purge(context.Context, int64) error
+	PurgeExpired(context.Context, time.Duration) (int64, error)
+	Batch(context.Context, model.Batch) ([]model.BatchResult, error)
 }
 
-func (s *documentService) Create(newDocument model.Document) (*model.Document, error) {
+func (s *documentService) Create(ctx context.Context, newDocument model.Document) (*model.Document, error) {
+	ctx, span := tracing.Start(ctx, "documentService.Create")
+	defer span.End()
+
 	if err := newDocument.Validate(); err != nil {
 		return nil, err
 	}
-	return model.DocumentRepository.Create(newDocument)
+	p := model.PrincipalFrom(ctx)
+	if p == nil {
+		return nil, model.ForbiddenValue
+	}
+	newDocument.Owner = p.Subject
+	created, err := model.DocumentRepository.Create(auditing(ctx), newDocument)
+	if err != nil {
+		return nil, err
+	}
+	ChangeHub.Publish()
+	return created, nil
 }
 
-func (s *documentService) Update(inputDocument model.Document) (*model.Document, error) {
+// Update overwrites a document expected to be at inputDocument.Version, or at
+// whichever version is current when it is zero. Its owner is kept.
+func (s *documentService) Update(ctx context.Context, inputDocument model.Document) (*model.Document, error) {
+	ctx, span := tracing.Start(ctx, "documentService.Update")
+	defer span.End()
+
 	if err := inputDocument.Validate(); err != nil {
 		return nil, err
 	}
-	_, err := model.DocumentRepository.Get(inputDocument.ID)
+	current, err := model.DocumentRepository.Get(ctx, inputDocument.ID)
+	if err != nil {
+		return nil, err
+	}
+	if err := authorize(ctx, current, model.ActionWrite); err != nil {
+		return nil, err
+	}
+	inputDocument.Owner = current.Owner
+	if inputDocument.Version == 0 {
+		inputDocument.Version = current.Version
+	}
+	if inputDocument.Version != current.Version {
+		return nil, model.VersionMismatchValue
+	}
+	updated, err := model.DocumentRepository.Update(auditing(ctx), inputDocument)
 	if err != nil {
 		return nil, err
 	}
-	return model.DocumentRepository.Update(inputDocument)
+	ChangeHub.Publish()
+	return updated, nil
 }
 
-func (s *documentService) Delete(id int64) error {
-	_, err := model.DocumentRepository.Get(id)
+// Delete moves a document expected to be at the given version to the trash,
+// or at whichever version is current when it is zero.
+func (s *documentService) Delete(ctx context.Context, id, version int64) error {
+	ctx, span := tracing.Start(ctx, "documentService.Delete")
+	defer span.End()
+
+	current, err := model.DocumentRepository.Get(ctx, id)
 	if err != nil {
 		return err
 	}
-	return model.DocumentRepository.Delete(id)
+	if err := authorize(ctx, current, model.ActionDelete); err != nil {
+		return err
+	}
+	if version == 0 {
+		version = current.Version
+	}
+	if version != current.Version {
+		return model.VersionMismatchValue
+	}
+	if err := model.DocumentRepository.Delete(auditing(ctx), id, version); err != nil {
+		return err
+	}
+	ChangeHub.Publish()
+	return nil
 }
 
-func (s *documentService) Get(id int64) (*model.Document, error) {
-	return model.DocumentRepository.Get(id)
+// Get returns a document the principal of ctx may read.
+func (s *documentService) Get(ctx context.Context, id int64) (*model.Document, error) {
+	ctx, span := tracing.Start(ctx, "documentService.Get")
+	defer span.End()
+
+	doc, err := model.DocumentRepository.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := authorize(ctx, doc, model.ActionRead); err != nil {
+		return nil, err
+	}
+	if err := audit(ctx, model.AuditRead, doc.ID, doc, doc); err != nil {
+		return nil, err
+	}
+	return doc, nil
 }
 
-func (s *documentService) GetAll() ([]*model.Document, error) {
-	return model.DocumentRepository.GetAll()
+// GetAll lists the documents the principal of ctx owns or holds a grant on,
+// or every document for admins.
+func (s *documentService) GetAll(ctx context.Context, opts model.ListOptions) (*model.DocumentPage, error) {
+	ctx, span := tracing.Start(ctx, "documentService.GetAll")
+	defer span.End()
+
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+	visible, err := visibleTo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	opts.VisibleTo = visible
+	page, err := model.DocumentRepository.GetAll(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	if err := audit(ctx, model.AuditList, 0, nil, nil); err != nil {
+		return nil, err
+	}
+	return page, nil
 }
@@ -1,44 +1,99 @@
 package service
 
 import (
+	"context"
 	"database/sql"
 	"github.com/go-sql-driver/mysql"
 	"github.com/stretchr/testify/assert"
 	"precisely/model"
 	"reflect"
 	"testing"
+	"time"
 )
 
 var (
 	getMessageDAO    func(id int64) (*model.Document, error)
 	createMessageDAO func(doc model.Document) (*model.Document, error)
 	updateMessageDAO func(doc model.Document) (*model.Document, error)
-	deleteMessageDAO func(id int64) error
-	getAllMessageDAO func() ([]*model.Document, error)
+	deleteMessageDAO func(id, version int64) error
+	getAllMessageDAO func(opts model.ListOptions) (*model.DocumentPage, error)
+
+	getRevisionsMessageDAO func(id int64) ([]*model.Revision, error)
+	getRevisionMessageDAO  func(id, rev int64) (*model.Revision, error)
+	getAsOfMessageDAO      func(id int64, asOf time.Time) (*model.Document, error)
+	getChangesMessageDAO   func(since int64, limit int) ([]*model.Change, error)
+	getLastSequenceDAO     func() (int64, error)
+
+	getTrashedMessageDAO         func(id int64) (*model.Document, error)
+	restoreDeletedMessageDAO     func(id int64) (*model.Document, error)
+	purgeMessageDAO              func(id int64) error
+	purgeDeletedBeforeMessageDAO func(before time.Time) (int64, error)
+
+	batchMessageDAO func(ops []model.BatchOperation, mode model.BatchMode) ([]model.BatchResult, error)
 )
 
 type dBMock struct{}
 
-func (m *dBMock) Get(id int64) (*model.Document, error) {
+func (m *dBMock) Get(_ context.Context, id int64) (*model.Document, error) {
 	return getMessageDAO(id)
 }
 
-func (m *dBMock) GetAll() ([]*model.Document, error) {
-	return getAllMessageDAO()
+func (m *dBMock) GetAll(_ context.Context, opts model.ListOptions) (*model.DocumentPage, error) {
+	return getAllMessageDAO(opts)
 }
 
-func (m *dBMock) Delete(id int64) error {
-	return deleteMessageDAO(id)
+func (m *dBMock) Delete(_ context.Context, id, version int64) error {
+	return deleteMessageDAO(id, version)
 }
 
-func (m *dBMock) Create(doc model.Document) (*model.Document, error) {
+func (m *dBMock) Create(_ context.Context, doc model.Document) (*model.Document, error) {
 	return createMessageDAO(doc)
 }
 
-func (m *dBMock) Update(doc model.Document) (*model.Document, error) {
+func (m *dBMock) Update(_ context.Context, doc model.Document) (*model.Document, error) {
 	return updateMessageDAO(doc)
 }
 
+func (m *dBMock) GetRevisions(_ context.Context, id int64) ([]*model.Revision, error) {
+	return getRevisionsMessageDAO(id)
+}
+
+func (m *dBMock) GetRevision(_ context.Context, id, rev int64) (*model.Revision, error) {
+	return getRevisionMessageDAO(id, rev)
+}
+
+func (m *dBMock) GetAsOf(_ context.Context, id int64, asOf time.Time) (*model.Document, error) {
+	return getAsOfMessageDAO(id, asOf)
+}
+
+func (m *dBMock) GetChanges(_ context.Context, since int64, limit int) ([]*model.Change, error) {
+	return getChangesMessageDAO(since, limit)
+}
+
+func (m *dBMock) GetLastSequence(_ context.Context) (int64, error) {
+	return getLastSequenceDAO()
+}
+
+func (m *dBMock) GetTrashed(_ context.Context, id int64) (*model.Document, error) {
+	return getTrashedMessageDAO(id)
+}
+
+func (m *dBMock) RestoreDeleted(_ context.Context, id int64) (*model.Document, error) {
+	return restoreDeletedMessageDAO(id)
+}
+
+func (m *dBMock) Purge(_ context.Context, id int64) error {
+	return purgeMessageDAO(id)
+}
+
+func (m *dBMock) PurgeDeletedBefore(_ context.Context, before time.Time) (int64, error) {
+	return purgeDeletedBeforeMessageDAO(before)
+}
+
+func (m *dBMock) Batch(_ context.Context, ops []model.BatchOperation, mode model.BatchMode) ([]model.BatchResult, error) {
+	return batchMessageDAO(ops, mode)
+}
+
 func (m *dBMock) Init(string, string, string, string, string, string) *sql.DB {
 	return nil
 }
@@ -57,7 +112,7 @@ func TestDocumentService_Get_Success(t *testing.T) {
 	getMessageDAO = func(id int64) (*model.Document, error) {
 		return mockData, nil
 	}
-	doc, err := DocumentService.Get(1)
+	doc, err := DocumentService.Get(admin, 1)
 	assert.NotNil(t, doc)
 	assert.Nil(t, err)
 	assert.True(t, reflect.DeepEqual(doc, mockData))
@@ -68,7 +123,7 @@ func TestDocumentService_Get_NotFound(t *testing.T) {
 	getMessageDAO = func(id int64) (*model.Document, error) {
 		return nil, sql.ErrNoRows
 	}
-	doc, err := DocumentService.Get(1)
+	doc, err := DocumentService.Get(admin, 1)
 	assert.Nil(t, doc)
 	assert.NotNil(t, err)
 	assert.EqualError(t, err, "sql: no rows in result set")
@@ -96,7 +151,7 @@ func TestDocumentService_Create_Success(t *testing.T) {
 		},
 		Signee: "signee",
 	}
-	savedDoc, err := DocumentService.Create(doc)
+	savedDoc, err := DocumentService.Create(admin, doc)
 	assert.Nil(t, err)
 	assert.NotNil(t, savedDoc)
 	assert.EqualValues(t, doc.Title, savedDoc.Title)
@@ -123,7 +178,7 @@ func TestDocumentService_Create_BadRequest(t *testing.T) {
 		},
 	}
 	for _, tt := range tests {
-		doc, err := DocumentService.Create(tt.doc)
+		doc, err := DocumentService.Create(admin, tt.doc)
 		assert.Nil(t, doc)
 		assert.NotNil(t, err)
 		assert.EqualValues(t, tt.errMessage, err.Error())
@@ -140,7 +195,7 @@ func TestDocumentService_Create_InternalFailure(t *testing.T) {
 		Title:  "title",
 		Signee: "signee",
 	}
-	savedDoc, err := DocumentService.Create(doc)
+	savedDoc, err := DocumentService.Create(admin, doc)
 	assert.Nil(t, savedDoc)
 	assert.NotNil(t, err)
 	assert.EqualValues(t, 1062, err.(*mysql.MySQLError).Number)
@@ -166,7 +221,7 @@ func TestDocumentService_Update_Success(t *testing.T) {
 		Title:  "update title",
 		Signee: "update signee",
 	}
-	updatedDoc, err := DocumentService.Update(doc)
+	updatedDoc, err := DocumentService.Update(admin, doc)
 	assert.NotNil(t, updatedDoc)
 	assert.Nil(t, err)
 	assert.EqualValues(t, doc.Title, updatedDoc.Title)
@@ -184,10 +239,10 @@ func TestDocumentService_Delete_Success(t *testing.T) {
 		}, nil
 	}
 
-	deleteMessageDAO = func(id int64) error {
+	deleteMessageDAO = func(id, version int64) error {
 		return nil
 	}
-	err := DocumentService.Delete(1)
+	err := DocumentService.Delete(admin, 1, 0)
 	assert.Nil(t, err)
 }
 
@@ -196,23 +251,63 @@ func TestDocumentService_Delete_NotFound(t *testing.T) {
 	getMessageDAO = func(id int64) (*model.Document, error) {
 		return nil, sql.ErrNoRows
 	}
-	err := DocumentService.Delete(1)
+	err := DocumentService.Delete(admin, 1, 0)
 	assert.NotNil(t, err)
 }
 
 func TestDocumentService_GetAll_Success(t *testing.T) {
 	model.DocumentRepository = &dBMock{}
-	getAllMessageDAO = func() ([]*model.Document, error) {
-		return []*model.Document{
-			{
-				ID:     1,
-				Title:  "title1",
-				Signee: "signee1",
+	getAllMessageDAO = func(opts model.ListOptions) (*model.DocumentPage, error) {
+		return &model.DocumentPage{
+			Documents: []*model.Document{
+				{
+					ID:     1,
+					Title:  "title1",
+					Signee: "signee1",
+				},
 			},
+			Total: 1,
 		}, nil
 	}
-	docs, err := DocumentService.GetAll()
+	page, err := DocumentService.GetAll(admin, model.ListOptions{})
 	assert.Nil(t, err)
-	assert.NotNil(t, docs)
-	assert.EqualValues(t, len(docs), 1)
+	assert.NotNil(t, page)
+	assert.EqualValues(t, len(page.Documents), 1)
+	assert.EqualValues(t, page.Total, 1)
+}
+
+func TestDocumentService_GetAll_InvalidOptions(t *testing.T) {
+	model.DocumentRepository = &dBMock{}
+	page, err := DocumentService.GetAll(admin, model.ListOptions{Sort: []model.SortField{{Field: "content"}}})
+	assert.Nil(t, page)
+	assert.Equal(t, model.SortInvalidValue, err)
+}
+
+func TestDocumentService_Update_VersionMismatch(t *testing.T) {
+	model.DocumentRepository = &dBMock{}
+	getMessageDAO = func(id int64) (*model.Document, error) {
+		return &model.Document{ID: 1, Title: "title", Signee: "signee", Version: 3}, nil
+	}
+	updateMessageDAO = func(doc model.Document) (*model.Document, error) {
+		t.Error("repository should not be called")
+		return nil, nil
+	}
+	doc, err := DocumentService.Update(admin, model.Document{ID: 1, Title: "title", Signee: "signee", Version: 2})
+	assert.Nil(t, doc)
+	assert.Equal(t, model.VersionMismatchValue, err)
+}
+
+func TestDocumentService_Delete_CurrentVersion(t *testing.T) {
+	model.DocumentRepository = &dBMock{}
+	getMessageDAO = func(id int64) (*model.Document, error) {
+		return &model.Document{ID: 1, Title: "title", Signee: "signee", Version: 3}, nil
+	}
+	var gotVersion int64
+	deleteMessageDAO = func(id, version int64) error {
+		gotVersion = version
+		return nil
+	}
+	assert.Nil(t, DocumentService.Delete(admin, 1, 0))
+	assert.EqualValues(t, 3, gotVersion)
+	assert.Equal(t, model.VersionMismatchValue, DocumentService.Delete(admin, 1, 2))
 }
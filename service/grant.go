@@ -0,0 +1,60 @@
+package service
+
+import (
+	"context"
+	"precisely/model"
+	"precisely/tracing"
+)
+
+var (
+	GrantService grantServiceInterface = &grantService{}
+)
+
+type grantService struct{}
+
+// grantServiceInterface shares documents. Only the principals allowed to
+// share a document, its owner and admins, may list or change its grants.
+type grantServiceInterface interface {
+	GetAll(context.Context, int64) ([]*model.Grant, error)
+	Grant(context.Context, model.Grant) (*model.Grant, error)
+	Revoke(context.Context, int64, int64) error
+}
+
+func (s *grantService) GetAll(ctx context.Context, documentID int64) ([]*model.Grant, error) {
+	ctx, span := tracing.Start(ctx, "grantService.GetAll")
+	defer span.End()
+
+	if err := authorizeID(ctx, documentID, model.ActionShare); err != nil {
+		return nil, err
+	}
+	return model.GrantRepository.GetAll(ctx, documentID)
+}
+
+// Grant gives a user or group a role on a document, replacing the role they
+// held on it if any.
+func (s *grantService) Grant(ctx context.Context, grant model.Grant) (*model.Grant, error) {
+	ctx, span := tracing.Start(ctx, "grantService.Grant")
+	defer span.End()
+
+	if err := grant.Validate(); err != nil {
+		return nil, err
+	}
+	doc, err := model.DocumentRepository.Get(ctx, grant.DocumentID)
+	if err != nil {
+		return nil, err
+	}
+	if err := authorize(ctx, doc, model.ActionShare); err != nil {
+		return nil, err
+	}
+	return model.GrantRepository.Put(ctx, grant)
+}
+
+func (s *grantService) Revoke(ctx context.Context, documentID, id int64) error {
+	ctx, span := tracing.Start(ctx, "grantService.Revoke")
+	defer span.End()
+
+	if err := authorizeID(ctx, documentID, model.ActionShare); err != nil {
+		return err
+	}
+	return model.GrantRepository.Delete(ctx, documentID, id)
+}
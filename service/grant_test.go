@@ -0,0 +1,190 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"precisely/model"
+	"testing"
+)
+
+var (
+	getGrantsDAO   func(documentID int64) ([]*model.Grant, error)
+	putGrantDAO    func(grant model.Grant) (*model.Grant, error)
+	deleteGrantDAO func(documentID, id int64) error
+)
+
+type grantDBMock struct{}
+
+func (m *grantDBMock) GetAll(_ context.Context, documentID int64) ([]*model.Grant, error) {
+	return getGrantsDAO(documentID)
+}
+
+func (m *grantDBMock) Put(_ context.Context, grant model.Grant) (*model.Grant, error) {
+	return putGrantDAO(grant)
+}
+
+func (m *grantDBMock) Delete(_ context.Context, documentID, id int64) error {
+	return deleteGrantDAO(documentID, id)
+}
+
+func as(subject string, groups ...string) context.Context {
+	return model.WithPrincipal(context.Background(), &model.Principal{Subject: subject, Groups: groups})
+}
+
+// mockSharedDocument makes document 1 owned by alice, with bob as its editor
+// and the legal group as its signer.
+func mockSharedDocument() {
+	model.DocumentRepository = &dBMock{}
+	model.GrantRepository = &grantDBMock{}
+	getMessageDAO = func(id int64) (*model.Document, error) {
+		if id != 1 {
+			return nil, sql.ErrNoRows
+		}
+		return &model.Document{ID: 1, Title: "title", Signee: "signee", Owner: "alice", Version: 1}, nil
+	}
+	getGrantsDAO = func(documentID int64) ([]*model.Grant, error) {
+		return []*model.Grant{
+			{ID: 1, DocumentID: 1, GranteeType: model.GranteeUser, Grantee: "bob", Role: model.RoleEditor},
+			{ID: 2, DocumentID: 1, GranteeType: model.GranteeGroup, Grantee: "legal", Role: model.RoleSigner},
+		}, nil
+	}
+	updateMessageDAO = func(doc model.Document) (*model.Document, error) {
+		doc.Version++
+		return &doc, nil
+	}
+	deleteMessageDAO = func(id, version int64) error {
+		return nil
+	}
+}
+
+func TestDocumentService_Access(t *testing.T) {
+	mockSharedDocument()
+	update := func(ctx context.Context) error {
+		_, err := DocumentService.Update(ctx, model.Document{ID: 1, Title: "new title", Signee: "signee", Owner: "mallory"})
+		return err
+	}
+	get := func(ctx context.Context) error {
+		_, err := DocumentService.Get(ctx, 1)
+		return err
+	}
+	remove := func(ctx context.Context) error {
+		return DocumentService.Delete(ctx, 1, 0)
+	}
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		op      func(context.Context) error
+		wantErr error
+	}{
+		{"Owner deletes", as("alice"), remove, nil},
+		{"Admin deletes", admin, remove, nil},
+		{"Editor updates", as("bob"), update, nil},
+		{"Editor deletes", as("bob"), remove, model.ForbiddenValue},
+		{"Group signer reads", as("carol", "legal"), get, nil},
+		{"Group signer updates", as("carol", "legal"), update, model.ForbiddenValue},
+		{"Stranger reads", as("dave"), get, sql.ErrNoRows},
+		{"Stranger updates", as("dave"), update, sql.ErrNoRows},
+		{"Anonymous reads", context.Background(), get, model.ForbiddenValue},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.wantErr, tt.op(tt.ctx))
+		})
+	}
+}
+
+func TestDocumentService_Update_KeepsOwner(t *testing.T) {
+	mockSharedDocument()
+	doc, err := DocumentService.Update(as("bob"), model.Document{ID: 1, Title: "new title", Signee: "signee", Owner: "bob"})
+	assert.Nil(t, err)
+	assert.Equal(t, "alice", doc.Owner)
+}
+
+func TestDocumentService_Create_Owner(t *testing.T) {
+	model.DocumentRepository = &dBMock{}
+	createMessageDAO = func(doc model.Document) (*model.Document, error) {
+		doc.ID = 1
+		return &doc, nil
+	}
+	doc, err := DocumentService.Create(as("bob"), model.Document{Title: "title", Signee: "signee", Owner: "alice"})
+	assert.Nil(t, err)
+	assert.Equal(t, "bob", doc.Owner)
+}
+
+func TestDocumentService_GetAll_Visibility(t *testing.T) {
+	model.DocumentRepository = &dBMock{}
+	var got *model.Principal
+	getAllMessageDAO = func(opts model.ListOptions) (*model.DocumentPage, error) {
+		got = opts.VisibleTo
+		return &model.DocumentPage{}, nil
+	}
+	_, err := DocumentService.GetAll(as("bob", "legal"), model.ListOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, &model.Principal{Subject: "bob", Groups: []string{"legal"}}, got)
+
+	_, err = DocumentService.GetAll(admin, model.ListOptions{})
+	assert.Nil(t, err)
+	assert.Nil(t, got)
+}
+
+func TestDocumentService_Batch_Forbidden(t *testing.T) {
+	mockSharedDocument()
+	batchMessageDAO = func(ops []model.BatchOperation, mode model.BatchMode) ([]model.BatchResult, error) {
+		return make([]model.BatchResult, len(ops)), nil
+	}
+
+	results, err := DocumentService.Batch(as("bob"), model.Batch{
+		Mode: model.BatchBestEffort,
+		Operations: []model.BatchOperation{
+			{Op: model.BatchUpdate, Document: model.Document{ID: 1, Title: "title", Signee: "signee", Version: 1}},
+			{Op: model.BatchDelete, Document: model.Document{ID: 1, Version: 1}},
+		},
+	})
+	assert.Nil(t, err)
+	assert.Nil(t, results[0].Err)
+	assert.Equal(t, model.ForbiddenValue, results[1].Err)
+
+	_, err = DocumentService.Batch(as("dave"), model.Batch{
+		Operations: []model.BatchOperation{
+			{Op: model.BatchDelete, Document: model.Document{ID: 1, Version: 1}},
+		},
+	})
+	assert.True(t, errors.Is(err, sql.ErrNoRows))
+}
+
+func TestGrantService_Grant(t *testing.T) {
+	mockSharedDocument()
+	putGrantDAO = func(grant model.Grant) (*model.Grant, error) {
+		grant.ID = 3
+		return &grant, nil
+	}
+	grant := model.Grant{DocumentID: 1, GranteeType: model.GranteeUser, Grantee: " carol ", Role: model.RoleViewer}
+
+	created, err := GrantService.Grant(as("alice"), grant)
+	assert.Nil(t, err)
+	assert.Equal(t, "carol", created.Grantee)
+
+	_, err = GrantService.Grant(as("bob"), grant)
+	assert.Equal(t, model.ForbiddenValue, err)
+
+	_, err = GrantService.Grant(as("alice"), model.Grant{DocumentID: 1, GranteeType: model.GranteeUser, Grantee: "carol", Role: "owner"})
+	assert.Equal(t, model.RoleInvalidValue, err)
+
+	_, err = GrantService.Grant(admin, model.Grant{DocumentID: 2, GranteeType: model.GranteeGroup, Grantee: "legal", Role: model.RoleViewer})
+	assert.Equal(t, sql.ErrNoRows, err)
+}
+
+func TestGrantService_Revoke(t *testing.T) {
+	mockSharedDocument()
+	deleteGrantDAO = func(documentID, id int64) error {
+		if id != 1 {
+			return sql.ErrNoRows
+		}
+		return nil
+	}
+	assert.Nil(t, GrantService.Revoke(as("alice"), 1, 1))
+	assert.Equal(t, sql.ErrNoRows, GrantService.Revoke(as("alice"), 1, 9))
+	assert.Equal(t, sql.ErrNoRows, GrantService.Revoke(as("dave"), 1, 1))
+}
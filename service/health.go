@@ -0,0 +1,51 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"precisely/model"
+	"precisely/tracing"
+	"sync/atomic"
+)
+
+var (
+	HealthService healthServiceInterface = &healthService{}
+)
+
+type healthService struct {
+	draining int32
+}
+
+type healthServiceInterface interface {
+	Ready(context.Context) error
+	Drain()
+}
+
+// Ready reports whether the server should receive traffic: it is not
+// draining, the database answers and its schema is at model.SchemaVersion.
+func (s *healthService) Ready(ctx context.Context) error {
+	ctx, span := tracing.Start(ctx, "healthService.Ready")
+	defer span.End()
+
+	if atomic.LoadInt32(&s.draining) != 0 {
+		return model.DrainingValue
+	}
+	if err := model.HealthRepository.Ping(ctx); err != nil {
+		return model.NotReady(err)
+	}
+	version, dirty, err := model.HealthRepository.SchemaVersion(ctx)
+	if err != nil {
+		return model.NotReady(err)
+	}
+	if dirty || version != model.SchemaVersion {
+		return fmt.Errorf("%w: database at %d (dirty: %t), expect %d",
+			model.SchemaVersionMismatchValue, version, dirty, model.SchemaVersion)
+	}
+	return nil
+}
+
+// Drain makes Ready fail from now on, so that traffic moves elsewhere before
+// the server shuts down.
+func (s *healthService) Drain() {
+	atomic.StoreInt32(&s.draining, 1)
+}
@@ -0,0 +1,72 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"precisely/model"
+	"testing"
+)
+
+var (
+	pingDAO          func() error
+	schemaVersionDAO func() (int64, bool, error)
+)
+
+type healthDBMock struct{}
+
+func (m *healthDBMock) Ping(_ context.Context) error {
+	return pingDAO()
+}
+
+func (m *healthDBMock) SchemaVersion(_ context.Context) (int64, bool, error) {
+	return schemaVersionDAO()
+}
+
+func TestHealthService_Ready(t *testing.T) {
+	model.HealthRepository = &healthDBMock{}
+	unreachable := errors.New("connection refused")
+	tests := []struct {
+		name    string
+		ping    error
+		version int64
+		dirty   bool
+		wantErr error
+	}{
+		{name: "Ready", version: model.SchemaVersion},
+		{name: "Unreachable", ping: unreachable, wantErr: unreachable},
+		{name: "Behind", version: model.SchemaVersion - 1, wantErr: model.SchemaVersionMismatchValue},
+		{name: "Dirty", version: model.SchemaVersion, dirty: true, wantErr: model.SchemaVersionMismatchValue},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pingDAO = func() error {
+				return tt.ping
+			}
+			schemaVersionDAO = func() (int64, bool, error) {
+				return tt.version, tt.dirty, nil
+			}
+			err := (&healthService{}).Ready(context.Background())
+			if tt.wantErr == nil {
+				assert.Nil(t, err)
+			} else {
+				assert.True(t, errors.Is(err, tt.wantErr), err)
+				assert.Equal(t, model.KindUnavailable, model.ErrorOf(err).Kind)
+			}
+		})
+	}
+}
+
+func TestHealthService_Drain(t *testing.T) {
+	model.HealthRepository = &healthDBMock{}
+	pingDAO = func() error {
+		return nil
+	}
+	schemaVersionDAO = func() (int64, bool, error) {
+		return model.SchemaVersion, false, nil
+	}
+	s := &healthService{}
+	assert.Nil(t, s.Ready(context.Background()))
+	s.Drain()
+	assert.Equal(t, model.DrainingValue, s.Ready(context.Background()))
+}
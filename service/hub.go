@@ -0,0 +1,199 @@
+package service
+
+import (
+	"context"
+	"github.com/sirupsen/logrus"
+	"precisely/model"
+	"sync"
+	"time"
+)
+
+var (
+	ChangeHub = newChangeHub()
+)
+
+// DefaultSubscriptionBuffer is how many changes a subscription holds for a
+// subscriber that has yet to take them, when the hub was not started with
+// another buffer.
+const DefaultSubscriptionBuffer = 64
+
+// changeHub fans the change feed out to in-process subscribers. The document
+// service publishes into it after every change, which has the hub read the
+// changes made since it last did and hand them to the subscriptions they
+// match. A subscription whose buffer is full is dropped rather than holding up
+// the others; its subscriber catches up from the change feed instead.
+type changeHub struct {
+	mu          sync.Mutex
+	last        int64
+	known       bool
+	buffer      int
+	subscribers map[*Subscription]bool
+	wake        chan struct{}
+}
+
+func newChangeHub() *changeHub {
+	return &changeHub{
+		buffer:      DefaultSubscriptionBuffer,
+		subscribers: make(map[*Subscription]bool),
+		wake:        make(chan struct{}, 1),
+	}
+}
+
+// Subscription receives on C the changes matching its filter after Since, the
+// sequence of the latest change when it subscribed. C is closed when the
+// subscription is cancelled or dropped for lagging behind.
+type Subscription struct {
+	C      <-chan *model.Change
+	Since  int64
+	c      chan *model.Change
+	filter model.ChangeFilter
+	lagged bool
+}
+
+// Lagged reports whether the subscription was dropped because its subscriber
+// did not keep up. It is only meaningful once C is closed.
+func (s *Subscription) Lagged() bool {
+	return s.lagged
+}
+
+// Publish has the hub hand out the changes made since it last did. It never
+// blocks: publishing while the hub is busy coalesces into its next round.
+func (h *changeHub) Publish() {
+	select {
+	case h.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Subscribe registers a subscription to the changes matching filter from now
+// on.
+func (h *changeHub) Subscribe(ctx context.Context, filter model.ChangeFilter) (*Subscription, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if err := h.catchUp(ctx); err != nil {
+		return nil, err
+	}
+	c := make(chan *model.Change, h.buffer)
+	s := &Subscription{C: c, Since: h.last, c: c, filter: filter}
+	h.subscribers[s] = true
+	return s, nil
+}
+
+// Unsubscribe cancels a subscription, unless it was already dropped.
+func (h *changeHub) Unsubscribe(s *Subscription) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.subscribers[s] {
+		delete(h.subscribers, s)
+		close(s.c)
+	}
+}
+
+// catchUp learns where the change feed stands the first time it is called,
+// and only then. The caller holds the lock.
+func (h *changeHub) catchUp(ctx context.Context) error {
+	if h.known {
+		return nil
+	}
+	last, err := model.DocumentRepository.GetLastSequence(ctx)
+	if err != nil {
+		return err
+	}
+	h.last, h.known = last, true
+	return nil
+}
+
+// fanOut reads the changes after the last one handed out and hands them to
+// the matching subscriptions, a page at a time until none is left. Moving the
+// cursor past a change is safe because the feed is numbered in commit order:
+// no change still to commit can be numbered below one already read.
+func (h *changeHub) fanOut(ctx context.Context) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if err := h.catchUp(ctx); err != nil {
+		return err
+	}
+	for {
+		changes, err := model.DocumentRepository.GetChanges(ctx, h.last, model.MaxListLimit)
+		if err != nil {
+			return err
+		}
+		for _, change := range changes {
+			h.broadcast(change)
+			h.last = change.Sequence
+		}
+		if len(changes) < model.MaxListLimit {
+			return nil
+		}
+	}
+}
+
+func (h *changeHub) broadcast(change *model.Change) {
+	for s := range h.subscribers {
+		if !s.filter.Matches(change) {
+			continue
+		}
+		select {
+		case s.c <- change:
+		default:
+			s.lagged = true
+			delete(h.subscribers, s)
+			close(s.c)
+		}
+	}
+}
+
+// ChangeHubConfig tunes the hub; the zero value of a field stands for its
+// default.
+type ChangeHubConfig struct {
+	// Interval is how often the hub also looks for changes unpublished, such
+	// as those made by other instances, 1s by default.
+	Interval time.Duration
+	// Buffer is how many changes a subscription holds, 64 by default.
+	Buffer int
+}
+
+func (c ChangeHubConfig) withDefaults() ChangeHubConfig {
+	if c.Interval <= 0 {
+		c.Interval = time.Second
+	}
+	if c.Buffer <= 0 {
+		c.Buffer = DefaultSubscriptionBuffer
+	}
+	return c
+}
+
+// StartChangeHub has the hub hand out changes whenever some are published and
+// every interval, until the returned stop function is called.
+func StartChangeHub(config ChangeHubConfig) (stop func()) {
+	config = config.withDefaults()
+	ChangeHub.mu.Lock()
+	ChangeHub.buffer = config.Buffer
+	ChangeHub.mu.Unlock()
+
+	ticker := time.NewTicker(config.Interval)
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ChangeHub.wake:
+			case <-ticker.C:
+			case <-ctx.Done():
+				return
+			}
+			if err := ChangeHub.fanOut(ctx); err != nil && ctx.Err() == nil {
+				logrus.WithError(err).Error("failed handing out changes")
+			}
+		}
+	}()
+	return func() {
+		cancel()
+		<-stopped
+	}
+}
@@ -0,0 +1,101 @@
+package service
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"precisely/model"
+	"testing"
+)
+
+// feedMock serves the changes it holds like the repository serves the change
+// feed.
+func feedMock(changes *[]*model.Change) {
+	model.DocumentRepository = &dBMock{}
+	getLastSequenceDAO = func() (int64, error) {
+		if len(*changes) == 0 {
+			return 0, nil
+		}
+		return (*changes)[len(*changes)-1].Sequence, nil
+	}
+	getChangesMessageDAO = func(since int64, limit int) ([]*model.Change, error) {
+		page := make([]*model.Change, 0)
+		for _, change := range *changes {
+			if change.Sequence > since && len(page) < limit {
+				page = append(page, change)
+			}
+		}
+		return page, nil
+	}
+}
+
+func TestChangeHub_FanOut(t *testing.T) {
+	changes := []*model.Change{{Sequence: 1, DocumentID: 1, Signee: "bob"}}
+	feedMock(&changes)
+	ChangeHub = newChangeHub()
+
+	all, err := ChangeHub.Subscribe(context.Background(), model.ChangeFilter{})
+	assert.Nil(t, err)
+	assert.EqualValues(t, 1, all.Since)
+	bob, _ := ChangeHub.Subscribe(context.Background(), model.ChangeFilter{Signee: "bob"})
+	second, _ := ChangeHub.Subscribe(context.Background(), model.ChangeFilter{DocumentID: 2})
+
+	changes = append(changes,
+		&model.Change{Sequence: 2, DocumentID: 2, Signee: "alice"},
+		&model.Change{Sequence: 3, DocumentID: 1, Signee: "bob"},
+	)
+	assert.Nil(t, ChangeHub.fanOut(context.Background()))
+	assert.EqualValues(t, 2, len(all.C))
+	assert.EqualValues(t, 1, len(bob.C))
+	assert.EqualValues(t, 3, (<-bob.C).Sequence)
+	assert.EqualValues(t, 1, len(second.C))
+	assert.EqualValues(t, 2, (<-second.C).Sequence)
+
+	ChangeHub.Unsubscribe(all)
+	<-all.C
+	<-all.C
+	_, open := <-all.C
+	assert.False(t, open)
+	assert.False(t, all.Lagged())
+}
+
+// TestChangeHub_FanOut_InterleavedTransactions has two transactions change
+// documents at once, the first holding the next sequence while the second
+// waits for it, with the hub fanning out between their commits.
+func TestChangeHub_FanOut_InterleavedTransactions(t *testing.T) {
+	changes := []*model.Change{{Sequence: 1, DocumentID: 1}}
+	feedMock(&changes)
+	ChangeHub = newChangeHub()
+	all, _ := ChangeHub.Subscribe(context.Background(), model.ChangeFilter{})
+
+	first := &model.Change{Sequence: 2, DocumentID: 2}
+	second := &model.Change{Sequence: 3, DocumentID: 3}
+	assert.Nil(t, ChangeHub.fanOut(context.Background()))
+	assert.EqualValues(t, 0, len(all.C))
+
+	changes = append(changes, first)
+	assert.Nil(t, ChangeHub.fanOut(context.Background()))
+	changes = append(changes, second)
+	assert.Nil(t, ChangeHub.fanOut(context.Background()))
+	assert.Nil(t, ChangeHub.fanOut(context.Background()))
+
+	assert.EqualValues(t, 2, len(all.C))
+	assert.Equal(t, first, <-all.C)
+	assert.Equal(t, second, <-all.C)
+}
+
+func TestChangeHub_DropsLaggingSubscriptions(t *testing.T) {
+	changes := []*model.Change{}
+	feedMock(&changes)
+	ChangeHub = newChangeHub()
+	ChangeHub.buffer = 1
+
+	slow, _ := ChangeHub.Subscribe(context.Background(), model.ChangeFilter{})
+	changes = append(changes, &model.Change{Sequence: 1}, &model.Change{Sequence: 2})
+	assert.Nil(t, ChangeHub.fanOut(context.Background()))
+
+	assert.EqualValues(t, 1, (<-slow.C).Sequence)
+	_, open := <-slow.C
+	assert.False(t, open)
+	assert.True(t, slow.Lagged())
+	ChangeHub.Unsubscribe(slow)
+}
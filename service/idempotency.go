@@ -0,0 +1,124 @@
+package service
+
+import (
+	"context"
+	"github.com/sirupsen/logrus"
+	"net/http"
+	"precisely/model"
+	"precisely/tracing"
+	"time"
+)
+
+var (
+	IdempotencyService idempotencyServiceInterface = &idempotencyService{}
+)
+
+// IdempotencyTTL is how long the response of a request made under an
+// idempotency key is replayed to its retries.
+var IdempotencyTTL = 24 * time.Hour
+
+// idempotencyLease is how long a key stays taken by a request that never
+// completed, such as one whose server went down, before a retry may take it.
+const idempotencyLease = time.Minute
+
+type idempotencyService struct{}
+
+// idempotencyServiceInterface keeps the requests made under idempotency keys
+// so that their retries get the response of the first attempt.
+type idempotencyServiceInterface interface {
+	Begin(context.Context, string, string) (*model.IdempotencyRecord, error)
+	Complete(context.Context, model.IdempotencyRecord, int, http.Header, []byte) error
+	Release(context.Context, model.IdempotencyRecord) error
+	PurgeExpired(context.Context) (int64, error)
+}
+
+// Begin takes an idempotency key for the request of a fingerprint, scoped to
+// the principal of ctx. When a request already took the key, it returns its
+// record if it completed with the same fingerprint, and fails with
+// IdempotencyKeyReusedValue or IdempotencyKeyInProgressValue otherwise.
+func (s *idempotencyService) Begin(ctx context.Context, key, fingerprint string) (*model.IdempotencyRecord, error) {
+	ctx, span := tracing.Start(ctx, "idempotencyService.Begin")
+	defer span.End()
+
+	if key == "" || len(key) > model.MaxIdempotencyKeyLength {
+		return nil, model.IdempotencyKeyInvalidValue
+	}
+	p := model.PrincipalFrom(ctx)
+	if p == nil {
+		return nil, model.ForbiddenValue
+	}
+	record, taken, err := model.IdempotencyRepository.Reserve(ctx, model.IdempotencyRecord{
+		Scope:       p.Subject,
+		Key:         key,
+		Fingerprint: fingerprint,
+		ExpiresAt:   time.Now().Add(idempotencyLease),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if taken {
+		return record, nil
+	}
+	if record.Fingerprint != fingerprint {
+		return nil, model.IdempotencyKeyReusedValue
+	}
+	if !record.Completed() {
+		return nil, model.IdempotencyKeyInProgressValue
+	}
+	return record, nil
+}
+
+// Complete records the response to the request of a key taken by Begin.
+func (s *idempotencyService) Complete(ctx context.Context, record model.IdempotencyRecord, statusCode int, header http.Header, body []byte) error {
+	ctx, span := tracing.Start(ctx, "idempotencyService.Complete")
+	defer span.End()
+
+	record.Complete(statusCode, header, body, time.Now().Add(IdempotencyTTL))
+	return model.IdempotencyRepository.Complete(ctx, record)
+}
+
+// Release frees a key taken by Begin whose request failed, so that a retry
+// runs it again.
+func (s *idempotencyService) Release(ctx context.Context, record model.IdempotencyRecord) error {
+	ctx, span := tracing.Start(ctx, "idempotencyService.Release")
+	defer span.End()
+
+	return model.IdempotencyRepository.Release(ctx, record.ID)
+}
+
+// PurgeExpired deletes the records no longer replayed.
+func (s *idempotencyService) PurgeExpired(ctx context.Context) (int64, error) {
+	ctx, span := tracing.Start(ctx, "idempotencyService.PurgeExpired")
+	defer span.End()
+
+	return model.IdempotencyRepository.DeleteExpiredBefore(ctx, time.Now())
+}
+
+// StartIdempotencyPurger runs PurgeExpired every interval until the returned
+// stop function is called.
+func StartIdempotencyPurger(interval time.Duration) (stop func()) {
+	ticker := time.NewTicker(interval)
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				purged, err := IdempotencyService.PurgeExpired(ctx)
+				if err != nil && ctx.Err() == nil {
+					logrus.WithError(err).Error("failed purging idempotency keys")
+				} else if purged > 0 {
+					logrus.WithField("purged", purged).Info("purged expired idempotency keys")
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return func() {
+		cancel()
+		<-stopped
+	}
+}
@@ -0,0 +1,62 @@
+package service
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"precisely/model"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestIdempotencyService_Begin(t *testing.T) {
+	model.IdempotencyRepository = model.NewMemoryIdempotencyRepository()
+	alice := model.WithPrincipal(context.Background(), &model.Principal{Subject: "alice", Roles: []string{"writer"}})
+	bob := model.WithPrincipal(context.Background(), &model.Principal{Subject: "bob", Roles: []string{"writer"}})
+
+	record, err := IdempotencyService.Begin(alice, "k1", "f1")
+	assert.Nil(t, err)
+	assert.False(t, record.Completed())
+	assert.Equal(t, "alice", record.Scope)
+
+	_, err = IdempotencyService.Begin(alice, "k1", "f1")
+	assert.Equal(t, model.IdempotencyKeyInProgressValue, err)
+	_, err = IdempotencyService.Begin(alice, "k1", "f2")
+	assert.Equal(t, model.IdempotencyKeyReusedValue, err)
+	_, err = IdempotencyService.Begin(bob, "k1", "f2")
+	assert.Nil(t, err, "keys are scoped to their principal")
+
+	start := time.Now()
+	assert.Nil(t, IdempotencyService.Complete(alice, *record, http.StatusCreated, http.Header{"Content-Type": {"application/json"}}, []byte(`{"id":1}`)))
+	replayed, err := IdempotencyService.Begin(alice, "k1", "f1")
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusCreated, replayed.StatusCode)
+	assert.Equal(t, `{"id":1}`, string(replayed.Body))
+	assert.False(t, replayed.ExpiresAt.Before(start.Add(IdempotencyTTL)))
+	_, err = IdempotencyService.Begin(alice, "k1", "f2")
+	assert.Equal(t, model.IdempotencyKeyReusedValue, err)
+}
+
+func TestIdempotencyService_Begin_Invalid(t *testing.T) {
+	model.IdempotencyRepository = model.NewMemoryIdempotencyRepository()
+	writer := model.WithPrincipal(context.Background(), &model.Principal{Subject: "ci", Roles: []string{"writer"}})
+	for _, key := range []string{"", strings.Repeat("k", model.MaxIdempotencyKeyLength+1)} {
+		_, err := IdempotencyService.Begin(writer, key, "f1")
+		assert.Equal(t, model.IdempotencyKeyInvalidValue, err)
+	}
+	_, err := IdempotencyService.Begin(context.Background(), "k1", "f1")
+	assert.Equal(t, model.ForbiddenValue, err)
+}
+
+func TestIdempotencyService_Release(t *testing.T) {
+	model.IdempotencyRepository = model.NewMemoryIdempotencyRepository()
+	writer := model.WithPrincipal(context.Background(), &model.Principal{Subject: "ci", Roles: []string{"writer"}})
+
+	record, err := IdempotencyService.Begin(writer, "k1", "f1")
+	assert.Nil(t, err)
+	assert.Nil(t, IdempotencyService.Release(writer, *record))
+	retried, err := IdempotencyService.Begin(writer, "k1", "f2")
+	assert.Nil(t, err, "a released key is taken again")
+	assert.NotEqual(t, record.ID, retried.ID)
+}
@@ -0,0 +1,298 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"precisely/logging"
+	"precisely/model"
+	"precisely/tracing"
+	"strings"
+	"sync"
+	"time"
+)
+
+// outboxBatchSize is how many events the relay claims at a time.
+const outboxBatchSize = 100
+
+// OutboxSink is a destination of the outbox events. A sink may receive an
+// event more than once, when the relay stops or fails before recording that
+// it did.
+type OutboxSink interface {
+	// Name identifies the sink in the outbox, which records the sinks every
+	// event reached, so it must not change across restarts.
+	Name() string
+	Send(context.Context, *model.OutboxEvent) error
+}
+
+// NewOutboxSinks returns the sink queueing webhook deliveries, followed by
+// the sinks named in the comma separated list: log, file, which appends the
+// events to path one per line, and http, which POSTs them to url.
+func NewOutboxSinks(names, path, url string) ([]OutboxSink, error) {
+	sinks := []OutboxSink{webhookSink{}}
+	for _, name := range strings.Split(names, ",") {
+		switch strings.TrimSpace(name) {
+		case "":
+		case "log":
+			sinks = append(sinks, logSink{})
+		case "file":
+			if path == "" {
+				return nil, fmt.Errorf("the file sink needs a path")
+			}
+			sinks = append(sinks, &fileSink{path: path})
+		case "http":
+			if url == "" {
+				return nil, fmt.Errorf("the http sink needs a url")
+			}
+			sinks = append(sinks, &httpSink{url: url, client: &http.Client{}})
+		default:
+			return nil, fmt.Errorf("unknown outbox sink %q, expect log, file or http", name)
+		}
+	}
+	return sinks, nil
+}
+
+// webhookSink queues the deliveries of the events to the webhooks subscribed
+// to them.
+type webhookSink struct{}
+
+func (webhookSink) Name() string {
+	return "webhooks"
+}
+
+func (webhookSink) Send(ctx context.Context, event *model.OutboxEvent) error {
+	return enqueue(ctx, event)
+}
+
+// logSink logs the events.
+type logSink struct{}
+
+func (logSink) Name() string {
+	return "log"
+}
+
+func (logSink) Send(ctx context.Context, event *model.OutboxEvent) error {
+	logrus.WithFields(logrus.Fields{
+		"event":       event.Event,
+		"event_id":    event.EventID,
+		"document_id": event.DocumentID,
+		"payload":     string(event.Payload),
+	}).Info("document event")
+	return nil
+}
+
+// fileSink appends the payloads of the events to a file, one per line.
+type fileSink struct {
+	mu   sync.Mutex
+	path string
+}
+
+func (s *fileSink) Name() string {
+	return "file"
+}
+
+// Send opens the file for every event, so that it may be rotated meanwhile.
+func (s *fileSink) Send(ctx context.Context, event *model.OutboxEvent) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, err := os.OpenFile(s.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(append([]byte(nil), event.Payload...), '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// httpSink POSTs the payloads of the events to a URL. Any answer but a 2xx
+// fails the attempt.
+type httpSink struct {
+	url    string
+	client *http.Client
+}
+
+func (s *httpSink) Name() string {
+	return "http"
+}
+
+func (s *httpSink) Send(ctx context.Context, event *model.OutboxEvent) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(event.Payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "precisely-outbox")
+	req.Header.Set("X-Precisely-Event", event.Event)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(ioutil.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("answered %s", resp.Status)
+	}
+	return nil
+}
+
+// publish records the event of a change that the document repository does
+// not make, such as a signature, which is therefore not written within the
+// transaction of the change. The change is already made, so failing to record
+// the event is logged rather than failing the operation.
+func publish(ctx context.Context, eventType string, doc *model.Document, signee string) {
+	event, err := model.NewOutboxEvent(eventType, *doc, signee, time.Now().UTC())
+	if err == nil {
+		_, err = model.OutboxRepository.Append(ctx, event)
+	}
+	if err != nil {
+		logging.FromContext(ctx).WithError(err).WithField("event", eventType).Error("failed recording an outbox event")
+	}
+}
+
+// OutboxRelayConfig tunes the relay of the outbox; the zero value of a field
+// but Sinks stands for its default.
+type OutboxRelayConfig struct {
+	Sinks []OutboxSink
+	// Interval is how often due events are looked for, 1s by default.
+	Interval time.Duration
+	// Timeout bounds each attempt at handing an event to the sinks, 10s by
+	// default.
+	Timeout time.Duration
+	// RetryBase is the wait after the first failed attempt, doubled after
+	// every other one up to MaxBackoff; 1s and 5m by default.
+	RetryBase  time.Duration
+	MaxBackoff time.Duration
+	// Retention is how long the events every sink received are kept, 24h by
+	// default. They are deleted every CleanupInterval, 1h by default.
+	Retention       time.Duration
+	CleanupInterval time.Duration
+}
+
+func (c OutboxRelayConfig) withDefaults() OutboxRelayConfig {
+	if c.Interval <= 0 {
+		c.Interval = time.Second
+	}
+	if c.Timeout <= 0 {
+		c.Timeout = 10 * time.Second
+	}
+	if c.RetryBase <= 0 {
+		c.RetryBase = time.Second
+	}
+	if c.MaxBackoff <= 0 {
+		c.MaxBackoff = 5 * time.Minute
+	}
+	if c.Retention <= 0 {
+		c.Retention = 24 * time.Hour
+	}
+	if c.CleanupInterval <= 0 {
+		c.CleanupInterval = time.Hour
+	}
+	return c
+}
+
+// StartOutboxRelay hands the due events of the outbox to the sinks every
+// interval, and deletes those past their retention every cleanup interval,
+// until the returned stop function is called.
+func StartOutboxRelay(config OutboxRelayConfig) (stop func()) {
+	relay := &outboxRelay{config: config.withDefaults()}
+	ticker := time.NewTicker(relay.config.Interval)
+	cleanup := time.NewTicker(relay.config.CleanupInterval)
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		defer ticker.Stop()
+		defer cleanup.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if err := relay.relayDue(ctx); err != nil && ctx.Err() == nil {
+					logrus.WithError(err).Error("failed relaying the outbox")
+				}
+			case <-cleanup.C:
+				deleted, err := model.OutboxRepository.DeleteDeliveredBefore(ctx, time.Now().Add(-relay.config.Retention))
+				if err != nil {
+					logrus.WithError(err).Error("failed cleaning up the outbox")
+				} else if deleted > 0 {
+					logrus.WithField("deleted", deleted).Info("cleaned up the outbox")
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return func() {
+		cancel()
+		<-stopped
+	}
+}
+
+type outboxRelay struct {
+	config OutboxRelayConfig
+}
+
+// relayDue hands the due events to the sinks, a batch at a time and in the
+// order they were written, until none is left.
+func (r *outboxRelay) relayDue(ctx context.Context) error {
+	for {
+		// The claim outlasts the slowest attempts of the batch.
+		claimed, err := model.OutboxRepository.ClaimDue(ctx, r.config.Timeout+time.Minute, outboxBatchSize)
+		if err != nil {
+			return err
+		}
+		for _, event := range claimed {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			r.relay(ctx, event)
+		}
+		if len(claimed) < outboxBatchSize {
+			return ctx.Err()
+		}
+	}
+}
+
+// relay hands an event to the sinks it has yet to reach and records the
+// outcome. A failing sink is retried later, without holding up the others.
+func (r *outboxRelay) relay(ctx context.Context, event *model.OutboxEvent) {
+	ctx, span := tracing.Start(ctx, "outboxRelay.relay")
+	defer span.End()
+
+	attemptCtx, cancel := context.WithTimeout(ctx, r.config.Timeout)
+	defer cancel()
+	failures := make([]string, 0)
+	for _, sink := range r.config.Sinks {
+		if event.DeliveredToSink(sink.Name()) {
+			continue
+		}
+		if err := sink.Send(attemptCtx, event); err != nil {
+			failures = append(failures, sink.Name()+": "+err.Error())
+			continue
+		}
+		event.DeliveredTo = append(event.DeliveredTo, sink.Name())
+	}
+	if ctx.Err() != nil {
+		return
+	}
+
+	log := logrus.WithField("event_id", event.EventID).WithField("outbox_id", event.ID)
+	if len(failures) > 0 {
+		event.Fail(strings.Join(failures, "; "), time.Now().UTC(), r.config.RetryBase, r.config.MaxBackoff)
+		log.WithField("error", event.Error).Warn("outbox event not relayed to every sink")
+	} else {
+		event.Succeed(time.Now().UTC())
+	}
+	if err := model.OutboxRepository.Update(ctx, *event); err != nil {
+		log.WithError(err).Error("failed recording an outbox attempt")
+	}
+}
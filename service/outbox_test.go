@@ -0,0 +1,95 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"precisely/model"
+	"strings"
+	"testing"
+	"time"
+)
+
+// recordingSink records the events it receives and fails them with err.
+type recordingSink struct {
+	name   string
+	err    error
+	events []string
+}
+
+func (s *recordingSink) Name() string {
+	return s.name
+}
+
+func (s *recordingSink) Send(_ context.Context, event *model.OutboxEvent) error {
+	s.events = append(s.events, event.Event)
+	return s.err
+}
+
+func TestOutboxRelay(t *testing.T) {
+	documents := model.DocumentRepository
+	defer func() { model.DocumentRepository = documents }()
+	model.DocumentRepository, _, _, _, model.OutboxRepository, _ = model.NewMemoryRepositories()
+	model.WebhookRepository = model.NewMemoryWebhookRepository()
+
+	webhook := subscribe(t, "https://example.com/hook", model.EventDocumentCreated)
+	created, err := DocumentService.Create(as("alice"), model.Document{Title: "title", Signee: "signee"})
+	assert.Nil(t, err)
+	assert.Nil(t, DocumentService.Delete(as("alice"), created.ID, 0))
+	publish(as("alice"), model.EventDocumentSigned, created, "bob")
+
+	file := &fileSink{path: filepath.Join(t.TempDir(), "events.jsonl")}
+	failing := &recordingSink{name: "failing", err: errors.New("unavailable")}
+	relay := &outboxRelay{config: OutboxRelayConfig{
+		Sinks:     []OutboxSink{webhookSink{}, file, failing},
+		RetryBase: time.Nanosecond,
+	}.withDefaults()}
+	assert.Nil(t, relay.relayDue(context.Background()))
+	assert.Equal(t, []string{model.EventDocumentCreated, model.EventDocumentDeleted, model.EventDocumentSigned}, failing.events)
+
+	// The retry only reaches the sink that failed.
+	failing.err = nil
+	time.Sleep(time.Millisecond)
+	assert.Nil(t, relay.relayDue(context.Background()))
+	assert.Len(t, failing.events, 6)
+	assert.Nil(t, relay.relayDue(context.Background()))
+	assert.Len(t, failing.events, 6)
+
+	lines, err := ioutil.ReadFile(file.path)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, strings.Count(string(lines), "\n"))
+	assert.Contains(t, string(lines), `"signee":"bob"`)
+	page, err := WebhookService.GetDeliveries(admin, model.DeliveryQuery{WebhookID: webhook.ID})
+	assert.Nil(t, err)
+	assert.Len(t, page.Deliveries, 1)
+
+	deleted, err := model.OutboxRepository.DeleteDeliveredBefore(context.Background(), time.Now().Add(time.Second))
+	assert.Nil(t, err)
+	assert.EqualValues(t, 3, deleted)
+}
+
+func TestHTTPSink(t *testing.T) {
+	status := http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, model.EventDocumentUpdated, r.Header.Get("X-Precisely-Event"))
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	sinks, err := NewOutboxSinks("log, http", "", server.URL)
+	if assert.Nil(t, err) && assert.Len(t, sinks, 3) {
+		event, _ := model.NewOutboxEvent(model.EventDocumentUpdated, model.Document{ID: 1}, "", time.Now())
+		assert.Nil(t, sinks[2].Send(context.Background(), &event))
+		status = http.StatusBadGateway
+		assert.EqualError(t, sinks[2].Send(context.Background(), &event), "answered 502 Bad Gateway")
+	}
+
+	_, err = NewOutboxSinks("file", "", "")
+	assert.NotNil(t, err)
+	_, err = NewOutboxSinks("kafka", "", "")
+	assert.NotNil(t, err)
+}
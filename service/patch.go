@@ -0,0 +1,75 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	jsonpatch "github.com/evanphx/json-patch/v5"
+	"precisely/model"
+	"precisely/tracing"
+)
+
+const (
+	MergePatchType = "application/merge-patch+json"
+	JSONPatchType  = "application/json-patch+json"
+)
+
+// patchable holds the fields of a document a patch may change.
+type patchable struct {
+	Title   string        `json:"title"`
+	Content model.Content `json:"content"`
+	Signee  string        `json:"signee"`
+}
+
+// Patch applies a JSON Merge Patch (RFC 7396) or a JSON Patch (RFC 6902) to
+// the title, content and signee of a document expected to be at the given
+// version, and writes the result through Update.
+func (s *documentService) Patch(ctx context.Context, id, version int64, patchType string, patch []byte) (*model.Document, error) {
+	ctx, span := tracing.Start(ctx, "documentService.Patch")
+	defer span.End()
+
+	current, err := model.DocumentRepository.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := authorize(ctx, current, model.ActionWrite); err != nil {
+		return nil, err
+	}
+	if version == 0 {
+		version = current.Version
+	}
+	if version != current.Version {
+		return nil, model.VersionMismatchValue
+	}
+
+	original, err := json.Marshal(patchable{Title: current.Title, Content: current.Content, Signee: current.Signee})
+	if err != nil {
+		return nil, err
+	}
+	var patched []byte
+	switch patchType {
+	case MergePatchType:
+		patched, err = jsonpatch.MergePatch(original, patch)
+	case JSONPatchType:
+		var ops jsonpatch.Patch
+		if ops, err = jsonpatch.DecodePatch(patch); err == nil {
+			patched, err = ops.Apply(original)
+		}
+	default:
+		return nil, model.PatchTypeInvalidValue
+	}
+	if err != nil {
+		return nil, model.PatchInvalidValue
+	}
+
+	var fields patchable
+	if err := json.Unmarshal(patched, &fields); err != nil {
+		return nil, model.PatchInvalidValue
+	}
+	return s.Update(ctx, model.Document{
+		ID:      id,
+		Title:   fields.Title,
+		Content: fields.Content,
+		Signee:  fields.Signee,
+		Version: version,
+	})
+}
@@ -0,0 +1,106 @@
+package service
+
+import (
+	"github.com/stretchr/testify/assert"
+	"precisely/model"
+	"testing"
+)
+
+func mockPatching() *model.Document {
+	model.DocumentRepository = &dBMock{}
+	getMessageDAO = func(id int64) (*model.Document, error) {
+		return &model.Document{
+			ID:      id,
+			Title:   "title",
+			Content: model.Content{Header: "header", Data: "data"},
+			Signee:  "signee",
+			Version: 2,
+		}, nil
+	}
+	updated := &model.Document{}
+	updateMessageDAO = func(doc model.Document) (*model.Document, error) {
+		*updated = doc
+		doc.Version++
+		return &doc, nil
+	}
+	return updated
+}
+
+func TestDocumentService_Patch_MergePatch(t *testing.T) {
+	updated := mockPatching()
+
+	doc, err := DocumentService.Patch(admin, 1, 2, MergePatchType, []byte(`{"content": {"data": "new data"}, "id": 9}`))
+	assert.Nil(t, err)
+	assert.EqualValues(t, 3, doc.Version)
+	assert.EqualValues(t, model.Document{
+		ID:      1,
+		Title:   "title",
+		Content: model.Content{Header: "header", Data: "new data"},
+		Signee:  "signee",
+		Version: 2,
+	}, *updated)
+}
+
+func TestDocumentService_Patch_JSONPatch(t *testing.T) {
+	updated := mockPatching()
+
+	doc, err := DocumentService.Patch(admin, 1, 0, JSONPatchType, []byte(`[
+		{"op": "test", "path": "/signee", "value": "signee"},
+		{"op": "replace", "path": "/content/data", "value": "new data"},
+		{"op": "copy", "from": "/content/data", "path": "/content/header"}
+	]`))
+	assert.Nil(t, err)
+	assert.NotNil(t, doc)
+	assert.EqualValues(t, model.Content{Header: "new data", Data: "new data"}, updated.Content)
+	assert.EqualValues(t, 2, updated.Version)
+}
+
+func TestDocumentService_Patch_Rejected(t *testing.T) {
+	tests := []struct {
+		name      string
+		version   int64
+		patchType string
+		patch     string
+		wantErr   error
+	}{
+		{
+			name:      "Stale version",
+			version:   1,
+			patchType: MergePatchType,
+			patch:     `{"title": "new title"}`,
+			wantErr:   model.VersionMismatchValue,
+		},
+		{
+			name:      "Removed title",
+			patchType: MergePatchType,
+			patch:     `{"title": null}`,
+			wantErr:   model.TitleInvalidValue,
+		},
+		{
+			name:      "Failed test operation",
+			patchType: JSONPatchType,
+			patch:     `[{"op": "test", "path": "/title", "value": "other"}]`,
+			wantErr:   model.PatchInvalidValue,
+		},
+		{
+			name:      "Wrong type",
+			patchType: MergePatchType,
+			patch:     `{"title": 1}`,
+			wantErr:   model.PatchInvalidValue,
+		},
+		{
+			name:      "Unsupported type",
+			patchType: "application/json",
+			patch:     `{}`,
+			wantErr:   model.PatchTypeInvalidValue,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockPatching()
+			doc, err := DocumentService.Patch(admin, 1, tt.version, tt.patchType, []byte(tt.patch))
+			assert.Nil(t, doc)
+			assert.Equal(t, tt.wantErr, err)
+		})
+	}
+}
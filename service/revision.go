@@ -0,0 +1,81 @@
+package service
+
+import (
+	"context"
+	"precisely/model"
+	"precisely/tracing"
+	"time"
+)
+
+func (s *documentService) GetRevisions(ctx context.Context, id int64) ([]*model.Revision, error) {
+	ctx, span := tracing.Start(ctx, "documentService.GetRevisions")
+	defer span.End()
+
+	if err := authorizeID(ctx, id, model.ActionRead); err != nil {
+		return nil, err
+	}
+	revisions, err := model.DocumentRepository.GetRevisions(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := audit(ctx, model.AuditRead, id, nil, nil); err != nil {
+		return nil, err
+	}
+	return revisions, nil
+}
+
+func (s *documentService) GetRevision(ctx context.Context, id, revision int64) (*model.Revision, error) {
+	ctx, span := tracing.Start(ctx, "documentService.GetRevision")
+	defer span.End()
+
+	if err := authorizeID(ctx, id, model.ActionRead); err != nil {
+		return nil, err
+	}
+	rev, err := model.DocumentRepository.GetRevision(ctx, id, revision)
+	if err != nil {
+		return nil, err
+	}
+	if err := audit(ctx, model.AuditRead, id, &rev.Document, &rev.Document); err != nil {
+		return nil, err
+	}
+	return rev, nil
+}
+
+func (s *documentService) GetAsOf(ctx context.Context, id int64, asOf time.Time) (*model.Document, error) {
+	ctx, span := tracing.Start(ctx, "documentService.GetAsOf")
+	defer span.End()
+
+	if err := authorizeID(ctx, id, model.ActionRead); err != nil {
+		return nil, err
+	}
+	doc, err := model.DocumentRepository.GetAsOf(ctx, id, asOf)
+	if err != nil {
+		return nil, err
+	}
+	if err := audit(ctx, model.AuditRead, id, doc, doc); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// Restore reverts a document expected to be at the given version to the
+// content it held at the given revision, or whichever version is current when
+// it is zero, recording the change as a new revision.
+func (s *documentService) Restore(ctx context.Context, id, revision, version int64) (*model.Document, error) {
+	ctx, span := tracing.Start(ctx, "documentService.Restore")
+	defer span.End()
+
+	if err := authorizeID(ctx, id, model.ActionWrite); err != nil {
+		return nil, err
+	}
+	rev, err := model.DocumentRepository.GetRevision(ctx, id, revision)
+	if err != nil {
+		return nil, err
+	}
+	if rev.Operation == model.RevisionDelete {
+		return nil, model.RevisionInvalidValue
+	}
+	doc := rev.Document
+	doc.Version = version
+	return s.Update(ctx, doc)
+}
@@ -0,0 +1,77 @@
+package service
+
+import (
+	"database/sql"
+	"github.com/stretchr/testify/assert"
+	"precisely/model"
+	"testing"
+)
+
+func TestDocumentService_Restore_Success(t *testing.T) {
+	model.DocumentRepository = &dBMock{}
+	getRevisionMessageDAO = func(id, rev int64) (*model.Revision, error) {
+		return &model.Revision{
+			Revision:  1,
+			Operation: model.RevisionCreate,
+			Document:  model.Document{ID: id, Title: "old title", Signee: "signee"},
+		}, nil
+	}
+	getMessageDAO = func(id int64) (*model.Document, error) {
+		return &model.Document{ID: id, Title: "new title", Signee: "signee"}, nil
+	}
+	var updated model.Document
+	updateMessageDAO = func(doc model.Document) (*model.Document, error) {
+		updated = doc
+		return &doc, nil
+	}
+	doc, err := DocumentService.Restore(admin, 1, 1, 0)
+	assert.Nil(t, err)
+	assert.EqualValues(t, "old title", doc.Title)
+	assert.EqualValues(t, 1, updated.ID)
+	assert.EqualValues(t, "old title", updated.Title)
+}
+
+func TestDocumentService_Restore_VersionMismatch(t *testing.T) {
+	model.DocumentRepository = &dBMock{}
+	getRevisionMessageDAO = func(id, rev int64) (*model.Revision, error) {
+		return &model.Revision{
+			Revision:  1,
+			Operation: model.RevisionCreate,
+			Document:  model.Document{ID: id, Title: "old title", Signee: "signee"},
+		}, nil
+	}
+	getMessageDAO = func(id int64) (*model.Document, error) {
+		return &model.Document{ID: id, Title: "new title", Signee: "signee", Version: 3}, nil
+	}
+	updateMessageDAO = func(doc model.Document) (*model.Document, error) {
+		t.Fatal("Update() of a stale restore reached the repository")
+		return nil, nil
+	}
+	doc, err := DocumentService.Restore(admin, 1, 1, 2)
+	assert.Nil(t, doc)
+	assert.Equal(t, model.VersionMismatchValue, err)
+}
+
+func TestDocumentService_Restore_DeleteRevision(t *testing.T) {
+	model.DocumentRepository = &dBMock{}
+	getRevisionMessageDAO = func(id, rev int64) (*model.Revision, error) {
+		return &model.Revision{
+			Revision:  3,
+			Operation: model.RevisionDelete,
+			Document:  model.Document{ID: id, Title: "title", Signee: "signee"},
+		}, nil
+	}
+	doc, err := DocumentService.Restore(admin, 1, 3, 0)
+	assert.Nil(t, doc)
+	assert.Equal(t, model.RevisionInvalidValue, err)
+}
+
+func TestDocumentService_Restore_NotFound(t *testing.T) {
+	model.DocumentRepository = &dBMock{}
+	getRevisionMessageDAO = func(id, rev int64) (*model.Revision, error) {
+		return nil, sql.ErrNoRows
+	}
+	doc, err := DocumentService.Restore(admin, 1, 9, 0)
+	assert.Nil(t, doc)
+	assert.Equal(t, sql.ErrNoRows, err)
+}
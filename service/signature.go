@@ -0,0 +1,161 @@
+package service
+
+import (
+	"context"
+	"crypto/ed25519"
+	"database/sql"
+	"errors"
+	"precisely/model"
+	"precisely/tracing"
+	"time"
+)
+
+var (
+	SignatureService signatureServiceInterface = &signatureService{}
+)
+
+type signatureService struct{}
+
+type signatureServiceInterface interface {
+	RegisterKey(context.Context, model.SigneeKey) (*model.SigneeKey, error)
+	GetKeys(context.Context, string) ([]*model.SigneeKey, error)
+	Sign(context.Context, int64, int64, []byte) (*model.Signature, error)
+	GetSignatures(context.Context, int64) ([]*model.Signature, error)
+	Verify(context.Context, int64) (*model.Verification, error)
+}
+
+// RegisterKey registers a public key to the signee it names, who must be the
+// principal of ctx unless an admin registers it for them.
+func (s *signatureService) RegisterKey(ctx context.Context, key model.SigneeKey) (*model.SigneeKey, error) {
+	ctx, span := tracing.Start(ctx, "signatureService.RegisterKey")
+	defer span.End()
+
+	if err := key.Validate(); err != nil {
+		return nil, err
+	}
+	if err := actAs(ctx, key.Signee); err != nil {
+		return nil, err
+	}
+	return model.SignatureRepository.CreateKey(ctx, key)
+}
+
+func (s *signatureService) GetKeys(ctx context.Context, signee string) ([]*model.SigneeKey, error) {
+	ctx, span := tracing.Start(ctx, "signatureService.GetKeys")
+	defer span.End()
+
+	return model.SignatureRepository.GetKeys(ctx, signee)
+}
+
+// Sign verifies a detached signature over the current content of a document
+// and stores it when it was made by a key of the document's signee or of one
+// of its workflow signees, and the principal of ctx is that signee or an
+// admin. A workflow signee must be pending and have their turn, and is marked
+// as signed along with the signature.
+func (s *signatureService) Sign(ctx context.Context, documentID, keyID int64, signature []byte) (*model.Signature, error) {
+	ctx, span := tracing.Start(ctx, "signatureService.Sign")
+	defer span.End()
+
+	doc, err := model.DocumentRepository.Get(ctx, documentID)
+	if err != nil {
+		return nil, err
+	}
+	if err := authorize(ctx, doc, model.ActionSign); err != nil {
+		return nil, err
+	}
+	key, err := model.SignatureRepository.GetKey(ctx, keyID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, model.KeyInvalidValue
+		}
+		return nil, err
+	}
+	if err := actAs(ctx, key.Signee); err != nil {
+		return nil, err
+	}
+	workflow, err := model.SigneeRepository.GetWorkflow(ctx, doc.ID)
+	if err != nil {
+		return nil, err
+	}
+	signee := workflow.SigneeNamed(key.Signee)
+	if signee == nil && key.Signee != doc.Signee {
+		return nil, model.SigneeMismatchValue
+	}
+	if signee != nil {
+		if err := workflow.CheckTurn(signee); err != nil {
+			return nil, err
+		}
+		signedAt := time.Now().UTC()
+		signee.Status = model.SigneeSigned
+		signee.SignedAt = &signedAt
+	}
+	if !ed25519.Verify(key.PublicKey, doc.CanonicalPayload(), signature) {
+		return nil, model.SignatureInvalidValue
+	}
+	stored, err := model.SignatureRepository.CreateSignature(auditing(ctx), model.Signature{
+		DocumentID: doc.ID,
+		KeyID:      key.ID,
+		Signee:     key.Signee,
+		Signature:  signature,
+		Digest:     doc.Digest(),
+	}, signee)
+	if err != nil {
+		return nil, err
+	}
+	publish(ctx, model.EventDocumentSigned, doc, key.Signee)
+	return stored, nil
+}
+
+func (s *signatureService) GetSignatures(ctx context.Context, documentID int64) ([]*model.Signature, error) {
+	ctx, span := tracing.Start(ctx, "signatureService.GetSignatures")
+	defer span.End()
+
+	doc, err := model.DocumentRepository.Get(ctx, documentID)
+	if err != nil {
+		return nil, err
+	}
+	if err := authorize(ctx, doc, model.ActionRead); err != nil {
+		return nil, err
+	}
+	return model.SignatureRepository.GetSignatures(ctx, documentID)
+}
+
+// Verify checks every stored signature of a document against its current
+// content. The document is valid when it has signatures and all of them hold.
+func (s *signatureService) Verify(ctx context.Context, documentID int64) (*model.Verification, error) {
+	ctx, span := tracing.Start(ctx, "signatureService.Verify")
+	defer span.End()
+
+	doc, err := model.DocumentRepository.Get(ctx, documentID)
+	if err != nil {
+		return nil, err
+	}
+	if err := authorize(ctx, doc, model.ActionRead); err != nil {
+		return nil, err
+	}
+	signatures, err := model.SignatureRepository.GetSignatures(ctx, documentID)
+	if err != nil {
+		return nil, err
+	}
+
+	payload := doc.CanonicalPayload()
+	result := &model.Verification{
+		DocumentID: doc.ID,
+		Digest:     doc.Digest(),
+		Valid:      len(signatures) > 0,
+		Signatures: make([]*model.SignatureVerification, 0, len(signatures)),
+	}
+	keys := make(map[int64]*model.SigneeKey)
+	for _, sig := range signatures {
+		key, ok := keys[sig.KeyID]
+		if !ok {
+			if key, err = model.SignatureRepository.GetKey(ctx, sig.KeyID); err != nil {
+				return nil, err
+			}
+			keys[sig.KeyID] = key
+		}
+		valid := ed25519.Verify(key.PublicKey, payload, sig.Signature)
+		result.Valid = result.Valid && valid
+		result.Signatures = append(result.Signatures, &model.SignatureVerification{Signature: *sig, Valid: valid})
+	}
+	return result, nil
+}
@@ -0,0 +1,237 @@
+package service
+
+import (
+	"context"
+	"crypto/ed25519"
+	"database/sql"
+	"github.com/stretchr/testify/assert"
+	"precisely/model"
+	"testing"
+)
+
+var (
+	createKeyDAO       func(key model.SigneeKey) (*model.SigneeKey, error)
+	getKeyDAO          func(id int64) (*model.SigneeKey, error)
+	getKeysDAO         func(signee string) ([]*model.SigneeKey, error)
+	createSignatureDAO func(sig model.Signature, signee *model.Signee) (*model.Signature, error)
+	getSignaturesDAO   func(documentID int64) ([]*model.Signature, error)
+)
+
+type signatureDBMock struct{}
+
+func (m *signatureDBMock) CreateKey(_ context.Context, key model.SigneeKey) (*model.SigneeKey, error) {
+	return createKeyDAO(key)
+}
+
+func (m *signatureDBMock) GetKey(_ context.Context, id int64) (*model.SigneeKey, error) {
+	return getKeyDAO(id)
+}
+
+func (m *signatureDBMock) GetKeys(_ context.Context, signee string) ([]*model.SigneeKey, error) {
+	return getKeysDAO(signee)
+}
+
+func (m *signatureDBMock) CreateSignature(_ context.Context, sig model.Signature, signee *model.Signee) (*model.Signature, error) {
+	return createSignatureDAO(sig, signee)
+}
+
+func (m *signatureDBMock) GetSignatures(_ context.Context, documentID int64) ([]*model.Signature, error) {
+	return getSignaturesDAO(documentID)
+}
+
+func mockSigning(t *testing.T, doc *model.Document) ed25519.PrivateKey {
+	publicKey, privateKey, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	model.DocumentRepository = &dBMock{}
+	model.SignatureRepository = &signatureDBMock{}
+	getMessageDAO = func(id int64) (*model.Document, error) {
+		if id != doc.ID {
+			return nil, sql.ErrNoRows
+		}
+		return doc, nil
+	}
+	getKeyDAO = func(id int64) (*model.SigneeKey, error) {
+		if id != 1 {
+			return nil, sql.ErrNoRows
+		}
+		return &model.SigneeKey{ID: 1, Signee: "signee", PublicKey: publicKey}, nil
+	}
+	createSignatureDAO = func(sig model.Signature, signee *model.Signee) (*model.Signature, error) {
+		sig.ID = 1
+		return &sig, nil
+	}
+	mockWorkflow(model.SigningParallel)
+	return privateKey
+}
+
+func TestSignatureService_RegisterKey_InvalidKey(t *testing.T) {
+	key, err := SignatureService.RegisterKey(admin, model.SigneeKey{Signee: "signee", PublicKey: []byte("short")})
+	assert.Nil(t, key)
+	assert.Equal(t, model.PublicKeyInvalidValue, err)
+}
+
+func TestSignatureService_RegisterKey_OtherSignee(t *testing.T) {
+	model.SignatureRepository = &signatureDBMock{}
+	createKeyDAO = func(key model.SigneeKey) (*model.SigneeKey, error) {
+		key.ID = 1
+		return &key, nil
+	}
+	publicKey := make([]byte, ed25519.PublicKeySize)
+
+	key, err := SignatureService.RegisterKey(as("a"), model.SigneeKey{Signee: "b", PublicKey: publicKey})
+	assert.Nil(t, key)
+	assert.Equal(t, model.ForbiddenValue, err)
+
+	key, err = SignatureService.RegisterKey(as("a"), model.SigneeKey{Signee: "a", PublicKey: publicKey})
+	assert.Nil(t, err)
+	assert.EqualValues(t, "a", key.Signee)
+}
+
+func TestSignatureService_Sign_Success(t *testing.T) {
+	doc := &model.Document{ID: 1, Title: "title", Content: model.Content{Header: "header", Data: "data"}, Signee: "signee"}
+	privateKey := mockSigning(t, doc)
+
+	sig, err := SignatureService.Sign(admin, 1, 1, ed25519.Sign(privateKey, doc.CanonicalPayload()))
+	assert.Nil(t, err)
+	assert.EqualValues(t, 1, sig.DocumentID)
+	assert.EqualValues(t, "signee", sig.Signee)
+	assert.EqualValues(t, doc.Digest(), sig.Digest)
+}
+
+func TestSignatureService_Sign_Rejected(t *testing.T) {
+	doc := &model.Document{ID: 1, Title: "title", Signee: "signee"}
+	privateKey := mockSigning(t, doc)
+	other := model.Document{Title: "other title"}
+
+	tests := []struct {
+		name      string
+		doc       *model.Document
+		keyID     int64
+		signature []byte
+		wantErr   error
+	}{
+		{
+			name:      "Wrong content",
+			doc:       doc,
+			keyID:     1,
+			signature: ed25519.Sign(privateKey, other.CanonicalPayload()),
+			wantErr:   model.SignatureInvalidValue,
+		},
+		{
+			name:      "Unknown key",
+			doc:       doc,
+			keyID:     2,
+			signature: ed25519.Sign(privateKey, doc.CanonicalPayload()),
+			wantErr:   model.KeyInvalidValue,
+		},
+		{
+			name:      "Other signee",
+			doc:       &model.Document{ID: 1, Title: "title", Signee: "someone else"},
+			keyID:     1,
+			signature: ed25519.Sign(privateKey, doc.CanonicalPayload()),
+			wantErr:   model.SigneeMismatchValue,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			*doc = *tt.doc
+			sig, err := SignatureService.Sign(admin, 1, tt.keyID, tt.signature)
+			assert.Nil(t, sig)
+			assert.Equal(t, tt.wantErr, err)
+		})
+	}
+}
+
+func TestSignatureService_Sign_WorkflowSignee(t *testing.T) {
+	doc := &model.Document{ID: 1, Title: "title", Signee: "owner"}
+	privateKey := mockSigning(t, doc)
+	mockWorkflow(model.SigningOrdered, model.SigneePending, model.SigneePending)
+	getKeyDAO = func(id int64) (*model.SigneeKey, error) {
+		return &model.SigneeKey{ID: id, Signee: "b", PublicKey: privateKey.Public().(ed25519.PublicKey)}, nil
+	}
+	signature := ed25519.Sign(privateKey, doc.CanonicalPayload())
+
+	sig, err := SignatureService.Sign(admin, 1, 2, signature)
+	assert.Nil(t, sig)
+	assert.Equal(t, model.SigneeOutOfTurnValue, err)
+
+	var updated *model.Signee
+	mockWorkflow(model.SigningOrdered, model.SigneeSigned, model.SigneePending)
+	createSignatureDAO = func(sig model.Signature, signee *model.Signee) (*model.Signature, error) {
+		updated = signee
+		return &sig, nil
+	}
+	sig, err = SignatureService.Sign(admin, 1, 2, signature)
+	assert.Nil(t, err)
+	assert.EqualValues(t, "b", sig.Signee)
+	if assert.NotNil(t, updated) {
+		assert.EqualValues(t, 2, updated.ID)
+		assert.EqualValues(t, model.SigneeSigned, updated.Status)
+		assert.NotNil(t, updated.SignedAt)
+	}
+}
+
+func TestSignatureService_Sign_OtherSignee(t *testing.T) {
+	doc := &model.Document{ID: 1, Title: "title", Signee: "owner"}
+	privateKey := mockSigning(t, doc)
+	mockSharedDocument()
+	getMessageDAO = func(id int64) (*model.Document, error) {
+		return &model.Document{ID: id, Title: "title", Signee: "owner", Owner: "alice"}, nil
+	}
+	mockWorkflow(model.SigningParallel, model.SigneePending, model.SigneePending)
+	getKeyDAO = func(id int64) (*model.SigneeKey, error) {
+		return &model.SigneeKey{ID: id, Signee: "b", PublicKey: privateKey.Public().(ed25519.PublicKey)}, nil
+	}
+	createSignatureDAO = func(sig model.Signature, signee *model.Signee) (*model.Signature, error) {
+		t.Fatal("CreateSignature() was called with the key of another signee")
+		return nil, nil
+	}
+
+	sig, err := SignatureService.Sign(as("a", "legal"), 1, 2, ed25519.Sign(privateKey, doc.CanonicalPayload()))
+	assert.Nil(t, sig)
+	assert.Equal(t, model.ForbiddenValue, err)
+}
+
+func TestSignatureService_Sign_SigneeDone(t *testing.T) {
+	doc := &model.Document{ID: 1, Title: "title", Signee: "owner"}
+	privateKey := mockSigning(t, doc)
+	getKeyDAO = func(id int64) (*model.SigneeKey, error) {
+		return &model.SigneeKey{ID: id, Signee: "a", PublicKey: privateKey.Public().(ed25519.PublicKey)}, nil
+	}
+	createSignatureDAO = func(sig model.Signature, signee *model.Signee) (*model.Signature, error) {
+		t.Fatal("CreateSignature() was called for a signee who is done")
+		return nil, nil
+	}
+	signature := ed25519.Sign(privateKey, doc.CanonicalPayload())
+
+	for _, status := range []string{model.SigneeSigned, model.SigneeDeclined} {
+		mockWorkflow(model.SigningParallel, status, model.SigneePending)
+		sig, err := SignatureService.Sign(admin, 1, 1, signature)
+		assert.Nil(t, sig)
+		assert.Equal(t, model.SigneeStatusConflict, err, status)
+	}
+}
+
+func TestSignatureService_Verify(t *testing.T) {
+	doc := &model.Document{ID: 1, Title: "title", Content: model.Content{Data: "data"}, Signee: "signee"}
+	privateKey := mockSigning(t, doc)
+	signature := ed25519.Sign(privateKey, doc.CanonicalPayload())
+	getSignaturesDAO = func(documentID int64) ([]*model.Signature, error) {
+		return []*model.Signature{
+			{ID: 1, DocumentID: 1, KeyID: 1, Signee: "signee", Signature: signature},
+		}, nil
+	}
+
+	verification, err := SignatureService.Verify(admin, 1)
+	assert.Nil(t, err)
+	assert.True(t, verification.Valid)
+	assert.True(t, verification.Signatures[0].Valid)
+
+	doc.Content.Data = "tampered data"
+	verification, err = SignatureService.Verify(admin, 1)
+	assert.Nil(t, err)
+	assert.False(t, verification.Valid)
+	assert.False(t, verification.Signatures[0].Valid)
+}
@@ -0,0 +1,138 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"precisely/model"
+	"precisely/tracing"
+	"time"
+	"unicode/utf8"
+)
+
+var (
+	SigneeService signeeServiceInterface = &signeeService{}
+)
+
+type signeeService struct{}
+
+type signeeServiceInterface interface {
+	GetWorkflow(context.Context, int64) (*model.SigningWorkflow, error)
+	SetSignees(context.Context, model.SigningWorkflow) (*model.SigningWorkflow, error)
+	Sign(context.Context, int64, int64) (*model.SigningWorkflow, error)
+	Decline(context.Context, int64, int64, string) (*model.SigningWorkflow, error)
+}
+
+func (s *signeeService) GetWorkflow(ctx context.Context, documentID int64) (*model.SigningWorkflow, error) {
+	ctx, span := tracing.Start(ctx, "signeeService.GetWorkflow")
+	defer span.End()
+
+	if err := authorizeID(ctx, documentID, model.ActionRead); err != nil {
+		return nil, err
+	}
+	return model.SigneeRepository.GetWorkflow(ctx, documentID)
+}
+
+func (s *signeeService) SetSignees(ctx context.Context, workflow model.SigningWorkflow) (*model.SigningWorkflow, error) {
+	ctx, span := tracing.Start(ctx, "signeeService.SetSignees")
+	defer span.End()
+
+	if err := workflow.Validate(); err != nil {
+		return nil, err
+	}
+	if err := authorizeID(ctx, workflow.DocumentID, model.ActionWrite); err != nil {
+		return nil, err
+	}
+	if _, err := model.SigneeRepository.GetWorkflow(ctx, workflow.DocumentID); err != nil {
+		return nil, err
+	}
+	return model.SigneeRepository.SetSignees(ctx, workflow)
+}
+
+// Sign marks a signee as signed when it is their turn. Only the signee, or an
+// admin, may sign for them.
+func (s *signeeService) Sign(ctx context.Context, documentID, signeeID int64) (*model.SigningWorkflow, error) {
+	ctx, span := tracing.Start(ctx, "signeeService.Sign")
+	defer span.End()
+
+	doc, err := model.DocumentRepository.Get(ctx, documentID)
+	if err != nil {
+		return nil, err
+	}
+	if err := authorize(ctx, doc, model.ActionSign); err != nil {
+		return nil, err
+	}
+	workflow, err := markSigned(ctx, documentID, signeeID)
+	if err != nil {
+		return nil, err
+	}
+	publish(ctx, model.EventDocumentSigned, doc, workflow.Signee(signeeID).Name)
+	return workflow, nil
+}
+
+// markSigned marks a signee as signed when it is their turn, for a principal
+// already authorized to sign the document.
+func markSigned(ctx context.Context, documentID, signeeID int64) (*model.SigningWorkflow, error) {
+	workflow, err := model.SigneeRepository.GetWorkflow(ctx, documentID)
+	if err != nil {
+		return nil, err
+	}
+	signee := workflow.Signee(signeeID)
+	if signee == nil {
+		return nil, sql.ErrNoRows
+	}
+	if err := actAs(ctx, signee.Name); err != nil {
+		return nil, err
+	}
+	if err := workflow.CheckTurn(signee); err != nil {
+		return nil, err
+	}
+	signedAt := time.Now().UTC()
+	signee.Status = model.SigneeSigned
+	signee.SignedAt = &signedAt
+	if err := model.SigneeRepository.UpdateStatus(auditing(ctx), *signee); err != nil {
+		return nil, err
+	}
+	workflow.RefreshState()
+	return workflow, nil
+}
+
+// Decline marks a pending signee as declined, which declines the whole
+// workflow. Only the signee, or an admin, may decline for them.
+func (s *signeeService) Decline(ctx context.Context, documentID, signeeID int64, reason string) (*model.SigningWorkflow, error) {
+	ctx, span := tracing.Start(ctx, "signeeService.Decline")
+	defer span.End()
+
+	if utf8.RuneCountInString(reason) > model.MaxReasonLength {
+		return nil, model.ReasonInvalidValue
+	}
+	doc, err := model.DocumentRepository.Get(ctx, documentID)
+	if err != nil {
+		return nil, err
+	}
+	if err := authorize(ctx, doc, model.ActionSign); err != nil {
+		return nil, err
+	}
+	workflow, err := model.SigneeRepository.GetWorkflow(ctx, documentID)
+	if err != nil {
+		return nil, err
+	}
+	signee := workflow.Signee(signeeID)
+	if signee == nil {
+		return nil, sql.ErrNoRows
+	}
+	if err := actAs(ctx, signee.Name); err != nil {
+		return nil, err
+	}
+	if signee.Status != model.SigneePending {
+		return nil, model.SigneeStatusConflict
+	}
+	declinedAt := time.Now().UTC()
+	signee.Status = model.SigneeDeclined
+	signee.Reason = reason
+	signee.DeclinedAt = &declinedAt
+	if err := model.SigneeRepository.UpdateStatus(auditing(ctx), *signee); err != nil {
+		return nil, err
+	}
+	workflow.RefreshState()
+	return workflow, nil
+}
@@ -0,0 +1,182 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"github.com/stretchr/testify/assert"
+	"precisely/model"
+	"strings"
+	"testing"
+)
+
+var (
+	getWorkflowDAO  func(documentID int64) (*model.SigningWorkflow, error)
+	setSigneesDAO   func(workflow model.SigningWorkflow) (*model.SigningWorkflow, error)
+	updateStatusDAO func(signee model.Signee) error
+)
+
+type signeeDBMock struct{}
+
+func (m *signeeDBMock) GetWorkflow(_ context.Context, documentID int64) (*model.SigningWorkflow, error) {
+	return getWorkflowDAO(documentID)
+}
+
+func (m *signeeDBMock) SetSignees(_ context.Context, workflow model.SigningWorkflow) (*model.SigningWorkflow, error) {
+	return setSigneesDAO(workflow)
+}
+
+func (m *signeeDBMock) UpdateStatus(_ context.Context, signee model.Signee) error {
+	return updateStatusDAO(signee)
+}
+
+func mockWorkflow(mode string, statuses ...string) {
+	model.SigneeRepository = &signeeDBMock{}
+	getWorkflowDAO = func(documentID int64) (*model.SigningWorkflow, error) {
+		workflow := &model.SigningWorkflow{DocumentID: documentID, Mode: mode, Signees: []*model.Signee{}}
+		for i, status := range statuses {
+			workflow.Signees = append(workflow.Signees, &model.Signee{
+				ID:         int64(i + 1),
+				DocumentID: documentID,
+				Position:   i + 1,
+				Name:       string(rune('a' + i)),
+				Status:     status,
+			})
+		}
+		workflow.RefreshState()
+		return workflow, nil
+	}
+	updateStatusDAO = func(signee model.Signee) error {
+		return nil
+	}
+}
+
+// mockSigneeDocument makes the document of every id exist, for the signee
+// operations that load it to authorize the principal.
+func mockSigneeDocument() {
+	model.DocumentRepository = &dBMock{}
+	getMessageDAO = func(id int64) (*model.Document, error) {
+		return &model.Document{ID: id, Title: "title", Signee: "signee", Version: 1}, nil
+	}
+}
+
+func TestSigneeService_SetSignees_Invalid(t *testing.T) {
+	tests := []struct {
+		workflow model.SigningWorkflow
+		wantErr  error
+	}{
+		{
+			workflow: model.SigningWorkflow{Mode: "random", Signees: []*model.Signee{{Name: "a"}}},
+			wantErr:  model.SigningModeInvalidValue,
+		},
+		{
+			workflow: model.SigningWorkflow{Mode: model.SigningOrdered},
+			wantErr:  model.SigneesInvalidValue,
+		},
+		{
+			workflow: model.SigningWorkflow{Signees: []*model.Signee{{Name: "a"}, {Name: " a "}}},
+			wantErr:  model.SigneesInvalidValue,
+		},
+	}
+	for _, tt := range tests {
+		workflow, err := SigneeService.SetSignees(admin, tt.workflow)
+		assert.Nil(t, workflow)
+		assert.Equal(t, tt.wantErr, err)
+	}
+}
+
+func TestSigneeService_Sign_Ordered(t *testing.T) {
+	mockSigneeDocument()
+	mockWorkflow(model.SigningOrdered, model.SigneePending, model.SigneePending)
+
+	workflow, err := SigneeService.Sign(admin, 1, 2)
+	assert.Nil(t, workflow)
+	assert.Equal(t, model.SigneeOutOfTurnValue, err)
+
+	workflow, err = SigneeService.Sign(admin, 1, 1)
+	assert.Nil(t, err)
+	assert.EqualValues(t, model.SigneeSigned, workflow.Signees[0].Status)
+	assert.NotNil(t, workflow.Signees[0].SignedAt)
+	assert.EqualValues(t, model.SigningPending, workflow.State)
+}
+
+func TestSigneeService_Sign_Completes(t *testing.T) {
+	mockSigneeDocument()
+	mockWorkflow(model.SigningParallel, model.SigneePending, model.SigneeSigned)
+
+	workflow, err := SigneeService.Sign(admin, 1, 1)
+	assert.Nil(t, err)
+	assert.EqualValues(t, model.SigningCompleted, workflow.State)
+}
+
+func TestSigneeService_Sign_Conflicts(t *testing.T) {
+	mockSigneeDocument()
+	mockWorkflow(model.SigningParallel, model.SigneeSigned, model.SigneeDeclined, model.SigneePending)
+
+	_, err := SigneeService.Sign(admin, 1, 1)
+	assert.Equal(t, model.SigneeStatusConflict, err)
+	_, err = SigneeService.Sign(admin, 1, 3)
+	assert.Equal(t, model.SigningDeclinedValue, err)
+	_, err = SigneeService.Sign(admin, 1, 4)
+	assert.Equal(t, sql.ErrNoRows, err)
+}
+
+func TestSigneeService_Decline(t *testing.T) {
+	mockSigneeDocument()
+	mockWorkflow(model.SigningOrdered, model.SigneePending, model.SigneePending)
+
+	workflow, err := SigneeService.Decline(admin, 1, 2, "wrong amount")
+	assert.Nil(t, err)
+	assert.EqualValues(t, model.SigneeDeclined, workflow.Signees[1].Status)
+	assert.EqualValues(t, "wrong amount", workflow.Signees[1].Reason)
+	assert.NotNil(t, workflow.Signees[1].DeclinedAt)
+	assert.EqualValues(t, model.SigningDeclined, workflow.State)
+}
+
+func TestSigneeService_Decline_ReasonTooLong(t *testing.T) {
+	mockSigneeDocument()
+	mockWorkflow(model.SigningOrdered, model.SigneePending, model.SigneePending)
+	updateStatusDAO = func(signee model.Signee) error {
+		t.Errorf("UpdateStatus() of signee %d was called", signee.ID)
+		return nil
+	}
+
+	_, err := SigneeService.Decline(admin, 1, 2, strings.Repeat("é", model.MaxReasonLength+1))
+	assert.Equal(t, model.ReasonInvalidValue, err)
+
+	mockWorkflow(model.SigningOrdered, model.SigneePending, model.SigneePending)
+	workflow, err := SigneeService.Decline(admin, 1, 2, strings.Repeat("é", model.MaxReasonLength))
+	assert.Nil(t, err)
+	assert.EqualValues(t, model.SigneeDeclined, workflow.Signees[1].Status)
+}
+
+func TestSigneeService_OtherSignee(t *testing.T) {
+	mockSharedDocument()
+	mockWorkflow(model.SigningParallel, model.SigneePending, model.SigneePending)
+	updateStatusDAO = func(signee model.Signee) error {
+		t.Errorf("UpdateStatus() of signee %d was called", signee.ID)
+		return nil
+	}
+
+	for _, ctx := range []context.Context{as("a", "legal"), as("alice")} {
+		workflow, err := SigneeService.Sign(ctx, 1, 2)
+		assert.Nil(t, workflow)
+		assert.Equal(t, model.ForbiddenValue, err)
+
+		workflow, err = SigneeService.Decline(ctx, 1, 2, "not mine to decline")
+		assert.Nil(t, workflow)
+		assert.Equal(t, model.ForbiddenValue, err)
+	}
+}
+
+func TestSigneeService_OwnSignee(t *testing.T) {
+	mockSharedDocument()
+	mockWorkflow(model.SigningParallel, model.SigneePending, model.SigneePending)
+
+	workflow, err := SigneeService.Sign(as("a", "legal"), 1, 1)
+	assert.Nil(t, err)
+	assert.EqualValues(t, model.SigneeSigned, workflow.Signees[0].Status)
+
+	workflow, err = SigneeService.Decline(as("b", "legal"), 1, 2, "wrong amount")
+	assert.Nil(t, err)
+	assert.EqualValues(t, model.SigneeDeclined, workflow.Signees[1].Status)
+}
@@ -0,0 +1,93 @@
+package service
+
+import (
+	"context"
+	"github.com/sirupsen/logrus"
+	"precisely/model"
+	"precisely/tracing"
+	"time"
+)
+
+func (s *documentService) RestoreDeleted(ctx context.Context, id int64) (*model.Document, error) {
+	ctx, span := tracing.Start(ctx, "documentService.RestoreDeleted")
+	defer span.End()
+
+	if _, err := authorizeTrashed(ctx, id); err != nil {
+		return nil, err
+	}
+	restored, err := model.DocumentRepository.RestoreDeleted(auditing(ctx), id)
+	if err != nil {
+		return nil, err
+	}
+	ChangeHub.Publish()
+	return restored, nil
+}
+
+func (s *documentService) Purge(ctx context.Context, id int64) error {
+	ctx, span := tracing.Start(ctx, "documentService.Purge")
+	defer span.End()
+
+	if _, err := authorizeTrashed(ctx, id); err != nil {
+		return err
+	}
+	return model.DocumentRepository.Purge(auditing(ctx), id)
+}
+
+// authorizeTrashed checks that the principal of ctx may take the document of
+// an id out of the trash, for good or not, and returns it.
+func authorizeTrashed(ctx context.Context, id int64) (*model.Document, error) {
+	doc, err := model.DocumentRepository.GetTrashed(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := authorize(ctx, doc, model.ActionDelete); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// PurgeExpired permanently deletes the documents that have been in the trash
+// for longer than retention, as an admin. A purge that deleted documents is
+// audited as a single entry without a document.
+func (s *documentService) PurgeExpired(ctx context.Context, retention time.Duration) (int64, error) {
+	ctx, span := tracing.Start(ctx, "documentService.PurgeExpired")
+	defer span.End()
+
+	if err := requireRole(ctx, model.RoleAdmin); err != nil {
+		return 0, err
+	}
+	return model.DocumentRepository.PurgeDeletedBefore(auditing(ctx), time.Now().Add(-retention))
+}
+
+// trashPurger is the principal the trash purger runs as.
+var trashPurger = &model.Principal{Subject: "trash-purger", Roles: []string{model.RoleAdmin}}
+
+// StartTrashPurger runs PurgeExpired every interval until the returned stop
+// function is called. Stop cancels a purge in progress and waits for it to
+// return.
+func StartTrashPurger(retention, interval time.Duration) (stop func()) {
+	ticker := time.NewTicker(interval)
+	ctx, cancel := context.WithCancel(model.WithPrincipal(context.Background(), trashPurger))
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				purged, err := DocumentService.PurgeExpired(ctx, retention)
+				if err != nil {
+					logrus.WithError(err).Error("failed purging the trash")
+				} else if purged > 0 {
+					logrus.WithField("purged", purged).Info("purged documents from the trash")
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return func() {
+		cancel()
+		<-stopped
+	}
+}
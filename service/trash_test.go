@@ -0,0 +1,38 @@
+package service
+
+import (
+	"github.com/stretchr/testify/assert"
+	"precisely/model"
+	"testing"
+	"time"
+)
+
+func TestDocumentService_PurgeExpired(t *testing.T) {
+	model.DocumentRepository = &dBMock{}
+	var got time.Time
+	purgeDeletedBeforeMessageDAO = func(before time.Time) (int64, error) {
+		got = before
+		return 3, nil
+	}
+	purged, err := DocumentService.PurgeExpired(admin, 24*time.Hour)
+	assert.Nil(t, err)
+	assert.EqualValues(t, 3, purged)
+	assert.WithinDuration(t, time.Now().Add(-24*time.Hour), got, time.Second)
+}
+
+func TestStartTrashPurger(t *testing.T) {
+	model.DocumentRepository = &dBMock{}
+	calls := make(chan time.Time, 10)
+	purgeDeletedBeforeMessageDAO = func(before time.Time) (int64, error) {
+		calls <- before
+		return 0, nil
+	}
+	stop := StartTrashPurger(time.Hour, time.Millisecond)
+	select {
+	case before := <-calls:
+		assert.WithinDuration(t, time.Now().Add(-time.Hour), before, time.Second)
+	case <-time.After(time.Second):
+		t.Error("purger did not run")
+	}
+	stop()
+}
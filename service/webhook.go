@@ -0,0 +1,359 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/base64"
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"precisely/model"
+	"precisely/tracing"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// webhookSecretPrefix marks the webhook secrets of the service.
+const webhookSecretPrefix = "whsec_"
+
+var (
+	WebhookService webhookServiceInterface = &webhookService{}
+)
+
+type webhookService struct{}
+
+// webhookServiceInterface manages the webhooks, which receive the events of
+// every document and are therefore reserved to admins.
+type webhookServiceInterface interface {
+	Create(context.Context, model.Webhook) (*model.Webhook, error)
+	Get(context.Context, int64) (*model.Webhook, error)
+	GetAll(context.Context) ([]*model.Webhook, error)
+	Update(context.Context, model.Webhook) (*model.Webhook, error)
+	Delete(context.Context, int64) error
+	GetDeliveries(context.Context, model.DeliveryQuery) (*model.DeliveryPage, error)
+	Redeliver(context.Context, int64, int64) (*model.Delivery, error)
+}
+
+// Create subscribes a webhook with a new secret, returned in Secret this once.
+func (s *webhookService) Create(ctx context.Context, webhook model.Webhook) (*model.Webhook, error) {
+	ctx, span := tracing.Start(ctx, "webhookService.Create")
+	defer span.End()
+
+	if err := requireRole(ctx, model.RoleAdmin); err != nil {
+		return nil, err
+	}
+	if err := webhook.Validate(); err != nil {
+		return nil, err
+	}
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, err
+	}
+	webhook.Secret = webhookSecretPrefix + base64.RawURLEncoding.EncodeToString(secret)
+	return model.WebhookRepository.Create(ctx, webhook)
+}
+
+func (s *webhookService) Get(ctx context.Context, id int64) (*model.Webhook, error) {
+	ctx, span := tracing.Start(ctx, "webhookService.Get")
+	defer span.End()
+
+	if err := requireRole(ctx, model.RoleAdmin); err != nil {
+		return nil, err
+	}
+	webhook, err := model.WebhookRepository.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	webhook.Secret = ""
+	return webhook, nil
+}
+
+func (s *webhookService) GetAll(ctx context.Context) ([]*model.Webhook, error) {
+	ctx, span := tracing.Start(ctx, "webhookService.GetAll")
+	defer span.End()
+
+	if err := requireRole(ctx, model.RoleAdmin); err != nil {
+		return nil, err
+	}
+	webhooks, err := model.WebhookRepository.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, webhook := range webhooks {
+		webhook.Secret = ""
+	}
+	return webhooks, nil
+}
+
+// Update changes the URL, events and disabled flag of a webhook. A disabled
+// webhook receives no new deliveries, but those already queued go on.
+func (s *webhookService) Update(ctx context.Context, webhook model.Webhook) (*model.Webhook, error) {
+	ctx, span := tracing.Start(ctx, "webhookService.Update")
+	defer span.End()
+
+	if err := requireRole(ctx, model.RoleAdmin); err != nil {
+		return nil, err
+	}
+	if err := webhook.Validate(); err != nil {
+		return nil, err
+	}
+	updated, err := model.WebhookRepository.Update(ctx, webhook)
+	if err != nil {
+		return nil, err
+	}
+	updated.Secret = ""
+	return updated, nil
+}
+
+func (s *webhookService) Delete(ctx context.Context, id int64) error {
+	ctx, span := tracing.Start(ctx, "webhookService.Delete")
+	defer span.End()
+
+	if err := requireRole(ctx, model.RoleAdmin); err != nil {
+		return err
+	}
+	return model.WebhookRepository.Delete(ctx, id)
+}
+
+// GetDeliveries lists deliveries newest first: those of a webhook for its
+// history, or the dead ones of every webhook for the dead-letter list.
+func (s *webhookService) GetDeliveries(ctx context.Context, query model.DeliveryQuery) (*model.DeliveryPage, error) {
+	ctx, span := tracing.Start(ctx, "webhookService.GetDeliveries")
+	defer span.End()
+
+	if err := requireRole(ctx, model.RoleAdmin); err != nil {
+		return nil, err
+	}
+	if query.WebhookID != 0 {
+		if _, err := model.WebhookRepository.Get(ctx, query.WebhookID); err != nil {
+			return nil, err
+		}
+	}
+	return model.WebhookRepository.GetDeliveries(ctx, query)
+}
+
+// Redeliver queues a new delivery of the event of a past delivery of a
+// webhook, whatever became of it, leaving the history of the past one as is.
+func (s *webhookService) Redeliver(ctx context.Context, webhookID, deliveryID int64) (*model.Delivery, error) {
+	ctx, span := tracing.Start(ctx, "webhookService.Redeliver")
+	defer span.End()
+
+	if err := requireRole(ctx, model.RoleAdmin); err != nil {
+		return nil, err
+	}
+	past, err := model.WebhookRepository.GetDelivery(ctx, deliveryID)
+	if err != nil {
+		return nil, err
+	}
+	if past.WebhookID != webhookID {
+		return nil, sql.ErrNoRows
+	}
+	now := time.Now().UTC()
+	created, err := model.WebhookRepository.CreateDeliveries(ctx, []model.Delivery{{
+		WebhookID:     webhookID,
+		EventID:       past.EventID,
+		Event:         past.Event,
+		Payload:       past.Payload,
+		Status:        model.DeliveryPending,
+		NextAttemptAt: &now,
+	}})
+	if err != nil {
+		return nil, err
+	}
+	return created[0], nil
+}
+
+// enqueue queues the deliveries of an outbox event to the webhooks subscribed
+// to it.
+func enqueue(ctx context.Context, event *model.OutboxEvent) error {
+	webhooks, err := model.WebhookRepository.GetAll(ctx)
+	if err != nil {
+		return err
+	}
+	now := time.Now().UTC()
+	deliveries := make([]model.Delivery, 0, len(webhooks))
+	for _, webhook := range webhooks {
+		if webhook.Subscribed(event.Event) {
+			deliveries = append(deliveries, model.Delivery{
+				WebhookID:     webhook.ID,
+				EventID:       event.EventID,
+				Event:         event.Event,
+				Payload:       event.Payload,
+				Status:        model.DeliveryPending,
+				NextAttemptAt: &now,
+			})
+		}
+	}
+	if len(deliveries) == 0 {
+		return nil
+	}
+	_, err = model.WebhookRepository.CreateDeliveries(ctx, deliveries)
+	return err
+}
+
+// WebhookDispatcherConfig tunes the delivery of webhooks; the zero value of a
+// field stands for its default.
+type WebhookDispatcherConfig struct {
+	// Interval is how often due deliveries are looked for, 5s by default.
+	Interval time.Duration
+	// Timeout bounds each attempt, 10s by default.
+	Timeout time.Duration
+	// RetryBase is the wait after the first failed attempt, doubled after
+	// every other one up to MaxBackoff; 30s and 1h by default.
+	RetryBase  time.Duration
+	MaxBackoff time.Duration
+	// MaxAttempts is how many attempts are made before a delivery is
+	// dead-lettered, 8 by default.
+	MaxAttempts int
+}
+
+// webhookBatchSize bounds the deliveries attempted at once.
+const webhookBatchSize = 20
+
+func (c WebhookDispatcherConfig) withDefaults() WebhookDispatcherConfig {
+	if c.Interval <= 0 {
+		c.Interval = 5 * time.Second
+	}
+	if c.Timeout <= 0 {
+		c.Timeout = 10 * time.Second
+	}
+	if c.RetryBase <= 0 {
+		c.RetryBase = 30 * time.Second
+	}
+	if c.MaxBackoff <= 0 {
+		c.MaxBackoff = time.Hour
+	}
+	if c.MaxAttempts <= 0 {
+		c.MaxAttempts = 8
+	}
+	return c
+}
+
+// StartWebhookDispatcher attempts the due deliveries every interval until the
+// returned stop function is called. Stop cancels the attempts in progress and
+// waits for them to return; those are attempted again once their claim ran
+// out, so a delivery may reach its webhook more than once.
+func StartWebhookDispatcher(config WebhookDispatcherConfig) (stop func()) {
+	config = config.withDefaults()
+	dispatcher := &webhookDispatcher{
+		config: config,
+		client: &http.Client{
+			Timeout: config.Timeout,
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+	}
+	ticker := time.NewTicker(config.Interval)
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if err := dispatcher.dispatchDue(ctx); err != nil && ctx.Err() == nil {
+					logrus.WithError(err).Error("failed dispatching webhook deliveries")
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return func() {
+		cancel()
+		<-stopped
+	}
+}
+
+type webhookDispatcher struct {
+	config WebhookDispatcherConfig
+	client *http.Client
+}
+
+// dispatchDue attempts the due deliveries, a batch at a time, until none is
+// left.
+func (d *webhookDispatcher) dispatchDue(ctx context.Context) error {
+	for {
+		// The claim outlasts the slowest attempt of the batch.
+		claimed, err := model.WebhookRepository.ClaimDue(ctx, d.config.Timeout+time.Minute, webhookBatchSize)
+		if err != nil {
+			return err
+		}
+		var wg sync.WaitGroup
+		for _, delivery := range claimed {
+			wg.Add(1)
+			go func(delivery *model.Delivery) {
+				defer wg.Done()
+				d.deliver(ctx, delivery)
+			}(delivery)
+		}
+		wg.Wait()
+		if len(claimed) < webhookBatchSize || ctx.Err() != nil {
+			return ctx.Err()
+		}
+	}
+}
+
+// deliver makes one attempt of a delivery and records its outcome. A webhook
+// that cannot be loaded fails the attempt like one that cannot be reached, so
+// that the delivery is still retried and eventually dead-lettered.
+func (d *webhookDispatcher) deliver(ctx context.Context, delivery *model.Delivery) {
+	ctx, span := tracing.Start(ctx, "webhookDispatcher.deliver")
+	defer span.End()
+
+	log := logrus.WithField("delivery_id", delivery.ID).WithField("webhook_id", delivery.WebhookID)
+	var code int
+	webhook, err := model.WebhookRepository.Get(ctx, delivery.WebhookID)
+	if err != nil {
+		log.WithError(err).Error("failed loading the webhook of a delivery")
+		err = fmt.Errorf("failed loading the webhook: %s", model.ErrorOf(err).Message)
+	} else {
+		code, err = d.post(ctx, webhook, delivery)
+	}
+	if ctx.Err() != nil {
+		return
+	}
+	if err != nil {
+		delivery.Fail(code, err.Error(), time.Now().UTC(), d.config.RetryBase, d.config.MaxBackoff, d.config.MaxAttempts)
+		log.WithError(err).WithField("status", delivery.Status).Warn("webhook delivery failed")
+	} else {
+		delivery.Succeed(code, time.Now().UTC())
+	}
+	if err := model.WebhookRepository.UpdateDelivery(ctx, *delivery); err != nil {
+		log.WithError(err).Error("failed recording a webhook delivery")
+	}
+}
+
+// post sends the payload of a delivery to its webhook, signed with the secret
+// of the webhook, and returns the status code of the response. Any answer but
+// a 2xx fails the attempt, redirects included.
+func (d *webhookDispatcher) post(ctx context.Context, webhook *model.Webhook, delivery *model.Delivery) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "precisely-webhooks")
+	req.Header.Set("X-Precisely-Event", delivery.Event)
+	req.Header.Set("X-Precisely-Delivery", strconv.FormatInt(delivery.ID, 10))
+	req.Header.Set("X-Precisely-Signature", model.SignWebhookPayload(webhook.Secret, time.Now().Unix(), delivery.Payload))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(ioutil.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("webhook answered %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
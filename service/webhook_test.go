@@ -0,0 +1,169 @@
+package service
+
+import (
+	"database/sql"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"precisely/model"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// webhookReceiver records the deliveries it receives and answers them with
+// status.
+type webhookReceiver struct {
+	mu       sync.Mutex
+	status   int
+	requests []*http.Request
+	bodies   [][]byte
+}
+
+func (rec *webhookReceiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := ioutil.ReadAll(r.Body)
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	rec.requests = append(rec.requests, r)
+	rec.bodies = append(rec.bodies, body)
+	w.WriteHeader(rec.status)
+}
+
+func subscribe(t *testing.T, url string, events ...string) *model.Webhook {
+	webhook, err := WebhookService.Create(admin, model.Webhook{URL: url, Events: events})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return webhook
+}
+
+// enqueueEvent queues the deliveries of an event about doc.
+func enqueueEvent(t *testing.T, eventType string, doc model.Document) {
+	event, err := model.NewOutboxEvent(eventType, doc, "", time.Now().UTC())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := enqueue(admin, &event); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWebhookService_Create(t *testing.T) {
+	model.WebhookRepository = model.NewMemoryWebhookRepository()
+
+	webhook := subscribe(t, "https://example.com/hook", model.EventDocumentCreated)
+	assert.True(t, strings.HasPrefix(webhook.Secret, webhookSecretPrefix))
+
+	webhooks, err := WebhookService.GetAll(admin)
+	assert.Nil(t, err)
+	if assert.Len(t, webhooks, 1) {
+		assert.Empty(t, webhooks[0].Secret)
+	}
+
+	_, err = WebhookService.Create(admin, model.Webhook{URL: "ftp://example.com", Events: []string{model.EventDocumentCreated}})
+	assert.Equal(t, model.WebhookURLInvalidValue, err)
+	long := "https://example.com/" + strings.Repeat("a", model.MaxWebhookURLLength)
+	_, err = WebhookService.Create(admin, model.Webhook{URL: long, Events: []string{model.EventDocumentCreated}})
+	assert.Equal(t, model.WebhookURLInvalidValue, err)
+	_, err = WebhookService.Create(admin, model.Webhook{URL: long[:model.MaxWebhookURLLength], Events: []string{model.EventDocumentCreated}})
+	assert.Nil(t, err)
+	_, err = WebhookService.Create(admin, model.Webhook{URL: "https://example.com", Events: []string{"document.read"}})
+	assert.Equal(t, model.WebhookEventsInvalidValue, err)
+	_, err = WebhookService.Create(as("bob"), model.Webhook{URL: "https://example.com", Events: []string{model.EventDocumentCreated}})
+	assert.Equal(t, model.ForbiddenValue, err)
+}
+
+func TestWebhookDispatcher_Signed(t *testing.T) {
+	model.WebhookRepository = model.NewMemoryWebhookRepository()
+	receiver := &webhookReceiver{status: http.StatusNoContent}
+	server := httptest.NewServer(receiver)
+	defer server.Close()
+
+	webhook := subscribe(t, server.URL, model.EventDocumentCreated)
+	enqueueEvent(t, model.EventDocumentCreated, model.Document{ID: 1, Title: "title"})
+	dispatcher := &webhookDispatcher{config: WebhookDispatcherConfig{}.withDefaults(), client: server.Client()}
+	assert.Nil(t, dispatcher.dispatchDue(admin))
+
+	if assert.Len(t, receiver.requests, 1) {
+		r := receiver.requests[0]
+		assert.Equal(t, model.EventDocumentCreated, r.Header.Get("X-Precisely-Event"))
+		signature := r.Header.Get("X-Precisely-Signature")
+		var timestamp int64
+		if _, err := fmt.Sscanf(signature, "t=%d,", &timestamp); assert.Nil(t, err) {
+			assert.Equal(t, model.SignWebhookPayload(webhook.Secret, timestamp, receiver.bodies[0]), signature)
+		}
+	}
+	page, err := WebhookService.GetDeliveries(admin, model.DeliveryQuery{WebhookID: webhook.ID})
+	assert.Nil(t, err)
+	if assert.Len(t, page.Deliveries, 1) {
+		assert.Equal(t, model.DeliverySucceeded, page.Deliveries[0].Status)
+		assert.Equal(t, http.StatusNoContent, page.Deliveries[0].ResponseCode)
+		assert.NotNil(t, page.Deliveries[0].DeliveredAt)
+	}
+}
+
+func TestWebhookDispatcher_WebhookGone(t *testing.T) {
+	model.WebhookRepository = model.NewMemoryWebhookRepository()
+	now := time.Now()
+	_, err := model.WebhookRepository.CreateDeliveries(admin, []model.Delivery{{WebhookID: 42, EventID: "evt", Event: model.EventDocumentDeleted, Payload: []byte(`{}`), Status: model.DeliveryPending, NextAttemptAt: &now}})
+	assert.Nil(t, err)
+	dispatcher := &webhookDispatcher{
+		config: WebhookDispatcherConfig{RetryBase: time.Nanosecond, MaxAttempts: 2}.withDefaults(),
+		client: http.DefaultClient,
+	}
+	for i := 0; i < 3; i++ {
+		assert.Nil(t, dispatcher.dispatchDue(admin))
+	}
+
+	dead, err := model.WebhookRepository.GetDeliveries(admin, model.DeliveryQuery{WebhookID: 42, Status: model.DeliveryDead})
+	assert.Nil(t, err)
+	if assert.Len(t, dead.Deliveries, 1) {
+		assert.Equal(t, 2, dead.Deliveries[0].Attempts)
+		assert.Contains(t, dead.Deliveries[0].Error, "failed loading the webhook")
+	}
+}
+
+func TestWebhookDispatcher_DeadLetterAndRedeliver(t *testing.T) {
+	model.WebhookRepository = model.NewMemoryWebhookRepository()
+	receiver := &webhookReceiver{status: http.StatusInternalServerError}
+	server := httptest.NewServer(receiver)
+	defer server.Close()
+
+	webhook := subscribe(t, server.URL, model.EventDocumentDeleted)
+	other := subscribe(t, "https://example.com/hook", model.EventDocumentCreated)
+	enqueueEvent(t, model.EventDocumentDeleted, model.Document{ID: 1})
+	dispatcher := &webhookDispatcher{
+		config: WebhookDispatcherConfig{RetryBase: time.Nanosecond, MaxAttempts: 2}.withDefaults(),
+		client: server.Client(),
+	}
+	for i := 0; i < 3; i++ {
+		assert.Nil(t, dispatcher.dispatchDue(admin))
+	}
+	assert.Len(t, receiver.requests, 2)
+
+	dead, err := WebhookService.GetDeliveries(admin, model.DeliveryQuery{Status: model.DeliveryDead})
+	assert.Nil(t, err)
+	if !assert.Len(t, dead.Deliveries, 1) {
+		return
+	}
+	assert.Equal(t, 2, dead.Deliveries[0].Attempts)
+	assert.Equal(t, http.StatusInternalServerError, dead.Deliveries[0].ResponseCode)
+
+	_, err = WebhookService.Redeliver(admin, other.ID, dead.Deliveries[0].ID)
+	assert.Equal(t, sql.ErrNoRows, err)
+	receiver.status = http.StatusOK
+	redelivery, err := WebhookService.Redeliver(admin, webhook.ID, dead.Deliveries[0].ID)
+	assert.Nil(t, err)
+	assert.Equal(t, dead.Deliveries[0].EventID, redelivery.EventID)
+	assert.Nil(t, dispatcher.dispatchDue(admin))
+
+	history, err := WebhookService.GetDeliveries(admin, model.DeliveryQuery{WebhookID: webhook.ID})
+	assert.Nil(t, err)
+	if assert.Len(t, history.Deliveries, 2) {
+		assert.Equal(t, model.DeliverySucceeded, history.Deliveries[0].Status)
+		assert.Equal(t, model.DeliveryDead, history.Deliveries[1].Status)
+	}
+}
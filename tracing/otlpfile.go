@@ -0,0 +1,254 @@
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/sdk/instrumentation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+	"io"
+	"strconv"
+	"sync"
+)
+
+// otlpFileExporter writes every batch of spans as one line of OTLP/JSON, an
+// ExportTraceServiceRequest, which the file receiver of the OpenTelemetry
+// Collector and other OTLP tooling read.
+type otlpFileExporter struct {
+	mu      sync.Mutex
+	encoder *json.Encoder
+}
+
+func newOTLPFileExporter(w io.Writer) *otlpFileExporter {
+	return &otlpFileExporter{encoder: json.NewEncoder(w)}
+}
+
+func (e *otlpFileExporter) ExportSpans(ctx context.Context, spans []sdktrace.ReadOnlySpan) error {
+	if len(spans) == 0 {
+		return nil
+	}
+	request := otlpTraces(spans)
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.encoder.Encode(request)
+}
+
+func (e *otlpFileExporter) Shutdown(context.Context) error {
+	return nil
+}
+
+// The OTLP/JSON messages of a trace export. Identifiers are hex, 64-bit
+// integers decimal strings and enums their number, as the OTLP/JSON encoding
+// requires.
+type (
+	otlpTraceRequest struct {
+		ResourceSpans []*otlpResourceSpans `json:"resourceSpans"`
+	}
+	otlpResourceSpans struct {
+		Resource   otlpResource      `json:"resource"`
+		ScopeSpans []*otlpScopeSpans `json:"scopeSpans"`
+		SchemaURL  string            `json:"schemaUrl,omitempty"`
+	}
+	otlpResource struct {
+		Attributes []otlpKeyValue `json:"attributes,omitempty"`
+	}
+	otlpScopeSpans struct {
+		Scope     otlpScope  `json:"scope"`
+		Spans     []otlpSpan `json:"spans"`
+		SchemaURL string     `json:"schemaUrl,omitempty"`
+	}
+	otlpScope struct {
+		Name    string `json:"name,omitempty"`
+		Version string `json:"version,omitempty"`
+	}
+	otlpSpan struct {
+		TraceID                string         `json:"traceId"`
+		SpanID                 string         `json:"spanId"`
+		TraceState             string         `json:"traceState,omitempty"`
+		ParentSpanID           string         `json:"parentSpanId,omitempty"`
+		Name                   string         `json:"name"`
+		Kind                   int            `json:"kind"`
+		StartTimeUnixNano      string         `json:"startTimeUnixNano"`
+		EndTimeUnixNano        string         `json:"endTimeUnixNano"`
+		Attributes             []otlpKeyValue `json:"attributes,omitempty"`
+		DroppedAttributesCount int            `json:"droppedAttributesCount,omitempty"`
+		Events                 []otlpEvent    `json:"events,omitempty"`
+		DroppedEventsCount     int            `json:"droppedEventsCount,omitempty"`
+		Links                  []otlpLink     `json:"links,omitempty"`
+		DroppedLinksCount      int            `json:"droppedLinksCount,omitempty"`
+		Status                 otlpStatus     `json:"status"`
+	}
+	otlpEvent struct {
+		TimeUnixNano           string         `json:"timeUnixNano"`
+		Name                   string         `json:"name"`
+		Attributes             []otlpKeyValue `json:"attributes,omitempty"`
+		DroppedAttributesCount int            `json:"droppedAttributesCount,omitempty"`
+	}
+	otlpLink struct {
+		TraceID                string         `json:"traceId"`
+		SpanID                 string         `json:"spanId"`
+		TraceState             string         `json:"traceState,omitempty"`
+		Attributes             []otlpKeyValue `json:"attributes,omitempty"`
+		DroppedAttributesCount int            `json:"droppedAttributesCount,omitempty"`
+	}
+	otlpStatus struct {
+		Message string `json:"message,omitempty"`
+		Code    int    `json:"code,omitempty"`
+	}
+	otlpKeyValue struct {
+		Key   string       `json:"key"`
+		Value otlpAnyValue `json:"value"`
+	}
+	otlpAnyValue struct {
+		StringValue *string         `json:"stringValue,omitempty"`
+		BoolValue   *bool           `json:"boolValue,omitempty"`
+		IntValue    string          `json:"intValue,omitempty"`
+		DoubleValue *float64        `json:"doubleValue,omitempty"`
+		ArrayValue  *otlpArrayValue `json:"arrayValue,omitempty"`
+	}
+	otlpArrayValue struct {
+		Values []otlpAnyValue `json:"values"`
+	}
+)
+
+// The status codes of OTLP, which are not numbered like codes.Code.
+const (
+	otlpStatusUnset = 0
+	otlpStatusOk    = 1
+	otlpStatusError = 2
+)
+
+// otlpTraces groups spans by resource and instrumentation scope, in the order
+// they first appear.
+func otlpTraces(spans []sdktrace.ReadOnlySpan) *otlpTraceRequest {
+	request := &otlpTraceRequest{}
+	resources := make(map[attribute.Distinct]*otlpResourceSpans)
+	scopes := make(map[attribute.Distinct]map[instrumentation.Library]*otlpScopeSpans)
+	for _, span := range spans {
+		var key attribute.Distinct
+		rs := &otlpResourceSpans{}
+		if res := span.Resource(); res != nil {
+			key = res.Equivalent()
+			rs.Resource.Attributes = otlpAttributes(res.Attributes())
+			rs.SchemaURL = res.SchemaURL()
+		}
+		if existing, ok := resources[key]; ok {
+			rs = existing
+		} else {
+			resources[key] = rs
+			scopes[key] = make(map[instrumentation.Library]*otlpScopeSpans)
+			request.ResourceSpans = append(request.ResourceSpans, rs)
+		}
+
+		library := span.InstrumentationLibrary()
+		ss, ok := scopes[key][library]
+		if !ok {
+			ss = &otlpScopeSpans{Scope: otlpScope{Name: library.Name, Version: library.Version}, SchemaURL: library.SchemaURL}
+			scopes[key][library] = ss
+			rs.ScopeSpans = append(rs.ScopeSpans, ss)
+		}
+		ss.Spans = append(ss.Spans, otlpSpanOf(span))
+	}
+	return request
+}
+
+func otlpSpanOf(span sdktrace.ReadOnlySpan) otlpSpan {
+	sc := span.SpanContext()
+	s := otlpSpan{
+		TraceID:                sc.TraceID().String(),
+		SpanID:                 sc.SpanID().String(),
+		TraceState:             sc.TraceState().String(),
+		Name:                   span.Name(),
+		Kind:                   int(span.SpanKind()),
+		StartTimeUnixNano:      strconv.FormatInt(span.StartTime().UnixNano(), 10),
+		EndTimeUnixNano:        strconv.FormatInt(span.EndTime().UnixNano(), 10),
+		Attributes:             otlpAttributes(span.Attributes()),
+		DroppedAttributesCount: span.DroppedAttributes(),
+		DroppedEventsCount:     span.DroppedEvents(),
+		DroppedLinksCount:      span.DroppedLinks(),
+		Status:                 otlpStatusOf(span.Status()),
+	}
+	if span.SpanKind() == trace.SpanKindUnspecified {
+		s.Kind = int(trace.SpanKindInternal)
+	}
+	if parent := span.Parent(); parent.SpanID().IsValid() {
+		s.ParentSpanID = parent.SpanID().String()
+	}
+	for _, event := range span.Events() {
+		s.Events = append(s.Events, otlpEvent{
+			TimeUnixNano:           strconv.FormatInt(event.Time.UnixNano(), 10),
+			Name:                   event.Name,
+			Attributes:             otlpAttributes(event.Attributes),
+			DroppedAttributesCount: event.DroppedAttributeCount,
+		})
+	}
+	for _, link := range span.Links() {
+		s.Links = append(s.Links, otlpLink{
+			TraceID:                link.SpanContext.TraceID().String(),
+			SpanID:                 link.SpanContext.SpanID().String(),
+			TraceState:             link.SpanContext.TraceState().String(),
+			Attributes:             otlpAttributes(link.Attributes),
+			DroppedAttributesCount: link.DroppedAttributeCount,
+		})
+	}
+	return s
+}
+
+func otlpStatusOf(status sdktrace.Status) otlpStatus {
+	switch status.Code {
+	case codes.Error:
+		return otlpStatus{Code: otlpStatusError, Message: status.Description}
+	case codes.Ok:
+		return otlpStatus{Code: otlpStatusOk}
+	}
+	return otlpStatus{Code: otlpStatusUnset}
+}
+
+func otlpAttributes(attrs []attribute.KeyValue) []otlpKeyValue {
+	kvs := make([]otlpKeyValue, 0, len(attrs))
+	for _, kv := range attrs {
+		kvs = append(kvs, otlpKeyValue{Key: string(kv.Key), Value: otlpValue(kv.Value)})
+	}
+	return kvs
+}
+
+func otlpValue(v attribute.Value) otlpAnyValue {
+	switch v.Type() {
+	case attribute.BOOL:
+		b := v.AsBool()
+		return otlpAnyValue{BoolValue: &b}
+	case attribute.INT64:
+		return otlpAnyValue{IntValue: strconv.FormatInt(v.AsInt64(), 10)}
+	case attribute.FLOAT64:
+		f := v.AsFloat64()
+		return otlpAnyValue{DoubleValue: &f}
+	case attribute.BOOLSLICE:
+		values := make([]otlpAnyValue, 0)
+		for _, b := range v.AsBoolSlice() {
+			values = append(values, otlpValue(attribute.BoolValue(b)))
+		}
+		return otlpAnyValue{ArrayValue: &otlpArrayValue{Values: values}}
+	case attribute.INT64SLICE:
+		values := make([]otlpAnyValue, 0)
+		for _, i := range v.AsInt64Slice() {
+			values = append(values, otlpValue(attribute.Int64Value(i)))
+		}
+		return otlpAnyValue{ArrayValue: &otlpArrayValue{Values: values}}
+	case attribute.FLOAT64SLICE:
+		values := make([]otlpAnyValue, 0)
+		for _, f := range v.AsFloat64Slice() {
+			values = append(values, otlpValue(attribute.Float64Value(f)))
+		}
+		return otlpAnyValue{ArrayValue: &otlpArrayValue{Values: values}}
+	case attribute.STRINGSLICE:
+		values := make([]otlpAnyValue, 0)
+		for _, s := range v.AsStringSlice() {
+			values = append(values, otlpValue(attribute.StringValue(s)))
+		}
+		return otlpAnyValue{ArrayValue: &otlpArrayValue{Values: values}}
+	}
+	s := v.Emit()
+	return otlpAnyValue{StringValue: &s}
+}
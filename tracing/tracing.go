@@ -0,0 +1,105 @@
+// Package tracing sets up OpenTelemetry: the exporter spans are sent to, the
+// W3C trace context propagation of incoming requests and the spans of the
+// handler, service and repository layers.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"github.com/gorilla/mux"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.7.0"
+	"go.opentelemetry.io/otel/trace"
+	"net/http"
+	"os"
+	"precisely/response"
+)
+
+const instrumentationName = "precisely"
+
+// Configure installs the tracer provider of the given exporter: none (or
+// empty) to drop the spans, stdout to print them, stdout-file to append what
+// stdout prints to path instead, or otlp-file to append them to path as
+// OTLP/JSON. The stdout exporters write the span stubs of the OpenTelemetry
+// stdout exporter, meant for reading; otlp-file writes an
+// ExportTraceServiceRequest per line, which a collector can ingest. The
+// returned function flushes the spans left and must be called before exiting.
+func Configure(exporter, path string) (shutdown func(context.Context) error, err error) {
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	var exp sdktrace.SpanExporter
+	closer := func() error { return nil }
+	switch exporter {
+	case "", "none":
+		return func(context.Context) error { return nil }, nil
+	case "stdout":
+		exp, err = stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+	case "stdout-file", "otlp-file":
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return nil, err
+		}
+		closer = f.Close
+		if exporter == "otlp-file" {
+			exp = newOTLPFileExporter(f)
+			break
+		}
+		if exp, err = stdouttrace.New(stdouttrace.WithWriter(f)); err != nil {
+			closer()
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unsupported exporter %q, expect none, stdout, stdout-file or otlp-file", exporter)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exp),
+		sdktrace.WithResource(resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceNameKey.String(instrumentationName))),
+	)
+	otel.SetTracerProvider(provider)
+	return func(ctx context.Context) error {
+		err := provider.Shutdown(ctx)
+		if cerr := closer(); err == nil {
+			err = cerr
+		}
+		return err
+	}, nil
+}
+
+// Start starts a span of the service as a child of the one of ctx.
+func Start(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	return otel.Tracer(instrumentationName).Start(ctx, name, opts...)
+}
+
+// Middleware continues the trace of the traceparent header, if any, with a
+// server span named after the method and template of the mux route.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+
+		route := r.URL.Path
+		if current := mux.CurrentRoute(r); current != nil {
+			if template, err := current.GetPathTemplate(); err == nil {
+				route = template
+			}
+		}
+		ctx, span := Start(ctx, r.Method+" "+route,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(semconv.HTTPServerAttributesFromHTTPRequest(instrumentationName, route, r)...),
+		)
+		defer span.End()
+
+		recorder := response.NewRecorder(w)
+		next.ServeHTTP(recorder, r.WithContext(ctx))
+
+		span.SetAttributes(semconv.HTTPAttributesFromHTTPStatusCode(recorder.Code)...)
+		span.SetStatus(semconv.SpanStatusFromHTTPStatusCodeAndSpanKind(recorder.Code, trace.SpanKindServer))
+	})
+}
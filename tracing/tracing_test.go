@@ -0,0 +1,120 @@
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.7.0"
+	"go.opentelemetry.io/otel/trace"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func record(t *testing.T) *tracetest.SpanRecorder {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	return recorder
+}
+
+func TestMiddleware(t *testing.T) {
+	spans := record(t)
+	_, err := Configure("none", "")
+	assert.Nil(t, err)
+
+	r := mux.NewRouter()
+	r.HandleFunc("/documents/{id:[0-9]+}", func(w http.ResponseWriter, r *http.Request) {
+		_, span := Start(r.Context(), "GetByIdHandler")
+		span.End()
+		w.WriteHeader(http.StatusInternalServerError)
+	}).Methods("GET")
+	r.Use(Middleware)
+
+	req, _ := http.NewRequest(http.MethodGet, "/documents/1", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	ended := spans.Ended()
+	if !assert.Len(t, ended, 2) {
+		t.FailNow()
+	}
+	handler, server := ended[0], ended[1]
+	assert.Equal(t, "GET /documents/{id:[0-9]+}", server.Name())
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", server.SpanContext().TraceID().String())
+	assert.Equal(t, "00f067aa0ba902b7", server.Parent().SpanID().String())
+	assert.Contains(t, server.Attributes(), semconv.HTTPStatusCodeKey.Int(http.StatusInternalServerError))
+	assert.Equal(t, codes.Error, server.Status().Code)
+	assert.Equal(t, server.SpanContext().SpanID(), handler.Parent().SpanID())
+}
+
+func TestConfigure_StdoutFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "traces.jsonl")
+	shutdown, err := Configure("stdout-file", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, span := Start(context.Background(), "documentService.Create")
+	span.End()
+	assert.Nil(t, shutdown(context.Background()))
+
+	traces, err := ioutil.ReadFile(path)
+	assert.Nil(t, err)
+	assert.True(t, strings.Contains(string(traces), `"Name":"documentService.Create"`), string(traces))
+}
+
+func TestConfigure_OTLPFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "traces.jsonl")
+	shutdown, err := Configure("otlp-file", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, span := Start(context.Background(), "documentService.Create", trace.WithAttributes(attribute.Int64("document.id", 7)))
+	span.SetStatus(codes.Error, "failed")
+	span.End()
+	assert.Nil(t, shutdown(context.Background()))
+
+	traces, err := ioutil.ReadFile(path)
+	assert.Nil(t, err)
+	lines := strings.Split(strings.TrimSpace(string(traces)), "\n")
+	if !assert.Len(t, lines, 1) {
+		t.FailNow()
+	}
+	var request otlpTraceRequest
+	if err := json.Unmarshal([]byte(lines[0]), &request); err != nil {
+		t.Fatal(err)
+	}
+	if !assert.Len(t, request.ResourceSpans, 1) || !assert.Len(t, request.ResourceSpans[0].ScopeSpans, 1) {
+		t.FailNow()
+	}
+	resource := request.ResourceSpans[0].Resource
+	assert.Contains(t, resource.Attributes, otlpKeyValue{Key: "service.name", Value: otlpValue(attribute.StringValue("precisely"))})
+	scope := request.ResourceSpans[0].ScopeSpans[0]
+	assert.Equal(t, "precisely", scope.Scope.Name)
+	if !assert.Len(t, scope.Spans, 1) {
+		t.FailNow()
+	}
+	got := scope.Spans[0]
+	assert.Equal(t, "documentService.Create", got.Name)
+	assert.Len(t, got.TraceID, 32)
+	assert.Len(t, got.SpanID, 16)
+	assert.Equal(t, int(trace.SpanKindInternal), got.Kind)
+	assert.Equal(t, []otlpKeyValue{{Key: "document.id", Value: otlpAnyValue{IntValue: "7"}}}, got.Attributes)
+	assert.Equal(t, otlpStatus{Code: otlpStatusError, Message: "failed"}, got.Status)
+	assert.Contains(t, lines[0], `"startTimeUnixNano":"`)
+}
+
+func TestConfigure_Unsupported(t *testing.T) {
+	for _, exporter := range []string{"zipkin", "file"} {
+		_, err := Configure(exporter, "")
+		assert.NotNil(t, err, exporter)
+	}
+}
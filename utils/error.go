@@ -0,0 +1,98 @@
+package utils
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"precisely/model"
+	"strings"
+)
+
+// StatusClientClosedRequest is answered when the client went away before the
+// request could be served.
+const StatusClientClosedRequest = 499
+
+// ProblemType is the content type of the RFC 7807 problem details errors are
+// answered with when ProblemDetails is set.
+const ProblemType = "application/problem+json"
+
+// ProblemDetails has errors answered as RFC 7807 problem details instead of
+// an HttpResponse.
+var ProblemDetails bool
+
+// Problem is an RFC 7807 problem details document, extended with the code of
+// the error and the ID the request is logged under.
+type Problem struct {
+	Type      string `json:"type"`
+	Title     string `json:"title"`
+	Status    int    `json:"status"`
+	Detail    string `json:"detail,omitempty"`
+	Code      string `json:"code"`
+	RequestID string `json:"request_id,omitempty"`
+}
+
+// kindStatus is the status the errors of each kind are answered with.
+var kindStatus = map[model.ErrorKind]int{
+	model.KindInvalid:              http.StatusBadRequest,
+	model.KindValidation:           http.StatusUnprocessableEntity,
+	model.KindNotFound:             http.StatusNotFound,
+	model.KindConflict:             http.StatusConflict,
+	model.KindPreconditionFailed:   http.StatusPreconditionFailed,
+	model.KindPreconditionRequired: http.StatusPreconditionRequired,
+	model.KindUnsupportedMediaType: http.StatusUnsupportedMediaType,
+	model.KindUnauthenticated:      http.StatusUnauthorized,
+	model.KindForbidden:            http.StatusForbidden,
+	model.KindUnavailable:          http.StatusServiceUnavailable,
+	model.KindTimeout:              http.StatusGatewayTimeout,
+	model.KindCanceled:             StatusClientClosedRequest,
+	model.KindInternal:             http.StatusInternalServerError,
+}
+
+// ErrorStatus is the status err is answered with, after its kind.
+func ErrorStatus(err error) int {
+	if status, ok := kindStatus[model.ErrorOf(err).Kind]; ok {
+		return status
+	}
+	return http.StatusInternalServerError
+}
+
+// PublicError returns err when it is or wraps an Error, and otherwise the
+// Error it stands for, so that the message of a driver never reaches clients.
+func PublicError(err error) error {
+	if e := model.ErrorOf(err); !errors.Is(err, e) {
+		return e
+	}
+	return err
+}
+
+// RespondError answers err with the status of its kind and its code. The
+// access log records the error the client is not shown, if any.
+func RespondError(w http.ResponseWriter, err error) {
+	err = PublicError(err)
+	JsonRespond(w, false, ErrorStatus(err), err, nil)
+}
+
+// errorCode is the code of err, or else one named after the status it is
+// answered with, such as bad_request.
+func errorCode(code int, err error) string {
+	var coded interface{ ErrorCode() string }
+	if errors.As(err, &coded) {
+		return coded.ErrorCode()
+	}
+	return strings.ReplaceAll(strings.ToLower(http.StatusText(code)), " ", "_")
+}
+
+func respondProblem(w http.ResponseWriter, code int, err error) {
+	problem := Problem{
+		Type:      "about:blank",
+		Title:     http.StatusText(code),
+		Status:    code,
+		Detail:    err.Error(),
+		Code:      errorCode(code, err),
+		RequestID: w.Header().Get(RequestIDHeader),
+	}
+	recordError(w, err)
+	w.Header().Set("Content-Type", ProblemType)
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(problem)
+}
@@ -0,0 +1,85 @@
+package utils
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/go-sql-driver/mysql"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"precisely/model"
+	"testing"
+)
+
+func TestRespondError(t *testing.T) {
+	tests := []struct {
+		name    string
+		err     error
+		code    int
+		message string
+		errCode string
+	}{
+		{name: "Validation", err: model.TitleInvalidValue, code: http.StatusUnprocessableEntity, message: model.TitleInvalidValue.Error(), errCode: "title_invalid"},
+		{name: "Wrapped", err: fmt.Errorf("%w: token has another issuer", model.UnauthenticatedValue), code: http.StatusUnauthorized, message: model.UnauthenticatedValue.Error() + ": token has another issuer", errCode: "unauthenticated"},
+		{name: "Not found", err: sql.ErrNoRows, code: http.StatusNotFound, message: model.NotFoundValue.Error(), errCode: "not_found"},
+		{name: "Duplicate title", err: &mysql.MySQLError{Number: 1062, Message: "Duplicate entry 'title' for key 'title'"}, code: http.StatusConflict, message: model.DuplicateTitleValue.Error(), errCode: "duplicate_title"},
+		{name: "Internal", err: errors.New("dial tcp 10.0.0.1:3306: secret"), code: http.StatusInternalServerError, message: model.InternalValue.Error(), errCode: "internal"},
+		{name: "Canceled", err: model.CanceledValue, code: StatusClientClosedRequest, message: model.CanceledValue.Error(), errCode: "canceled"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			RespondError(rr, tt.err)
+
+			var res HttpResponse
+			if err := json.Unmarshal(rr.Body.Bytes(), &res); err != nil {
+				t.Fatal(err)
+			}
+			assert.Equal(t, tt.code, rr.Code)
+			assert.Equal(t, tt.code, res.Code)
+			assert.Equal(t, tt.message, res.Error)
+			assert.Equal(t, tt.errCode, res.ErrorCode)
+		})
+	}
+}
+
+func TestJsonRespond_ErrorCodeOfStatus(t *testing.T) {
+	rr := httptest.NewRecorder()
+	JsonRespond(rr, false, http.StatusBadRequest, errors.New("unexpected EOF"), nil)
+
+	var res HttpResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &res); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "bad_request", res.ErrorCode)
+}
+
+func TestRespondError_ProblemDetails(t *testing.T) {
+	ProblemDetails = true
+	defer func() { ProblemDetails = false }()
+
+	rr := httptest.NewRecorder()
+	rr.Header().Set(RequestIDHeader, "r1")
+	RespondError(rr, model.VersionMismatchValue)
+
+	var problem Problem
+	if err := json.Unmarshal(rr.Body.Bytes(), &problem); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, http.StatusPreconditionFailed, rr.Code)
+	assert.Equal(t, ProblemType, rr.Header().Get("Content-Type"))
+	assert.Equal(t, Problem{
+		Type:      "about:blank",
+		Title:     "Precondition Failed",
+		Status:    http.StatusPreconditionFailed,
+		Detail:    model.VersionMismatchValue.Error(),
+		Code:      "version_mismatch",
+		RequestID: "r1",
+	}, problem)
+
+	rr = httptest.NewRecorder()
+	JsonRespond(rr, true, http.StatusOK, nil, "data")
+	assert.Contains(t, rr.Body.String(), `"data":"data"`, "successes are answered as usual")
+}
@@ -5,24 +5,65 @@ import (
 	"net/http"
 )
 
+// RequestIDHeader carries the ID a request is logged under, set on the
+// response by the logging middleware.
+const RequestIDHeader = "X-Request-ID"
+
 type HttpResponse struct {
-	Code   int         `json:"code"`
-	Status bool        `json:"status"`
-	Data   interface{} `json:"data"`
-	Error  string      `json:"error"`
+	Code      int         `json:"code"`
+	Status    bool        `json:"status"`
+	Data      interface{} `json:"data"`
+	Meta      interface{} `json:"meta,omitempty"`
+	Error     string      `json:"error"`
+	ErrorCode string      `json:"error_code,omitempty"`
+	RequestID string      `json:"request_id,omitempty"`
+}
+
+type PageMeta struct {
+	Total      int64  `json:"total"`
+	Limit      int    `json:"limit"`
+	Offset     int    `json:"offset"`
+	NextCursor string `json:"next_cursor,omitempty"`
 }
 
 func JsonRespond(w http.ResponseWriter, status bool, code int, err error, data interface{}) {
+	JsonRespondWithMeta(w, status, code, err, data, nil)
+}
+
+func JsonRespondWithMeta(w http.ResponseWriter, status bool, code int, err error, data interface{}, meta interface{}) {
+	if err != nil && ProblemDetails {
+		respondProblem(w, code, err)
+		return
+	}
 
 	response := HttpResponse{
-		Status: status,
-		Code:   code,
-		Data:   data,
+		Status:    status,
+		Code:      code,
+		Data:      data,
+		Meta:      meta,
+		RequestID: w.Header().Get(RequestIDHeader),
 	}
 	if err != nil {
 		response.Error = err.Error()
+		response.ErrorCode = errorCode(code, err)
+		recordError(w, err)
 	}
 
 	w.WriteHeader(code)
 	json.NewEncoder(w).Encode(response)
 }
+
+// recordError hands err to every wrapped response writer that records the
+// error of a response, such as the one of the access log.
+func recordError(w http.ResponseWriter, err error) {
+	for {
+		if rw, ok := w.(interface{ RecordError(error) }); ok {
+			rw.RecordError(err)
+		}
+		rw, ok := w.(interface{ Unwrap() http.ResponseWriter })
+		if !ok {
+			return
+		}
+		w = rw.Unwrap()
+	}
+}